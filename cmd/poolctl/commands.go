@@ -0,0 +1,446 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+)
+
+// command is a poolctl subcommand. Commands that do not talk to the service get a nil
+// client.
+type command struct {
+	usage   string
+	summary string
+	offline bool
+	run     func(ctx context.Context, env *env, args []string) error
+}
+
+// env is what a command runs with
+type env struct {
+	client  pb.ConnectionPoolServiceClient
+	printer *printer
+	// timeout bounds each call to the service
+	timeout time.Duration
+}
+
+// call returns a context bounding one call to the service
+func (e *env) call(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, e.timeout)
+}
+
+var errUsage = errors.New("invalid usage")
+
+var commands = map[string]command{
+	"acquire": {
+		usage:   "acquire [-dsn DSN] [-read-only] [-min-lsn LSN] TENANT",
+		summary: "Lease a connection and print its ID",
+		run:     runAcquire,
+	},
+	"release": {
+		usage:   "release TENANT CONNECTION_ID",
+		summary: "Release a leased connection",
+		run:     runRelease,
+	},
+	"stats": {
+		usage:   "stats TENANT",
+		summary: "Show a tenant's pool statistics",
+		run:     runStats,
+	},
+	"pools": {
+		usage:   "pools [-tenant TENANT]",
+		summary: "List open pools",
+		run:     runPools,
+	},
+	"leases": {
+		usage:   "leases [-tenant TENANT] [-limit 10]",
+		summary: "List the longest-held leases of each tenant and who holds them",
+		run:     runLeases,
+	},
+	"watch": {
+		usage:   "watch [-interval 2s] [-count N] [-changes] [-threshold 0.9] [TENANT...]",
+		summary: "Stream pool statistics of some or all tenants",
+		run:     runWatch,
+	},
+	"drain": {
+		usage:   "drain [-timeout 30s] TENANT",
+		summary: "Stop new leases, wait for existing ones and close the tenant's pools",
+		run:     runDrain,
+	},
+	"evict": {
+		usage:   "evict TENANT",
+		summary: "End the tenant's leases now and close its pools",
+		run:     runEvict,
+	},
+	"validate-dsn": {
+		usage:   "validate-dsn DSN",
+		summary: "Check that a DSN parses and show where it points",
+		offline: true,
+		run:     runValidateDSN,
+	},
+	"check-dsn": {
+		usage:   "check-dsn [-connect-timeout 10s] DSN",
+		summary: "Have the service connect to a DSN and report each stage and what it found",
+		run:     runCheckDSN,
+	},
+}
+
+// parseArgs parses a command's flags and checks it got exactly one positional argument
+func parseArgs(fs *flag.FlagSet, args []string) (string, error) {
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return "", fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() != 1 {
+		return "", errUsage
+	}
+	return fs.Arg(0), nil
+}
+
+func runAcquire(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("acquire", flag.ContinueOnError)
+	dsn := fs.String("dsn", "", "DSN to connect to instead of the tenant's configured one")
+	readOnly := fs.Bool("read-only", false, "Route to a replica")
+	minLSN := fs.String("min-lsn", "", "Require a replica that has replayed this LSN")
+	tenantID, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := e.call(ctx)
+	defer cancel()
+	resp, err := e.client.GetConnection(ctx, &pb.ConnectionRequest{TenantId: tenantID, Dsn: *dsn, ReadOnly: *readOnly, MinLsn: *minLSN, CallSite: "poolctl"})
+	if err != nil {
+		return err
+	}
+	return e.printer.message(resp, []string{"CONNECTION_ID", "FROM_REPLICA"},
+		[]string{resp.ConnectionId, strconv.FormatBool(resp.FromReplica)})
+}
+
+func runRelease(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("release", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() != 2 {
+		return errUsage
+	}
+	tenantID, connectionID := fs.Arg(0), fs.Arg(1)
+
+	ctx, cancel := e.call(ctx)
+	defer cancel()
+	resp, err := e.client.ReleaseConnection(ctx, &pb.ConnectionRelease{ConnectionId: connectionID, TenantId: tenantID})
+	if err != nil {
+		return err
+	}
+	return e.printer.message(resp, nil, []string{"released " + connectionID})
+}
+
+var statsHeader = []string{"ACTIVE", "IDLE", "TOTAL"}
+
+func statsRow(resp *pb.StatsResponse) []string {
+	return []string{
+		strconv.Itoa(int(resp.ActiveConnections)),
+		strconv.Itoa(int(resp.IdleConnections)),
+		strconv.Itoa(int(resp.TotalConnections)),
+	}
+}
+
+func runStats(ctx context.Context, e *env, args []string) error {
+	tenantID, err := parseArgs(flag.NewFlagSet("stats", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := e.call(ctx)
+	defer cancel()
+	resp, err := e.client.GetPoolStats(ctx, &pb.StatsRequest{TenantId: tenantID})
+	if err != nil {
+		return err
+	}
+	return e.printer.message(resp, statsHeader, statsRow(resp))
+}
+
+func runPools(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("pools", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	tenantID := fs.String("tenant", "", "Only list this tenant's pools")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	ctx, cancel := e.call(ctx)
+	defer cancel()
+	resp, err := e.client.ListPools(ctx, &pb.ListPoolsRequest{TenantId: *tenantID})
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, len(resp.Pools))
+	for i, p := range resp.Pools {
+		rows[i] = []string{
+			p.TenantId,
+			p.Server,
+			strconv.Itoa(int(p.ActiveConnections)),
+			strconv.Itoa(int(p.IdleConnections)),
+			strconv.Itoa(int(p.TotalConnections)),
+			strconv.Itoa(int(p.MaxConnections)),
+			strconv.Itoa(int(p.Leases)),
+			strconv.FormatBool(p.Draining),
+		}
+	}
+	return e.printer.message(resp, []string{"TENANT", "SERVER", "ACTIVE", "IDLE", "TOTAL", "MAX", "LEASES", "DRAINING"}, rows...)
+}
+
+func runLeases(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("leases", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	tenantID := fs.String("tenant", "", "Only list this tenant's leases")
+	limit := fs.Int("limit", 10, "Leases to list per tenant (0 lists all)")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	ctx, cancel := e.call(ctx)
+	defer cancel()
+	resp, err := e.client.ListLeases(ctx, &pb.ListLeasesRequest{TenantId: *tenantID, Limit: int32(*limit)})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	rows := make([][]string, len(resp.Leases))
+	for i, l := range resp.Leases {
+		client := l.Identity
+		if client == "" {
+			client = l.Peer
+		} else if l.Peer != "" {
+			client += "@" + l.Peer
+		}
+		rows[i] = []string{
+			l.TenantId,
+			l.Handle,
+			client,
+			l.CallSite,
+			now.Sub(l.AcquiredAt.AsTime()).Round(time.Second).String(),
+			now.Sub(l.LastActiveAt.AsTime()).Round(time.Second).String(),
+			strconv.FormatBool(l.Attached),
+		}
+	}
+	return e.printer.message(resp, []string{"TENANT", "LEASE", "CLIENT", "CALL_SITE", "HELD", "IDLE", "ATTACHED"}, rows...)
+}
+
+// runWatch polls a tenant's stats until interrupted, or count times when count is set.
+// Tables print a row per poll under a single header.
+func runWatch(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	interval := fs.Duration("interval", 2*time.Second, "Time between updates")
+	count := fs.Int("count", 0, "Stop after this many updates (0 runs until interrupted)")
+	changes := fs.Bool("changes", false, "Only show tenants whose stats changed")
+	threshold := fs.Float64("threshold", 0, "Show tenants as soon as their utilization crosses this fraction of max connections")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("%w: interval must be positive", errUsage)
+	}
+	if *threshold < 0 || *threshold > 1 {
+		return fmt.Errorf("%w: threshold must be between 0 and 1", errUsage)
+	}
+
+	// The stream lasts until interrupted, so it is not bounded by the call timeout
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := e.client.WatchPoolStats(ctx, &pb.WatchPoolStatsRequest{
+		TenantIds:            fs.Args(),
+		IntervalMs:           int32(max(*interval/time.Millisecond, 1)),
+		DeltasOnly:           *changes,
+		UtilizationThreshold: *threshold,
+	})
+	if err != nil {
+		return err
+	}
+
+	header := append([]string{"TIME", "TENANT"}, statsHeader...)
+	header = append(header, "MAX", "ALERT")
+	for n := 0; *count == 0 || n < *count; n++ {
+		resp, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		rows := make([][]string, 0, len(resp.Tenants))
+		for _, tenant := range resp.Tenants {
+			alert := ""
+			if tenant.OverThreshold {
+				alert = "over threshold"
+			}
+			rows = append(rows, []string{
+				resp.Time.AsTime().Local().Format(time.TimeOnly),
+				tenant.TenantId,
+				strconv.Itoa(int(tenant.ActiveConnections)),
+				strconv.Itoa(int(tenant.IdleConnections)),
+				strconv.Itoa(int(tenant.TotalConnections)),
+				strconv.Itoa(int(tenant.MaxConnections)),
+				alert,
+			})
+		}
+		if err := e.printer.message(resp, header, rows...); err != nil {
+			return err
+		}
+		header = nil
+	}
+	return nil
+}
+
+func runDrain(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("drain", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 30*time.Second, "How long to wait for leases before evicting them")
+	tenantID, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	// The call lasts as long as the drain, so it gets the drain timeout on top
+	ctx, cancel := context.WithTimeout(ctx, e.timeout+*timeout)
+	defer cancel()
+	resp, err := e.client.DrainTenant(ctx, &pb.DrainRequest{TenantId: tenantID, TimeoutSeconds: int32(timeout.Round(time.Second) / time.Second)})
+	if err != nil {
+		return err
+	}
+	return e.printer.message(resp, []string{"EVICTED_LEASES", "CLOSED_POOLS"},
+		[]string{strconv.Itoa(int(resp.EvictedLeases)), strconv.Itoa(int(resp.ClosedPools))})
+}
+
+func runEvict(ctx context.Context, e *env, args []string) error {
+	tenantID, err := parseArgs(flag.NewFlagSet("evict", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := e.call(ctx)
+	defer cancel()
+	resp, err := e.client.EvictTenant(ctx, &pb.EvictRequest{TenantId: tenantID})
+	if err != nil {
+		return err
+	}
+	return e.printer.message(resp, []string{"EVICTED_LEASES", "CLOSED_POOLS"},
+		[]string{strconv.Itoa(int(resp.EvictedLeases)), strconv.Itoa(int(resp.ClosedPools))})
+}
+
+// dsnInfo is where a DSN points. The password is never shown.
+type dsnInfo struct {
+	Valid    bool   `json:"valid"`
+	Host     string `json:"host,omitempty"`
+	Port     uint16 `json:"port,omitempty"`
+	Database string `json:"database,omitempty"`
+	User     string `json:"user,omitempty"`
+	TLS      bool   `json:"tls"`
+	Error    string `json:"error,omitempty"`
+}
+
+func runValidateDSN(ctx context.Context, e *env, args []string) error {
+	dsn, err := parseArgs(flag.NewFlagSet("validate-dsn", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+
+	config, err := pgconn.ParseConfig(dsn)
+	info := dsnInfo{Valid: err == nil}
+	if err != nil {
+		info.Error = err.Error()
+	} else {
+		info.Host = config.Host
+		info.Port = config.Port
+		info.Database = config.Database
+		info.User = config.User
+		info.TLS = config.TLSConfig != nil
+	}
+
+	if e.printer.format == FormatJSON {
+		if perr := e.printer.value(info); perr != nil {
+			return perr
+		}
+	} else if err == nil {
+		perr := e.printer.table([]string{"HOST", "PORT", "DATABASE", "USER", "TLS"},
+			[]string{info.Host, strconv.Itoa(int(info.Port)), info.Database, info.User, strconv.FormatBool(info.TLS)})
+		if perr != nil {
+			return perr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("invalid DSN: %w", err)
+	}
+	return nil
+}
+
+func runCheckDSN(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("check-dsn", flag.ContinueOnError)
+	connectTimeout := fs.Duration("connect-timeout", 0, "How long the service may take; its default when zero")
+	dsn, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	// The call may take as long as the service is allowed to
+	ctx, cancel := context.WithTimeout(ctx, max(e.timeout, *connectTimeout+time.Second))
+	defer cancel()
+	resp, err := e.client.ValidateDSN(ctx, &pb.ValidateDSNRequest{Dsn: dsn, TimeoutMs: int32(connectTimeout.Milliseconds())})
+	if err != nil {
+		return err
+	}
+
+	if e.printer.format == FormatJSON {
+		if err := e.printer.message(resp, nil); err != nil {
+			return err
+		}
+	} else {
+		rows := make([][]string, len(resp.Stages))
+		for i, stage := range resp.Stages {
+			latency := ""
+			if stage.Status != "skipped" {
+				latency = time.Duration(stage.LatencyMs * float64(time.Millisecond)).Round(time.Microsecond).String()
+			}
+			rows[i] = []string{stage.Stage, stage.Status, latency, stage.Error}
+		}
+		if err := e.printer.table([]string{"STAGE", "STATUS", "LATENCY", "ERROR"}, rows...); err != nil {
+			return err
+		}
+		if resp.Valid {
+			extensions := make([]string, len(resp.Extensions))
+			for i, ext := range resp.Extensions {
+				extensions[i] = ext.Name + "@" + ext.Version
+			}
+			fmt.Fprintln(e.printer.out)
+			err := e.printer.table([]string{"DSN", "VERSION", "MAX_CONNECTIONS", "ROLE", "EXTENSIONS"}, []string{
+				resp.NormalizedDsn,
+				resp.ServerVersion,
+				strconv.Itoa(int(resp.MaxConnections)),
+				resp.GetPrivileges().GetRole(),
+				strings.Join(extensions, ","),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if !resp.Valid {
+		return fmt.Errorf("DSN check failed: %s", resp.Error)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Command poolctl manages a running connection pool manager over gRPC
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+	"google.golang.org/grpc/status"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run parses the global flags, runs the subcommand and returns the exit code
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	var (
+		opts    ClientOptions
+		format  string
+		timeout time.Duration
+	)
+	fs := flag.NewFlagSet("poolctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.Addr, "addr", envOr("POOLCTL_ADDR", "localhost:50052"), "gRPC address of the service ($POOLCTL_ADDR)")
+	fs.StringVar(&opts.CAFile, "ca", "", "CA bundle that signed the server certificate (system roots when empty)")
+	fs.StringVar(&opts.CertFile, "cert", "", "Client certificate for mTLS")
+	fs.StringVar(&opts.KeyFile, "key", "", "Client certificate key for mTLS")
+	fs.StringVar(&opts.ServerName, "server-name", "", "Name expected on the server certificate (defaults to the host in -addr)")
+	fs.BoolVar(&opts.InsecureSkipVerify, "insecure-skip-verify", false, "Do not verify the server certificate")
+	fs.BoolVar(&opts.Plaintext, "plaintext", false, "Connect without TLS")
+	fs.StringVar(&opts.Token, "token", os.Getenv("POOLCTL_TOKEN"), "Bearer token ($POOLCTL_TOKEN)")
+	fs.StringVar(&format, "o", FormatTable, "Output format: table or json")
+	fs.DurationVar(&timeout, "timeout", 10*time.Second, "Timeout of each call to the service")
+	fs.Usage = func() { usage(fs) }
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if format != FormatTable && format != FormatJSON {
+		fmt.Fprintf(stderr, "poolctl: unknown output format %q\n", format)
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	name := fs.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "poolctl: unknown command %q\n", name)
+		fs.Usage()
+		return 2
+	}
+
+	e := &env{printer: &printer{out: stdout, format: format}, timeout: timeout}
+	if !cmd.offline {
+		conn, err := dial(opts)
+		if err != nil {
+			fmt.Fprintf(stderr, "poolctl: %v\n", err)
+			return 1
+		}
+		defer conn.Close()
+		e.client = pb.NewConnectionPoolServiceClient(conn)
+	}
+
+	if err := cmd.run(ctx, e, fs.Args()[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintf(stderr, "poolctl: %v\nusage: poolctl [flags] %s\n", err, cmd.usage)
+			return 2
+		}
+		if s, ok := status.FromError(err); ok {
+			fmt.Fprintf(stderr, "poolctl: %s: %s\n", s.Code(), s.Message())
+		} else {
+			fmt.Fprintf(stderr, "poolctl: %v\n", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+func usage(fs *flag.FlagSet) {
+	out := fs.Output()
+	fmt.Fprintf(out, "usage: poolctl [flags] COMMAND [args]\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-14s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	fs.PrintDefaults()
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Output formats accepted by -o
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+)
+
+// printer writes command results as aligned tables or, for scripts, one JSON document
+// per result
+type printer struct {
+	out    io.Writer
+	format string
+}
+
+var jsonOptions = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+
+// message prints a response message as JSON, or as a table of the given columns
+func (p *printer) message(msg proto.Message, header []string, rows ...[]string) error {
+	if p.format == FormatJSON {
+		data, err := jsonOptions.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		// Compact through encoding/json so each result is on one line
+		return p.value(json.RawMessage(data))
+	}
+	return p.table(header, rows...)
+}
+
+// value prints any JSON-encodable value on one line
+func (p *printer) value(v interface{}) error {
+	return json.NewEncoder(p.out).Encode(v)
+}
+
+func (p *printer) table(header []string, rows ...[]string) error {
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	if header != nil {
+		fmt.Fprintln(w, strings.Join(header, "\t"))
+	}
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
@@ -5,6 +5,9 @@ import (
 	"os"
 	"testing"
 	"time"
+
+	"github.com/teresa-solution/connection-pool-manager/internal/service"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
 )
 
 // TestLoadTLSCredentials_WithValidCerts tests loading valid certificates
@@ -68,7 +71,7 @@ func TestSetupGRPCServer_WithValidCreds(t *testing.T) {
 	}
 
 	// Test gRPC server setup
-	server := setupGRPCServer(creds)
+	server := setupGRPCServer(creds, service.NewConnectionPoolServiceServerWithManager(pool.NewConnectionPoolManager()))
 	if server == nil {
 		t.Error("setupGRPCServer() returned nil")
 	}
@@ -236,7 +239,7 @@ func TestConcurrentServerStarts(t *testing.T) {
 			t.Fatalf("Failed to load credentials: %v", err)
 		}
 
-		server := setupGRPCServer(creds)
+		server := setupGRPCServer(creds, service.NewConnectionPoolServiceServerWithManager(pool.NewConnectionPoolManager()))
 		if server == nil {
 			t.Errorf("Server %d creation failed", i)
 		} else {
@@ -302,7 +305,7 @@ func TestMemoryUsage(t *testing.T) {
 	}
 
 	// Test that gRPC servers can be stopped
-	server := setupGRPCServer(nil)
+	server := setupGRPCServer(nil, service.NewConnectionPoolServiceServerWithManager(pool.NewConnectionPoolManager()))
 	server.Stop() // Should not panic or cause issues
 }
 
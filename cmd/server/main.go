@@ -1,20 +1,38 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/teresa-solution/connection-pool-manager/internal/auth"
+	"github.com/teresa-solution/connection-pool-manager/internal/gateway"
+	"github.com/teresa-solution/connection-pool-manager/internal/health"
+	"github.com/teresa-solution/connection-pool-manager/internal/logging"
+	"github.com/teresa-solution/connection-pool-manager/internal/proxy"
 	"github.com/teresa-solution/connection-pool-manager/internal/service"
+	"github.com/teresa-solution/connection-pool-manager/internal/tracing"
+	"github.com/teresa-solution/connection-pool-manager/internal/webhook"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
 )
 
 // Config holds the application configuration
@@ -23,34 +41,175 @@ type Config struct {
 	CertFile string
 	KeyFile  string
 	HTTPPort string
+
+	// TenantsFile is an optional JSON file of per-tenant pool settings
+	TenantsFile          string
+	ReplicaCheckInterval time.Duration
+
+	// LeaseTTL is how long leases of tenants without their own lease_ttl live unrenewed
+	LeaseTTL time.Duration
+	// LeaseReapInterval is how often expired leases are reclaimed
+	LeaseReapInterval time.Duration
+	// LeaseHoldWarning is how long a lease is held before a warning is logged about it
+	LeaseHoldWarning time.Duration
+	// StatsHistoryInterval is how often pool stats are sampled for GetPoolStatsHistory
+	StatsHistoryInterval time.Duration
+	// StatsHistoryRetention is how long stats samples are kept
+	StatsHistoryRetention time.Duration
+	// EventBuffer is how many recent pool events are kept for WatchEvents replay
+	EventBuffer int
+	// WatchdogInterval is how often backends are checked against the tenants' watchdog limits
+	WatchdogInterval time.Duration
+
+	// ProxyAddr enables the PostgreSQL wire-protocol proxy when set
+	ProxyAddr string
+
+	Tracing tracing.Config
+	Logging logging.Config
+
+	// RegistryDSN is the tenant registry database checked for readiness. Not checked when empty.
+	RegistryDSN string
+	// DrainDelay is how long the service reports not ready before it stops on SIGTERM
+	DrainDelay time.Duration
+
+	// AuthTokensFile is a JSON file of bearer tokens accepted on gRPC and HTTP APIs
+	AuthTokensFile string
+	// ClientCAFile lets clients authenticate with certificates signed by this CA
+	ClientCAFile string
+	// AdminCerts are the client certificate common names allowed to call admin methods
+	AdminCerts []string
+
+	// DSNPolicy limits what tenant DSNs may point to
+	DSNPolicy pool.DSNPolicy
+
+	// CreationLimits bounds how many pools are created at once
+	CreationLimits pool.CreationLimits
+
+	// Prewarm opens the tenants' pools at startup on top of those whose config asks for it
+	Prewarm pool.PrewarmOptions
+
+	// WebhooksFile is a JSON file of webhooks alerted about pool trouble. No alerts are sent when empty.
+	WebhooksFile string
+	// WebhookDeadLetterFile gets the webhook payloads that could not be delivered
+	WebhookDeadLetterFile string
+}
+
+// defaultConfig returns the configuration used when no flags are given
+func defaultConfig() Config {
+	return Config{
+		Port:                  50052,
+		CertFile:              "certs/cert.pem",
+		KeyFile:               "certs/key.pem",
+		HTTPPort:              ":8082",
+		ReplicaCheckInterval:  10 * time.Second,
+		LeaseTTL:              pool.DefaultLeaseTTL,
+		LeaseReapInterval:     10 * time.Second,
+		LeaseHoldWarning:      pool.DefaultHoldWarning,
+		WatchdogInterval:      time.Second,
+		EventBuffer:           pool.DefaultEventBuffer,
+		StatsHistoryInterval:  pool.DefaultHistoryInterval,
+		StatsHistoryRetention: pool.DefaultHistoryRetention,
+		CreationLimits:        pool.CreationLimits{Global: 16, PerHost: 4, Warmup: pool.DefaultCreationWarmup},
+		Prewarm:               pool.PrewarmOptions{Concurrency: pool.DefaultPrewarmConcurrency},
+	}
 }
 
 // parseFlags parses command line flags and returns configuration
 func parseFlags() Config {
+	config := defaultConfig()
+	var logSampleRate uint
+	var allowedHosts, adminCerts string
+	var prewarm, prewarmCritical string
+
 	// Only define and parse flags if they haven't already been parsed
 	// This prevents conflicts with test flags
 	if !flag.Parsed() {
-		var port = flag.Int("port", 50052, "Port gRPC server")
+		flag.IntVar(&config.Port, "port", config.Port, "Port gRPC server")
+		flag.StringVar(&config.TenantsFile, "tenants", config.TenantsFile, "JSON file with per-tenant pool settings")
+		flag.DurationVar(&config.ReplicaCheckInterval, "replica-check-interval", config.ReplicaCheckInterval, "Interval between replica lag checks")
+		flag.DurationVar(&config.LeaseTTL, "lease-ttl", config.LeaseTTL, "Default time a lease lives without renewal")
+		flag.DurationVar(&config.LeaseReapInterval, "lease-reap-interval", config.LeaseReapInterval, "Interval between reclaims of expired leases")
+		flag.DurationVar(&config.LeaseHoldWarning, "lease-hold-warning", config.LeaseHoldWarning, "Log a warning about leases held longer than this (0 disables)")
+		flag.DurationVar(&config.StatsHistoryInterval, "stats-history-interval", config.StatsHistoryInterval, "Interval between samples of pool stats kept for GetPoolStatsHistory (0 disables)")
+		flag.DurationVar(&config.StatsHistoryRetention, "stats-history-retention", config.StatsHistoryRetention, "How long pool stats samples are kept")
+		flag.IntVar(&config.EventBuffer, "event-buffer", config.EventBuffer, "Number of recent pool events kept for WatchEvents replay")
+		flag.DurationVar(&config.WatchdogInterval, "watchdog-interval", config.WatchdogInterval, "Interval between checks of backends against the tenants' watchdog limits")
+		flag.StringVar(&config.ProxyAddr, "proxy-addr", config.ProxyAddr, "Address of the PostgreSQL proxy listener, e.g. :6432 (disabled when empty)")
+		flag.StringVar(&config.Tracing.Exporter, "trace-exporter", config.Tracing.Exporter, "Trace exporter: none, stdout or otlp")
+		flag.StringVar(&config.Tracing.OTLPEndpoint, "otlp-endpoint", config.Tracing.OTLPEndpoint, "OTLP gRPC collector address, e.g. localhost:4317")
+		flag.BoolVar(&config.Tracing.OTLPInsecure, "otlp-insecure", config.Tracing.OTLPInsecure, "Connect to the OTLP collector without TLS")
+		flag.StringVar(&config.AuthTokensFile, "auth-tokens", config.AuthTokensFile, "JSON file of accepted bearer tokens (authentication disabled when neither this nor -client-ca is set)")
+		flag.StringVar(&config.ClientCAFile, "client-ca", config.ClientCAFile, "CA bundle for authenticating clients by certificate")
+		flag.StringVar(&adminCerts, "admin-certs", "", "Comma-separated client certificate common names allowed to list leases and drain or evict tenants")
+		flag.StringVar(&config.WebhooksFile, "webhooks", config.WebhooksFile, "JSON file of webhooks alerted about saturation, failures, leaks and failovers (disabled when empty)")
+		flag.StringVar(&config.WebhookDeadLetterFile, "webhook-dead-letter", config.WebhookDeadLetterFile, "File appended with webhook payloads that could not be delivered (only logged when empty)")
+		flag.StringVar(&allowedHosts, "dsn-allowed-hosts", "", "Comma-separated host patterns tenant DSNs may point to, e.g. *.db.internal (any host when empty)")
+		flag.BoolVar(&config.DSNPolicy.RequireTLS, "dsn-require-tls", false, "Reject tenant DSNs that may connect without TLS")
+		flag.IntVar(&config.CreationLimits.Global, "creation-concurrency", config.CreationLimits.Global, "Pools created at once across all database hosts (unlimited when 0)")
+		flag.IntVar(&config.CreationLimits.PerHost, "creation-host-concurrency", config.CreationLimits.PerHost, "Pools created at once against one database host (unlimited when 0)")
+		flag.DurationVar(&config.CreationLimits.Warmup, "creation-warmup", config.CreationLimits.Warmup, "Longest a pool creation holds its slot while its minimum connections open")
+		flag.StringVar(&prewarm, "prewarm", "", "Comma-separated tenants whose pools are opened at startup")
+		flag.StringVar(&prewarmCritical, "prewarm-critical", "", "Comma-separated tenants prewarmed at startup that /readyz waits for")
+		flag.IntVar(&config.Prewarm.Concurrency, "prewarm-concurrency", config.Prewarm.Concurrency, "Number of pools opened at once while prewarming")
+		flag.StringVar(&config.RegistryDSN, "registry-dsn", config.RegistryDSN, "Tenant registry DSN checked by /readyz (not checked when empty)")
+		flag.DurationVar(&config.DrainDelay, "drain-delay", config.DrainDelay, "Time to report not ready before shutting down")
+		flag.StringVar(&config.Logging.Format, "log-format", config.Logging.Format, "Log output format: json or console")
+		flag.StringVar(&config.Logging.Level, "log-level", config.Logging.Level, "Minimum log level: debug, info, warn or error")
+		flag.UintVar(&logSampleRate, "log-sample-rate", 0, "Keep one in every N debug and info messages (disabled when 0 or 1)")
+		flag.StringVar(&config.Logging.File, "log-file", config.Logging.File, "Also write JSON logs to this file, rotated by size")
+		flag.IntVar(&config.Logging.MaxSizeMB, "log-max-size", config.Logging.MaxSizeMB, "Log file size in MB at which it is rotated (default 100)")
+		flag.IntVar(&config.Logging.MaxBackups, "log-max-backups", config.Logging.MaxBackups, "Rotated log files to keep (all when 0)")
+		flag.IntVar(&config.Logging.MaxAgeDays, "log-max-age", config.Logging.MaxAgeDays, "Days to keep rotated log files (forever when 0)")
+		flag.BoolVar(&config.Logging.Compress, "log-compress", config.Logging.Compress, "Gzip rotated log files")
+		flag.Float64Var(&config.Tracing.SampleRatio, "trace-sample-ratio", config.Tracing.SampleRatio, "Fraction of new traces to record (default 1)")
 		flag.Parse()
+		config.Logging.SampleRate = uint32(logSampleRate)
+		config.DSNPolicy.AllowedHosts = splitList(allowedHosts)
+		config.AdminCerts = splitList(adminCerts)
+		config.Prewarm.Tenants = splitList(prewarm)
+		config.Prewarm.Critical = splitList(prewarmCritical)
+	}
+
+	// If flags are already parsed (e.g. during testing), defaults are returned
+	return config
+}
 
-		return Config{
-			Port:     *port,
-			CertFile: "certs/cert.pem",
-			KeyFile:  "certs/key.pem",
-			HTTPPort: ":8082",
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
 		}
 	}
+	return items
+}
 
-	// If flags are already parsed (e.g. during testing), return defaults
-	return Config{
-		Port:     50052,
-		CertFile: "certs/cert.pem",
-		KeyFile:  "certs/key.pem",
-		HTTPPort: ":8082",
+// setupPoolManager creates the pool manager and loads tenant settings from the config
+func setupPoolManager(config Config) (*pool.ConnectionPoolManager, error) {
+	poolManager := pool.NewConnectionPoolManager()
+	poolManager.SetDefaultLeaseTTL(config.LeaseTTL)
+	poolManager.SetHoldWarning(config.LeaseHoldWarning)
+	poolManager.SetStatsHistory(config.StatsHistoryInterval, config.StatsHistoryRetention)
+	poolManager.SetEventBuffer(config.EventBuffer)
+	poolManager.SetDSNPolicy(config.DSNPolicy)
+	poolManager.SetCreationLimits(config.CreationLimits)
+	if config.TenantsFile == "" {
+		return poolManager, nil
 	}
+
+	tenants, err := pool.LoadTenantConfigs(config.TenantsFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, tenant := range tenants {
+		poolManager.SetTenantConfig(tenant)
+	}
+	log.Info().Int("tenants", len(tenants)).Msg("Loaded tenant configs")
+	return poolManager, nil
 }
 
-// setupLogger configures the zerolog logger
+// setupLogger configures the zerolog logger used until the logging config is applied
 func setupLogger() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
@@ -61,32 +220,110 @@ func loadTLSCredentials(certFile, keyFile string) (credentials.TransportCredenti
 	return credentials.NewServerTLSFromFile(certFile, keyFile)
 }
 
+// loadClientCATLSConfig builds a server TLS config that verifies client certificates
+// signed by the CA in caFile when clients present one
+func loadClientCATLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// setupAuthenticator creates the authenticator shared by the gRPC server and the HTTP gateway
+func setupAuthenticator(config Config) (*auth.Authenticator, error) {
+	var tokens []auth.Token
+	if config.AuthTokensFile != "" {
+		var err error
+		if tokens, err = auth.LoadTokens(config.AuthTokensFile); err != nil {
+			return nil, err
+		}
+	}
+	authenticator := auth.NewAuthenticator(tokens, config.ClientCAFile != "")
+	authenticator.SetAdminCerts(config.AdminCerts)
+	return authenticator, nil
+}
+
+// setupWebhooks creates the webhook dispatcher, or returns nil when no webhooks are configured
+func setupWebhooks(config Config, poolManager *pool.ConnectionPoolManager) (*webhook.Dispatcher, error) {
+	if config.WebhooksFile == "" {
+		return nil, nil
+	}
+	webhooks, err := webhook.LoadWebhooks(config.WebhooksFile)
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Int("webhooks", len(webhooks)).Msg("Loaded webhooks")
+	return webhook.NewDispatcher(poolManager, webhooks, webhook.Options{DeadLetterFile: config.WebhookDeadLetterFile}), nil
+}
+
+// loadProxyTLSConfig loads the certificate offered to proxy clients that request SSL
+func loadProxyTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
 // createTCPListener creates a TCP listener on the specified port
 func createTCPListener(port int) (net.Listener, error) {
 	return net.Listen("tcp", fmt.Sprintf(":%d", port))
 }
 
-// setupGRPCServer creates and configures the gRPC server
-func setupGRPCServer(creds credentials.TransportCredentials) *grpc.Server {
-	server := grpc.NewServer(grpc.Creds(creds))
-	service.RegisterServer(server, service.NewConnectionPoolServiceServer())
+// setupGRPCServer creates and configures the gRPC server. Extra options are applied
+// before the built-in ones, so authentication interceptors passed in run before the
+// request logger and it records the caller's identity.
+func setupGRPCServer(creds credentials.TransportCredentials, srv *service.ConnectionPoolServiceServer, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(append(opts,
+		grpc.Creds(creds),
+		// Pinging idle clients finds dead ones, so HoldLease streams release their lease
+		grpc.KeepaliveParams(keepalive.ServerParameters{Time: 30 * time.Second, Timeout: 10 * time.Second}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{MinTime: 10 * time.Second, PermitWithoutStream: true}),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(logging.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(logging.StreamServerInterceptor()),
+	)...)
+	service.RegisterServer(server, srv)
 	return server
 }
 
-// setupHTTPMux creates and configures the HTTP mux with health and metrics endpoints
+// setupHealthChecker creates the readiness checker, including the tenant registry
+// check when a registry DSN is configured and the prewarm check
+func setupHealthChecker(config Config, poolManager *pool.ConnectionPoolManager, prewarmer *pool.Prewarmer) (*health.Checker, error) {
+	checks := []health.Check{{Name: "prewarm", Func: prewarmer.Ready}}
+	if config.RegistryDSN != "" {
+		// The registry pool connects lazily, so an unreachable registry fails readiness rather than startup
+		registry, err := pgxpool.New(context.Background(), config.RegistryDSN)
+		if err != nil {
+			return nil, fmt.Errorf("invalid registry DSN: %w", err)
+		}
+		checks = append(checks, health.Check{Name: "registry", Func: registry.Ping})
+	}
+	return health.NewChecker(poolManager, health.Options{}, checks...), nil
+}
+
+// setupHTTPMux creates and configures the HTTP mux with the metrics endpoint. The
+// health checker adds the probes.
 func setupHTTPMux() *http.ServeMux {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/health", healthHandler)
 	mux.Handle("/metrics", promhttp.Handler())
 	return mux
 }
 
-// healthHandler handles health check requests
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
-}
-
 // createHTTPServer creates the HTTP server for health checks and metrics
 func createHTTPServer(addr string, handler http.Handler) *http.Server {
 	return &http.Server{
@@ -107,6 +344,18 @@ func startGRPCServer(server *grpc.Server, listener net.Listener) chan error {
 	return errChan
 }
 
+// startProxyServer starts the PostgreSQL proxy in a goroutine
+func startProxyServer(server *proxy.Server, listener net.Listener) chan error {
+	errChan := make(chan error, 1)
+	go func() {
+		log.Info().Msgf("PostgreSQL proxy listening at %v", listener.Addr())
+		if err := server.Serve(listener); err != nil {
+			errChan <- err
+		}
+	}()
+	return errChan
+}
+
 // startHTTPServer starts the HTTP server in a goroutine
 func startHTTPServer(server *http.Server, certFile, keyFile string) chan error {
 	errChan := make(chan error, 1)
@@ -128,49 +377,180 @@ func setupSignalHandler() chan os.Signal {
 
 // Application encapsulates the application state and behavior
 type Application struct {
-	config     Config
-	grpcServer *grpc.Server
-	httpServer *http.Server
-	listener   net.Listener
+	config      Config
+	poolManager *pool.ConnectionPoolManager
+	service     *service.ConnectionPoolServiceServer
+	grpcServer  *grpc.Server
+	httpServer  *http.Server
+	listener    net.Listener
+	checker     *health.Checker
+	webhooks    *webhook.Dispatcher
+	prewarmer   *pool.Prewarmer
+
+	proxyServer   *proxy.Server
+	proxyListener net.Listener
+
+	tracerProvider *tracing.Provider
+	logCloser      io.Closer
 }
 
 // NewApplication creates a new application instance
 func NewApplication() (*Application, error) {
 	config := parseFlags()
 
+	// Apply the logging config
+	_, logCloser, err := logging.Setup(config.Logging)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up logging: %w", err)
+	}
+
 	// Load TLS credentials
 	creds, err := loadTLSCredentials(config.CertFile, config.KeyFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load TLS credentials: %w", err)
 	}
 
+	// Trust client certificates when a client CA is configured
+	var clientTLSConfig *tls.Config
+	if config.ClientCAFile != "" {
+		if clientTLSConfig, err = loadClientCATLSConfig(config.CertFile, config.KeyFile, config.ClientCAFile); err != nil {
+			return nil, fmt.Errorf("failed to load client CA: %w", err)
+		}
+		creds = credentials.NewTLS(clientTLSConfig)
+	}
+	authenticator, err := setupAuthenticator(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load auth tokens: %w", err)
+	}
+
+	// Load tenant settings
+	poolManager, err := setupPoolManager(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenant configs: %w", err)
+	}
+
+	webhooks, err := setupWebhooks(config, poolManager)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhooks: %w", err)
+	}
+
+	prewarmer, err := pool.NewPrewarmer(poolManager, config.Prewarm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up prewarming: %w", err)
+	}
+
+	// Setup tracing before any pool is created
+	tracerProvider, err := tracing.Setup(context.Background(), config.Tracing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up tracing: %w", err)
+	}
+
 	// Create TCP listener
 	listener, err := createTCPListener(config.Port)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create TCP listener: %w", err)
 	}
 
+	// Setup health checks
+	checker, err := setupHealthChecker(config, poolManager, prewarmer)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
 	// Setup servers
-	grpcServer := setupGRPCServer(creds)
+	srv := service.NewConnectionPoolServiceServerWithManager(poolManager)
+	grpcServer := setupGRPCServer(creds, srv,
+		grpc.ChainUnaryInterceptor(authenticator.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(authenticator.StreamServerInterceptor()),
+	)
+	healthpb.RegisterHealthServer(grpcServer, checker.GRPCServer())
 	httpMux := setupHTTPMux()
+	checker.RegisterHTTP(httpMux)
+
+	// Serve the REST/JSON gateway next to health and metrics
+	gatewayHandler, err := gateway.NewHandler(context.Background(), srv, authenticator)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set up REST gateway: %w", err)
+	}
+	httpMux.Handle("/v1/", gatewayHandler)
+	httpMux.Handle("/openapi.json", gatewayHandler)
 	httpServer := createHTTPServer(config.HTTPPort, httpMux)
+	httpServer.TLSConfig = clientTLSConfig
 
-	return &Application{
-		config:     config,
-		grpcServer: grpcServer,
-		httpServer: httpServer,
-		listener:   listener,
-	}, nil
+	app := &Application{
+		config:      config,
+		poolManager: poolManager,
+		service:     srv,
+		grpcServer:  grpcServer,
+		httpServer:  httpServer,
+		listener:    listener,
+		checker:     checker,
+		webhooks:    webhooks,
+		prewarmer:   prewarmer,
+
+		tracerProvider: tracerProvider,
+		logCloser:      logCloser,
+	}
+
+	// Setup the optional PostgreSQL proxy
+	if config.ProxyAddr != "" {
+		tlsConfig, err := loadProxyTLSConfig(config.CertFile, config.KeyFile)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to load proxy TLS config: %w", err)
+		}
+		proxyListener, err := net.Listen("tcp", config.ProxyAddr)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to create proxy listener: %w", err)
+		}
+		app.proxyServer = proxy.NewServer(poolManager, tlsConfig)
+		app.proxyListener = proxyListener
+	}
+
+	return app, nil
 }
 
 // Run starts the application
 func (app *Application) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Start measuring replica lag
+	app.poolManager.StartReplicaMonitor(ctx, app.config.ReplicaCheckInterval)
+	if app.config.LeaseReapInterval > 0 {
+		app.poolManager.StartLeaseReaper(ctx, app.config.LeaseReapInterval)
+	}
+	if app.config.StatsHistoryInterval > 0 {
+		app.poolManager.StartStatsHistory(ctx)
+	}
+	if app.config.WatchdogInterval > 0 {
+		app.poolManager.StartWatchdog(ctx, app.config.WatchdogInterval)
+	}
+
+	if app.webhooks != nil {
+		app.webhooks.Run(ctx)
+	}
+	// Open pools ahead of their first request; readiness waits for the critical ones
+	app.prewarmer.Run(ctx)
+
+	// Keep grpc.health.v1 statuses current for Watch clients
+	app.checker.Run(ctx, 10*time.Second)
+
 	// Start gRPC server
 	grpcErrChan := startGRPCServer(app.grpcServer, app.listener)
 
 	// Start HTTP server
 	httpErrChan := startHTTPServer(app.httpServer, app.config.CertFile, app.config.KeyFile)
 
+	// Start PostgreSQL proxy
+	var proxyErrChan chan error
+	if app.proxyServer != nil {
+		proxyErrChan = startProxyServer(app.proxyServer, app.proxyListener)
+	}
+
 	// Setup signal handling
 	signalChan := setupSignalHandler()
 
@@ -180,10 +560,27 @@ func (app *Application) Run() error {
 		return fmt.Errorf("gRPC server error: %w", err)
 	case err := <-httpErrChan:
 		return fmt.Errorf("HTTP server error: %w", err)
+	case err := <-proxyErrChan:
+		return fmt.Errorf("proxy server error: %w", err)
 	case <-signalChan:
 		log.Info().Msg("Shutting down server...")
+		// Stop reporting ready and give load balancers time to notice
+		app.checker.SetDraining()
+		time.Sleep(app.config.DrainDelay)
+		if app.proxyServer != nil {
+			app.proxyServer.Close()
+		}
+		// Streams run until their clients close them, so end them before waiting for calls
+		app.service.Shutdown()
 		app.grpcServer.GracefulStop()
+		if app.tracerProvider != nil {
+			// Flush spans still waiting in the batcher
+			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			app.tracerProvider.Shutdown(shutdownCtx)
+			shutdownCancel()
+		}
 		log.Info().Msg("Server exiting")
+		app.logCloser.Close()
 		return nil
 	}
 }
@@ -13,6 +13,8 @@ import (
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/teresa-solution/connection-pool-manager/internal/service"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
 	"google.golang.org/grpc"
 )
 
@@ -161,7 +163,7 @@ func TestCreateTCPListener(t *testing.T) {
 
 func TestSetupGRPCServer(t *testing.T) {
 	// Create a simple server without credentials for testing
-	server := setupGRPCServer(nil)
+	server := setupGRPCServer(nil, service.NewConnectionPoolServiceServerWithManager(pool.NewConnectionPoolManager()))
 
 	if server == nil {
 		t.Error("setupGRPCServer() returned nil")
@@ -178,23 +180,9 @@ func TestSetupHTTPMux(t *testing.T) {
 		t.Error("setupHTTPMux() returned nil")
 	}
 
-	// Test that the health endpoint is registered
-	req := httptest.NewRequest("GET", "/health", nil)
-	rec := httptest.NewRecorder()
-	mux.ServeHTTP(rec, req)
-
-	if rec.Code != http.StatusOK {
-		t.Errorf("health endpoint returned status %d, want %d", rec.Code, http.StatusOK)
-	}
-
-	expectedBody := "OK"
-	if body := rec.Body.String(); body != expectedBody {
-		t.Errorf("health endpoint returned body %q, want %q", body, expectedBody)
-	}
-
 	// Test that the metrics endpoint is registered
-	req = httptest.NewRequest("GET", "/metrics", nil)
-	rec = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rec := httptest.NewRecorder()
 	mux.ServeHTTP(rec, req)
 
 	// Metrics endpoint should return 200 (prometheus metrics)
@@ -203,45 +191,6 @@ func TestSetupHTTPMux(t *testing.T) {
 	}
 }
 
-func TestHealthHandler(t *testing.T) {
-	tests := []struct {
-		name           string
-		method         string
-		expectedStatus int
-		expectedBody   string
-	}{
-		{
-			name:           "GET request",
-			method:         "GET",
-			expectedStatus: http.StatusOK,
-			expectedBody:   "OK",
-		},
-		{
-			name:           "POST request",
-			method:         "POST",
-			expectedStatus: http.StatusOK,
-			expectedBody:   "OK",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest(tt.method, "/health", nil)
-			rec := httptest.NewRecorder()
-
-			healthHandler(rec, req)
-
-			if rec.Code != tt.expectedStatus {
-				t.Errorf("healthHandler() status = %d, want %d", rec.Code, tt.expectedStatus)
-			}
-
-			if body := rec.Body.String(); body != tt.expectedBody {
-				t.Errorf("healthHandler() body = %q, want %q", body, tt.expectedBody)
-			}
-		})
-	}
-}
-
 func TestCreateHTTPServer(t *testing.T) {
 	handler := http.NewServeMux()
 	addr := ":8080"
@@ -434,3 +383,12 @@ func BenchmarkSetupHTTPMux(b *testing.B) {
 		setupHTTPMux()
 	}
 }
+
+func TestSplitList(t *testing.T) {
+	if got := splitList(" *.db.internal, ,10.0.0.5"); !reflect.DeepEqual(got, []string{"*.db.internal", "10.0.0.5"}) {
+		t.Errorf("splitList() = %q", got)
+	}
+	if got := splitList(""); got != nil {
+		t.Errorf("splitList(\"\") = %q, want nil", got)
+	}
+}
@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUnauthenticated is returned when a caller has neither a valid token nor a
+// trusted client certificate
+var ErrUnauthenticated = errors.New("missing or invalid credentials")
+
+// ErrNotAdmin is returned when a caller who is not an admin calls an admin method
+var ErrNotAdmin = errors.New("method requires an admin identity")
+
+// adminMethods are the methods that see or end other callers' leases
+var adminMethods = map[string]bool{
+	pb.ConnectionPoolService_ListLeases_FullMethodName:  true,
+	pb.ConnectionPoolService_DrainTenant_FullMethodName: true,
+	pb.ConnectionPoolService_EvictTenant_FullMethodName: true,
+}
+
+// Token is a bearer token accepted from callers, named for logs and audit
+type Token struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	// Admin lets the token's holder call admin methods
+	Admin bool `json:"admin"`
+}
+
+// LoadTokens reads a JSON array of tokens from path
+func LoadTokens(path string) ([]Token, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tokens []Token
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse tokens file %s: %w", path, err)
+	}
+	for i, token := range tokens {
+		if token.Name == "" || token.Token == "" {
+			return nil, fmt.Errorf("token %d needs a name and a token", i)
+		}
+	}
+	return tokens, nil
+}
+
+// Authenticator applies the same rules to gRPC and HTTP callers: a caller is let in
+// with a verified client certificate or a known bearer token. With no tokens and
+// client certificates not trusted, every caller is let in.
+type Authenticator struct {
+	tokens      []Token
+	clientCerts bool
+	adminCerts  map[string]bool
+}
+
+// NewAuthenticator creates an authenticator accepting tokens, and verified client
+// certificates when clientCerts is set
+func NewAuthenticator(tokens []Token, clientCerts bool) *Authenticator {
+	return &Authenticator{tokens: tokens, clientCerts: clientCerts}
+}
+
+// SetAdminCerts makes callers with a client certificate of one of these common names admins
+func (a *Authenticator) SetAdminCerts(commonNames []string) {
+	a.adminCerts = make(map[string]bool, len(commonNames))
+	for _, name := range commonNames {
+		a.adminCerts[name] = true
+	}
+}
+
+// Enabled reports whether callers have to authenticate
+func (a *Authenticator) Enabled() bool {
+	return len(a.tokens) > 0 || a.clientCerts
+}
+
+// Authenticate returns the caller's identity from an Authorization header value and
+// the connection's TLS state
+func (a *Authenticator) Authenticate(authorization string, state *tls.ConnectionState) (string, error) {
+	c, err := a.authenticate(authorization, state)
+	return c.identity, err
+}
+
+// caller is an authenticated caller
+type caller struct {
+	identity string
+	admin    bool
+}
+
+func (a *Authenticator) authenticate(authorization string, state *tls.ConnectionState) (caller, error) {
+	// Without authentication every caller may do everything
+	if !a.Enabled() {
+		return caller{admin: true}, nil
+	}
+	if a.clientCerts && state != nil && len(state.VerifiedChains) > 0 {
+		name := state.VerifiedChains[0][0].Subject.CommonName
+		return caller{identity: "cert:" + name, admin: a.adminCerts[name]}, nil
+	}
+
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok || token == "" {
+		return caller{}, ErrUnauthenticated
+	}
+	for _, known := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(known.Token)) == 1 {
+			return caller{identity: "token:" + known.Name, admin: known.Admin}, nil
+		}
+	}
+	return caller{}, ErrUnauthenticated
+}
+
+type callerKey struct{}
+
+// IdentityFromContext returns the authenticated caller's identity
+func IdentityFromContext(ctx context.Context) (string, bool) {
+	c, ok := ctx.Value(callerKey{}).(caller)
+	return c.identity, ok
+}
+
+// Authorize checks that the authenticated caller in ctx may call method. Admin methods
+// need an admin identity; a context no authenticator has seen is refused them.
+func Authorize(ctx context.Context, method string) error {
+	if !adminMethods[method] {
+		return nil
+	}
+	if c, _ := ctx.Value(callerKey{}).(caller); !c.admin {
+		return status.Error(codes.PermissionDenied, ErrNotAdmin.Error())
+	}
+	return nil
+}
+
+// isPublicMethod reports whether a gRPC method is open to unauthenticated callers
+func isPublicMethod(method string) bool {
+	// Probes must work without credentials
+	return strings.HasPrefix(method, "/grpc.health.v1.Health/")
+}
+
+func (a *Authenticator) authenticateGRPC(ctx context.Context, method string) (context.Context, error) {
+	if isPublicMethod(method) {
+		return ctx, nil
+	}
+
+	authorization := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+	var state *tls.ConnectionState
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state = &tlsInfo.State
+		}
+	}
+
+	c, err := a.authenticate(authorization, state)
+	if err != nil {
+		// Rejected calls never reach the request logger, which runs after this
+		rejected(ctx, err, method).Msg("Rejected unauthenticated request")
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	ctx = context.WithValue(ctx, callerKey{}, c)
+	if err := Authorize(ctx, method); err != nil {
+		rejected(ctx, err, method).Str("identity", c.identity).Msg("Rejected unauthorized request")
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// rejected starts a warning about a call turned away before it was handled
+func rejected(ctx context.Context, err error, method string) *zerolog.Event {
+	event := log.Warn().Err(err).Str("method", method)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		event = event.Str("peer", p.Addr.String())
+	}
+	return event
+}
+
+// UnaryServerInterceptor rejects unauthenticated unary calls, and calls of admin methods
+// by callers who are not admins
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := a.authenticateGRPC(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor rejects unauthenticated streams, and streams of admin methods
+// opened by callers who are not admins
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticateGRPC(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// Middleware rejects unauthenticated HTTP requests with 401. Handlers of admin methods
+// call Authorize themselves, as the method is not known here.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := a.authenticate(r.Header.Get("Authorization"), r.TLS)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": codes.Unauthenticated, "message": err.Error()})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), callerKey{}, c)))
+	})
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func verifiedState(commonName string) *tls.ConnectionState {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+	return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	tokens := []Token{{Name: "ci", Token: "s3cret"}}
+
+	tests := []struct {
+		name          string
+		authenticator *Authenticator
+		authorization string
+		state         *tls.ConnectionState
+		wantIdentity  string
+		wantErr       bool
+	}{
+		{name: "Disabled", authenticator: NewAuthenticator(nil, false)},
+		{name: "Valid token", authenticator: NewAuthenticator(tokens, false), authorization: "Bearer s3cret", wantIdentity: "token:ci"},
+		{name: "Wrong token", authenticator: NewAuthenticator(tokens, false), authorization: "Bearer nope", wantErr: true},
+		{name: "Missing token", authenticator: NewAuthenticator(tokens, false), wantErr: true},
+		{name: "Not a bearer token", authenticator: NewAuthenticator(tokens, false), authorization: "Basic s3cret", wantErr: true},
+		{name: "Client certificate", authenticator: NewAuthenticator(tokens, true), state: verifiedState("billing"), wantIdentity: "cert:billing"},
+		{name: "Client certificates not trusted", authenticator: NewAuthenticator(tokens, false), state: verifiedState("billing"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := tt.authenticator.Authenticate(tt.authorization, tt.state)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrUnauthenticated)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantIdentity, identity)
+		})
+	}
+}
+
+func TestLoadTokens(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "tokens.json")
+	require.NoError(t, os.WriteFile(valid, []byte(`[{"name": "ci", "token": "s3cret"}]`), 0600))
+	tokens, err := LoadTokens(valid)
+	require.NoError(t, err)
+	assert.Equal(t, []Token{{Name: "ci", Token: "s3cret"}}, tokens)
+
+	admin := filepath.Join(dir, "admin.json")
+	require.NoError(t, os.WriteFile(admin, []byte(`[{"name": "ops", "token": "0ps", "admin": true}]`), 0600))
+	tokens, err = LoadTokens(admin)
+	require.NoError(t, err)
+	assert.Equal(t, []Token{{Name: "ops", Token: "0ps", Admin: true}}, tokens)
+
+	missingName := filepath.Join(dir, "missing.json")
+	require.NoError(t, os.WriteFile(missingName, []byte(`[{"token": "s3cret"}]`), 0600))
+	_, err = LoadTokens(missingName)
+	assert.Error(t, err)
+}
+
+func TestAuthenticator_UnaryServerInterceptor(t *testing.T) {
+	interceptor := NewAuthenticator([]Token{{Name: "ci", Token: "s3cret"}, {Name: "ops", Token: "0ps", Admin: true}}, false).UnaryServerInterceptor()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		identity, _ := IdentityFromContext(ctx)
+		return identity, nil
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		md       metadata.MD
+		want     interface{}
+		wantCode codes.Code
+	}{
+		{name: "Authenticated", method: "/connectionpool.ConnectionPoolService/GetPoolStats", md: metadata.Pairs("authorization", "Bearer s3cret"), want: "token:ci"},
+		{name: "Unauthenticated", method: "/connectionpool.ConnectionPoolService/GetPoolStats", wantCode: codes.Unauthenticated},
+		{name: "Health checks are public", method: "/grpc.health.v1.Health/Check", want: ""},
+		{name: "Admin method", method: "/connectionpool.ConnectionPoolService/ListLeases", md: metadata.Pairs("authorization", "Bearer 0ps"), want: "token:ops"},
+		{name: "Admin method without admin token", method: "/connectionpool.ConnectionPoolService/EvictTenant", md: metadata.Pairs("authorization", "Bearer s3cret"), wantCode: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			resp, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if tt.wantCode != codes.OK {
+				assert.Equal(t, tt.wantCode, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, resp)
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	authenticator := NewAuthenticator([]Token{{Name: "ci", Token: "s3cret"}}, true)
+	authenticator.SetAdminCerts([]string{"ops"})
+	interceptor := authenticator.UnaryServerInterceptor()
+
+	tests := []struct {
+		name     string
+		state    *tls.ConnectionState
+		wantCode codes.Code
+	}{
+		{name: "Admin certificate", state: verifiedState("ops")},
+		{name: "Other certificate", state: verifiedState("billing"), wantCode: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: *tt.state}})
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/connectionpool.ConnectionPoolService/DrainTenant"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, nil
+			})
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+
+	// Admin methods are refused to callers no authenticator has seen
+	assert.Equal(t, codes.PermissionDenied, status.Code(Authorize(context.Background(), "/connectionpool.ConnectionPoolService/ListLeases")))
+	assert.NoError(t, Authorize(context.Background(), "/connectionpool.ConnectionPoolService/GetPoolStats"))
+}
+
+func TestAuthenticator_Middleware(t *testing.T) {
+	handler := NewAuthenticator([]Token{{Name: "ci", Token: "s3cret"}}, false).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := IdentityFromContext(r.Context())
+		w.Write([]byte(identity))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/tenants/acme/stats", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/tenants/acme/stats", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "token:ci", rec.Body.String())
+}
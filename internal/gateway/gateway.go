@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/teresa-solution/connection-pool-manager/internal/auth"
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// NewHandler returns the REST/JSON API for srv. Requests are served in process and
+// checked by the same authenticator as gRPC calls, and admin methods are only served to
+// admins. The OpenAPI document is served without authentication at /openapi.json.
+func NewHandler(ctx context.Context, srv pb.ConnectionPoolServiceServer, authenticator *auth.Authenticator) (http.Handler, error) {
+	gwmux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+	)
+	if err := pb.RegisterConnectionPoolServiceHandlerServer(ctx, gwmux, adminServer{srv}); err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/v1/", authenticator.Middleware(gwmux))
+	mux.HandleFunc("/openapi.json", openAPIHandler)
+	return mux, nil
+}
+
+// adminServer authorizes calls of admin methods, which the gRPC interceptors do for
+// gRPC callers but which in-process gateway calls bypass
+type adminServer struct {
+	pb.ConnectionPoolServiceServer
+}
+
+func (s adminServer) ListLeases(ctx context.Context, req *pb.ListLeasesRequest) (*pb.ListLeasesResponse, error) {
+	if err := auth.Authorize(ctx, pb.ConnectionPoolService_ListLeases_FullMethodName); err != nil {
+		return nil, err
+	}
+	return s.ConnectionPoolServiceServer.ListLeases(ctx, req)
+}
+
+func (s adminServer) DrainTenant(ctx context.Context, req *pb.DrainRequest) (*pb.DrainResponse, error) {
+	if err := auth.Authorize(ctx, pb.ConnectionPoolService_DrainTenant_FullMethodName); err != nil {
+		return nil, err
+	}
+	return s.ConnectionPoolServiceServer.DrainTenant(ctx, req)
+}
+
+func (s adminServer) EvictTenant(ctx context.Context, req *pb.EvictRequest) (*pb.EvictResponse, error) {
+	if err := auth.Authorize(ctx, pb.ConnectionPoolService_EvictTenant_FullMethodName); err != nil {
+		return nil, err
+	}
+	return s.ConnectionPoolServiceServer.EvictTenant(ctx, req)
+}
+
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(pb.OpenAPI)
+}
@@ -0,0 +1,114 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/auth"
+	"github.com/teresa-solution/connection-pool-manager/internal/pgtest"
+	"github.com/teresa-solution/connection-pool-manager/internal/service"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+)
+
+// setupGateway serves the gateway for tenant "acme" on a fake database, accepting the
+// bearer token "s3cret"
+func setupGateway(t *testing.T) *httptest.Server {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+
+	poolManager := pool.NewConnectionPoolManager()
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "acme", DSN: db.DSN()})
+
+	authenticator := auth.NewAuthenticator([]auth.Token{{Name: "ci", Token: "s3cret"}}, false)
+	handler, err := NewHandler(context.Background(), service.NewConnectionPoolServiceServerWithManager(poolManager), authenticator)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(func() {
+		server.Close()
+		poolManager.ReleaseConnection(context.Background(), "acme", db.DSN())
+		db.Close()
+	})
+	return server
+}
+
+func call(t *testing.T, server *httptest.Server, method, path, body string) (int, map[string]interface{}) {
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+	return resp.StatusCode, decoded
+}
+
+func TestGateway_LeaseLifecycle(t *testing.T) {
+	server := setupGateway(t)
+
+	code, body := call(t, server, http.MethodPost, "/v1/tenants/acme/connections", `{}`)
+	require.Equal(t, http.StatusOK, code, body)
+	connectionID, _ := body["connection_id"].(string)
+	require.NotEmpty(t, connectionID)
+	// Unpopulated fields are present with proto field names
+	assert.Equal(t, false, body["from_replica"])
+
+	code, body = call(t, server, http.MethodPost, "/v1/tenants/acme/connections/"+connectionID+"/query", `{"sql": "SELECT pg_backend_pid()"}`)
+	require.Equal(t, http.StatusOK, code, body)
+	assert.Equal(t, []interface{}{"pg_backend_pid"}, body["columns"])
+	assert.Equal(t, "SELECT 1", body["command_tag"])
+
+	code, body = call(t, server, http.MethodDelete, "/v1/tenants/acme/connections/"+connectionID, "")
+	require.Equal(t, http.StatusOK, code, body)
+	assert.Equal(t, true, body["success"])
+
+	code, body = call(t, server, http.MethodDelete, "/v1/tenants/acme/connections/"+connectionID, "")
+	assert.NotEqual(t, http.StatusOK, code)
+	assert.Contains(t, body["message"], "lease not found")
+}
+
+func TestGateway_AdminMethods(t *testing.T) {
+	server := setupGateway(t)
+
+	// The token of setupGateway is not an admin's
+	code, body := call(t, server, http.MethodGet, "/v1/leases", "")
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Contains(t, body["message"], "admin")
+	code, _ = call(t, server, http.MethodPost, "/v1/tenants/acme/evict", `{}`)
+	assert.Equal(t, http.StatusForbidden, code)
+}
+
+func TestGateway_RequiresAuthentication(t *testing.T) {
+	server := setupGateway(t)
+
+	resp, err := http.Get(server.URL + "/v1/tenants/acme/stats")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestGateway_OpenAPI(t *testing.T) {
+	server := setupGateway(t)
+
+	resp, err := http.Get(server.URL + "/openapi.json")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var doc struct {
+		Swagger string                 `json:"swagger"`
+		Paths   map[string]interface{} `json:"paths"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+	assert.Equal(t, "2.0", doc.Swagger)
+	assert.Contains(t, doc.Paths, "/v1/tenants/{tenantId}/connections")
+	assert.Contains(t, doc.Paths, "/v1/tenants/{tenantId}/connections/{connectionId}")
+}
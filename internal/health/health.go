@@ -0,0 +1,264 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// ServiceName is the gRPC health service name of the pool service. The empty name
+// reports the same status.
+const ServiceName = "connectionpool.ConnectionPoolService"
+
+// TenantServicePrefix prefixes per-tenant gRPC health service names, e.g. "tenant/acme"
+const TenantServicePrefix = "tenant/"
+
+// Check is a dependency the service needs in order to serve requests
+type Check struct {
+	Name string
+	Func func(ctx context.Context) error
+}
+
+// Options tune when the service is reported ready
+type Options struct {
+	// CheckTimeout bounds each dependency check. Defaults to 2s.
+	CheckTimeout time.Duration
+	// FailureWindow is how long a failed pool creation counts against readiness. Defaults to 1m.
+	FailureWindow time.Duration
+	// MaxPoolFailureRatio is the share of configured tenants whose pool creation may fail
+	// within the window before the service is no longer ready. Defaults to 0.5; at 1 or
+	// more, failing tenants never make the service unready.
+	MaxPoolFailureRatio float64
+}
+
+// defaultMaxPoolFailureRatio lets a failing minority of tenant databases be reported per
+// tenant without taking the instance out of the load balancer
+const defaultMaxPoolFailureRatio = 0.5
+
+// Report is the readiness of the service and the reasons behind it
+type Report struct {
+	Ready    bool `json:"ready"`
+	Draining bool `json:"draining,omitempty"`
+	// Checks maps each dependency to "ok" or its error
+	Checks map[string]string `json:"checks,omitempty"`
+	// PoolFailures maps tenants whose pool creation recently failed to the error
+	PoolFailures map[string]string `json:"pool_failures,omitempty"`
+}
+
+// Checker tracks liveness and readiness for the HTTP probes and grpc.health.v1
+type Checker struct {
+	poolManager *pool.ConnectionPoolManager
+	checks      []Check
+	opts        Options
+
+	draining atomic.Bool
+	grpc     *health.Server
+	// updateMu serialises status updates to the gRPC health server
+	updateMu sync.Mutex
+}
+
+// NewChecker creates a checker for the pool manager and extra dependency checks
+func NewChecker(poolManager *pool.ConnectionPoolManager, opts Options, checks ...Check) *Checker {
+	if opts.CheckTimeout <= 0 {
+		opts.CheckTimeout = 2 * time.Second
+	}
+	if opts.FailureWindow <= 0 {
+		opts.FailureWindow = time.Minute
+	}
+	if opts.MaxPoolFailureRatio <= 0 {
+		opts.MaxPoolFailureRatio = defaultMaxPoolFailureRatio
+	}
+	return &Checker{
+		poolManager: poolManager,
+		checks:      checks,
+		opts:        opts,
+		grpc:        health.NewServer(),
+	}
+}
+
+// SetDraining marks the service as shutting down. It stops being ready straight away
+// so load balancers move traffic elsewhere before connections are closed.
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+	c.grpc.Shutdown()
+}
+
+// Draining reports whether SetDraining has been called
+func (c *Checker) Draining() bool {
+	return c.draining.Load()
+}
+
+// Readiness runs the dependency checks and reports whether the service can serve requests
+func (c *Checker) Readiness(ctx context.Context) Report {
+	report := Report{Ready: true, Draining: c.Draining()}
+	if report.Draining {
+		report.Ready = false
+	}
+
+	if len(c.checks) > 0 {
+		report.Checks = make(map[string]string, len(c.checks))
+	}
+	for _, check := range c.checks {
+		checkCtx, cancel := context.WithTimeout(ctx, c.opts.CheckTimeout)
+		err := check.Func(checkCtx)
+		cancel()
+		if err != nil {
+			report.Ready = false
+			report.Checks[check.Name] = err.Error()
+		} else {
+			report.Checks[check.Name] = "ok"
+		}
+	}
+
+	failures := c.recentFailures()
+	if len(failures) > 0 {
+		report.PoolFailures = make(map[string]string, len(failures))
+		for tenantID, failure := range failures {
+			report.PoolFailures[tenantID] = failure.Err.Error()
+		}
+	}
+	if tenants := len(c.poolManager.TenantConfigs()); float64(len(failures)) > c.opts.MaxPoolFailureRatio*float64(tenants) {
+		report.Ready = false
+	}
+	return report
+}
+
+// TenantReady reports whether a configured tenant can be served. A tenant is not
+// ready while its last pool creation failed within the failure window.
+func (c *Checker) TenantReady(tenantID string) (ready bool, known bool) {
+	if _, ok := c.poolManager.TenantConfig(tenantID); !ok {
+		return false, false
+	}
+	_, failed := c.recentFailures()[tenantID]
+	return !failed && !c.Draining(), true
+}
+
+// recentFailures returns the configured tenants whose pool creation failed within the window
+func (c *Checker) recentFailures() map[string]pool.PoolFailure {
+	failures := c.poolManager.PoolFailures()
+	for tenantID, failure := range failures {
+		_, configured := c.poolManager.TenantConfig(tenantID)
+		if !configured || time.Since(failure.At) > c.opts.FailureWindow {
+			delete(failures, tenantID)
+		}
+	}
+	return failures
+}
+
+// Run refreshes the gRPC health statuses every interval until ctx is done, so
+// Watch clients see changes
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			c.update(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// update pushes the current readiness of the service and each tenant to the gRPC
+// health server
+func (c *Checker) update(ctx context.Context) {
+	report := c.Readiness(ctx)
+
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+	c.grpc.SetServingStatus("", servingStatus(report.Ready))
+	c.grpc.SetServingStatus(ServiceName, servingStatus(report.Ready))
+	for _, cfg := range c.poolManager.TenantConfigs() {
+		ready, _ := c.TenantReady(cfg.TenantID)
+		c.grpc.SetServingStatus(TenantServicePrefix+cfg.TenantID, servingStatus(ready))
+	}
+}
+
+func servingStatus(ready bool) healthpb.HealthCheckResponse_ServingStatus {
+	if ready {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
+
+// GRPCServer returns the grpc.health.v1 service backed by the checker
+func (c *Checker) GRPCServer() healthpb.HealthServer {
+	return &grpcServer{Server: c.grpc, checker: c}
+}
+
+// grpcServer refreshes the statuses before answering Check so it never reports
+// readiness older than the request
+type grpcServer struct {
+	*health.Server
+	checker *Checker
+}
+
+func (s *grpcServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	s.checker.update(ctx)
+	resp, err := s.Server.Check(ctx, req)
+	if status.Code(err) == codes.NotFound {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.Service)
+	}
+	return resp, err
+}
+
+// RegisterHTTP adds the /livez and /readyz probes to mux. /health, kept for older
+// probes, reports readiness too.
+func (c *Checker) RegisterHTTP(mux *http.ServeMux) {
+	mux.HandleFunc("/livez", c.livezHandler)
+	mux.HandleFunc("/readyz", c.readyzHandler)
+	mux.HandleFunc("/health", c.readyzHandler)
+}
+
+// livezHandler reports that the process is up. It does not depend on Postgres so a
+// database outage does not get the service restarted.
+func (c *Checker) livezHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// readyzHandler reports readiness as JSON. With ?tenant=<id> it reports that tenant only.
+func (c *Checker) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		ready bool
+		body  interface{}
+	)
+	if tenantID := r.URL.Query().Get("tenant"); tenantID != "" {
+		var known bool
+		ready, known = c.TenantReady(tenantID)
+		if !known {
+			http.Error(w, "unknown tenant", http.StatusNotFound)
+			return
+		}
+		body = map[string]interface{}{"tenant_id": tenantID, "ready": ready}
+	} else {
+		report := c.Readiness(r.Context())
+		ready = report.Ready
+		body = report
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if ready {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Debug().Err(err).Msg("Failed to write readiness report")
+	}
+}
@@ -0,0 +1,167 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// unreachableDSN returns a DSN for a local port nothing listens on
+func unreachableDSN(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener.Close()
+	return fmt.Sprintf("postgres://app@%s/broken?sslmode=disable", listener.Addr())
+}
+
+// setupChecker registers tenants "acme" and "broken", and fails pool creation for "broken"
+func setupChecker(t *testing.T, opts Options, checks ...Check) *Checker {
+	poolManager := pool.NewConnectionPoolManager()
+	dsn := unreachableDSN(t)
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "acme", DSN: "postgres://localhost/acme"})
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "broken", DSN: dsn, Creation: pool.CreationPolicy{Verify: true, Retries: -1}})
+	_, err := poolManager.GetConnection(context.Background(), "broken", dsn)
+	require.Error(t, err)
+	return NewChecker(poolManager, opts, checks...)
+}
+
+func TestChecker_Readiness(t *testing.T) {
+	registryDown := Check{Name: "registry", Func: func(ctx context.Context) error { return errors.New("connection refused") }}
+	registryUp := Check{Name: "registry", Func: func(ctx context.Context) error { return nil }}
+
+	tests := []struct {
+		name      string
+		opts      Options
+		checks    []Check
+		drain     bool
+		wantReady bool
+	}{
+		{name: "Pool failure within allowance", opts: Options{}, checks: []Check{registryUp}, wantReady: true},
+		{name: "Pool failure over allowance", opts: Options{MaxPoolFailureRatio: 0.25}, wantReady: false},
+		{name: "Registry unavailable", opts: Options{}, checks: []Check{registryDown}, wantReady: false},
+		{name: "Draining", opts: Options{}, drain: true, wantReady: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := setupChecker(t, tt.opts, tt.checks...)
+			if tt.drain {
+				checker.SetDraining()
+			}
+
+			report := checker.Readiness(context.Background())
+			assert.Equal(t, tt.wantReady, report.Ready)
+			assert.Equal(t, tt.drain, report.Draining)
+			assert.Contains(t, report.PoolFailures, "broken")
+			for _, check := range tt.checks {
+				assert.Contains(t, report.Checks, check.Name)
+			}
+		})
+	}
+}
+
+func TestChecker_GRPC(t *testing.T) {
+	checker := setupChecker(t, Options{})
+	server := checker.GRPCServer()
+	ctx := context.Background()
+
+	tests := []struct {
+		service  string
+		want     healthpb.HealthCheckResponse_ServingStatus
+		wantCode codes.Code
+	}{
+		{service: "", want: healthpb.HealthCheckResponse_SERVING},
+		{service: ServiceName, want: healthpb.HealthCheckResponse_SERVING},
+		{service: "tenant/acme", want: healthpb.HealthCheckResponse_SERVING},
+		{service: "tenant/broken", want: healthpb.HealthCheckResponse_NOT_SERVING},
+		{service: "tenant/unknown", wantCode: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			resp, err := server.Check(ctx, &healthpb.HealthCheckRequest{Service: tt.service})
+			if tt.wantCode != codes.OK {
+				assert.Equal(t, tt.wantCode, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, resp.Status)
+		})
+	}
+
+	checker.SetDraining()
+	resp, err := server.Check(ctx, &healthpb.HealthCheckRequest{Service: "tenant/acme"})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+}
+
+func TestChecker_HTTP(t *testing.T) {
+	checker := setupChecker(t, Options{MaxPoolFailureRatio: 0.25})
+	mux := http.NewServeMux()
+	checker.RegisterHTTP(mux)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{name: "Live while not ready", path: "/livez", wantStatus: http.StatusOK},
+		{name: "Not ready", path: "/readyz", wantStatus: http.StatusServiceUnavailable},
+		{name: "Legacy health reports readiness", path: "/health", wantStatus: http.StatusServiceUnavailable},
+		{name: "Ready tenant", path: "/readyz?tenant=acme", wantStatus: http.StatusOK},
+		{name: "Failing tenant", path: "/readyz?tenant=broken", wantStatus: http.StatusServiceUnavailable},
+		{name: "Unknown tenant", path: "/readyz?tenant=unknown", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var report Report
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	assert.False(t, report.Ready)
+	assert.Contains(t, report.PoolFailures, "broken")
+}
+
+func TestChecker_CallerErrorsStayReady(t *testing.T) {
+	poolManager := pool.NewConnectionPoolManager()
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "acme", DSN: "postgres://localhost/acme"})
+	poolManager.SetDSNPolicy(pool.DSNPolicy{AllowedHosts: []string{"localhost"}})
+	checker := NewChecker(poolManager, Options{})
+	ctx := context.Background()
+
+	// Bad or denied DSNs from callers, configured tenant or not, are not the service's fault
+	for _, call := range []struct{ tenantID, dsn string }{
+		{"anyone", "postgres://u@h:badport/db"},
+		{"acme", "postgres://u@h:badport/db"},
+		{"acme", "postgres://u@db.example.com/acme"},
+		{"anyone", "postgres://u@db.example.com/acme"},
+	} {
+		_, err := poolManager.GetConnection(ctx, call.tenantID, call.dsn)
+		require.Error(t, err)
+	}
+
+	report := checker.Readiness(ctx)
+	assert.True(t, report.Ready)
+	assert.Empty(t, report.PoolFailures)
+	ready, _ := checker.TenantReady("acme")
+	assert.True(t, ready)
+}
@@ -0,0 +1,124 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/teresa-solution/connection-pool-manager/internal/auth"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDHeader carries the request ID in gRPC metadata. An incoming ID is kept so a
+// request can be followed across services; otherwise one is generated.
+const RequestIDHeader = "x-request-id"
+
+// UnaryServerInterceptor logs every unary RPC and makes a logger carrying the request
+// ID, method, tenant, peer and authenticated identity available to the handler through
+// zerolog.Ctx. It has to run after the authentication interceptor to see the identity.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, logger := requestLogger(ctx, info.FullMethod, req)
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logResult(logger, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor. The
+// tenant is not known when a stream opens, so it is left out.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, logger := requestLogger(stream.Context(), info.FullMethod, nil)
+		start := time.Now()
+		err := handler(srv, &loggedStream{ServerStream: stream, ctx: ctx})
+		logResult(logger, start, err)
+		return err
+	}
+}
+
+// requestLogger derives the per-request logger and stores it in the returned context
+func requestLogger(ctx context.Context, method string, req interface{}) (context.Context, *zerolog.Logger) {
+	requestID := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDHeader); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" {
+		requestID = newRequestID()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, requestID))
+
+	base := zerolog.Ctx(ctx)
+	if base.GetLevel() == zerolog.Disabled {
+		base = &log.Logger
+	}
+	fields := base.With().Str("request_id", requestID).Str("method", method)
+	if r, ok := req.(interface{ GetTenantId() string }); ok && r.GetTenantId() != "" {
+		fields = fields.Str("tenant_id", r.GetTenantId())
+	}
+	// The connection ID is all a caller needs to use a lease, so only its handle is
+	// written to the logs
+	if r, ok := req.(interface{ GetConnectionId() string }); ok && r.GetConnectionId() != "" {
+		fields = fields.Str("lease", pool.LeaseHandle(r.GetConnectionId()))
+	}
+	if identity := peerIdentity(ctx); identity != "" {
+		fields = fields.Str("peer", identity)
+	}
+	if identity, ok := auth.IdentityFromContext(ctx); ok && identity != "" {
+		fields = fields.Str("identity", identity)
+	}
+	logger := fields.Logger()
+	return logger.WithContext(ctx), &logger
+}
+
+func logResult(logger *zerolog.Logger, start time.Time, err error) {
+	code := status.Code(err)
+	event := logger.Info()
+	if err != nil {
+		event = logger.Warn().Err(err)
+	}
+	event.Dur("duration", time.Since(start)).Str("code", code.String()).Msg("Handled request")
+}
+
+// peerIdentity returns the client certificate's common name, or the peer address
+// when the client did not present a certificate
+func peerIdentity(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.PeerCertificates) > 0 {
+		return tlsInfo.State.PeerCertificates[0].Subject.CommonName
+	}
+	if p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// loggedStream replaces a stream's context with one carrying the request logger
+type loggedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *loggedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/auth"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	original := log.Logger
+	defer func() { log.Logger = original }()
+
+	tests := []struct {
+		name       string
+		requestID  string
+		handlerErr error
+		wantCode   string
+		wantLevel  string
+	}{
+		{name: "Success with incoming request ID", requestID: "req-123", wantCode: "OK", wantLevel: "info"},
+		{name: "Failure", handlerErr: status.Error(codes.NotFound, "no such pool"), wantCode: "NotFound", wantLevel: "warn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log.Logger = zerolog.New(&buf)
+
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 7), Port: 4242}})
+			if tt.requestID != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(RequestIDHeader, tt.requestID))
+			}
+
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				// Code further down logs through the request logger
+				zerolog.Ctx(ctx).Info().Msg("inside handler")
+				return nil, tt.handlerErr
+			}
+			info := &grpc.UnaryServerInfo{FullMethod: "/connectionpool.ConnectionPoolService/GetPoolStats"}
+			_, err := UnaryServerInterceptor()(ctx, &pb.StatsRequest{TenantId: "acme"}, info, handler)
+			assert.Equal(t, tt.handlerErr, err)
+
+			var entries []map[string]interface{}
+			decoder := json.NewDecoder(&buf)
+			for decoder.More() {
+				var entry map[string]interface{}
+				require.NoError(t, decoder.Decode(&entry))
+				entries = append(entries, entry)
+			}
+			require.Len(t, entries, 2)
+
+			for _, entry := range entries {
+				assert.Equal(t, info.FullMethod, entry["method"])
+				assert.Equal(t, "acme", entry["tenant_id"])
+				assert.Equal(t, "10.0.0.7:4242", entry["peer"])
+				if tt.requestID != "" {
+					assert.Equal(t, tt.requestID, entry["request_id"])
+				} else {
+					assert.NotEmpty(t, entry["request_id"])
+				}
+			}
+			assert.Equal(t, "inside handler", entries[0]["message"])
+			assert.Equal(t, tt.wantCode, entries[1]["code"])
+			assert.Equal(t, tt.wantLevel, entries[1]["level"])
+			assert.Contains(t, entries[1], "duration")
+		})
+	}
+}
+
+func TestUnaryServerInterceptor_LeaseAndIdentity(t *testing.T) {
+	original := log.Logger
+	defer func() { log.Logger = original }()
+	var buf bytes.Buffer
+	log.Logger = zerolog.New(&buf)
+
+	// Authentication runs first, as the server chains it, so the identity is logged
+	authenticator := auth.NewAuthenticator([]auth.Token{{Name: "ci", Token: "s3cret"}}, false)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer s3cret"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/connectionpool.ConnectionPoolService/ExecuteQuery"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }
+	logged := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return UnaryServerInterceptor()(ctx, req, info, handler)
+	}
+	req := &pb.QueryRequest{ConnectionId: "conn-acme-SECRETLEASEID", TenantId: "acme"}
+	_, err := authenticator.UnaryServerInterceptor()(ctx, req, info, logged)
+	require.NoError(t, err)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "token:ci", entry["identity"])
+	assert.Equal(t, pool.LeaseHandle(req.ConnectionId), entry["lease"])
+	assert.Regexp(t, `^[0-9a-f]{12}$`, entry["lease"])
+	assert.NotContains(t, buf.String(), req.ConnectionId)
+}
+
+func TestPeerIdentity_NoPeer(t *testing.T) {
+	assert.Empty(t, peerIdentity(context.Background()))
+}
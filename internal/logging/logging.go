@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Output formats accepted in Config
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Config controls where and how the service logs
+type Config struct {
+	// Format is json or console. Defaults to json.
+	Format string
+	// Level is a zerolog level name such as debug, info or warn. Defaults to info.
+	Level string
+	// SampleRate keeps one in every SampleRate debug and info messages. Warnings and
+	// errors are never sampled. Zero or one disables sampling.
+	SampleRate uint32
+
+	// File additionally writes JSON logs to this path, rotated by size
+	File string
+	// MaxSizeMB is the size at which the log file is rotated. Defaults to 100.
+	MaxSizeMB int
+	// MaxBackups is the number of rotated files kept. Zero keeps them all.
+	MaxBackups int
+	// MaxAgeDays removes rotated files older than this. Zero keeps them regardless of age.
+	MaxAgeDays int
+	Compress   bool
+}
+
+// Setup builds the logger described by cfg and installs it as the global and default
+// context logger. The returned closer flushes the file sink, if any.
+func Setup(cfg Config) (zerolog.Logger, io.Closer, error) {
+	level := zerolog.InfoLevel
+	if cfg.Level != "" {
+		var err error
+		if level, err = zerolog.ParseLevel(cfg.Level); err != nil {
+			return zerolog.Logger{}, nil, fmt.Errorf("invalid log level %q", cfg.Level)
+		}
+	}
+
+	var out io.Writer
+	switch cfg.Format {
+	case "", FormatJSON:
+		out = os.Stderr
+	case FormatConsole:
+		out = zerolog.ConsoleWriter{Out: os.Stderr}
+	default:
+		return zerolog.Logger{}, nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+
+	var closer io.Closer = nopCloser{}
+	if cfg.File != "" {
+		maxSize := cfg.MaxSizeMB
+		if maxSize <= 0 {
+			maxSize = 100
+		}
+		file := &lumberjack.Logger{
+			Filename:   cfg.File,
+			MaxSize:    maxSize,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAgeDays,
+			Compress:   cfg.Compress,
+		}
+		out = zerolog.MultiLevelWriter(out, file)
+		closer = file
+	}
+
+	logger := zerolog.New(out).Level(level).With().Timestamp().Logger()
+	if cfg.SampleRate > 1 {
+		sampler := &zerolog.BasicSampler{N: cfg.SampleRate}
+		logger = logger.Sample(zerolog.LevelSampler{TraceSampler: sampler, DebugSampler: sampler, InfoSampler: sampler})
+	}
+
+	log.Logger = logger
+	zerolog.DefaultContextLogger = &log.Logger
+	return logger, closer, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetup(t *testing.T) {
+	original := log.Logger
+	defer func() { log.Logger = original }()
+
+	tests := []struct {
+		name      string
+		cfg       Config
+		wantLevel zerolog.Level
+		wantErr   bool
+	}{
+		{name: "Defaults", cfg: Config{}, wantLevel: zerolog.InfoLevel},
+		{name: "Console debug", cfg: Config{Format: FormatConsole, Level: "debug"}, wantLevel: zerolog.DebugLevel},
+		{name: "JSON warn", cfg: Config{Format: FormatJSON, Level: "warn"}, wantLevel: zerolog.WarnLevel},
+		{name: "Unknown format", cfg: Config{Format: "xml"}, wantErr: true},
+		{name: "Unknown level", cfg: Config{Level: "loud"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, closer, err := Setup(tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer closer.Close()
+			assert.Equal(t, tt.wantLevel, logger.GetLevel())
+			assert.Equal(t, tt.wantLevel, log.Logger.GetLevel())
+		})
+	}
+}
+
+func TestSetup_FileSink(t *testing.T) {
+	original := log.Logger
+	defer func() { log.Logger = original }()
+
+	path := filepath.Join(t.TempDir(), "pool.log")
+	logger, closer, err := Setup(Config{File: path, SampleRate: 2})
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		logger.Info().Int("i", i).Msg("sampled")
+	}
+	logger.Warn().Msg("kept")
+	require.NoError(t, closer.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := splitLines(data)
+	// Every second info message and every warning are written
+	require.Len(t, lines, 3)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[2], &entry))
+	assert.Equal(t, "warn", entry["level"])
+	assert.Equal(t, "kept", entry["message"])
+}
+
+func splitLines(data []byte) [][]byte {
+	var lines [][]byte
+	start := 0
+	for i, b := range data {
+		if b == '\n' {
+			lines = append(lines, data[start:i])
+			start = i + 1
+		}
+	}
+	return lines
+}
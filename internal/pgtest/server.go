@@ -0,0 +1,547 @@
+// Package pgtest provides a minimal PostgreSQL server for tests that need a real
+// wire-protocol peer but no database. Only trust authentication, the simple query
+// protocol and unnamed or named statements of the extended protocol with text
+// parameters are supported. A parameter written as $n::bytea is described as bytea and
+// may also be bound in binary.
+package pgtest
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+// Result is the response to a single statement
+type Result struct {
+	Columns []string
+	// Types holds the columns' type OIDs; columns without one are text
+	Types []uint32
+	Rows  [][]string
+	Tag   string
+	// Err makes the statement fail with this message
+	Err string
+
+	// nulls marks values of the single echoed row that are NULL
+	nulls []bool
+}
+
+// Handler answers a statement. Returning false falls back to the default response.
+type Handler func(pid uint32, query string) (Result, bool)
+
+// Server is a fake PostgreSQL server listening on localhost
+type Server struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	handler Handler
+	// startupErr fails every new connection's startup when set
+	startupErr *pgproto3.ErrorResponse
+	queries    []string
+	cancels    []uint32
+	conns      map[uint32]net.Conn
+	nextPID    uint32
+	wg         sync.WaitGroup
+}
+
+// NewServer starts a fake server on a random localhost port
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		listener: listener,
+		conns:    make(map[uint32]net.Conn),
+		nextPID:  1000,
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// DSN returns a connection string for the server
+func (s *Server) DSN() string {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return fmt.Sprintf("host=127.0.0.1 port=%d user=test dbname=test sslmode=disable", addr.Port)
+}
+
+// SetHandler installs a handler consulted before the default responses
+func (s *Server) SetHandler(handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler = handler
+}
+
+// SetStartupError makes new connections fail startup with a FATAL error carrying the
+// SQLSTATE code and message, as a failed login or a missing database would. An empty
+// code lets connections in again.
+func (s *Server) SetStartupError(code, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.startupErr = nil
+	if code != "" {
+		s.startupErr = &pgproto3.ErrorResponse{Severity: "FATAL", Code: code, Message: message}
+	}
+}
+
+// Queries returns every statement received so far, in order
+func (s *Server) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.queries...)
+}
+
+// CancelRequests returns the backend PIDs of every cancel request received so far.
+// Cancel requests are recorded but do not interrupt handlers.
+func (s *Server) CancelRequests() []uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]uint32(nil), s.cancels...)
+}
+
+// ConnCount returns the number of open client connections
+func (s *Server) ConnCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// Close stops the server and closes every connection
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	backend := pgproto3.NewBackend(conn, conn)
+
+	msg, err := backend.ReceiveStartupMessage()
+	if err != nil {
+		return
+	}
+	switch msg.(type) {
+	case *pgproto3.SSLRequest, *pgproto3.GSSEncRequest:
+		if _, err := conn.Write([]byte{'N'}); err != nil {
+			return
+		}
+		if _, err := backend.ReceiveStartupMessage(); err != nil {
+			return
+		}
+	case *pgproto3.CancelRequest:
+		s.mu.Lock()
+		s.cancels = append(s.cancels, msg.(*pgproto3.CancelRequest).ProcessID)
+		s.mu.Unlock()
+		return
+	}
+
+	s.mu.Lock()
+	if startupErr := s.startupErr; startupErr != nil {
+		s.mu.Unlock()
+		backend.Send(startupErr)
+		backend.Flush()
+		return
+	}
+	s.nextPID++
+	pid := s.nextPID
+	s.conns[pid] = conn
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, pid)
+		s.mu.Unlock()
+	}()
+
+	backend.Send(&pgproto3.AuthenticationOk{})
+	backend.Send(&pgproto3.ParameterStatus{Name: "server_version", Value: "16.0"})
+	backend.Send(&pgproto3.ParameterStatus{Name: "client_encoding", Value: "UTF8"})
+	backend.Send(&pgproto3.ParameterStatus{Name: "standard_conforming_strings", Value: "on"})
+	backend.Send(&pgproto3.BackendKeyData{ProcessID: pid, SecretKey: pid})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+	if err := backend.Flush(); err != nil {
+		return
+	}
+
+	txStatus := byte('I')
+	ext := &extendedState{statements: make(map[string]string), portals: make(map[string]*portal)}
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return
+		}
+		switch msg := msg.(type) {
+		case *pgproto3.Query:
+			txStatus = s.query(backend, pid, msg.String, txStatus)
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: txStatus})
+			if err := backend.Flush(); err != nil {
+				return
+			}
+		case *pgproto3.Parse, *pgproto3.Bind, *pgproto3.Describe, *pgproto3.Execute, *pgproto3.Close:
+			txStatus = s.extended(backend, pid, ext, msg, txStatus)
+		case *pgproto3.Flush:
+			if err := backend.Flush(); err != nil {
+				return
+			}
+		case *pgproto3.Sync:
+			ext.failed = false
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: txStatus})
+			if err := backend.Flush(); err != nil {
+				return
+			}
+		case *pgproto3.Terminate:
+			return
+		default:
+			backend.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "0A000", Message: fmt.Sprintf("pgtest: unsupported message %T", msg)})
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: txStatus})
+			if err := backend.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) query(backend *pgproto3.Backend, pid uint32, sql string, txStatus byte) byte {
+	statements := splitStatements(sql)
+	if len(statements) == 0 {
+		backend.Send(&pgproto3.EmptyQueryResponse{})
+		return txStatus
+	}
+
+	for _, stmt := range statements {
+		if txStatus == 'E' && !isTxEnd(stmt) {
+			s.record(stmt)
+			backend.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "25P02", Message: "current transaction is aborted"})
+			return txStatus
+		}
+
+		result := s.execute(pid, stmt, nil, nil)
+		if result.Err != "" {
+			backend.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "XX000", Message: result.Err})
+			if txStatus == 'T' {
+				return 'E'
+			}
+			return txStatus
+		}
+		if len(result.Columns) > 0 {
+			backend.Send(rowDescription(result))
+		}
+		sendRows(backend, result)
+		txStatus = nextTxStatus(stmt, txStatus)
+	}
+	return txStatus
+}
+
+func (s *Server) record(stmt string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries = append(s.queries, stmt)
+}
+
+// execute records a statement and works out its result
+func (s *Server) execute(pid uint32, stmt string, params [][]byte, types []uint32) Result {
+	s.record(stmt)
+	s.mu.Lock()
+	handler := s.handler
+	s.mu.Unlock()
+
+	if handler != nil {
+		if result, ok := handler(pid, stmt); ok {
+			return result
+		}
+	}
+	if len(params) > 0 && keyword(stmt) == "SELECT" {
+		return echoResult(params, types)
+	}
+	return defaultResult(pid, stmt)
+}
+
+// echoResult answers a parameterised SELECT with one row holding its parameters, so
+// "SELECT $1, $2" returns what was bound. NULL parameters are returned as NULL.
+func echoResult(params [][]byte, types []uint32) Result {
+	result := Result{Columns: make([]string, len(params)), Types: types, Rows: [][]string{make([]string, len(params))}, Tag: "SELECT 1"}
+	result.nulls = make([]bool, len(params))
+	for i, param := range params {
+		result.Columns[i] = "?column?"
+		result.Rows[0][i] = string(param)
+		result.nulls[i] = param == nil
+	}
+	return result
+}
+
+func rowDescription(result Result) *pgproto3.RowDescription {
+	fields := make([]pgproto3.FieldDescription, len(result.Columns))
+	for i, name := range result.Columns {
+		oid := uint32(textOID)
+		if i < len(result.Types) {
+			oid = result.Types[i]
+		}
+		fields[i] = pgproto3.FieldDescription{Name: []byte(name), DataTypeOID: oid, DataTypeSize: -1, TypeModifier: -1}
+	}
+	return &pgproto3.RowDescription{Fields: fields}
+}
+
+func sendRows(backend *pgproto3.Backend, result Result) {
+	for _, row := range result.Rows {
+		values := make([][]byte, len(row))
+		for i, v := range row {
+			if i >= len(result.nulls) || !result.nulls[i] {
+				values[i] = []byte(v)
+			}
+		}
+		backend.Send(&pgproto3.DataRow{Values: values})
+	}
+	backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(result.Tag)})
+}
+
+func nextTxStatus(stmt string, txStatus byte) byte {
+	switch keyword(stmt) {
+	case "BEGIN", "START":
+		return 'T'
+	case "COMMIT", "END", "ROLLBACK", "ABORT":
+		return 'I'
+	}
+	return txStatus
+}
+
+// extendedState is a connection's prepared statements and portals
+type extendedState struct {
+	statements map[string]string
+	portals    map[string]*portal
+	// failed skips messages after an error until the next Sync
+	failed bool
+}
+
+type portal struct {
+	sql    string
+	params [][]byte
+	types  []uint32
+	// result is worked out when the portal is first described or executed
+	result *Result
+}
+
+func (p *portal) run(s *Server, pid uint32) Result {
+	if p.result == nil {
+		result := s.execute(pid, p.sql, p.params, p.types)
+		p.result = &result
+	}
+	return *p.result
+}
+
+// extended handles a message of the extended query protocol
+func (s *Server) extended(backend *pgproto3.Backend, pid uint32, ext *extendedState, msg pgproto3.FrontendMessage, txStatus byte) byte {
+	if ext.failed {
+		return txStatus
+	}
+	fail := func(code, message string) byte {
+		ext.failed = true
+		backend.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: code, Message: message})
+		if txStatus == 'T' {
+			return 'E'
+		}
+		return txStatus
+	}
+
+	switch msg := msg.(type) {
+	case *pgproto3.Parse:
+		if len(splitStatements(msg.Query)) > 1 {
+			return fail("42601", "cannot insert multiple commands into a prepared statement")
+		}
+		ext.statements[msg.Name] = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(msg.Query), ";"))
+		backend.Send(&pgproto3.ParseComplete{})
+	case *pgproto3.Bind:
+		sql, ok := ext.statements[msg.PreparedStatement]
+		if !ok {
+			return fail("26000", fmt.Sprintf("prepared statement %q does not exist", msg.PreparedStatement))
+		}
+		types := paramTypes(sql)
+		params := make([][]byte, len(msg.Parameters))
+		for i, param := range msg.Parameters {
+			format := int16(0)
+			switch len(msg.ParameterFormatCodes) {
+			case 0:
+			case 1:
+				format = msg.ParameterFormatCodes[0]
+			default:
+				format = msg.ParameterFormatCodes[i]
+			}
+			switch {
+			case format == 0 || param == nil:
+				params[i] = param
+			case i < len(types) && types[i] == byteaOID:
+				// Kept in bytea's text output format, as it would be echoed
+				params[i] = []byte(`\x` + hex.EncodeToString(param))
+			default:
+				return fail("0A000", "pgtest: only text and bytea parameters are supported")
+			}
+		}
+		ext.portals[msg.DestinationPortal] = &portal{sql: sql, params: params, types: types}
+		backend.Send(&pgproto3.BindComplete{})
+	case *pgproto3.Describe:
+		if msg.ObjectType == 'S' {
+			sql, ok := ext.statements[msg.Name]
+			if !ok {
+				return fail("26000", fmt.Sprintf("prepared statement %q does not exist", msg.Name))
+			}
+			backend.Send(&pgproto3.ParameterDescription{ParameterOIDs: paramTypes(sql)})
+			// Columns are only known once parameters are bound
+			backend.Send(&pgproto3.NoData{})
+			return txStatus
+		}
+		p, ok := ext.portals[msg.Name]
+		if !ok {
+			return fail("34000", fmt.Sprintf("portal %q does not exist", msg.Name))
+		}
+		if txStatus == 'E' && !isTxEnd(p.sql) {
+			return txStatus
+		}
+		if result := p.run(s, pid); result.Err == "" && len(result.Columns) > 0 {
+			backend.Send(rowDescription(result))
+		} else {
+			backend.Send(&pgproto3.NoData{})
+		}
+	case *pgproto3.Execute:
+		p, ok := ext.portals[msg.Portal]
+		if !ok {
+			return fail("34000", fmt.Sprintf("portal %q does not exist", msg.Portal))
+		}
+		if txStatus == 'E' && !isTxEnd(p.sql) {
+			s.record(p.sql)
+			ext.failed = true
+			backend.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "25P02", Message: "current transaction is aborted"})
+			return txStatus
+		}
+		result := p.run(s, pid)
+		if result.Err != "" {
+			return fail("XX000", result.Err)
+		}
+		sendRows(backend, result)
+		return nextTxStatus(p.sql, txStatus)
+	case *pgproto3.Close:
+		if msg.ObjectType == 'S' {
+			delete(ext.statements, msg.Name)
+		} else {
+			delete(ext.portals, msg.Name)
+		}
+		backend.Send(&pgproto3.CloseComplete{})
+	}
+	return txStatus
+}
+
+// Type OIDs of parameters
+const (
+	byteaOID = 17
+	textOID  = 25
+)
+
+// paramTypes returns the type of each of sql's parameters: bytea when it is written as
+// $n::bytea, and text otherwise
+func paramTypes(sql string) []uint32 {
+	types := make([]uint32, countParams(sql))
+	for i := range types {
+		types[i] = textOID
+		if strings.Contains(sql, fmt.Sprintf("$%d::bytea", i+1)) {
+			types[i] = byteaOID
+		}
+	}
+	return types
+}
+
+// countParams returns the highest $n placeholder in sql
+func countParams(sql string) int {
+	highest := 0
+	for i := 0; i < len(sql); i++ {
+		if sql[i] != '$' {
+			continue
+		}
+		n := 0
+		for i+1 < len(sql) && sql[i+1] >= '0' && sql[i+1] <= '9' {
+			i++
+			n = n*10 + int(sql[i]-'0')
+		}
+		highest = max(highest, n)
+	}
+	return highest
+}
+
+func defaultResult(pid uint32, stmt string) Result {
+	if strings.EqualFold(stmt, "SELECT pg_backend_pid()") {
+		return Result{Columns: []string{"pg_backend_pid"}, Rows: [][]string{{fmt.Sprint(pid)}}, Tag: "SELECT 1"}
+	}
+	tag := keyword(stmt)
+	if tag == "SELECT" {
+		tag = "SELECT 0"
+	}
+	if tag == "DISCARD" {
+		tag = "DISCARD ALL"
+	}
+	return Result{Tag: tag}
+}
+
+func splitStatements(sql string) []string {
+	var statements []string
+	for _, part := range strings.Split(sql, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" || strings.HasPrefix(part, "--") {
+			continue
+		}
+		statements = append(statements, part)
+	}
+	return statements
+}
+
+func keyword(stmt string) string {
+	fields := strings.Fields(stmt)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+func isTxEnd(stmt string) bool {
+	switch keyword(stmt) {
+	case "COMMIT", "END", "ROLLBACK", "ABORT":
+		return true
+	}
+	return false
+}
+
+// ErrClosed is returned by Kill when the backend does not exist
+var ErrClosed = errors.New("pgtest: no such backend")
+
+// Kill drops the connection of a backend, as if it had crashed
+func (s *Server) Kill(pid uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conn, exists := s.conns[pid]
+	if !exists {
+		return ErrClosed
+	}
+	return conn.Close()
+}
@@ -0,0 +1,228 @@
+// Package proxy implements a PostgreSQL wire-protocol frontend so libpq clients can use
+// the tenant pools managed by the connection pool manager.
+package proxy
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/rs/zerolog/log"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+)
+
+const startupTimeout = 30 * time.Second
+
+// Server accepts PostgreSQL client connections and forwards them to tenant pools
+type Server struct {
+	poolManager *pool.ConnectionPoolManager
+	tlsConfig   *tls.Config
+
+	mu        sync.Mutex
+	listeners []net.Listener
+	sessions  map[uint32]*session
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// NewServer creates a proxy server. Clients may upgrade to TLS when tlsConfig is set.
+func NewServer(poolManager *pool.ConnectionPoolManager, tlsConfig *tls.Config) *Server {
+	return &Server{
+		poolManager: poolManager,
+		tlsConfig:   tlsConfig,
+		sessions:    make(map[uint32]*session),
+	}
+}
+
+// Serve accepts client connections on listener until Close is called
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return net.ErrClosed
+	}
+	s.listeners = append(s.listeners, listener)
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handleConn(conn)
+		}()
+	}
+}
+
+// Close stops accepting clients, disconnects every session and waits for them to end
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for _, listener := range s.listeners {
+		listener.Close()
+	}
+	for _, sess := range s.sessions {
+		sess.conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(startupTimeout))
+
+	conn, client, startup, err := s.startup(conn)
+	if err != nil {
+		log.Debug().Err(err).Str("remote_addr", conn.RemoteAddr().String()).Msg("Proxy startup failed")
+		return
+	}
+	if startup == nil {
+		return
+	}
+
+	tenant, ok := s.resolveTenant(startup.Parameters)
+	if !ok {
+		sendFatal(client, "3D000", fmt.Sprintf("no tenant for database %q or user %q", startup.Parameters["database"], startup.Parameters["user"]))
+		return
+	}
+	if tenant.ProxyPassword == "" {
+		sendFatal(client, "28000", fmt.Sprintf("proxy access is not enabled for tenant %s", tenant.TenantID))
+		return
+	}
+	if err := authenticateSCRAM(client, tenant.ProxyPassword); err != nil {
+		log.Warn().Err(err).Str("tenant_id", tenant.TenantID).Str("remote_addr", conn.RemoteAddr().String()).Msg("Proxy authentication failed")
+		sendFatal(client, "28P01", fmt.Sprintf("password authentication failed for user %q", startup.Parameters["user"]))
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	sess := newSession(s, conn, client, tenant)
+	s.register(sess)
+	defer s.unregister(sess)
+
+	// Pool logs for this client carry the tenant and client address
+	logger := log.With().Str("tenant_id", tenant.TenantID).Str("remote_addr", conn.RemoteAddr().String()).Logger()
+	ctx := pool.WithClient(logger.WithContext(context.Background()), pool.ClientInfo{
+		Peer:     conn.RemoteAddr().String(),
+		Identity: startup.Parameters["user"],
+		CallSite: startup.Parameters["application_name"],
+	})
+	if err := sess.run(ctx); err != nil {
+		logger.Debug().Err(err).Msg("Proxy session ended with error")
+	}
+}
+
+// startup reads the startup packet, upgrading to TLS when requested. A nil startup
+// message means the connection was a cancel request and has been handled.
+func (s *Server) startup(conn net.Conn) (net.Conn, *pgproto3.Backend, *pgproto3.StartupMessage, error) {
+	client := pgproto3.NewBackend(conn, conn)
+	for {
+		msg, err := client.ReceiveStartupMessage()
+		if err != nil {
+			return conn, nil, nil, err
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.StartupMessage:
+			return conn, client, msg, nil
+		case *pgproto3.SSLRequest:
+			if s.tlsConfig == nil {
+				if _, err := conn.Write([]byte{'N'}); err != nil {
+					return conn, nil, nil, err
+				}
+				continue
+			}
+			if _, err := conn.Write([]byte{'S'}); err != nil {
+				return conn, nil, nil, err
+			}
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return conn, nil, nil, err
+			}
+			conn = tlsConn
+			client = pgproto3.NewBackend(conn, conn)
+		case *pgproto3.GSSEncRequest:
+			if _, err := conn.Write([]byte{'N'}); err != nil {
+				return conn, nil, nil, err
+			}
+		case *pgproto3.CancelRequest:
+			s.cancel(msg.ProcessID, msg.SecretKey)
+			return conn, nil, nil, nil
+		default:
+			return conn, nil, nil, fmt.Errorf("unexpected startup message %T", msg)
+		}
+	}
+}
+
+// resolveTenant identifies the tenant from the startup database name, then the user name
+func (s *Server) resolveTenant(params map[string]string) (pool.TenantConfig, bool) {
+	if tenant, ok := s.poolManager.TenantConfig(params["database"]); ok {
+		return tenant, true
+	}
+	return s.poolManager.TenantConfig(params["user"])
+}
+
+func (s *Server) register(sess *session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		var key [8]byte
+		rand.Read(key[:])
+		sess.processID = binary.BigEndian.Uint32(key[:4])
+		sess.secretKey = binary.BigEndian.Uint32(key[4:])
+		if _, taken := s.sessions[sess.processID]; !taken && sess.processID != 0 {
+			break
+		}
+	}
+	s.sessions[sess.processID] = sess
+	if s.closed {
+		sess.conn.Close()
+	}
+}
+
+func (s *Server) unregister(sess *session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, sess.processID)
+}
+
+// cancel forwards a client cancel request to the backend currently serving the session
+func (s *Server) cancel(processID, secretKey uint32) {
+	s.mu.Lock()
+	sess, exists := s.sessions[processID]
+	s.mu.Unlock()
+	if !exists || sess.secretKey != secretKey {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sess.cancelBackend(ctx); err != nil && !errors.Is(err, errNoBackend) {
+		log.Warn().Err(err).Str("tenant_id", sess.tenant.TenantID).Msg("Failed to forward cancel request")
+	}
+}
+
+func sendFatal(client *pgproto3.Backend, code, message string) {
+	client.Send(&pgproto3.ErrorResponse{Severity: "FATAL", Code: code, Message: message})
+	client.Flush()
+}
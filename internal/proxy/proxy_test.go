@@ -0,0 +1,331 @@
+package proxy
+
+import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/pgtest"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+)
+
+// setupProxy starts a fake database and a proxy in front of it with one tenant "acme"
+// using the given pool mode
+func setupProxy(t *testing.T, mode pool.PoolMode) (*pgtest.Server, string) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+
+	poolManager := pool.NewConnectionPoolManager()
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "acme", DSN: db.DSN(), ProxyPassword: "s3cret", PoolMode: mode})
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "closed", DSN: db.DSN()})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := NewServer(poolManager, nil)
+	go server.Serve(listener)
+
+	t.Cleanup(func() {
+		server.Close()
+		db.Close()
+	})
+	return db, listener.Addr().String()
+}
+
+func countQueries(db *pgtest.Server, query string) int {
+	count := 0
+	for _, q := range db.Queries() {
+		if q == query {
+			count++
+		}
+	}
+	return count
+}
+
+func proxyDSN(addr, user, database, password string) string {
+	host, port, _ := net.SplitHostPort(addr)
+	return fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=disable connect_timeout=5", host, port, user, database, password)
+}
+
+func TestProxy_QueryThroughTenantPool(t *testing.T) {
+	db, addr := setupProxy(t, pool.PoolModeSession)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := pgconn.Connect(ctx, proxyDSN(addr, "app", "acme", "s3cret"))
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+
+	assert.Equal(t, "16.0", conn.ParameterStatus("server_version"))
+
+	results, err := conn.Exec(ctx, "SELECT pg_backend_pid()").ReadAll()
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Len(t, results[0].Rows, 1)
+	assert.NotEmpty(t, string(results[0].Rows[0][0]))
+
+	_, err = conn.Exec(ctx, "BEGIN; SELECT 1; COMMIT").ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, byte('I'), conn.TxStatus())
+	assert.Contains(t, db.Queries(), "SELECT pg_backend_pid()")
+}
+
+func TestProxy_TenantFromUser(t *testing.T) {
+	_, addr := setupProxy(t, pool.PoolModeSession)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := pgconn.Connect(ctx, proxyDSN(addr, "acme", "postgres", "s3cret"))
+	require.NoError(t, err)
+	conn.Close(ctx)
+}
+
+func TestProxy_RejectedClients(t *testing.T) {
+	_, addr := setupProxy(t, pool.PoolModeSession)
+
+	tests := []struct {
+		name     string
+		user     string
+		database string
+		password string
+		wantCode string
+	}{
+		{
+			name:     "Wrong password",
+			user:     "app",
+			database: "acme",
+			password: "wrong",
+			wantCode: "28P01",
+		},
+		{
+			name:     "Unknown tenant",
+			user:     "app",
+			database: "unknown",
+			password: "s3cret",
+			wantCode: "3D000",
+		},
+		{
+			name:     "Proxy access disabled",
+			user:     "app",
+			database: "closed",
+			password: "s3cret",
+			wantCode: "28000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			_, err := pgconn.Connect(ctx, proxyDSN(addr, tt.user, tt.database, tt.password))
+			require.Error(t, err)
+			var pgErr *pgconn.PgError
+			require.ErrorAs(t, err, &pgErr)
+			assert.Equal(t, tt.wantCode, pgErr.Code)
+		})
+	}
+}
+
+func TestProxy_BackendReturnedAfterDisconnect(t *testing.T) {
+	db, addr := setupProxy(t, pool.PoolModeSession)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := pgconn.Connect(ctx, proxyDSN(addr, "app", "acme", "s3cret"))
+	require.NoError(t, err)
+	_, err = conn.Exec(ctx, "SET search_path TO acme").ReadAll()
+	require.NoError(t, err)
+	require.NoError(t, conn.Close(ctx))
+
+	// The backend is reset before it goes back to the pool
+	assert.Eventually(t, func() bool { return countQueries(db, "DISCARD ALL") == 1 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestProxy_TransactionModeReturnsBackendAtCommit(t *testing.T) {
+	db, addr := setupProxy(t, pool.PoolModeTransaction)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := pgconn.Connect(ctx, proxyDSN(addr, "app", "acme", "s3cret"))
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+
+	// The backend used to report server parameters has already been returned
+	require.Eventually(t, func() bool { return countQueries(db, "DISCARD ALL") == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	_, err = conn.Exec(ctx, "BEGIN").ReadAll()
+	require.NoError(t, err)
+	_, err = conn.Exec(ctx, "SELECT 1").ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, 1, countQueries(db, "DISCARD ALL"), "backend must be held inside a transaction")
+
+	_, err = conn.Exec(ctx, "COMMIT").ReadAll()
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return countQueries(db, "DISCARD ALL") == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	// A new statement attaches a backend again
+	results, err := conn.Exec(ctx, "SELECT pg_backend_pid()").ReadAll()
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Eventually(t, func() bool { return countQueries(db, "DISCARD ALL") == 3 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestProxy_StatementModeRejectsTransactions(t *testing.T) {
+	_, addr := setupProxy(t, pool.PoolModeStatement)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := pgconn.Connect(ctx, proxyDSN(addr, "app", "acme", "s3cret"))
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+
+	_, err = conn.Exec(ctx, "SELECT 1").ReadAll()
+	require.NoError(t, err)
+
+	// The client is disconnected once the transaction is opened
+	_, _ = conn.Exec(ctx, "BEGIN").ReadAll()
+	_, err = conn.Exec(ctx, "SELECT 1").ReadAll()
+	assert.Error(t, err)
+}
+
+func TestScramAttribute(t *testing.T) {
+	msg := "n=user,r=abc123,s=c2FsdA=="
+	assert.Equal(t, "user", scramAttribute(msg, 'n'))
+	assert.Equal(t, "abc123", scramAttribute(msg, 'r'))
+	assert.Equal(t, "c2FsdA==", scramAttribute(msg, 's'))
+	assert.Empty(t, scramAttribute(msg, 'p'))
+}
+
+func TestAuthenticateSCRAM(t *testing.T) {
+	tests := []struct {
+		name      string
+		gs2Header string
+		binding   string
+		password  string
+		wantErr   string
+	}{
+		{name: "no channel binding", gs2Header: "n,,", binding: "biws", password: "secret"},
+		// libpq over TLS sends y,, when the server offers no SCRAM-SHA-256-PLUS
+		{name: "client supports channel binding", gs2Header: "y,,", binding: "eSws", password: "secret"},
+		{name: "binding does not match header", gs2Header: "y,,", binding: "biws", password: "secret", wantErr: errAuthFailed.Error()},
+		{name: "channel binding requested", gs2Header: "p=tls-server-end-point,,", wantErr: "channel binding is not supported"},
+		{name: "wrong password", gs2Header: "n,,", binding: "biws", password: "wrong", wantErr: errAuthFailed.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverConn, clientConn := net.Pipe()
+			defer clientConn.Close()
+			errCh := make(chan error, 1)
+			go func() {
+				defer serverConn.Close()
+				backend := pgproto3.NewBackend(serverConn, serverConn)
+				err := authenticateSCRAM(backend, "secret")
+				if err == nil {
+					err = backend.Flush()
+				}
+				errCh <- err
+			}()
+
+			frontend := pgproto3.NewFrontend(clientConn, clientConn)
+			msg, err := frontend.Receive()
+			require.NoError(t, err)
+			require.IsType(t, &pgproto3.AuthenticationSASL{}, msg)
+
+			clientFirstBare := "n=,r=rOprNGfwEbeRWgbNEkqO"
+			frontend.Send(&pgproto3.SASLInitialResponse{AuthMechanism: scramMechanism, Data: []byte(tt.gs2Header + clientFirstBare)})
+			require.NoError(t, frontend.Flush())
+			if tt.password == "" {
+				assert.EqualError(t, <-errCh, tt.wantErr)
+				return
+			}
+
+			msg, err = frontend.Receive()
+			require.NoError(t, err)
+			serverFirst := string(msg.(*pgproto3.AuthenticationSASLContinue).Data)
+			salt, err := base64.StdEncoding.DecodeString(scramAttribute(serverFirst, 's'))
+			require.NoError(t, err)
+
+			clientFinalWithoutProof := "c=" + tt.binding + ",r=" + scramAttribute(serverFirst, 'r')
+			saltedPassword, err := pbkdf2.Key(sha256.New, tt.password, salt, scramIterations, sha256.Size)
+			require.NoError(t, err)
+			clientKey := scramHMAC(saltedPassword, "Client Key")
+			storedKey := sha256.Sum256(clientKey)
+			clientSignature := scramHMAC(storedKey[:], clientFirstBare+","+serverFirst+","+clientFinalWithoutProof)
+			proof := make([]byte, len(clientKey))
+			for i := range clientKey {
+				proof[i] = clientKey[i] ^ clientSignature[i]
+			}
+			frontend.Send(&pgproto3.SASLResponse{Data: []byte(clientFinalWithoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof))})
+			require.NoError(t, frontend.Flush())
+			if tt.wantErr != "" {
+				assert.EqualError(t, <-errCh, tt.wantErr)
+				return
+			}
+
+			msg, err = frontend.Receive()
+			require.NoError(t, err)
+			require.IsType(t, &pgproto3.AuthenticationSASLFinal{}, msg)
+			require.NoError(t, <-errCh)
+		})
+	}
+}
+
+func TestProxy_WatchdogCancelsLongStatement(t *testing.T) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetHandler(func(pid uint32, query string) (pgtest.Result, bool) {
+		if query != "SELECT * FROM events" {
+			return pgtest.Result{}, false
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for !slices.Contains(db.Queries(), fmt.Sprintf("SELECT pg_cancel_backend(%d)", pid)) && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		return pgtest.Result{Err: "canceling statement due to user request"}, true
+	})
+
+	poolManager := pool.NewConnectionPoolManager()
+	poolManager.SetTenantConfig(pool.TenantConfig{
+		TenantID:      "acme",
+		DSN:           db.DSN(),
+		ProxyPassword: "s3cret",
+		Watchdog:      pool.WatchdogPolicy{MaxStatementDuration: pool.Duration{Duration: 20 * time.Millisecond}},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	poolManager.StartWatchdog(ctx, 10*time.Millisecond)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := NewServer(poolManager, nil)
+	go server.Serve(listener)
+	defer server.Close()
+
+	conn, err := pgconn.Connect(ctx, proxyDSN(listener.Addr().String(), "app", "acme", "s3cret"))
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+
+	// Quick statements are left alone
+	for range 3 {
+		_, err = conn.Exec(ctx, "SELECT 1").ReadAll()
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	_, err = conn.Exec(ctx, "SELECT * FROM events").ReadAll()
+	assert.ErrorContains(t, err, "canceling statement")
+	_, err = conn.Exec(ctx, "SELECT 1").ReadAll()
+	require.NoError(t, err)
+}
@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+const (
+	scramMechanism  = "SCRAM-SHA-256"
+	scramIterations = 4096
+)
+
+var errAuthFailed = errors.New("password authentication failed")
+
+// authenticateSCRAM runs the server side of a SCRAM-SHA-256 exchange and checks the
+// client's proof against password
+func authenticateSCRAM(backend *pgproto3.Backend, password string) error {
+	backend.Send(&pgproto3.AuthenticationSASL{AuthMechanisms: []string{scramMechanism}})
+	if err := backend.Flush(); err != nil {
+		return err
+	}
+
+	if err := backend.SetAuthType(pgproto3.AuthTypeSASL); err != nil {
+		return err
+	}
+	msg, err := backend.Receive()
+	if err != nil {
+		return err
+	}
+	initial, ok := msg.(*pgproto3.SASLInitialResponse)
+	if !ok || initial.AuthMechanism != scramMechanism {
+		return fmt.Errorf("unexpected SASL message %T", msg)
+	}
+
+	// client-first-message is "<gs2 header>n=<user>,r=<client nonce>"; the user name is
+	// ignored as PostgreSQL does, the tenant was already chosen from the startup message.
+	// The header is "n,," from clients without channel binding, and "y,," from clients
+	// that support it but were not offered SCRAM-SHA-256-PLUS, as libpq over TLS.
+	clientFirst := string(initial.Data)
+	gs2Header := clientFirst[:min(3, len(clientFirst))]
+	if gs2Header != "n,," && gs2Header != "y,," {
+		return errors.New("channel binding is not supported")
+	}
+	clientFirstBare := clientFirst[3:]
+	clientNonce := scramAttribute(clientFirstBare, 'r')
+	if clientNonce == "" {
+		return errors.New("missing client nonce")
+	}
+
+	salt := make([]byte, 16)
+	serverNonce := make([]byte, 18)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	if _, err := rand.Read(serverNonce); err != nil {
+		return err
+	}
+	nonce := clientNonce + base64.RawStdEncoding.EncodeToString(serverNonce)
+	serverFirst := fmt.Sprintf("r=%s,s=%s,i=%d", nonce, base64.StdEncoding.EncodeToString(salt), scramIterations)
+
+	backend.Send(&pgproto3.AuthenticationSASLContinue{Data: []byte(serverFirst)})
+	if err := backend.Flush(); err != nil {
+		return err
+	}
+
+	if err := backend.SetAuthType(pgproto3.AuthTypeSASLContinue); err != nil {
+		return err
+	}
+	msg, err = backend.Receive()
+	if err != nil {
+		return err
+	}
+	response, ok := msg.(*pgproto3.SASLResponse)
+	if !ok {
+		return fmt.Errorf("unexpected SASL message %T", msg)
+	}
+
+	// The client repeats its gs2 header in c=, so a downgraded header is detected
+	clientFinal := string(response.Data)
+	proofIndex := strings.LastIndex(clientFinal, ",p=")
+	if proofIndex < 0 || scramAttribute(clientFinal, 'r') != nonce ||
+		scramAttribute(clientFinal, 'c') != base64.StdEncoding.EncodeToString([]byte(gs2Header)) {
+		return errAuthFailed
+	}
+	clientFinalWithoutProof := clientFinal[:proofIndex]
+	proof, err := base64.StdEncoding.DecodeString(clientFinal[proofIndex+3:])
+	if err != nil || len(proof) != sha256.Size {
+		return errAuthFailed
+	}
+
+	saltedPassword, err := pbkdf2.Key(sha256.New, password, salt, scramIterations, sha256.Size)
+	if err != nil {
+		return err
+	}
+	clientKey := scramHMAC(saltedPassword, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	authMessage := clientFirstBare + "," + serverFirst + "," + clientFinalWithoutProof
+	clientSignature := scramHMAC(storedKey[:], authMessage)
+
+	recoveredKey := make([]byte, len(proof))
+	for i := range proof {
+		recoveredKey[i] = proof[i] ^ clientSignature[i]
+	}
+	recoveredStoredKey := sha256.Sum256(recoveredKey)
+	if subtle.ConstantTimeCompare(recoveredStoredKey[:], storedKey[:]) != 1 {
+		return errAuthFailed
+	}
+
+	serverKey := scramHMAC(saltedPassword, "Server Key")
+	serverSignature := scramHMAC(serverKey, authMessage)
+	backend.Send(&pgproto3.AuthenticationSASLFinal{Data: []byte("v=" + base64.StdEncoding.EncodeToString(serverSignature))})
+	return nil
+}
+
+func scramHMAC(key []byte, msg string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msg))
+	return mac.Sum(nil)
+}
+
+// scramAttribute returns the value of a single-letter attribute in a SCRAM message
+func scramAttribute(msg string, name byte) string {
+	for _, part := range strings.Split(msg, ",") {
+		if len(part) > 2 && part[0] == name && part[1] == '=' {
+			return part[2:]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,350 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+)
+
+var errNoBackend = errors.New("no backend attached")
+
+// reportedParameters are the server parameters passed on to proxy clients at startup
+var reportedParameters = []string{
+	"server_version", "server_encoding", "client_encoding", "application_name",
+	"DateStyle", "IntervalStyle", "TimeZone", "integer_datetimes",
+	"standard_conforming_strings", "is_superuser", "session_authorization",
+	"default_transaction_read_only", "in_hot_standby",
+}
+
+// session is a single proxy client connection. The client holds a lease on the
+// tenant pool, and a backend is attached to the session as the pool mode allows.
+type session struct {
+	server *Server
+	conn   net.Conn
+	tenant pool.TenantConfig
+	lease  *pool.Lease
+
+	// processID and secretKey are the proxy's own BackendKeyData for cancel requests
+	processID uint32
+	secretKey uint32
+
+	clientMu sync.Mutex
+	client   *pgproto3.Backend
+
+	mu       sync.Mutex
+	attached *attachment
+	// pending counts messages sent to the backend that are still owed a ReadyForQuery
+	pending int
+	// unsynced is set while extended-protocol messages are waiting for a Sync
+	unsynced bool
+	txStatus byte
+	readers  sync.WaitGroup
+}
+
+// attachment is a backend currently serving a session
+type attachment struct {
+	conn *pgxpool.Conn
+	done chan error
+}
+
+func newSession(server *Server, conn net.Conn, client *pgproto3.Backend, tenant pool.TenantConfig) *session {
+	return &session{
+		server:   server,
+		conn:     conn,
+		client:   client,
+		tenant:   tenant,
+		txStatus: 'I',
+	}
+}
+
+// run completes the startup handshake and relays messages until the client leaves
+func (sess *session) run(ctx context.Context) error {
+	poolManager := sess.server.poolManager
+	lease, err := poolManager.AcquireLease(ctx, sess.tenant.TenantID, sess.tenant.DSN)
+	if err != nil {
+		sendFatal(sess.client, "08006", fmt.Sprintf("could not connect to tenant database: %v", err))
+		return err
+	}
+	sess.lease = lease
+	defer poolManager.ReleaseLease(ctx, lease.ID)
+	// The lease lives as long as the client connection, so it never expires
+	lease.SetTTL(0)
+	// Closing the client connection ends the session, which releases the lease
+	lease.OnEvict(func() {
+		sess.clientMu.Lock()
+		sendFatal(sess.client, "57P01", "terminating connection due to administrator command")
+		sess.clientMu.Unlock()
+		sess.conn.Close()
+	})
+
+	// A backend is needed up front to report the server parameters
+	conn, err := lease.Attach(ctx)
+	if err != nil {
+		sendFatal(sess.client, "08006", fmt.Sprintf("could not connect to tenant database: %v", err))
+		return err
+	}
+
+	sess.clientMu.Lock()
+	sess.client.Send(&pgproto3.AuthenticationOk{})
+	pgConn := conn.Conn().PgConn()
+	for _, name := range reportedParameters {
+		if value := pgConn.ParameterStatus(name); value != "" {
+			sess.client.Send(&pgproto3.ParameterStatus{Name: name, Value: value})
+		}
+	}
+	sess.client.Send(&pgproto3.BackendKeyData{ProcessID: sess.processID, SecretKey: sess.secretKey})
+	sess.client.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+	err = sess.client.Flush()
+	sess.clientMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if lease.Mode == pool.PoolModeSession {
+		if err := sess.startRelay(ctx, conn); err != nil {
+			return err
+		}
+	} else {
+		lease.Detach(ctx, pool.BackendIdle)
+	}
+	defer sess.stopRelay(ctx)
+
+	logger := zerolog.Ctx(ctx)
+	logger.Info().Str("mode", string(lease.Mode)).Msg("Proxy client connected")
+	defer logger.Info().Msg("Proxy client disconnected")
+
+	return sess.relayClient(ctx)
+}
+
+// relayClient forwards client messages to the backend until the client terminates,
+// attaching a backend whenever the session has none
+func (sess *session) relayClient(ctx context.Context) error {
+	for {
+		msg, err := sess.client.Receive()
+		if err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		if _, ok := msg.(*pgproto3.Terminate); ok {
+			return nil
+		}
+
+		att, err := sess.track(ctx, msg)
+		if err != nil {
+			sess.clientMu.Lock()
+			sendFatal(sess.client, "08006", fmt.Sprintf("could not get a backend connection: %v", err))
+			sess.clientMu.Unlock()
+			return err
+		}
+
+		frontend := att.conn.Conn().PgConn().Frontend()
+		frontend.Send(msg)
+		if err := frontend.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// track records a message about to be sent and returns the backend it goes to. The
+// counters are updated before the message is sent so the backend relay never hands
+// the backend back while a reply is still due.
+func (sess *session) track(ctx context.Context, msg pgproto3.FrontendMessage) (*attachment, error) {
+	sess.lease.Touch()
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if sess.attached == nil {
+		sess.mu.Unlock()
+		conn, err := sess.lease.Attach(ctx)
+		if err == nil {
+			err = sess.startRelay(ctx, conn)
+		}
+		sess.mu.Lock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch msg.(type) {
+	case *pgproto3.Query, *pgproto3.FunctionCall:
+		sess.startStatement()
+	case *pgproto3.Sync:
+		sess.startStatement()
+		sess.unsynced = false
+	case *pgproto3.Parse, *pgproto3.Bind, *pgproto3.Describe, *pgproto3.Execute, *pgproto3.Close, *pgproto3.Flush:
+		sess.unsynced = true
+	}
+	return sess.attached, nil
+}
+
+// startStatement counts a message owed a ReadyForQuery, telling the watchdog when the
+// backend starts working. Called with mu held.
+func (sess *session) startStatement() {
+	if sess.pending == 0 {
+		sess.lease.StatementStarted()
+	}
+	sess.pending++
+}
+
+// startRelay starts forwarding messages from an attached backend to the client
+func (sess *session) startRelay(ctx context.Context, conn *pgxpool.Conn) error {
+	// The relay reads and writes the backend socket directly
+	if err := conn.Conn().PgConn().SyncConn(ctx); err != nil {
+		sess.lease.Detach(ctx, pool.BackendBroken)
+		return err
+	}
+
+	att := &attachment{conn: conn, done: make(chan error, 1)}
+	sess.mu.Lock()
+	sess.attached = att
+	sess.pending = 0
+	sess.unsynced = false
+	sess.mu.Unlock()
+
+	sess.readers.Add(1)
+	go sess.relayBackend(ctx, att)
+	return nil
+}
+
+// relayBackend forwards backend messages to the client. In transaction and statement
+// modes it hands the backend back to the pool once the client has nothing in flight.
+func (sess *session) relayBackend(ctx context.Context, att *attachment) {
+	defer sess.readers.Done()
+	frontend := att.conn.Conn().PgConn().Frontend()
+
+	for {
+		msg, err := frontend.Receive()
+		if err != nil {
+			sess.mu.Lock()
+			owned := sess.attached == att
+			if owned {
+				sess.attached = nil
+			}
+			sess.mu.Unlock()
+			if owned {
+				// The backend failed underneath the client; drop the client as PostgreSQL would
+				sess.lease.Detach(ctx, pool.BackendBroken)
+				sess.conn.Close()
+			}
+			att.done <- err
+			return
+		}
+
+		if rfq, ok := msg.(*pgproto3.ReadyForQuery); ok && rfq.TxStatus != 'I' && sess.lease.Mode == pool.PoolModeStatement {
+			// Statement mode cannot keep a backend for an open transaction. The client gets
+			// the error in place of ReadyForQuery so it cannot send anything further.
+			sess.mu.Lock()
+			if sess.attached == att {
+				sess.attached = nil
+			}
+			sess.mu.Unlock()
+			sess.clientMu.Lock()
+			sendFatal(sess.client, "08P01", pool.ErrTransactionInStatementMode.Error())
+			sess.clientMu.Unlock()
+			sess.conn.Close()
+			sess.lease.Detach(ctx, pool.BackendInTransaction)
+			att.done <- nil
+			return
+		}
+
+		sess.clientMu.Lock()
+		sess.client.Send(msg)
+		if frontend.ReadBufferLen() == 0 {
+			err = sess.client.Flush()
+		}
+		sess.clientMu.Unlock()
+		if err != nil {
+			sess.mu.Lock()
+			if sess.attached == att {
+				sess.attached = nil
+			}
+			sess.mu.Unlock()
+			sess.lease.Detach(ctx, pool.BackendBroken)
+			sess.conn.Close()
+			att.done <- err
+			return
+		}
+
+		rfq, ok := msg.(*pgproto3.ReadyForQuery)
+		if !ok {
+			continue
+		}
+
+		sess.mu.Lock()
+		sess.pending--
+		sess.txStatus = rfq.TxStatus
+		if sess.pending == 0 {
+			sess.lease.StatementFinished(rfq.TxStatus == 'I')
+		}
+		idle := sess.pending == 0 && !sess.unsynced
+		if !idle || sess.attached != att || sess.lease.Mode == pool.PoolModeSession {
+			sess.mu.Unlock()
+			continue
+		}
+		if rfq.TxStatus != 'I' {
+			sess.mu.Unlock()
+			continue
+		}
+		sess.attached = nil
+		sess.mu.Unlock()
+
+		sess.lease.Detach(ctx, pool.BackendIdle)
+		att.done <- nil
+		return
+	}
+}
+
+// stopRelay detaches the session's backend when the client leaves. An open transaction
+// is rolled back; a backend left mid-query is destroyed instead of returned to the pool.
+func (sess *session) stopRelay(ctx context.Context) {
+	sess.mu.Lock()
+	att := sess.attached
+	sess.attached = nil
+	sess.mu.Unlock()
+
+	if att != nil {
+		pgConn := att.conn.Conn().PgConn()
+		pgConn.Conn().SetReadDeadline(time.Now())
+		err := <-att.done
+		pgConn.Conn().SetReadDeadline(time.Time{})
+
+		sess.mu.Lock()
+		synced := sess.pending == 0 && !sess.unsynced
+		txStatus := sess.txStatus
+		sess.mu.Unlock()
+		synced = synced && errors.Is(err, os.ErrDeadlineExceeded) && pgConn.Frontend().ReadBufferLen() == 0
+
+		switch {
+		case !synced:
+			sess.lease.Detach(ctx, pool.BackendBroken)
+		case txStatus != 'I':
+			sess.lease.Detach(ctx, pool.BackendInTransaction)
+		default:
+			sess.lease.Detach(ctx, pool.BackendIdle)
+		}
+	}
+	sess.readers.Wait()
+}
+
+// cancelBackend sends a cancel request for the query running on the attached backend
+func (sess *session) cancelBackend(ctx context.Context) error {
+	sess.mu.Lock()
+	att := sess.attached
+	sess.mu.Unlock()
+	if att == nil {
+		return errNoBackend
+	}
+	return att.conn.Conn().PgConn().CancelRequest(ctx)
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain is the domain of the ErrorInfo details attached to errors
+const errorDomain = "connection-pool-manager"
+
+// errTenantRequired is returned for lease requests that do not name the lease's tenant
+var errTenantRequired = errors.New("invalid tenant ID")
+
+// creationCodes maps the reasons a pool could not be created to status codes. Transient
+// failures may go away on their own; the others need the tenant's config or the
+// database fixed first.
+var creationCodes = map[pool.CreationReason]codes.Code{
+	pool.ReasonDNS:              codes.Unavailable,
+	pool.ReasonTCP:              codes.Unavailable,
+	pool.ReasonTimeout:          codes.Unavailable,
+	pool.ReasonTLS:              codes.FailedPrecondition,
+	pool.ReasonAuth:             codes.FailedPrecondition,
+	pool.ReasonDatabaseNotFound: codes.FailedPrecondition,
+	pool.ReasonInvalidDSN:       codes.InvalidArgument,
+	pool.ReasonUnknown:          codes.Unavailable,
+}
+
+// rpcError gives err the gRPC status code clients should act on. The message is kept
+// as is so it matches the response's Error field.
+func rpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var (
+		parseErr   *pgconn.ParseConfigError
+		connectErr *pgconn.ConnectError
+		openErr    *pool.BreakerOpenError
+		createErr  *pool.CreationError
+	)
+	// Clients are told when the breaker lets calls through again
+	if errors.As(err, &openErr) {
+		st, detailErr := status.New(codes.Unavailable, err.Error()).WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(openErr.RetryAfter)})
+		if detailErr == nil {
+			return st.Err()
+		}
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	// Failed pool creations carry their reason so clients need not parse the message
+	if errors.As(err, &createErr) {
+		if code, ok := creationCodes[createErr.Reason]; ok {
+			st, detailErr := status.New(code, err.Error()).WithDetails(&errdetails.ErrorInfo{
+				Reason: strings.ToUpper(string(createErr.Reason)),
+				Domain: errorDomain,
+				Metadata: map[string]string{
+					"tenant_id": createErr.TenantID,
+					"server":    createErr.Server,
+					"attempts":  strconv.Itoa(createErr.Attempts),
+				},
+			})
+			if detailErr == nil {
+				return st.Err()
+			}
+			return status.Error(code, err.Error())
+		}
+	}
+	code := codes.Unknown
+	switch {
+	case errors.Is(err, errTenantRequired), errors.Is(err, pool.ErrInvalidLSN), errors.Is(err, pool.ErrInvalidTxOptions), errors.As(err, &parseErr):
+		code = codes.InvalidArgument
+	case errors.Is(err, pool.ErrLeaseNotFound), errors.Is(err, pool.ErrPoolNotFound), errors.Is(err, pool.ErrNoStatsHistory):
+		code = codes.NotFound
+	case errors.Is(err, pool.ErrTenantDraining), errors.As(err, &connectErr):
+		code = codes.Unavailable
+	case errors.Is(err, pool.ErrDSNNotAllowed):
+		code = codes.PermissionDenied
+	case errors.Is(err, pool.ErrTransactionInStatementMode):
+		code = codes.FailedPrecondition
+	case errors.Is(err, pool.ErrTransactionRolledBack):
+		code = codes.Aborted
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	}
+	return status.Error(code, err.Error())
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRPCError(t *testing.T) {
+	_, parseErr := pgconn.ParseConfig("postgres://host:notaport/db")
+
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{name: "lease not found", err: fmt.Errorf("%w: conn-1", pool.ErrLeaseNotFound), want: codes.NotFound},
+		{name: "pool not found", err: fmt.Errorf("%w for tenant acme", pool.ErrPoolNotFound), want: codes.NotFound},
+		{name: "draining", err: fmt.Errorf("%w: acme", pool.ErrTenantDraining), want: codes.Unavailable},
+		{name: "circuit open", err: &pool.BreakerOpenError{TenantID: "acme", Host: "db:5432", RetryAfter: time.Second}, want: codes.Unavailable},
+		{name: "creation unreachable", err: &pool.CreationError{TenantID: "acme", Reason: pool.ReasonTCP, Err: errors.New("dial error")}, want: codes.Unavailable},
+		{name: "creation auth", err: &pool.CreationError{TenantID: "acme", Reason: pool.ReasonAuth, Err: errors.New("password authentication failed")}, want: codes.FailedPrecondition},
+		{name: "creation invalid DSN", err: &pool.CreationError{TenantID: "acme", Reason: pool.ReasonInvalidDSN, Err: parseErr}, want: codes.InvalidArgument},
+		{name: "DSN not allowed", err: fmt.Errorf("%w: host db is not allowed", pool.ErrDSNNotAllowed), want: codes.PermissionDenied},
+		{name: "statement mode", err: pool.ErrTransactionInStatementMode, want: codes.FailedPrecondition},
+		{name: "invalid LSN", err: fmt.Errorf("%w %q", pool.ErrInvalidLSN, "x"), want: codes.InvalidArgument},
+		{name: "invalid DSN", err: parseErr, want: codes.InvalidArgument},
+		{name: "invalid transaction options", err: fmt.Errorf("%w: unknown isolation level", pool.ErrInvalidTxOptions), want: codes.InvalidArgument},
+		{name: "rolled back", err: pool.ErrTransactionRolledBack, want: codes.Aborted},
+		{name: "deadline", err: context.DeadlineExceeded, want: codes.DeadlineExceeded},
+		{name: "already a status", err: status.Error(codes.PermissionDenied, "no"), want: codes.PermissionDenied},
+		{name: "other", err: errors.New("boom"), want: codes.Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := rpcError(tt.err)
+			assert.Equal(t, tt.want, status.Code(err))
+			assert.Equal(t, status.Convert(tt.err).Message(), status.Convert(err).Message())
+		})
+	}
+	assert.NoError(t, rpcError(nil))
+}
+
+func TestRPCError_RetryInfo(t *testing.T) {
+	err := rpcError(fmt.Errorf("acquire: %w", &pool.BreakerOpenError{TenantID: "acme", Host: "db:5432", RetryAfter: 12 * time.Second}))
+	st := status.Convert(err)
+	assert.Equal(t, codes.Unavailable, st.Code())
+	require.Len(t, st.Details(), 1)
+	retryInfo, ok := st.Details()[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	assert.Equal(t, 12*time.Second, retryInfo.RetryDelay.AsDuration())
+}
+
+func TestRPCError_ErrorInfo(t *testing.T) {
+	err := rpcError(&pool.CreationError{TenantID: "acme", Server: "db:5432/acme", Reason: pool.ReasonDatabaseNotFound, Attempts: 1, Err: errors.New("database does not exist")})
+	st := status.Convert(err)
+	assert.Equal(t, codes.FailedPrecondition, st.Code())
+	require.Len(t, st.Details(), 1)
+	info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	assert.Equal(t, "DATABASE_NOT_FOUND", info.Reason)
+	assert.Equal(t, map[string]string{"tenant_id": "acme", "server": "db:5432/acme", "attempts": "1"}, info.Metadata)
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"slices"
+
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// WatchEvents streams the pool lifecycle events matching the request until the client
+// goes away. Events a slow client falls behind on are skipped, which shows as a gap in
+// their sequence numbers.
+func (s *ConnectionPoolServiceServer) WatchEvents(req *pb.WatchEventsRequest, stream pb.ConnectionPoolService_WatchEventsServer) error {
+	filter := pool.EventFilter{TenantIDs: req.TenantIds}
+	for _, name := range req.Types {
+		eventType := pool.EventType(name)
+		if !slices.Contains(pool.EventTypes, eventType) {
+			return status.Errorf(codes.InvalidArgument, "unknown event type %q", name)
+		}
+		filter.Types = append(filter.Types, eventType)
+	}
+
+	sub := s.poolManager.SubscribeEvents(filter, req.Replay, req.AfterSeq)
+	defer sub.Close()
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.shutdown:
+			return errShuttingDown
+		case event := <-sub.Events():
+			if err := stream.Send(eventToProto(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func eventToProto(event pool.Event) *pb.PoolEvent {
+	return &pb.PoolEvent{
+		Seq:                    event.Seq,
+		Time:                   timestamppb.New(event.Time),
+		Type:                   string(event.Type),
+		TenantId:               event.TenantID,
+		Server:                 event.Server,
+		FromServer:             event.FromServer,
+		Lease:                  event.Lease,
+		Healthy:                event.Healthy,
+		Error:                  event.Error,
+		MaxConnections:         event.MaxConns,
+		PreviousMaxConnections: event.PreviousMaxConns,
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConnectionPoolServiceServer_WatchEvents(t *testing.T) {
+	client, _ := setupHoldService(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lease, err := client.GetConnection(ctx, &pb.ConnectionRequest{TenantId: "acme"})
+	require.NoError(t, err)
+
+	// The pool created before the stream opened is replayed
+	stream, err := client.WatchEvents(ctx, &pb.WatchEventsRequest{
+		TenantIds: []string{"acme"},
+		Types:     []string{"pool_created", "lease_evicted"},
+		Replay:    true,
+	})
+	require.NoError(t, err)
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "pool_created", event.Type)
+	assert.Equal(t, "acme", event.TenantId)
+	assert.Equal(t, uint64(1), event.Seq)
+
+	_, err = client.EvictTenant(ctx, &pb.EvictRequest{TenantId: "acme"})
+	require.NoError(t, err)
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "lease_evicted", event.Type)
+	assert.Equal(t, pool.LeaseHandle(lease.ConnectionId), event.Lease)
+	assert.Equal(t, uint64(2), event.Seq)
+
+	stream, err = client.WatchEvents(ctx, &pb.WatchEventsRequest{Types: []string{"exploded"}})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestConnectionPoolServiceServer_WatchEventsShutdown(t *testing.T) {
+	srv := NewConnectionPoolServiceServerWithManager(pool.NewConnectionPoolManager())
+	client, _ := serveBufconn(t, srv)
+
+	stream, err := client.WatchEvents(context.Background(), &pb.WatchEventsRequest{})
+	require.NoError(t, err)
+	srv.Shutdown()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// HoldLease acquires a lease with the stream's first message and holds it until the
+// stream ends. Every ping renews the lease, so a client that stops pinging loses it to
+// the reaper even if its connection lingers. The lease is released as soon as the
+// client closes the stream or goes away or the server shuts down, and the stream fails
+// if the lease is evicted or expires.
+func (s *ConnectionPoolServiceServer) HoldLease(stream pb.ConnectionPoolService_HoldLeaseServer) error {
+	ctx := stream.Context()
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	req := first.GetAcquire()
+	if req == nil {
+		return status.Error(codes.InvalidArgument, "the first message must acquire a lease")
+	}
+
+	lease, fromReplica, err := s.acquire(ctx, req)
+	if err != nil {
+		return rpcError(err)
+	}
+	evicted := make(chan struct{})
+	var once sync.Once
+	lease.OnEvict(func() { once.Do(func() { close(evicted) }) })
+	// The lease may already be gone after an eviction, so a failed release is expected
+	defer s.poolManager.ReleaseLease(context.WithoutCancel(ctx), lease.ID)
+
+	err = stream.Send(&pb.HoldLeaseResponse{Msg: &pb.HoldLeaseResponse_Lease{Lease: &pb.ConnectionResponse{
+		ConnectionId: lease.ID,
+		FromReplica:  fromReplica,
+		ExpiresAt:    expiryTimestamp(lease.ExpiresAt()),
+	}}})
+	if err != nil {
+		return err
+	}
+
+	pings := make(chan error)
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err == nil && msg.GetPing() == nil {
+				err = status.Error(codes.InvalidArgument, "only pings may follow the first message")
+			}
+			select {
+			case pings <- err:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	ttl := time.Duration(req.TtlSeconds) * time.Second
+	for {
+		select {
+		case <-evicted:
+			return status.Errorf(codes.Unavailable, "lease %s was evicted or expired", lease.Handle())
+		case <-ctx.Done():
+			return rpcError(ctx.Err())
+		case <-s.shutdown:
+			return errShuttingDown
+		case err := <-pings:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			expiresAt, err := s.poolManager.RenewLease(ctx, lease.ID, ttl)
+			if err != nil {
+				return rpcError(err)
+			}
+			pong := &pb.LeasePong{ExpiresAt: expiryTimestamp(expiresAt)}
+			if err := stream.Send(&pb.HoldLeaseResponse{Msg: &pb.HoldLeaseResponse_Pong{Pong: pong}}); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/pgtest"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// setupHoldService serves tenant "acme" over bufconn and returns a client for it
+func setupHoldService(t *testing.T) (pb.ConnectionPoolServiceClient, *pool.ConnectionPoolManager) {
+	client, poolManager, _ := setupHoldServer(t)
+	return client, poolManager
+}
+
+// setupHoldServer is setupHoldService also returning the service
+func setupHoldServer(t *testing.T) (pb.ConnectionPoolServiceClient, *pool.ConnectionPoolManager, *ConnectionPoolServiceServer) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+	poolManager := pool.NewConnectionPoolManager()
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "acme", DSN: db.DSN()})
+
+	srv := NewConnectionPoolServiceServerWithManager(poolManager)
+	client, _ := serveBufconn(t, srv)
+	t.Cleanup(func() {
+		poolManager.ReleaseConnection(context.Background(), "acme", db.DSN())
+		db.Close()
+	})
+	return client, poolManager, srv
+}
+
+// serveBufconn serves srv over bufconn and returns a client for it and the gRPC server
+func serveBufconn(t *testing.T, srv *ConnectionPoolServiceServer) (pb.ConnectionPoolServiceClient, *grpc.Server) {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	RegisterServer(server, srv)
+	go server.Serve(listener)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+	})
+	return pb.NewConnectionPoolServiceClient(conn), server
+}
+
+// holdLease opens a HoldLease stream and acquires a lease for tenant "acme" on it
+func holdLease(t *testing.T, ctx context.Context, client pb.ConnectionPoolServiceClient) (pb.ConnectionPoolService_HoldLeaseClient, *pb.ConnectionResponse) {
+	stream, err := client.HoldLease(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pb.HoldLeaseRequest{Msg: &pb.HoldLeaseRequest_Acquire{
+		Acquire: &pb.ConnectionRequest{TenantId: "acme", TtlSeconds: 30},
+	}}))
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.NotNil(t, resp.GetLease())
+	return stream, resp.GetLease()
+}
+
+func TestConnectionPoolServiceServer_HoldLease(t *testing.T) {
+	client, poolManager := setupHoldService(t)
+	ctx := context.Background()
+
+	stream, lease := holdLease(t, ctx, client)
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), lease.ExpiresAt.AsTime(), time.Second)
+	_, held := poolManager.Lease(lease.ConnectionId)
+	assert.True(t, held)
+
+	// Pings renew the lease
+	poolManager.RenewLease(ctx, lease.ConnectionId, time.Second)
+	require.NoError(t, stream.Send(&pb.HoldLeaseRequest{Msg: &pb.HoldLeaseRequest_Ping{Ping: &pb.LeasePing{}}}))
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.NotNil(t, resp.GetPong())
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), resp.GetPong().ExpiresAt.AsTime(), time.Second)
+
+	// Closing the stream releases the lease
+	require.NoError(t, stream.CloseSend())
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+	_, held = poolManager.Lease(lease.ConnectionId)
+	assert.False(t, held)
+}
+
+func TestConnectionPoolServiceServer_HoldLeaseEnds(t *testing.T) {
+	tests := []struct {
+		name     string
+		end      func(cancel context.CancelFunc, poolManager *pool.ConnectionPoolManager, srv *ConnectionPoolServiceServer)
+		wantCode codes.Code
+	}{
+		{
+			name: "client goes away",
+			end: func(cancel context.CancelFunc, _ *pool.ConnectionPoolManager, _ *ConnectionPoolServiceServer) {
+				cancel()
+			},
+			wantCode: codes.Canceled,
+		},
+		{
+			name: "tenant evicted",
+			end: func(_ context.CancelFunc, poolManager *pool.ConnectionPoolManager, _ *ConnectionPoolServiceServer) {
+				go poolManager.EvictTenant(context.Background(), "acme")
+			},
+			wantCode: codes.Unavailable,
+		},
+		{
+			name: "server shuts down",
+			end: func(_ context.CancelFunc, _ *pool.ConnectionPoolManager, srv *ConnectionPoolServiceServer) {
+				srv.Shutdown()
+			},
+			wantCode: codes.Unavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, poolManager, srv := setupHoldServer(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			stream, lease := holdLease(t, ctx, client)
+			tt.end(cancel, poolManager, srv)
+
+			_, err := stream.Recv()
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Eventually(t, func() bool {
+				_, held := poolManager.Lease(lease.ConnectionId)
+				return !held
+			}, time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestConnectionPoolServiceServer_HoldLeaseRequiresAcquire(t *testing.T) {
+	client, _ := setupHoldService(t)
+
+	stream, err := client.HoldLease(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pb.HoldLeaseRequest{Msg: &pb.HoldLeaseRequest_Ping{Ping: &pb.LeasePing{}}}))
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
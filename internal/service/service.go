@@ -2,62 +2,394 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"sync"
 	"time"
 
+	"github.com/teresa-solution/connection-pool-manager/internal/auth"
 	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
 	pb "github.com/teresa-solution/connection-pool-manager/proto"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type ConnectionPoolServiceServer struct {
 	pb.UnimplementedConnectionPoolServiceServer
 	poolManager *pool.ConnectionPoolManager
+
+	// shutdown is closed to end the streams, which otherwise run until clients close them
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
 }
 
 func NewConnectionPoolServiceServer() *ConnectionPoolServiceServer {
+	return NewConnectionPoolServiceServerWithManager(pool.NewConnectionPoolManager())
+}
+
+// NewConnectionPoolServiceServerWithManager creates a server backed by an existing pool manager
+func NewConnectionPoolServiceServerWithManager(poolManager *pool.ConnectionPoolManager) *ConnectionPoolServiceServer {
 	return &ConnectionPoolServiceServer{
-		poolManager: pool.NewConnectionPoolManager(),
+		poolManager: poolManager,
+		shutdown:    make(chan struct{}),
 	}
 }
 
+// errShuttingDown ends streams when the server shuts down, so clients reconnect elsewhere
+var errShuttingDown = status.Error(codes.Unavailable, "server is shutting down")
+
+// Shutdown ends the server's open streams and makes new ones end straight away. Call
+// it before stopping the gRPC server gracefully, which waits for every stream to end.
+func (s *ConnectionPoolServiceServer) Shutdown() {
+	s.shutdownOnce.Do(func() { close(s.shutdown) })
+}
+
 func (s *ConnectionPoolServiceServer) GetConnection(ctx context.Context, req *pb.ConnectionRequest) (*pb.ConnectionResponse, error) {
-	_, err := s.poolManager.GetConnection(ctx, req.TenantId, req.Dsn)
+	lease, fromReplica, err := s.acquire(ctx, req)
 	if err != nil {
-		return &pb.ConnectionResponse{Error: err.Error()}, err
+		return &pb.ConnectionResponse{Error: err.Error()}, rpcError(err)
 	}
-	// Simulate connection ID (in practice, this could be a unique identifier)
-	connID := fmt.Sprintf("conn-%s-%d", req.TenantId, time.Now().UnixNano())
-	return &pb.ConnectionResponse{ConnectionId: connID}, nil
+	return &pb.ConnectionResponse{ConnectionId: lease.ID, FromReplica: fromReplica, ExpiresAt: expiryTimestamp(lease.ExpiresAt())}, nil
 }
 
-func (s *ConnectionPoolServiceServer) ReleaseConnection(ctx context.Context, req *pb.ConnectionRelease) (*pb.ReleaseResponse, error) {
-	// Parse connection ID to extract tenantID and dsn (simplified logic)
-	parts := strings.Split(req.ConnectionId, "-")
-	if len(parts) < 2 {
-		return &pb.ReleaseResponse{Success: false, Error: "invalid connection ID"}, fmt.Errorf("invalid connection ID")
+// acquire leases a connection for req, routing read-only requests to a replica, and
+// reports whether the lease is on one
+func (s *ConnectionPoolServiceServer) acquire(ctx context.Context, req *pb.ConnectionRequest) (*pool.Lease, bool, error) {
+	setSpanTenant(ctx, req.TenantId)
+	dsn := s.resolveDSN(req.TenantId, req.Dsn)
+	fromReplica := false
+	if req.ReadOnly || req.MinLsn != "" {
+		var err error
+		dsn, fromReplica, err = s.getReadDSN(ctx, req)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	lease, err := s.poolManager.AcquireLease(pool.WithClient(ctx, clientInfo(ctx, req.CallSite)), req.TenantId, dsn)
+	if err != nil {
+		return nil, false, err
+	}
+	if req.TtlSeconds > 0 {
+		lease.SetTTL(time.Duration(req.TtlSeconds) * time.Second)
+	}
+	return lease, fromReplica, nil
+}
+
+// expiryTimestamp converts a lease expiry, leaving it unset for leases that never expire
+func expiryTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// clientInfo describes the caller for lease diagnostics
+func clientInfo(ctx context.Context, callSite string) pool.ClientInfo {
+	client := pool.ClientInfo{CallSite: callSite}
+	client.Identity, _ = auth.IdentityFromContext(ctx)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		client.Peer = p.Addr.String()
+	}
+	return client
+}
+
+func (s *ConnectionPoolServiceServer) RenewLease(ctx context.Context, req *pb.RenewLeaseRequest) (*pb.RenewLeaseResponse, error) {
+	if _, err := s.lease(ctx, req.TenantId, req.ConnectionId); err != nil {
+		return &pb.RenewLeaseResponse{Error: err.Error()}, rpcError(err)
 	}
-	tenantID := parts[1]
-	err := s.poolManager.ReleaseConnection(ctx, tenantID, "host=localhost port=5432 user=admin password=securepassword dbname=tenant_registry")
+	expiresAt, err := s.poolManager.RenewLease(ctx, req.ConnectionId, time.Duration(req.TtlSeconds)*time.Second)
 	if err != nil {
-		return &pb.ReleaseResponse{Success: false, Error: err.Error()}, err
+		return &pb.RenewLeaseResponse{Error: err.Error()}, rpcError(err)
+	}
+	return &pb.RenewLeaseResponse{ExpiresAt: expiryTimestamp(expiresAt)}, nil
+}
+
+// getReadDSN routes a read-only request to a replica when the tenant has any
+func (s *ConnectionPoolServiceServer) getReadDSN(ctx context.Context, req *pb.ConnectionRequest) (string, bool, error) {
+	var minLSN pool.LSN
+	if req.MinLsn != "" {
+		var err error
+		if minLSN, err = pool.ParseLSN(req.MinLsn); err != nil {
+			return "", false, err
+		}
+	}
+
+	cfg, ok := s.poolManager.TenantConfig(req.TenantId)
+	if !ok || len(cfg.ReplicaDSNs) == 0 {
+		return s.resolveDSN(req.TenantId, req.Dsn), false, nil
+	}
+
+	_, dsn, err := s.poolManager.GetReplicaConnection(ctx, req.TenantId, minLSN)
+	if err != nil {
+		return "", false, err
+	}
+	return dsn, dsn != cfg.DSN, nil
+}
+
+// resolveDSN falls back to the tenant's configured DSN when the request has none
+func (s *ConnectionPoolServiceServer) resolveDSN(tenantID, dsn string) string {
+	if dsn != "" {
+		return dsn
+	}
+	if cfg, ok := s.poolManager.TenantConfig(tenantID); ok {
+		return cfg.DSN
+	}
+	return dsn
+}
+
+func (s *ConnectionPoolServiceServer) ReleaseConnection(ctx context.Context, req *pb.ConnectionRelease) (*pb.ReleaseResponse, error) {
+	if req.ConnectionId == "" {
+		return &pb.ReleaseResponse{Success: false, Error: "invalid connection ID"}, status.Error(codes.InvalidArgument, "invalid connection ID")
+	}
+	if _, err := s.lease(ctx, req.TenantId, req.ConnectionId); err != nil {
+		return &pb.ReleaseResponse{Success: false, Error: err.Error()}, rpcError(err)
+	}
+	if err := s.poolManager.ReleaseLease(ctx, req.ConnectionId); err != nil {
+		return &pb.ReleaseResponse{Success: false, Error: err.Error()}, rpcError(err)
 	}
 	return &pb.ReleaseResponse{Success: true}, nil
 }
 
-func (s *ConnectionPoolServiceServer) GetPoolStats(ctx context.Context, req *pb.StatsRequest) (*pb.StatsResponse, error) {
-	stats, err := s.poolManager.GetStats(ctx, req.TenantId, "host=localhost port=5432 user=admin password=securepassword dbname=tenant_registry")
+func (s *ConnectionPoolServiceServer) ExecuteQuery(ctx context.Context, req *pb.QueryRequest) (*pb.QueryResponse, error) {
+	lease, err := s.lease(ctx, req.TenantId, req.ConnectionId)
 	if err != nil {
-		return &pb.StatsResponse{Error: err.Error()}, err
+		return &pb.QueryResponse{Error: err.Error()}, rpcError(err)
+	}
+
+	params := make([]pool.Param, len(req.Params))
+	for i, param := range req.Params {
+		switch {
+		case param.IsNull:
+		case param.IsBytes:
+			// Non-nil even when empty, since nil is NULL
+			params[i] = pool.Param{Value: append([]byte{}, param.BytesValue...), Raw: true}
+		default:
+			params[i] = pool.Param{Value: []byte(param.Value)}
+		}
+	}
+
+	result, err := lease.Exec(ctx, req.Sql, params)
+	if err != nil {
+		return &pb.QueryResponse{Error: err.Error()}, rpcError(err)
+	}
+
+	rows := make([]*pb.Row, len(result.Rows))
+	for i, row := range result.Rows {
+		values := make([]*pb.NullableString, len(row))
+		for j, value := range row {
+			values[j] = &pb.NullableString{Value: string(value), IsNull: value == nil}
+		}
+		rows[i] = &pb.Row{Values: values}
+	}
+	return &pb.QueryResponse{
+		Columns:     result.Columns,
+		ColumnTypes: result.ColumnTypes,
+		Rows:        rows,
+		CommandTag:  result.CommandTag,
+	}, nil
+}
+
+func (s *ConnectionPoolServiceServer) BeginTransaction(ctx context.Context, req *pb.BeginRequest) (*pb.TransactionResponse, error) {
+	opts := pool.TxOptions{IsoLevel: req.IsolationLevel, ReadOnly: req.ReadOnly, Deferrable: req.Deferrable}
+	return s.transaction(ctx, req.TenantId, req.ConnectionId, func(lease *pool.Lease) error {
+		return lease.Begin(ctx, opts)
+	})
+}
+
+func (s *ConnectionPoolServiceServer) CommitTransaction(ctx context.Context, req *pb.TransactionRequest) (*pb.TransactionResponse, error) {
+	return s.transaction(ctx, req.TenantId, req.ConnectionId, func(lease *pool.Lease) error {
+		return lease.Commit(ctx)
+	})
+}
+
+func (s *ConnectionPoolServiceServer) RollbackTransaction(ctx context.Context, req *pb.TransactionRequest) (*pb.TransactionResponse, error) {
+	return s.transaction(ctx, req.TenantId, req.ConnectionId, func(lease *pool.Lease) error {
+		return lease.Rollback(ctx)
+	})
+}
+
+// lease looks up a lease for a request, which has to name the lease's tenant. A lease of
+// another tenant is reported as not found, so its existence is not revealed.
+func (s *ConnectionPoolServiceServer) lease(ctx context.Context, tenantID, connectionID string) (*pool.Lease, error) {
+	if tenantID == "" {
+		return nil, errTenantRequired
+	}
+	lease, ok := s.poolManager.TenantLease(tenantID, connectionID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", pool.ErrLeaseNotFound, pool.LeaseHandle(connectionID))
+	}
+	setSpanTenant(ctx, lease.TenantID)
+	return lease, nil
+}
+
+// transaction runs a transaction control operation on a lease
+func (s *ConnectionPoolServiceServer) transaction(ctx context.Context, tenantID, connectionID string, op func(*pool.Lease) error) (*pb.TransactionResponse, error) {
+	lease, err := s.lease(ctx, tenantID, connectionID)
+	if err != nil {
+		return &pb.TransactionResponse{Error: err.Error()}, rpcError(err)
+	}
+	if err := op(lease); err != nil {
+		return &pb.TransactionResponse{Error: err.Error()}, rpcError(err)
+	}
+	return &pb.TransactionResponse{}, nil
+}
+
+func (s *ConnectionPoolServiceServer) GetPoolStats(ctx context.Context, req *pb.StatsRequest) (*pb.StatsResponse, error) {
+	setSpanTenant(ctx, req.TenantId)
+	breakers := breakersToProto(s.poolManager.Breakers(req.TenantId))
+	stats, err := s.poolManager.TenantStats(req.TenantId)
+	// A tenant kept from opening pools by its breakers still reports them
+	if err != nil && !(errors.Is(err, pool.ErrPoolNotFound) && len(breakers) > 0) {
+		return &pb.StatsResponse{Error: err.Error()}, rpcError(err)
 	}
 	return &pb.StatsResponse{
 		ActiveConnections: int32(stats.ActiveConnections),
 		IdleConnections:   int32(stats.IdleConnections),
 		TotalConnections:  int32(stats.TotalConnections),
+		Breakers:          breakers,
 	}, nil
 }
 
+func breakersToProto(statuses []pool.BreakerStatus) []*pb.CircuitBreaker {
+	breakers := make([]*pb.CircuitBreaker, len(statuses))
+	for i, status := range statuses {
+		breakers[i] = &pb.CircuitBreaker{
+			Host:     status.Host,
+			State:    string(status.State),
+			Requests: int32(status.Requests),
+			Failures: int32(status.Failures),
+		}
+		if !status.OpenedAt.IsZero() {
+			breakers[i].OpenedAt = timestamppb.New(status.OpenedAt)
+			breakers[i].RetryAt = timestamppb.New(status.RetryAt)
+		}
+	}
+	return breakers
+}
+
+// defaultHistoryWindow is how far back GetPoolStatsHistory looks when the request sets no start
+const defaultHistoryWindow = time.Hour
+
+func (s *ConnectionPoolServiceServer) GetPoolStatsHistory(ctx context.Context, req *pb.StatsHistoryRequest) (*pb.StatsHistoryResponse, error) {
+	setSpanTenant(ctx, req.TenantId)
+	end := time.Now()
+	if req.End != nil {
+		end = req.End.AsTime()
+	}
+	start := end.Add(-defaultHistoryWindow)
+	if req.Start != nil {
+		start = req.Start.AsTime()
+	}
+	if end.Before(start) {
+		return &pb.StatsHistoryResponse{Error: "end is before start"}, status.Error(codes.InvalidArgument, "end is before start")
+	}
+	if req.ResolutionSeconds < 0 {
+		return &pb.StatsHistoryResponse{Error: "invalid resolution"}, status.Error(codes.InvalidArgument, "invalid resolution")
+	}
+
+	points, err := s.poolManager.StatsHistory(req.TenantId, start, end, time.Duration(req.ResolutionSeconds)*time.Second)
+	if err != nil {
+		return &pb.StatsHistoryResponse{Error: err.Error()}, rpcError(err)
+	}
+	resp := &pb.StatsHistoryResponse{Points: make([]*pb.StatsPoint, len(points))}
+	for i, point := range points {
+		resp.Points[i] = &pb.StatsPoint{
+			Time:                 timestamppb.New(point.Time),
+			Samples:              int32(point.Samples),
+			AvgActiveConnections: point.AvgActive,
+			MaxActiveConnections: point.MaxActive,
+			AvgIdleConnections:   point.AvgIdle,
+			AvgTotalConnections:  point.AvgTotal,
+			MaxTotalConnections:  point.MaxTotal,
+			MaxConnections:       point.MaxConns,
+			MaxLeases:            point.MaxLeases,
+		}
+	}
+	return resp, nil
+}
+
+func (s *ConnectionPoolServiceServer) ListPools(ctx context.Context, req *pb.ListPoolsRequest) (*pb.ListPoolsResponse, error) {
+	infos := s.poolManager.ListPools(req.TenantId)
+	pools := make([]*pb.PoolInfo, len(infos))
+	for i, info := range infos {
+		pools[i] = &pb.PoolInfo{
+			TenantId:          info.TenantID,
+			Server:            info.Server,
+			ActiveConnections: info.Stats.ActiveConnections,
+			IdleConnections:   info.Stats.IdleConnections,
+			TotalConnections:  info.Stats.TotalConnections,
+			MaxConnections:    info.MaxConns,
+			Leases:            int32(info.Leases),
+			Draining:          info.Draining,
+		}
+	}
+	return &pb.ListPoolsResponse{Pools: pools}, nil
+}
+
+func (s *ConnectionPoolServiceServer) ListLeases(ctx context.Context, req *pb.ListLeasesRequest) (*pb.ListLeasesResponse, error) {
+	if req.TenantId != "" {
+		setSpanTenant(ctx, req.TenantId)
+	}
+	infos := s.poolManager.LongestHeldLeases(req.TenantId, int(req.Limit))
+	leases := make([]*pb.LeaseInfo, len(infos))
+	for i, info := range infos {
+		leases[i] = &pb.LeaseInfo{
+			Handle:       info.Handle,
+			TenantId:     info.TenantID,
+			PoolMode:     string(info.Mode),
+			Peer:         info.Client.Peer,
+			Identity:     info.Client.Identity,
+			CallSite:     info.Client.CallSite,
+			AcquiredAt:   timestamppb.New(info.AcquiredAt),
+			LastActiveAt: timestamppb.New(info.LastActive),
+			ExpiresAt:    expiryTimestamp(info.ExpiresAt),
+			Attached:     info.Attached,
+		}
+	}
+	return &pb.ListLeasesResponse{Leases: leases}, nil
+}
+
+// defaultDrainTimeout is how long DrainTenant waits for leases when the request sets no timeout
+const defaultDrainTimeout = 30 * time.Second
+
+func (s *ConnectionPoolServiceServer) DrainTenant(ctx context.Context, req *pb.DrainRequest) (*pb.DrainResponse, error) {
+	setSpanTenant(ctx, req.TenantId)
+	if req.TenantId == "" {
+		return &pb.DrainResponse{Error: "invalid tenant ID"}, status.Error(codes.InvalidArgument, "invalid tenant ID")
+	}
+	timeout := defaultDrainTimeout
+	if req.TimeoutSeconds > 0 {
+		timeout = time.Duration(req.TimeoutSeconds) * time.Second
+	}
+	result, err := s.poolManager.DrainTenant(ctx, req.TenantId, timeout)
+	if err != nil {
+		return &pb.DrainResponse{Error: err.Error()}, rpcError(err)
+	}
+	return &pb.DrainResponse{EvictedLeases: int32(result.EvictedLeases), ClosedPools: int32(result.ClosedPools)}, nil
+}
+
+func (s *ConnectionPoolServiceServer) EvictTenant(ctx context.Context, req *pb.EvictRequest) (*pb.EvictResponse, error) {
+	setSpanTenant(ctx, req.TenantId)
+	if req.TenantId == "" {
+		return &pb.EvictResponse{Error: "invalid tenant ID"}, status.Error(codes.InvalidArgument, "invalid tenant ID")
+	}
+	result, err := s.poolManager.EvictTenant(ctx, req.TenantId)
+	if err != nil {
+		return &pb.EvictResponse{Error: err.Error()}, rpcError(err)
+	}
+	return &pb.EvictResponse{EvictedLeases: int32(result.EvictedLeases), ClosedPools: int32(result.ClosedPools)}, nil
+}
+
+// setSpanTenant tags the RPC's server span with the tenant it acts for
+func setSpanTenant(ctx context.Context, tenantID string) {
+	trace.SpanFromContext(ctx).SetAttributes(pool.TenantAttribute.String(tenantID))
+}
+
 // Register the gRPC server
 func RegisterServer(s *grpc.Server, srv *ConnectionPoolServiceServer) {
 	pb.RegisterConnectionPoolServiceServer(s, srv)
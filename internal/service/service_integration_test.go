@@ -80,27 +80,28 @@ func TestConnectionPoolServiceServer_ConnectionIDParsing(t *testing.T) {
 	}
 }
 
-func TestConnectionPoolServiceServer_HardcodedDSN(t *testing.T) {
-	// Test that the service uses hardcoded DSN in ReleaseConnection
+func TestConnectionPoolServiceServer_ReleaseUnknownLease(t *testing.T) {
+	// Test that releasing a connection ID that was never leased fails
 	server := NewConnectionPoolServiceServer()
 	ctx := context.Background()
 
 	req := &pb.ConnectionRelease{
+		TenantId:     "tenant1",
 		ConnectionId: "conn-tenant1-1234567890",
 	}
 
 	resp, err := server.ReleaseConnection(ctx, req)
 
-	// This will error because there's no pool to release, but we can verify the error message
+	// This will error because there's no lease to release, but we can verify the error message
 	assert.Error(t, err)
 	assert.NotNil(t, resp)
 	assert.False(t, resp.Success)
 	assert.NotEmpty(t, resp.Error)
-	assert.Contains(t, resp.Error, "pool not found")
+	assert.Contains(t, resp.Error, "lease not found")
 }
 
-func TestConnectionPoolServiceServer_GetPoolStatsHardcodedDSN(t *testing.T) {
-	// Test that the service uses hardcoded DSN in GetPoolStats
+func TestConnectionPoolServiceServer_GetPoolStatsNoPools(t *testing.T) {
+	// Test that GetPoolStats reports a tenant without pools
 	server := NewConnectionPoolServiceServer()
 	ctx := context.Background()
 
@@ -2,11 +2,21 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/pgtest"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
 	pb "github.com/teresa-solution/connection-pool-manager/proto"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestNewConnectionPoolServiceServer(t *testing.T) {
@@ -246,3 +256,304 @@ func BenchmarkConnectionPoolServiceServer_ReleaseConnection(b *testing.B) {
 		_, _ = server.ReleaseConnection(ctx, req)
 	}
 }
+
+func TestConnectionPoolServiceServer_GetConnection_ReadOnly(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *pb.ConnectionRequest
+	}{
+		{
+			name: "Invalid min LSN",
+			req: &pb.ConnectionRequest{
+				TenantId: "tenant1",
+				Dsn:      "invalid-dsn",
+				MinLsn:   "not-an-lsn",
+			},
+		},
+		{
+			name: "Read only without replicas uses the request DSN",
+			req: &pb.ConnectionRequest{
+				TenantId: "tenant1",
+				Dsn:      "invalid-dsn",
+				ReadOnly: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewConnectionPoolServiceServer()
+
+			resp, err := server.GetConnection(context.Background(), tt.req)
+			assert.Error(t, err)
+			require.NotNil(t, resp)
+			assert.NotEmpty(t, resp.Error)
+			assert.False(t, resp.FromReplica)
+		})
+	}
+}
+
+func TestConnectionPoolServiceServer_LeaseLifecycle(t *testing.T) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+	defer db.Close()
+
+	poolManager := pool.NewConnectionPoolManager()
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "acme", DSN: db.DSN()})
+	defer poolManager.ReleaseConnection(context.Background(), "acme", db.DSN())
+
+	server := NewConnectionPoolServiceServerWithManager(poolManager)
+	ctx := context.Background()
+
+	// The DSN comes from the tenant config when the request has none
+	conn, err := server.GetConnection(ctx, &pb.ConnectionRequest{TenantId: "acme"})
+	require.NoError(t, err)
+	assert.Contains(t, conn.ConnectionId, "conn-acme-")
+
+	resp, err := server.ExecuteQuery(ctx, &pb.QueryRequest{TenantId: "acme", ConnectionId: conn.ConnectionId, Sql: "SELECT pg_backend_pid()"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pg_backend_pid"}, resp.Columns)
+	require.Len(t, resp.Rows, 1)
+	assert.False(t, resp.Rows[0].Values[0].IsNull)
+	assert.Equal(t, "SELECT 1", resp.CommandTag)
+
+	// Another tenant's lease is not found, whether it is used or released
+	resp, err = server.ExecuteQuery(ctx, &pb.QueryRequest{TenantId: "globex", ConnectionId: conn.ConnectionId, Sql: "SELECT 1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Contains(t, resp.Error, "lease not found")
+	assert.NotContains(t, resp.Error, conn.ConnectionId)
+	release, err := server.ReleaseConnection(ctx, &pb.ConnectionRelease{TenantId: "globex", ConnectionId: conn.ConnectionId})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.False(t, release.Success)
+	_, err = server.CommitTransaction(ctx, &pb.TransactionRequest{TenantId: "globex", ConnectionId: conn.ConnectionId})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	release, err = server.ReleaseConnection(ctx, &pb.ConnectionRelease{TenantId: "acme", ConnectionId: conn.ConnectionId})
+	require.NoError(t, err)
+	assert.True(t, release.Success)
+
+	resp, err = server.ExecuteQuery(ctx, &pb.QueryRequest{TenantId: "acme", ConnectionId: conn.ConnectionId, Sql: "SELECT 1"})
+	assert.Error(t, err)
+	assert.Contains(t, resp.Error, "lease not found")
+}
+
+func TestConnectionPoolServiceServer_Transactions(t *testing.T) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+	defer db.Close()
+
+	poolManager := pool.NewConnectionPoolManager()
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "acme", DSN: db.DSN(), PoolMode: pool.PoolModeTransaction})
+	defer poolManager.ReleaseConnection(context.Background(), "acme", db.DSN())
+
+	server := NewConnectionPoolServiceServerWithManager(poolManager)
+	ctx := context.Background()
+	conn, err := server.GetConnection(ctx, &pb.ConnectionRequest{TenantId: "acme"})
+	require.NoError(t, err)
+
+	_, err = server.BeginTransaction(ctx, &pb.BeginRequest{TenantId: "acme", ConnectionId: conn.ConnectionId, IsolationLevel: "serializable"})
+	require.NoError(t, err)
+	_, err = server.CommitTransaction(ctx, &pb.TransactionRequest{TenantId: "acme", ConnectionId: conn.ConnectionId})
+	require.NoError(t, err)
+	_, err = server.BeginTransaction(ctx, &pb.BeginRequest{TenantId: "acme", ConnectionId: conn.ConnectionId})
+	require.NoError(t, err)
+	_, err = server.RollbackTransaction(ctx, &pb.TransactionRequest{TenantId: "acme", ConnectionId: conn.ConnectionId})
+	require.NoError(t, err)
+	assert.Contains(t, db.Queries(), "BEGIN ISOLATION LEVEL SERIALIZABLE")
+
+	resp, err := server.BeginTransaction(ctx, &pb.BeginRequest{TenantId: "acme", ConnectionId: conn.ConnectionId, IsolationLevel: "snapshot"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, resp.Error, "unknown isolation level")
+
+	_, err = server.CommitTransaction(ctx, &pb.TransactionRequest{TenantId: "acme", ConnectionId: "conn-missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestConnectionPoolServiceServer_RenewLease(t *testing.T) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+	defer db.Close()
+
+	poolManager := pool.NewConnectionPoolManager()
+	poolManager.SetDefaultLeaseTTL(time.Minute)
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "acme", DSN: db.DSN()})
+	defer poolManager.ReleaseConnection(context.Background(), "acme", db.DSN())
+
+	server := NewConnectionPoolServiceServerWithManager(poolManager)
+	ctx := context.Background()
+	conn, err := server.GetConnection(ctx, &pb.ConnectionRequest{TenantId: "acme", TtlSeconds: 30})
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), conn.ExpiresAt.AsTime(), time.Second)
+
+	resp, err := server.RenewLease(ctx, &pb.RenewLeaseRequest{TenantId: "acme", ConnectionId: conn.ConnectionId, TtlSeconds: 600})
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), resp.ExpiresAt.AsTime(), time.Second)
+
+	resp, err = server.RenewLease(ctx, &pb.RenewLeaseRequest{TenantId: "acme", ConnectionId: conn.ConnectionId})
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), resp.ExpiresAt.AsTime(), time.Second)
+
+	_, err = server.ReleaseConnection(ctx, &pb.ConnectionRelease{TenantId: "acme", ConnectionId: conn.ConnectionId})
+	require.NoError(t, err)
+	resp, err = server.RenewLease(ctx, &pb.RenewLeaseRequest{TenantId: "acme", ConnectionId: conn.ConnectionId})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Contains(t, resp.Error, "lease not found")
+}
+
+func TestConnectionPoolServiceServer_ListLeases(t *testing.T) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+	defer db.Close()
+
+	poolManager := pool.NewConnectionPoolManager()
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "acme", DSN: db.DSN()})
+	defer poolManager.ReleaseConnection(context.Background(), "acme", db.DSN())
+
+	server := NewConnectionPoolServiceServerWithManager(poolManager)
+	ctx := context.Background()
+	conn, err := server.GetConnection(ctx, &pb.ConnectionRequest{TenantId: "acme", CallSite: "billing.go:17"})
+	require.NoError(t, err)
+	defer server.ReleaseConnection(ctx, &pb.ConnectionRelease{TenantId: "acme", ConnectionId: conn.ConnectionId})
+
+	resp, err := server.ListLeases(ctx, &pb.ListLeasesRequest{TenantId: "acme"})
+	require.NoError(t, err)
+	require.Len(t, resp.Leases, 1)
+	lease := resp.Leases[0]
+	assert.Equal(t, pool.LeaseHandle(conn.ConnectionId), lease.Handle)
+	assert.Equal(t, "billing.go:17", lease.CallSite)
+	assert.Equal(t, "session", lease.PoolMode)
+	assert.True(t, lease.Attached)
+	assert.NotNil(t, lease.AcquiredAt)
+	assert.Equal(t, conn.ExpiresAt.AsTime(), lease.ExpiresAt.AsTime())
+
+	resp, err = server.ListLeases(ctx, &pb.ListLeasesRequest{TenantId: "globex"})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Leases)
+}
+
+func TestConnectionPoolServiceServer_GetPoolStatsHistory(t *testing.T) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+	defer db.Close()
+
+	poolManager := pool.NewConnectionPoolManager()
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "acme", DSN: db.DSN()})
+	poolManager.SetStatsHistory(10*time.Millisecond, time.Minute)
+	defer poolManager.ReleaseConnection(context.Background(), "acme", db.DSN())
+
+	server := NewConnectionPoolServiceServerWithManager(poolManager)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resp, err := server.GetPoolStatsHistory(ctx, &pb.StatsHistoryRequest{TenantId: "acme"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Contains(t, resp.Error, "no stats history")
+
+	conn, err := server.GetConnection(ctx, &pb.ConnectionRequest{TenantId: "acme"})
+	require.NoError(t, err)
+	defer server.ReleaseConnection(ctx, &pb.ConnectionRelease{TenantId: "acme", ConnectionId: conn.ConnectionId})
+	poolManager.StartStatsHistory(ctx)
+
+	require.Eventually(t, func() bool {
+		resp, err = server.GetPoolStatsHistory(ctx, &pb.StatsHistoryRequest{TenantId: "acme"})
+		return err == nil && len(resp.Points) >= 3
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), resp.Points[0].MaxActiveConnections)
+	assert.Equal(t, int32(1), resp.Points[0].MaxLeases)
+
+	// One step wide enough for every sample
+	resp, err = server.GetPoolStatsHistory(ctx, &pb.StatsHistoryRequest{TenantId: "acme", ResolutionSeconds: 3600})
+	require.NoError(t, err)
+	require.Len(t, resp.Points, 1)
+	assert.Equal(t, 1.0, resp.Points[0].AvgActiveConnections)
+
+	_, err = server.GetPoolStatsHistory(ctx, &pb.StatsHistoryRequest{
+		TenantId: "acme",
+		Start:    timestamppb.Now(),
+		End:      timestamppb.New(time.Now().Add(-time.Minute)),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestConnectionPoolServiceServer_AdminRPCs(t *testing.T) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+	defer db.Close()
+
+	poolManager := pool.NewConnectionPoolManager()
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "acme", DSN: db.DSN()})
+	defer poolManager.ReleaseConnection(context.Background(), "acme", db.DSN())
+
+	server := NewConnectionPoolServiceServerWithManager(poolManager)
+	ctx := context.Background()
+
+	conn, err := server.GetConnection(ctx, &pb.ConnectionRequest{TenantId: "acme"})
+	require.NoError(t, err)
+
+	stats, err := server.GetPoolStats(ctx, &pb.StatsRequest{TenantId: "acme"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), stats.ActiveConnections)
+
+	pools, err := server.ListPools(ctx, &pb.ListPoolsRequest{})
+	require.NoError(t, err)
+	require.Len(t, pools.Pools, 1)
+	assert.Equal(t, "acme", pools.Pools[0].TenantId)
+	assert.Equal(t, int32(1), pools.Pools[0].Leases)
+
+	evict, err := server.EvictTenant(ctx, &pb.EvictRequest{TenantId: "acme"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), evict.EvictedLeases)
+	assert.Equal(t, int32(1), evict.ClosedPools)
+
+	_, err = server.ExecuteQuery(ctx, &pb.QueryRequest{TenantId: "acme", ConnectionId: conn.ConnectionId, Sql: "SELECT 1"})
+	assert.Error(t, err)
+
+	drain, err := server.DrainTenant(ctx, &pb.DrainRequest{TenantId: "acme"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), drain.ClosedPools)
+
+	_, err = server.DrainTenant(ctx, &pb.DrainRequest{})
+	assert.Error(t, err)
+}
+
+func TestConnectionPoolServiceServer_SpanTenant(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, span := provider.Tracer("test").Start(context.Background(), "GetPoolStats")
+
+	server := NewConnectionPoolServiceServer()
+	_, err := server.GetPoolStats(ctx, &pb.StatsRequest{TenantId: "acme"})
+	assert.Error(t, err)
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Contains(t, spans[0].Attributes(), pool.TenantAttribute.String("acme"))
+}
+
+func TestConnectionPoolServiceServer_CircuitBreaker(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	dsn := fmt.Sprintf("postgres://app@%s/acme?sslmode=disable", listener.Addr())
+	listener.Close()
+
+	poolManager := pool.NewConnectionPoolManager()
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "acme", DSN: dsn, Breaker: pool.BreakerPolicy{MinRequests: 1}})
+	defer poolManager.ReleaseConnection(context.Background(), "acme", dsn)
+	server := NewConnectionPoolServiceServerWithManager(poolManager)
+	ctx := context.Background()
+
+	_, err = server.GetConnection(ctx, &pb.ConnectionRequest{TenantId: "acme"})
+	require.Error(t, err)
+	resp, err := server.GetConnection(ctx, &pb.ConnectionRequest{TenantId: "acme"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Contains(t, resp.Error, "circuit breaker open")
+
+	stats, err := server.GetPoolStats(ctx, &pb.StatsRequest{TenantId: "acme"})
+	require.NoError(t, err)
+	require.Len(t, stats.Breakers, 1)
+	assert.Equal(t, listener.Addr().String(), stats.Breakers[0].Host)
+	assert.Equal(t, "open", stats.Breakers[0].State)
+	assert.Equal(t, int32(1), stats.Breakers[0].Failures)
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), stats.Breakers[0].RetryAt.AsTime(), time.Second)
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ValidateDSN reports how each stage of checking and connecting to a DSN went. A DSN
+// that fails validation is a result rather than an RPC error, so the stages reach the
+// caller; only a malformed request fails the call.
+func (s *ConnectionPoolServiceServer) ValidateDSN(ctx context.Context, req *pb.ValidateDSNRequest) (*pb.ValidateDSNResponse, error) {
+	if req.Dsn == "" {
+		return nil, status.Error(codes.InvalidArgument, "dsn is required")
+	}
+	if req.TimeoutMs < 0 {
+		return nil, status.Error(codes.InvalidArgument, "timeout_ms must not be negative")
+	}
+
+	report := s.poolManager.ValidateDSN(ctx, req.Dsn, time.Duration(req.TimeoutMs)*time.Millisecond)
+	resp := &pb.ValidateDSNResponse{
+		Valid:          report.Valid,
+		NormalizedDsn:  report.NormalizedDSN,
+		Host:           report.Host,
+		Port:           int32(report.Port),
+		Database:       report.Database,
+		User:           report.User,
+		SslMode:        report.SSLMode,
+		ServerVersion:  report.ServerVersion,
+		MaxConnections: int32(report.MaxConnections),
+	}
+	if report.Err != nil {
+		resp.Error = report.Err.Error()
+		resp.Reason = string(report.Reason)
+	}
+	for _, stage := range report.Stages {
+		resp.Stages = append(resp.Stages, &pb.ValidationStage{
+			Stage:     string(stage.Stage),
+			Status:    string(stage.Status),
+			LatencyMs: float64(stage.Latency) / float64(time.Millisecond),
+			Error:     stage.Error,
+		})
+	}
+	for _, ext := range report.Extensions {
+		resp.Extensions = append(resp.Extensions, &pb.Extension{Name: ext.Name, Version: ext.Version})
+	}
+	if p := report.Privileges; p != nil {
+		resp.Privileges = rolePrivileges(p)
+	}
+	return resp, nil
+}
+
+func rolePrivileges(p *pool.RolePrivileges) *pb.RolePrivileges {
+	return &pb.RolePrivileges{
+		Role:        p.Role,
+		Superuser:   p.Superuser,
+		CreateDb:    p.CreateDB,
+		CreateRole:  p.CreateRole,
+		Replication: p.Replication,
+		BypassRls:   p.BypassRLS,
+		Connect:     p.Connect,
+		Create:      p.Create,
+		Temp:        p.Temp,
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConnectionPoolServiceServer_ValidateDSN(t *testing.T) {
+	client, poolManager := setupHoldService(t)
+	ctx := context.Background()
+	cfg, _ := poolManager.TenantConfig("acme")
+
+	resp, err := client.ValidateDSN(ctx, &pb.ValidateDSNRequest{Dsn: cfg.DSN + " password=secret"})
+	require.NoError(t, err)
+	assert.True(t, resp.Valid)
+	assert.Empty(t, resp.Error)
+	assert.Equal(t, "disable", resp.SslMode)
+	assert.NotContains(t, resp.NormalizedDsn, "secret")
+	assert.Equal(t, "16.0", resp.ServerVersion)
+	require.Len(t, resp.Stages, 7)
+	assert.Equal(t, "auth", resp.Stages[5].Stage)
+	assert.Equal(t, "ok", resp.Stages[5].Status)
+	assert.Empty(t, poolManager.ListPools(""))
+
+	// An unreachable database is a result, not an RPC error
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener.Close()
+	resp, err = client.ValidateDSN(ctx, &pb.ValidateDSNRequest{Dsn: "postgres://app@" + listener.Addr().String() + "/acme?sslmode=disable", TimeoutMs: 2000})
+	require.NoError(t, err)
+	assert.False(t, resp.Valid)
+	assert.Equal(t, "tcp", resp.Reason)
+	assert.NotEmpty(t, resp.Error)
+	assert.Equal(t, "failed", resp.Stages[3].Status)
+
+	_, err = client.ValidateDSN(ctx, &pb.ValidateDSNRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.ValidateDSN(ctx, &pb.ValidateDSNRequest{Dsn: cfg.DSN, TimeoutMs: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// defaultWatchInterval is the time between WatchPoolStats updates when the request sets none
+	defaultWatchInterval = time.Second
+	// minWatchInterval keeps watchers from polling the pools in a tight loop
+	minWatchInterval = 100 * time.Millisecond
+	// thresholdCheckInterval is how often tenants are checked against a watcher's
+	// utilization threshold between updates
+	thresholdCheckInterval = 100 * time.Millisecond
+)
+
+// WatchPoolStats streams the stats of the requested tenants every interval until the
+// client goes away. Tenants crossing the utilization threshold are sent as soon as it is
+// noticed rather than at the next update.
+func (s *ConnectionPoolServiceServer) WatchPoolStats(req *pb.WatchPoolStatsRequest, stream pb.ConnectionPoolService_WatchPoolStatsServer) error {
+	if req.UtilizationThreshold < 0 || req.UtilizationThreshold > 1 {
+		return status.Error(codes.InvalidArgument, "utilization threshold must be between 0 and 1")
+	}
+	if req.IntervalMs < 0 {
+		return status.Error(codes.InvalidArgument, "invalid interval")
+	}
+	interval := defaultWatchInterval
+	if req.IntervalMs > 0 {
+		interval = max(time.Duration(req.IntervalMs)*time.Millisecond, minWatchInterval)
+	}
+	check := interval
+	if req.UtilizationThreshold > 0 {
+		check = min(interval, thresholdCheckInterval)
+	}
+
+	ctx := stream.Context()
+	watch := newStatsWatch(req)
+	ticker := time.NewTicker(check)
+	defer ticker.Stop()
+	var nextUpdate time.Time
+	for {
+		now := time.Now()
+		due := !now.Before(nextUpdate)
+		if due {
+			nextUpdate = now.Add(interval)
+		}
+		if resp := watch.update(s.poolManager.StatsSnapshot(now), now, due); resp != nil {
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.shutdown:
+			return errShuttingDown
+		case <-ticker.C:
+		}
+	}
+}
+
+// statsWatch is what a WatchPoolStats stream has sent so far
+type statsWatch struct {
+	tenants   []string
+	deltas    bool
+	threshold float64
+	// sent holds the stats last sent per tenant
+	sent map[string]pool.StatsSample
+	// over marks tenants last seen at or above the threshold
+	over map[string]bool
+}
+
+func newStatsWatch(req *pb.WatchPoolStatsRequest) *statsWatch {
+	return &statsWatch{
+		tenants:   req.TenantIds,
+		deltas:    req.DeltasOnly,
+		threshold: req.UtilizationThreshold,
+		sent:      make(map[string]pool.StatsSample),
+		over:      make(map[string]bool),
+	}
+}
+
+// update works out the message to send for a snapshot, or nil when there is nothing to
+// send. Regular updates are due every interval; in between only tenants crossing the
+// threshold are sent. Tenants without pools count as all zero.
+func (w *statsWatch) update(snapshot map[string]pool.StatsSample, now time.Time, due bool) *pb.WatchPoolStatsResponse {
+	tenants := w.tenants
+	if len(tenants) == 0 {
+		// Tenants whose pools closed are sent once more so watchers see them go
+		for tenantID := range w.sent {
+			if _, ok := snapshot[tenantID]; !ok {
+				tenants = append(tenants, tenantID)
+			}
+		}
+		for tenantID := range snapshot {
+			tenants = append(tenants, tenantID)
+		}
+		sort.Strings(tenants)
+	}
+
+	resp := &pb.WatchPoolStatsResponse{Time: timestamppb.New(now), ThresholdTriggered: !due}
+	for _, tenantID := range tenants {
+		sample, open := snapshot[tenantID]
+		over := w.threshold > 0 && sample.MaxConns > 0 &&
+			float64(sample.ActiveConnections) >= w.threshold*float64(sample.MaxConns)
+		crossed := w.threshold > 0 && over != w.over[tenantID]
+		w.over[tenantID] = over
+
+		last, sent := w.sent[tenantID]
+		changed := !sent || !sameStats(last, sample)
+		if !crossed && (!due || (w.deltas && !changed)) {
+			continue
+		}
+
+		resp.Tenants = append(resp.Tenants, &pb.TenantStats{
+			TenantId:          tenantID,
+			ActiveConnections: sample.ActiveConnections,
+			IdleConnections:   sample.IdleConnections,
+			TotalConnections:  sample.TotalConnections,
+			MaxConnections:    sample.MaxConns,
+			Leases:            sample.Leases,
+			OverThreshold:     over,
+		})
+		if open || len(w.tenants) > 0 {
+			w.sent[tenantID] = sample
+		} else {
+			delete(w.sent, tenantID)
+			delete(w.over, tenantID)
+		}
+	}
+
+	// Regular updates go out even when empty, unless only changes were asked for
+	if len(resp.Tenants) == 0 && (!due || w.deltas) {
+		return nil
+	}
+	return resp
+}
+
+// sameStats reports whether two samples hold the same figures, whenever they were taken
+func sameStats(a, b pool.StatsSample) bool {
+	a.Time, b.Time = time.Time{}, time.Time{}
+	return a == b
+}
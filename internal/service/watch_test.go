@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConnectionPoolServiceServer_WatchPoolStats(t *testing.T) {
+	client, _ := setupHoldService(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Updates are an hour apart so anything after the first is threshold triggered
+	stream, err := client.WatchPoolStats(ctx, &pb.WatchPoolStatsRequest{
+		TenantIds:            []string{"acme"},
+		IntervalMs:           int32(time.Hour / time.Millisecond),
+		UtilizationThreshold: 0.05,
+	})
+	require.NoError(t, err)
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	assert.False(t, resp.ThresholdTriggered)
+	require.Len(t, resp.Tenants, 1)
+	assert.Equal(t, "acme", resp.Tenants[0].TenantId)
+	assert.False(t, resp.Tenants[0].OverThreshold)
+
+	// One of the pool's 20 connections is 5% of it
+	lease, err := client.GetConnection(ctx, &pb.ConnectionRequest{TenantId: "acme"})
+	require.NoError(t, err)
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	assert.True(t, resp.ThresholdTriggered)
+	require.Len(t, resp.Tenants, 1)
+	assert.True(t, resp.Tenants[0].OverThreshold)
+	assert.Equal(t, int32(1), resp.Tenants[0].ActiveConnections)
+	assert.Equal(t, int32(20), resp.Tenants[0].MaxConnections)
+
+	_, err = client.ReleaseConnection(ctx, &pb.ConnectionRelease{TenantId: "acme", ConnectionId: lease.ConnectionId})
+	require.NoError(t, err)
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	assert.True(t, resp.ThresholdTriggered)
+	assert.False(t, resp.Tenants[0].OverThreshold)
+
+	stream, err = client.WatchPoolStats(ctx, &pb.WatchPoolStatsRequest{UtilizationThreshold: 2})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestStatsWatch_Update(t *testing.T) {
+	now := time.Now()
+	sample := func(active int32) pool.StatsSample {
+		return pool.StatsSample{Time: now, Stats: pool.Stats{ActiveConnections: active}, MaxConns: 10}
+	}
+	tenants := func(resp *pb.WatchPoolStatsResponse) map[string]int32 {
+		if resp == nil {
+			return nil
+		}
+		active := make(map[string]int32)
+		for _, tenant := range resp.Tenants {
+			active[tenant.TenantId] = tenant.ActiveConnections
+		}
+		return active
+	}
+
+	t.Run("all tenants", func(t *testing.T) {
+		w := newStatsWatch(&pb.WatchPoolStatsRequest{})
+		assert.Equal(t, map[string]int32{"acme": 1, "globex": 2},
+			tenants(w.update(map[string]pool.StatsSample{"acme": sample(1), "globex": sample(2)}, now, true)))
+		// Nothing goes out between updates without a threshold
+		assert.Nil(t, w.update(map[string]pool.StatsSample{"acme": sample(5)}, now, false))
+		// A closed tenant is sent as zero once
+		assert.Equal(t, map[string]int32{"acme": 1, "globex": 0},
+			tenants(w.update(map[string]pool.StatsSample{"acme": sample(1)}, now, true)))
+		assert.Equal(t, map[string]int32{"acme": 1},
+			tenants(w.update(map[string]pool.StatsSample{"acme": sample(1)}, now, true)))
+		// Updates go out with no tenants open
+		assert.Equal(t, map[string]int32{"acme": 0}, tenants(w.update(map[string]pool.StatsSample{}, now, true)))
+		assert.Equal(t, map[string]int32{}, tenants(w.update(map[string]pool.StatsSample{}, now, true)))
+	})
+
+	t.Run("deltas only", func(t *testing.T) {
+		w := newStatsWatch(&pb.WatchPoolStatsRequest{TenantIds: []string{"acme", "globex"}, DeltasOnly: true})
+		assert.Equal(t, map[string]int32{"acme": 1, "globex": 0},
+			tenants(w.update(map[string]pool.StatsSample{"acme": sample(1)}, now, true)))
+		assert.Nil(t, w.update(map[string]pool.StatsSample{"acme": sample(1)}, now.Add(time.Second), true))
+		assert.Equal(t, map[string]int32{"globex": 3},
+			tenants(w.update(map[string]pool.StatsSample{"acme": sample(1), "globex": sample(3)}, now, true)))
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		w := newStatsWatch(&pb.WatchPoolStatsRequest{TenantIds: []string{"acme"}, UtilizationThreshold: 0.9})
+		assert.NotNil(t, w.update(map[string]pool.StatsSample{"acme": sample(8)}, now, true))
+		assert.Nil(t, w.update(map[string]pool.StatsSample{"acme": sample(8)}, now, false))
+
+		resp := w.update(map[string]pool.StatsSample{"acme": sample(9)}, now, false)
+		require.NotNil(t, resp)
+		assert.True(t, resp.ThresholdTriggered)
+		assert.True(t, resp.Tenants[0].OverThreshold)
+		assert.Nil(t, w.update(map[string]pool.StatsSample{"acme": sample(10)}, now, false))
+
+		resp = w.update(map[string]pool.StatsSample{"acme": sample(10)}, now, true)
+		require.NotNil(t, resp)
+		assert.False(t, resp.ThresholdTriggered)
+		assert.True(t, resp.Tenants[0].OverThreshold)
+	})
+}
+
+func TestConnectionPoolServiceServer_WatchPoolStatsShutdown(t *testing.T) {
+	srv := NewConnectionPoolServiceServerWithManager(pool.NewConnectionPoolManager())
+	client, server := serveBufconn(t, srv)
+
+	stream, err := client.WatchPoolStats(context.Background(), &pb.WatchPoolStatsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	// A graceful stop waits for the stream, which ends on shutdown
+	srv.Shutdown()
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("GracefulStop did not return")
+	}
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
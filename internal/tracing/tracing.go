@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Exporter names accepted in Config
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+	ExporterMemory = "memory"
+)
+
+// Config selects where spans are exported
+type Config struct {
+	// Exporter is one of none, stdout, otlp or memory. Tracing is disabled when empty.
+	Exporter string
+	// OTLPEndpoint is the host:port of the OTLP gRPC collector
+	OTLPEndpoint string
+	// OTLPInsecure disables TLS to the collector
+	OTLPInsecure bool
+	// SampleRatio is the fraction of new traces recorded. Incoming sampled traces are always recorded.
+	SampleRatio float64
+	ServiceName string
+}
+
+// Provider is the tracer provider installed by Setup
+type Provider struct {
+	*sdktrace.TracerProvider
+	// Memory holds the finished spans when the memory exporter is used
+	Memory *tracetest.InMemoryExporter
+}
+
+// Setup installs a global tracer provider and W3C trace context propagation. The
+// returned provider is nil when tracing is disabled.
+func Setup(ctx context.Context, cfg Config) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	provider := &Provider{}
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		var err error
+		if exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout)); err != nil {
+			return nil, err
+		}
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{}
+		if cfg.OTLPEndpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint))
+		}
+		if cfg.OTLPInsecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		var err error
+		if exporter, err = otlptracegrpc.New(ctx, opts...); err != nil {
+			return nil, err
+		}
+	case ExporterMemory:
+		provider.Memory = tracetest.NewInMemoryExporter()
+		exporter = provider.Memory
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "connection-pool-manager"
+	}
+	ratio := cfg.SampleRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	}
+	if provider.Memory != nil {
+		// Tests read spans as soon as they end
+		opts = append(opts, sdktrace.WithSyncer(exporter))
+	} else {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	provider.TracerProvider = sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider.TracerProvider)
+	return provider, nil
+}
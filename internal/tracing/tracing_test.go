@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name         string
+		exporter     string
+		wantProvider bool
+		wantErr      bool
+	}{
+		{name: "Disabled by default", exporter: ""},
+		{name: "None", exporter: ExporterNone},
+		{name: "Stdout", exporter: ExporterStdout, wantProvider: true},
+		{name: "Memory", exporter: ExporterMemory, wantProvider: true},
+		{name: "Unknown exporter", exporter: "zipkin", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := Setup(context.Background(), Config{Exporter: tt.exporter})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if !tt.wantProvider {
+				assert.Nil(t, provider)
+				return
+			}
+			require.NotNil(t, provider)
+			assert.NoError(t, provider.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestSetup_MemoryExporterRecordsSpans(t *testing.T) {
+	provider, err := Setup(context.Background(), Config{Exporter: ExporterMemory})
+	require.NoError(t, err)
+	defer provider.Shutdown(context.Background())
+
+	_, span := provider.Tracer("test").Start(context.Background(), "work")
+	span.End()
+
+	spans := provider.Memory.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "work", spans[0].Name)
+}
@@ -0,0 +1,341 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+)
+
+// queueSize is how many payloads may wait for delivery per webhook before new ones are
+// dead-lettered
+const queueSize = 100
+
+var deliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "webhook_deliveries_total",
+	Help: "Webhook delivery attempts, by webhook and result",
+}, []string{"webhook", "result"})
+
+// Options tune deliveries
+type Options struct {
+	// MaxAttempts is how often a payload is tried before it is dead-lettered. Defaults to 5.
+	MaxAttempts int
+	// Backoff is the wait before the first retry, doubled for each one after. Defaults to 1s.
+	Backoff time.Duration
+	// MaxBackoff caps the wait between retries. Defaults to 1m.
+	MaxBackoff time.Duration
+	// Timeout bounds each attempt. Defaults to 10s.
+	Timeout time.Duration
+	// SaturationInterval is how often pools are checked for saturation. Defaults to 5s.
+	SaturationInterval time.Duration
+	// DeadLetterFile gets a JSON line for each payload that could not be delivered.
+	// Undelivered payloads are only logged when empty.
+	DeadLetterFile string
+}
+
+// DeadLetter is a payload that could not be delivered
+type DeadLetter struct {
+	Time     time.Time `json:"time"`
+	Webhook  string    `json:"webhook"`
+	URL      string    `json:"url"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	Payload  Payload   `json:"payload"`
+}
+
+// Dispatcher turns pool events and stats into alerts and delivers them to webhooks
+type Dispatcher struct {
+	poolManager *pool.ConnectionPoolManager
+	endpoints   []*endpoint
+	opts        Options
+	client      *http.Client
+	// deadLetterMu serialises writes to the dead-letter file
+	deadLetterMu sync.Mutex
+}
+
+// endpoint is a webhook with its delivery queue and what its rules have seen
+type endpoint struct {
+	Webhook
+	queue chan Payload
+	// failures holds the recent pool creation failures per tenant
+	failures map[string][]time.Time
+	// saturated marks tenants last seen saturated
+	saturated map[string]bool
+}
+
+// rule returns the tenant's rule, or the global one when it has none
+func (e *endpoint) rule(tenantID string) (Rule, bool) {
+	if len(e.Rules) == 0 {
+		return Rule{}.withDefaults(), true
+	}
+	global, found := Rule{}, false
+	for _, rule := range e.Rules {
+		if rule.TenantID == tenantID {
+			return rule.withDefaults(), true
+		}
+		if rule.TenantID == "" {
+			global, found = rule.withDefaults(), true
+		}
+	}
+	return global, found
+}
+
+// NewDispatcher creates a dispatcher sending the pool manager's alerts to webhooks
+func NewDispatcher(poolManager *pool.ConnectionPoolManager, webhooks []Webhook, opts Options) *Dispatcher {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = time.Minute
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.SaturationInterval <= 0 {
+		opts.SaturationInterval = 5 * time.Second
+	}
+	d := &Dispatcher{poolManager: poolManager, opts: opts, client: &http.Client{Timeout: opts.Timeout}}
+	for _, webhook := range webhooks {
+		d.endpoints = append(d.endpoints, &endpoint{
+			Webhook:   webhook,
+			queue:     make(chan Payload, queueSize),
+			failures:  make(map[string][]time.Time),
+			saturated: make(map[string]bool),
+		})
+	}
+	return d
+}
+
+// Run watches the pool manager and delivers alerts until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	for _, e := range d.endpoints {
+		go d.send(ctx, e)
+	}
+
+	sub := d.poolManager.SubscribeEvents(pool.EventFilter{
+		Types: []pool.EventType{pool.EventCreationFailed, pool.EventLeaseReclaimed, pool.EventFailover},
+	}, false, 0)
+	go func() {
+		defer sub.Close()
+		ticker := time.NewTicker(d.opts.SaturationInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-sub.Events():
+				d.handleEvent(event)
+			case now := <-ticker.C:
+				d.checkSaturation(now)
+			}
+		}
+	}()
+}
+
+// handleEvent queues the alerts an event fires
+func (d *Dispatcher) handleEvent(event pool.Event) {
+	for _, e := range d.endpoints {
+		rule, ok := e.rule(event.TenantID)
+		if !ok {
+			continue
+		}
+		payload := Payload{TenantID: event.TenantID, Time: event.Time, Server: event.Server}
+		switch event.Type {
+		case pool.EventCreationFailed:
+			if !rule.fires(AlertCreationFailures) {
+				continue
+			}
+			failures := e.failures[event.TenantID]
+			for len(failures) > 0 && event.Time.Sub(failures[0]) > rule.CreationFailureWindow.Duration {
+				failures = failures[1:]
+			}
+			failures = append(failures, event.Time)
+			if len(failures) < rule.CreationFailures {
+				e.failures[event.TenantID] = failures
+				continue
+			}
+			// Start counting afresh so a tenant that keeps failing fires once per burst
+			delete(e.failures, event.TenantID)
+			payload.Alert, payload.Failures, payload.Error = AlertCreationFailures, len(failures), event.Error
+		case pool.EventLeaseReclaimed:
+			if !rule.fires(AlertLeaseLeak) {
+				continue
+			}
+			payload.Alert, payload.Lease = AlertLeaseLeak, event.Lease
+		case pool.EventFailover:
+			if !rule.fires(AlertFailover) {
+				continue
+			}
+			payload.Alert, payload.FromServer = AlertFailover, event.FromServer
+		default:
+			continue
+		}
+		d.enqueue(e, payload)
+	}
+}
+
+// checkSaturation queues an alert for each tenant that became saturated since the last check
+func (d *Dispatcher) checkSaturation(now time.Time) {
+	snapshot := d.poolManager.StatsSnapshot(now)
+	for _, e := range d.endpoints {
+		for tenantID := range e.saturated {
+			if _, ok := snapshot[tenantID]; !ok {
+				delete(e.saturated, tenantID)
+			}
+		}
+		for tenantID, sample := range snapshot {
+			rule, ok := e.rule(tenantID)
+			if !ok || !rule.fires(AlertSaturation) || sample.MaxConns == 0 {
+				continue
+			}
+			utilization := float64(sample.ActiveConnections) / float64(sample.MaxConns)
+			saturated := utilization >= rule.SaturationThreshold
+			if saturated && !e.saturated[tenantID] {
+				d.enqueue(e, Payload{
+					Alert:             AlertSaturation,
+					TenantID:          tenantID,
+					Time:              now,
+					ActiveConnections: sample.ActiveConnections,
+					MaxConnections:    sample.MaxConns,
+					Utilization:       utilization,
+				})
+			}
+			e.saturated[tenantID] = saturated
+		}
+	}
+}
+
+// enqueue hands a payload to the endpoint's sender, dead-lettering it when the queue is full
+func (d *Dispatcher) enqueue(e *endpoint, payload Payload) {
+	payload.ID = newPayloadID()
+	payload.Webhook = e.Name
+	select {
+	case e.queue <- payload:
+	default:
+		d.deadLetter(e, payload, 0, errors.New("delivery queue is full"))
+	}
+}
+
+func newPayloadID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// send delivers the endpoint's payloads one at a time until ctx is done
+func (d *Dispatcher) send(ctx context.Context, e *endpoint) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload := <-e.queue:
+			d.deliver(ctx, e, payload)
+		}
+	}
+}
+
+// deliver posts the payload, retrying with exponential backoff, and dead-letters it
+// when every attempt failed or the endpoint rejected it
+func (d *Dispatcher) deliver(ctx context.Context, e *endpoint, payload Payload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		d.deadLetter(e, payload, 0, err)
+		return
+	}
+
+	backoff := d.opts.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := d.post(ctx, e, payload.ID, body)
+		if err == nil {
+			deliveries.WithLabelValues(e.Name, "delivered").Inc()
+			return
+		}
+		if !retry || attempt == d.opts.MaxAttempts {
+			d.deadLetter(e, payload, attempt, err)
+			return
+		}
+		deliveries.WithLabelValues(e.Name, "retried").Inc()
+		log.Debug().Err(err).Str("webhook", e.Name).Str("payload_id", payload.ID).Int("attempt", attempt).Msg("Webhook delivery failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			d.deadLetter(e, payload, attempt, err)
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, d.opts.MaxBackoff)
+	}
+}
+
+// post makes one delivery attempt. Network errors, 429 and 5xx responses are worth
+// retrying; other failures are not.
+func (d *Dispatcher) post(ctx context.Context, e *endpoint, id string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IDHeader, id)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(e.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	err = fmt.Errorf("webhook responded %s", resp.Status)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// deadLetter logs a payload that could not be delivered and appends it to the
+// dead-letter file
+func (d *Dispatcher) deadLetter(e *endpoint, payload Payload, attempts int, err error) {
+	deliveries.WithLabelValues(e.Name, "dead_lettered").Inc()
+	log.Error().Err(err).
+		Str("webhook", e.Name).
+		Str("payload_id", payload.ID).
+		Str("alert", string(payload.Alert)).
+		Str("tenant_id", payload.TenantID).
+		Int("attempts", attempts).
+		Msg("Webhook delivery failed, dead-lettering payload")
+	if d.opts.DeadLetterFile == "" {
+		return
+	}
+
+	line, _ := json.Marshal(DeadLetter{Time: time.Now(), Webhook: e.Name, URL: e.URL, Attempts: attempts, Error: err.Error(), Payload: payload})
+	d.deadLetterMu.Lock()
+	defer d.deadLetterMu.Unlock()
+	f, ferr := os.OpenFile(d.opts.DeadLetterFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if ferr == nil {
+		_, ferr = f.Write(append(line, '\n'))
+		if cerr := f.Close(); ferr == nil {
+			ferr = cerr
+		}
+	}
+	if ferr != nil {
+		log.Error().Err(ferr).Str("file", d.opts.DeadLetterFile).Msg("Failed to write dead-letter file")
+	}
+}
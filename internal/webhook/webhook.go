@@ -0,0 +1,176 @@
+// Package webhook sends signed alerts about pool saturation, failing pool creation,
+// lease leaks and failovers to HTTP endpoints
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+)
+
+// Headers set on every delivery
+const (
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the timestamp, a dot
+	// and the body, keyed with the webhook's secret
+	SignatureHeader = "X-Webhook-Signature"
+	// TimestampHeader carries the Unix time the delivery was signed at
+	TimestampHeader = "X-Webhook-Timestamp"
+	// IDHeader carries the payload ID, the same on every attempt
+	IDHeader = "X-Webhook-ID"
+)
+
+// Alert is a condition a webhook fires on
+type Alert string
+
+const (
+	// AlertSaturation fires when a tenant's active connections reach the rule's share of
+	// its pool size
+	AlertSaturation Alert = "pool_saturated"
+	// AlertCreationFailures fires when a tenant's pool creation fails repeatedly within
+	// the rule's window
+	AlertCreationFailures Alert = "creation_failures"
+	// AlertLeaseLeak fires for each lease reclaimed after it expired
+	AlertLeaseLeak Alert = "lease_leaked"
+	// AlertFailover fires when a tenant's reads move to another replica or the primary
+	AlertFailover Alert = "failover"
+)
+
+// Alerts lists every alert
+var Alerts = []Alert{AlertSaturation, AlertCreationFailures, AlertLeaseLeak, AlertFailover}
+
+const (
+	defaultSaturationThreshold   = 0.9
+	defaultCreationFailures      = 3
+	defaultCreationFailureWindow = 5 * time.Minute
+)
+
+// Webhook is an endpoint and the rules deciding what is sent to it
+type Webhook struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+	// Rules applies to every tenant with all alerts and default thresholds when empty
+	Rules []Rule `json:"rules,omitempty"`
+}
+
+// Rule selects the alerts sent for a tenant, or for every tenant without a rule of
+// its own when TenantID is empty
+type Rule struct {
+	TenantID string `json:"tenant_id,omitempty"`
+	// Alerts fired under the rule; all of them when empty
+	Alerts []Alert `json:"alerts,omitempty"`
+	// SaturationThreshold is the share of the pool size in use that counts as
+	// saturated. Defaults to 0.9.
+	SaturationThreshold float64 `json:"saturation_threshold,omitempty"`
+	// CreationFailures is how many failed pool creations within CreationFailureWindow
+	// fire an alert. Defaults to 3 within 5m.
+	CreationFailures      int           `json:"creation_failures,omitempty"`
+	CreationFailureWindow pool.Duration `json:"creation_failure_window,omitempty"`
+}
+
+func (r Rule) fires(alert Alert) bool {
+	return len(r.Alerts) == 0 || slices.Contains(r.Alerts, alert)
+}
+
+func (r Rule) withDefaults() Rule {
+	if r.SaturationThreshold <= 0 {
+		r.SaturationThreshold = defaultSaturationThreshold
+	}
+	if r.CreationFailures <= 0 {
+		r.CreationFailures = defaultCreationFailures
+	}
+	if r.CreationFailureWindow.Duration <= 0 {
+		r.CreationFailureWindow.Duration = defaultCreationFailureWindow
+	}
+	return r
+}
+
+// LoadWebhooks reads a JSON array of webhooks from path
+func LoadWebhooks(path string) ([]Webhook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var webhooks []Webhook
+	if err := json.Unmarshal(data, &webhooks); err != nil {
+		return nil, fmt.Errorf("failed to parse webhooks file %s: %w", path, err)
+	}
+	for i, webhook := range webhooks {
+		if err := webhook.Validate(); err != nil {
+			return nil, fmt.Errorf("webhook %d: %w", i, err)
+		}
+	}
+	return webhooks, nil
+}
+
+// Validate checks that the webhook can be sent to and its rules make sense
+func (w Webhook) Validate() error {
+	if w.Name == "" || w.Secret == "" {
+		return fmt.Errorf("webhook needs a name and a secret")
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook %s: invalid url %q", w.Name, w.URL)
+	}
+	tenants := make(map[string]bool)
+	for _, rule := range w.Rules {
+		if tenants[rule.TenantID] {
+			return fmt.Errorf("webhook %s: more than one rule for tenant %q", w.Name, rule.TenantID)
+		}
+		tenants[rule.TenantID] = true
+		for _, alert := range rule.Alerts {
+			if !slices.Contains(Alerts, alert) {
+				return fmt.Errorf("webhook %s: unknown alert %q", w.Name, alert)
+			}
+		}
+		if rule.SaturationThreshold < 0 || rule.SaturationThreshold > 1 {
+			return fmt.Errorf("webhook %s: saturation threshold must be between 0 and 1", w.Name)
+		}
+	}
+	return nil
+}
+
+// Payload is the JSON body of a delivery
+type Payload struct {
+	// ID is the same on every attempt so receivers can drop duplicates
+	ID       string    `json:"id"`
+	Webhook  string    `json:"webhook"`
+	Alert    Alert     `json:"alert"`
+	TenantID string    `json:"tenant_id"`
+	Time     time.Time `json:"time"`
+	// Server is the pool concerned, or where reads moved to on failover
+	Server     string `json:"server,omitempty"`
+	FromServer string `json:"from_server,omitempty"`
+	// Lease is the handle of the leaked lease, as ListLeases reports it
+	Lease string `json:"lease,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Failures is how many pool creations failed within the rule's window
+	Failures          int     `json:"failures,omitempty"`
+	ActiveConnections int32   `json:"active_connections,omitempty"`
+	MaxConnections    int32   `json:"max_connections,omitempty"`
+	Utilization       float64 `json:"utilization,omitempty"`
+}
+
+// Sign returns the signature header value of a body signed at timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the secret's signature of body at timestamp.
+// Receivers should also reject timestamps too far from their own clock.
+func Verify(secret, signature string, timestamp int64, body []byte) bool {
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
@@ -0,0 +1,239 @@
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/pgtest"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+)
+
+const testSecret = "s3cret"
+
+// receiver is a local webhook endpoint answering with the given statuses in turn, and
+// 200 once they run out
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	attempts int
+	payloads chan Payload
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses, payloads: make(chan Payload, 10)}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		timestamp, _ := strconv.ParseInt(req.Header.Get(TimestampHeader), 10, 64)
+		if !Verify(testSecret, req.Header.Get(SignatureHeader), timestamp, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		r.mu.Lock()
+		r.attempts++
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		r.mu.Unlock()
+		if status == http.StatusOK {
+			var payload Payload
+			json.Unmarshal(body, &payload)
+			assert.Equal(t, payload.ID, req.Header.Get(IDHeader))
+			r.payloads <- payload
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) next(t *testing.T) Payload {
+	select {
+	case payload := <-r.payloads:
+		return payload
+	case <-time.After(2 * time.Second):
+		t.Fatal("no webhook delivered")
+		return Payload{}
+	}
+}
+
+func TestLoadWebhooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "valid", content: `[{"name": "oncall", "url": "https://hooks.example.com/pool", "secret": "x",
+			"rules": [{"alerts": ["failover"]}, {"tenant_id": "acme", "saturation_threshold": 0.8, "creation_failure_window": "1m"}]}]`},
+		{name: "no secret", content: `[{"name": "oncall", "url": "https://hooks.example.com/pool"}]`, wantErr: "needs a name and a secret"},
+		{name: "bad url", content: `[{"name": "oncall", "url": "ftp://hooks", "secret": "x"}]`, wantErr: "invalid url"},
+		{name: "unknown alert", content: `[{"name": "oncall", "url": "http://hooks", "secret": "x", "rules": [{"alerts": ["meltdown"]}]}]`, wantErr: "unknown alert"},
+		{name: "duplicate rule", content: `[{"name": "oncall", "url": "http://hooks", "secret": "x", "rules": [{"tenant_id": "acme"}, {"tenant_id": "acme"}]}]`, wantErr: "more than one rule"},
+		{name: "bad threshold", content: `[{"name": "oncall", "url": "http://hooks", "secret": "x", "rules": [{"saturation_threshold": 90}]}]`, wantErr: "between 0 and 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "webhooks.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+			webhooks, err := LoadWebhooks(path)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, webhooks, 1)
+			assert.Equal(t, time.Minute, webhooks[0].Rules[1].CreationFailureWindow.Duration)
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"alert":"failover"}`)
+	signature := Sign(testSecret, 1700000000, body)
+	assert.True(t, Verify(testSecret, signature, 1700000000, body))
+	assert.False(t, Verify(testSecret, signature, 1700000001, body))
+	assert.False(t, Verify("other", signature, 1700000000, body))
+}
+
+func TestDispatcher_Alerts(t *testing.T) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+	defer db.Close()
+	poolManager := pool.NewConnectionPoolManager()
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "acme", DSN: db.DSN()})
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "broken", DSN: "invalid-dsn"})
+	defer poolManager.ReleaseConnection(context.Background(), "acme", db.DSN())
+
+	r := newReceiver(t)
+	d := NewDispatcher(poolManager, []Webhook{{
+		Name:   "oncall",
+		URL:    r.URL,
+		Secret: testSecret,
+		Rules: []Rule{
+			{Alerts: []Alert{AlertCreationFailures}, CreationFailures: 2},
+			{TenantID: "acme", Alerts: []Alert{AlertSaturation, AlertLeaseLeak}, SaturationThreshold: 0.05},
+		},
+	}}, Options{SaturationInterval: 10 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.Run(ctx)
+	poolManager.StartLeaseReaper(ctx, 10*time.Millisecond)
+
+	for range 2 {
+		_, err := poolManager.GetConnection(ctx, "broken", "invalid-dsn")
+		require.Error(t, err)
+	}
+	payload := r.next(t)
+	assert.Equal(t, AlertCreationFailures, payload.Alert)
+	assert.Equal(t, "oncall", payload.Webhook)
+	assert.Equal(t, "broken", payload.TenantID)
+	assert.Equal(t, 2, payload.Failures)
+	assert.NotEmpty(t, payload.Error)
+
+	// One of the pool's 20 connections saturates it at a 5% threshold
+	lease, err := poolManager.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	payload = r.next(t)
+	assert.Equal(t, AlertSaturation, payload.Alert)
+	assert.Equal(t, "acme", payload.TenantID)
+	assert.Equal(t, int32(1), payload.ActiveConnections)
+	assert.Equal(t, int32(20), payload.MaxConnections)
+
+	lease.SetTTL(time.Millisecond)
+	payload = r.next(t)
+	assert.Equal(t, AlertLeaseLeak, payload.Alert)
+	assert.Equal(t, lease.Handle(), payload.Lease)
+}
+
+func TestDispatcher_Rules(t *testing.T) {
+	d := NewDispatcher(pool.NewConnectionPoolManager(), []Webhook{{
+		Name: "oncall",
+		Rules: []Rule{
+			{Alerts: []Alert{AlertFailover}},
+			{TenantID: "acme", Alerts: []Alert{AlertLeaseLeak}},
+		},
+	}}, Options{})
+	queued := func() []Payload {
+		var payloads []Payload
+		for len(d.endpoints[0].queue) > 0 {
+			payloads = append(payloads, <-d.endpoints[0].queue)
+		}
+		return payloads
+	}
+
+	// A tenant's own rule replaces the global one
+	d.handleEvent(pool.Event{Type: pool.EventFailover, TenantID: "acme"})
+	assert.Empty(t, queued())
+	d.handleEvent(pool.Event{Type: pool.EventFailover, TenantID: "globex", Server: "primary:5432/globex", FromServer: "replica:5432/globex"})
+	payloads := queued()
+	require.Len(t, payloads, 1)
+	assert.Equal(t, AlertFailover, payloads[0].Alert)
+	assert.Equal(t, "replica:5432/globex", payloads[0].FromServer)
+	assert.NotEmpty(t, payloads[0].ID)
+
+	d.handleEvent(pool.Event{Type: pool.EventLeaseReclaimed, TenantID: "globex"})
+	assert.Empty(t, queued())
+	d.handleEvent(pool.Event{Type: pool.EventLeaseReclaimed, TenantID: "acme", Lease: "0123456789ab"})
+	assert.Len(t, queued(), 1)
+}
+
+func TestDispatcher_RetriesAndDeadLetters(t *testing.T) {
+	deadLetters := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	newDispatcher := func(url string) (*Dispatcher, *endpoint) {
+		d := NewDispatcher(pool.NewConnectionPoolManager(), []Webhook{{Name: "oncall", URL: url, Secret: testSecret}},
+			Options{MaxAttempts: 3, Backoff: time.Millisecond, DeadLetterFile: deadLetters})
+		return d, d.endpoints[0]
+	}
+	ctx := context.Background()
+
+	// Server errors are retried until the payload goes through
+	r := newReceiver(t, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	d, e := newDispatcher(r.URL)
+	d.deliver(ctx, e, Payload{ID: "p1", Alert: AlertFailover, TenantID: "acme"})
+	assert.Equal(t, "p1", r.next(t).ID)
+	assert.Equal(t, 3, r.attempts)
+
+	// Retries run out
+	r = newReceiver(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	d, e = newDispatcher(r.URL)
+	d.deliver(ctx, e, Payload{ID: "p2", Alert: AlertFailover, TenantID: "acme"})
+	assert.Equal(t, 3, r.attempts)
+
+	// Rejected payloads are not retried
+	r = newReceiver(t, http.StatusBadRequest)
+	d, e = newDispatcher(r.URL)
+	d.deliver(ctx, e, Payload{ID: "p3", Alert: AlertLeaseLeak, TenantID: "acme"})
+	assert.Equal(t, 1, r.attempts)
+
+	f, err := os.Open(deadLetters)
+	require.NoError(t, err)
+	defer f.Close()
+	var letters []DeadLetter
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var letter DeadLetter
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &letter))
+		letters = append(letters, letter)
+	}
+	require.Len(t, letters, 2)
+	assert.Equal(t, "p2", letters[0].Payload.ID)
+	assert.Equal(t, 3, letters[0].Attempts)
+	assert.Contains(t, letters[0].Error, "502")
+	assert.Equal(t, "p3", letters[1].Payload.ID)
+	assert.Equal(t, 1, letters[1].Attempts)
+	assert.Equal(t, "oncall", letters[1].Webhook)
+}
@@ -0,0 +1,157 @@
+// Package client is a Go client for the connection pool manager. It dials the service,
+// retries calls while it is unavailable and wraps leases so they are always released.
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Options configure a Client
+type Options struct {
+	// TLSConfig secures the connection. A nil config verifies the server against the
+	// system roots. Set Certificates for mTLS.
+	TLSConfig *tls.Config
+	// Insecure connects without TLS
+	Insecure bool
+	// Token is sent as a bearer token on every call
+	Token string
+
+	// MaxRetries is how often a call failing with Unavailable is retried. Defaults to 3;
+	// a negative value disables retries.
+	MaxRetries int
+	// InitialBackoff is the wait before the first retry, doubled on every further one.
+	// Defaults to 100ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries. Defaults to 2s.
+	MaxBackoff time.Duration
+
+	// LeaseTTL is how long leases live on the service without renewal, in whole
+	// seconds. Zero uses the tenant's TTL.
+	LeaseTTL time.Duration
+	// DisableRenewal stops leases from being renewed in the background, leaving them
+	// to expire unless renewed with Lease.Renew
+	DisableRenewal bool
+
+	// DialOptions are passed to grpc.NewClient after the client's own
+	DialOptions []grpc.DialOption
+}
+
+// Client talks to a connection pool manager
+type Client struct {
+	conn *grpc.ClientConn
+	rpc  pb.ConnectionPoolServiceClient
+	opts Options
+}
+
+// New creates a client for the service at target, e.g. "pool-manager:50052"
+func New(target string, opts Options) (*Client, error) {
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = 3
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 2 * time.Second
+	}
+
+	var dialOpts []grpc.DialOption
+	if opts.Insecure {
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	} else {
+		tlsConfig := opts.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	}
+	if opts.Token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(bearerToken{token: opts.Token, requireTLS: !opts.Insecure}))
+	}
+	dialOpts = append(dialOpts, opts.DialOptions...)
+
+	conn, err := grpc.NewClient(target, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, rpc: pb.NewConnectionPoolServiceClient(conn), opts: opts}, nil
+}
+
+// Close closes the connection to the service. Leases still held are no longer renewed
+// and are reclaimed once they expire, so release them first.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// RPC returns the generated gRPC client for calls the Client does not wrap
+func (c *Client) RPC() pb.ConnectionPoolServiceClient {
+	return c.rpc
+}
+
+// retry runs call until it succeeds, fails with anything but Unavailable, runs out of
+// retries or ctx is done. The error is converted to an *Error.
+func (c *Client) retry(ctx context.Context, call func(ctx context.Context) error) error {
+	backoff := c.opts.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		err := convertError(call(ctx))
+		if err == nil || !errors.Is(err, ErrUnavailable) || attempt >= c.opts.MaxRetries {
+			return err
+		}
+
+		// Full jitter keeps clients from retrying in lockstep after an outage
+		wait := backoff/2 + rand.N(backoff/2+1)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, c.opts.MaxBackoff)
+	}
+}
+
+// Stats is the state of a tenant's pools
+type Stats struct {
+	ActiveConnections int32
+	IdleConnections   int32
+	TotalConnections  int32
+}
+
+// Stats returns the summed stats of a tenant's pools
+func (c *Client) Stats(ctx context.Context, tenantID string) (Stats, error) {
+	var resp *pb.StatsResponse
+	err := c.retry(ctx, func(ctx context.Context) (err error) {
+		resp, err = c.rpc.GetPoolStats(ctx, &pb.StatsRequest{TenantId: tenantID})
+		return err
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+	return Stats{
+		ActiveConnections: resp.ActiveConnections,
+		IdleConnections:   resp.IdleConnections,
+		TotalConnections:  resp.TotalConnections,
+	}, nil
+}
+
+// bearerToken sends a token in the authorization metadata of every call
+type bearerToken struct {
+	token      string
+	requireTLS bool
+}
+
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+func (t bearerToken) RequireTransportSecurity() bool {
+	return t.requireTLS
+}
@@ -0,0 +1,389 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/auth"
+	"github.com/teresa-solution/connection-pool-manager/internal/pgtest"
+	"github.com/teresa-solution/connection-pool-manager/internal/service"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// startServer serves srv over bufconn, requiring token "s3cret", and returns a client
+// connected to it
+func startServer(t *testing.T, srv pb.ConnectionPoolServiceServer, opts Options) *Client {
+	listener := bufconn.Listen(1 << 20)
+	authenticator := auth.NewAuthenticator([]auth.Token{{Name: "app", Token: "s3cret"}}, false)
+	server := grpc.NewServer(grpc.UnaryInterceptor(authenticator.UnaryServerInterceptor()))
+	pb.RegisterConnectionPoolServiceServer(server, srv)
+	go server.Serve(listener)
+
+	opts.Insecure = true
+	opts.DialOptions = append(opts.DialOptions, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}))
+	c, err := New("passthrough:///bufnet", opts)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		c.Close()
+		server.Stop()
+	})
+	return c
+}
+
+// setupService starts the real service with tenant "acme" on a fake database
+func setupService(t *testing.T) (*Client, *pool.ConnectionPoolManager) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+
+	poolManager := pool.NewConnectionPoolManager()
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "acme", DSN: db.DSN()})
+	t.Cleanup(func() {
+		poolManager.EvictTenant(context.Background(), "acme")
+		db.Close()
+	})
+
+	c := startServer(t, service.NewConnectionPoolServiceServerWithManager(poolManager), Options{Token: "s3cret"})
+	return c, poolManager
+}
+
+func TestClient_WithLease(t *testing.T) {
+	c, poolManager := setupService(t)
+	ctx := context.Background()
+
+	var leaseID string
+	err := c.WithLease(ctx, "acme", func(lease *Lease) error {
+		leaseID = lease.ID
+		held, ok := poolManager.Lease(lease.ID)
+		require.True(t, ok)
+		assert.Contains(t, held.Client.CallSite, "client.TestClient_WithLease (client_test.go:")
+		assert.Equal(t, "token:app", held.Client.Identity)
+		result, err := lease.Exec(ctx, "SELECT pg_backend_pid()")
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT 1", result.CommandTag)
+		require.Len(t, result.Rows, 1)
+		return nil
+	})
+	require.NoError(t, err)
+	_, held := poolManager.Lease(leaseID)
+	assert.False(t, held)
+
+	stats, err := c.Stats(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), stats.ActiveConnections)
+}
+
+func TestClient_WithLeaseAlwaysReleases(t *testing.T) {
+	c, poolManager := setupService(t)
+	ctx := context.Background()
+	failure := errors.New("boom")
+
+	tests := []struct {
+		name string
+		fn   func(*Lease) error
+	}{
+		{name: "error", fn: func(*Lease) error { return failure }},
+		{name: "panic", fn: func(*Lease) error { panic(failure) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var leaseID string
+			fn := func(lease *Lease) error {
+				leaseID = lease.ID
+				return tt.fn(lease)
+			}
+			func() {
+				defer func() { recover() }()
+				err := c.WithLease(ctx, "acme", fn)
+				assert.ErrorIs(t, err, failure)
+			}()
+
+			require.NotEmpty(t, leaseID)
+			_, held := poolManager.Lease(leaseID)
+			assert.False(t, held)
+		})
+	}
+}
+
+func TestClient_TypedErrors(t *testing.T) {
+	c, _ := setupService(t)
+	ctx := context.Background()
+
+	lease, err := c.Acquire(ctx, "acme")
+	require.NoError(t, err)
+	require.NoError(t, lease.Release(ctx))
+	// Releasing twice is a no-op
+	require.NoError(t, lease.Release(ctx))
+
+	_, err = lease.Exec(ctx, "SELECT 1")
+	assert.ErrorIs(t, err, ErrNotFound)
+	var clientErr *Error
+	require.ErrorAs(t, err, &clientErr)
+	assert.Equal(t, codes.NotFound, clientErr.Code)
+	assert.Contains(t, clientErr.Message, "lease not found")
+
+	_, err = c.Acquire(ctx, "acme", MinLSN("nonsense"))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+
+	_, err = c.Stats(ctx, "nobody")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = lease.Exec(ctx, "SELECT $1", struct{}{})
+	assert.ErrorContains(t, err, "unsupported type")
+
+	anonymous := startServer(t, &flakyServer{}, Options{MaxRetries: -1})
+	_, err = anonymous.Stats(ctx, "acme")
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+// flakyServer fails GetPoolStats with Unavailable until it has been called failures times
+type flakyServer struct {
+	pb.UnimplementedConnectionPoolServiceServer
+	failures int32
+	calls    atomic.Int32
+}
+
+func (s *flakyServer) GetPoolStats(ctx context.Context, req *pb.StatsRequest) (*pb.StatsResponse, error) {
+	if s.calls.Add(1) <= s.failures {
+		return nil, status.Error(codes.Unavailable, "tenant is draining")
+	}
+	return &pb.StatsResponse{ActiveConnections: 3}, nil
+}
+
+func TestClient_RetriesUnavailable(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int32
+		maxRetries int
+		wantCalls  int32
+		wantErr    error
+	}{
+		{name: "recovers", failures: 2, maxRetries: 3, wantCalls: 3},
+		{name: "gives up", failures: 5, maxRetries: 2, wantCalls: 3, wantErr: ErrUnavailable},
+		{name: "retries disabled", failures: 1, maxRetries: -1, wantCalls: 1, wantErr: ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &flakyServer{failures: tt.failures}
+			c := startServer(t, srv, Options{Token: "s3cret", MaxRetries: tt.maxRetries, InitialBackoff: time.Millisecond})
+
+			stats, err := c.Stats(context.Background(), "acme")
+			assert.Equal(t, tt.wantCalls, srv.calls.Load())
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int32(3), stats.ActiveConnections)
+		})
+	}
+}
+
+// renewingServer grants leases that expire a second after being acquired or renewed
+type renewingServer struct {
+	pb.UnimplementedConnectionPoolServiceServer
+	ttlSeconds atomic.Int32
+	renewals   atomic.Int32
+	released   atomic.Bool
+}
+
+func (s *renewingServer) GetConnection(ctx context.Context, req *pb.ConnectionRequest) (*pb.ConnectionResponse, error) {
+	s.ttlSeconds.Store(req.TtlSeconds)
+	return &pb.ConnectionResponse{ConnectionId: "conn-1", ExpiresAt: timestamppb.New(time.Now().Add(time.Second))}, nil
+}
+
+func (s *renewingServer) RenewLease(ctx context.Context, req *pb.RenewLeaseRequest) (*pb.RenewLeaseResponse, error) {
+	if s.released.Load() {
+		return nil, status.Error(codes.NotFound, "lease not found")
+	}
+	s.renewals.Add(1)
+	return &pb.RenewLeaseResponse{ExpiresAt: timestamppb.New(time.Now().Add(time.Second))}, nil
+}
+
+func (s *renewingServer) ReleaseConnection(ctx context.Context, req *pb.ConnectionRelease) (*pb.ReleaseResponse, error) {
+	s.released.Store(true)
+	return &pb.ReleaseResponse{Success: true}, nil
+}
+
+func TestLease_Renewal(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("renews until released", func(t *testing.T) {
+		srv := &renewingServer{}
+		c := startServer(t, srv, Options{Token: "s3cret", LeaseTTL: 90 * time.Second})
+
+		lease, err := c.Acquire(ctx, "acme")
+		require.NoError(t, err)
+		assert.Equal(t, int32(90), srv.ttlSeconds.Load())
+		first := lease.ExpiresAt()
+
+		require.Eventually(t, func() bool { return srv.renewals.Load() >= 2 }, 3*time.Second, 10*time.Millisecond)
+		assert.True(t, lease.ExpiresAt().After(first))
+
+		require.NoError(t, lease.Release(ctx))
+		renewals := srv.renewals.Load()
+		time.Sleep(500 * time.Millisecond)
+		assert.Equal(t, renewals, srv.renewals.Load())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		srv := &renewingServer{}
+		c := startServer(t, srv, Options{Token: "s3cret", DisableRenewal: true})
+
+		lease, err := c.Acquire(ctx, "acme")
+		require.NoError(t, err)
+		defer lease.Release(ctx)
+		time.Sleep(500 * time.Millisecond)
+		assert.Zero(t, srv.renewals.Load())
+
+		expiresAt, err := lease.Renew(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), srv.renewals.Load())
+		assert.Equal(t, expiresAt, lease.ExpiresAt())
+	})
+}
+
+func TestClient_Hold(t *testing.T) {
+	c, poolManager := setupService(t)
+	ctx := context.Background()
+
+	lease, err := c.Hold(ctx, "acme")
+	require.NoError(t, err)
+	_, held := poolManager.Lease(lease.ID)
+	assert.True(t, held)
+	_, err = lease.Exec(ctx, "SELECT 1")
+	require.NoError(t, err)
+
+	require.NoError(t, lease.Release(ctx))
+	select {
+	case <-lease.Done():
+	default:
+		t.Fatal("stream is still open after release")
+	}
+	assert.NoError(t, lease.Err())
+	_, held = poolManager.Lease(lease.ID)
+	assert.False(t, held)
+
+	// The service ending the lease is reported on Done
+	lease, err = c.Hold(ctx, "acme")
+	require.NoError(t, err)
+	go poolManager.EvictTenant(ctx, "acme")
+	select {
+	case <-lease.Done():
+	case <-time.After(time.Second):
+		t.Fatal("eviction did not end the lease")
+	}
+	assert.ErrorIs(t, lease.Err(), ErrUnavailable)
+	assert.Error(t, lease.Release(ctx))
+}
+
+func TestEncodeParam(t *testing.T) {
+	tests := []struct {
+		arg  interface{}
+		want *pb.NullableString
+	}{
+		{arg: nil, want: &pb.NullableString{IsNull: true}},
+		{arg: []byte(nil), want: &pb.NullableString{IsNull: true}},
+		{arg: []byte{}, want: &pb.NullableString{BytesValue: []byte{}, IsBytes: true}},
+		{arg: []byte{0xde, 0xad, 0x00, 0xff}, want: &pb.NullableString{BytesValue: []byte{0xde, 0xad, 0x00, 0xff}, IsBytes: true}},
+		{arg: json.RawMessage(`{"a":1}`), want: &pb.NullableString{BytesValue: []byte(`{"a":1}`), IsBytes: true}},
+		{arg: json.RawMessage(nil), want: &pb.NullableString{IsNull: true}},
+		{arg: "text", want: &pb.NullableString{Value: "text"}},
+		{arg: 42, want: &pb.NullableString{Value: "42"}},
+		{arg: int64(-7), want: &pb.NullableString{Value: "-7"}},
+		{arg: 1.5, want: &pb.NullableString{Value: "1.5"}},
+		{arg: true, want: &pb.NullableString{Value: "true"}},
+		{arg: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), want: &pb.NullableString{Value: "2024-05-01T12:00:00Z"}},
+	}
+
+	for _, tt := range tests {
+		got, err := encodeParam(tt.arg)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want.Value, got.Value, "%v", tt.arg)
+		assert.Equal(t, tt.want.IsNull, got.IsNull, "%v", tt.arg)
+		assert.Equal(t, tt.want.BytesValue, got.BytesValue, "%v", tt.arg)
+		assert.Equal(t, tt.want.IsBytes, got.IsBytes, "%v", tt.arg)
+	}
+}
+
+func TestLease_ExecBytes(t *testing.T) {
+	c, _ := setupService(t)
+	ctx := context.Background()
+	lease, err := c.Acquire(ctx, "acme")
+	require.NoError(t, err)
+	defer lease.Release(ctx)
+
+	// Bytes that are not valid UTF-8 reach a bytea parameter unchanged, and come back
+	// in bytea's text format
+	want := []byte{0xff, 0xfe, 0x00, '\\', 'x', 0x80}
+	result, err := lease.Exec(ctx, "SELECT $1::bytea", want)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{pgtype.ByteaOID}, result.ColumnTypes)
+	got := result.Rows[0][0].String
+	require.True(t, strings.HasPrefix(got, `\x`), got)
+	decoded, err := hex.DecodeString(got[2:])
+	require.NoError(t, err)
+	assert.Equal(t, want, decoded)
+
+	// Any other parameter takes the bytes as text, so JSON is not turned into hex
+	result, err = lease.Exec(ctx, "SELECT $1", json.RawMessage(`{"a":1}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, result.Rows[0][0].String)
+	result, err = lease.Exec(ctx, "SELECT $1, $2::bytea", []byte("text"), []byte("text"))
+	require.NoError(t, err)
+	assert.Equal(t, "text", result.Rows[0][0].String)
+	assert.Equal(t, `\x74657874`, result.Rows[0][1].String)
+}
+
+func TestNewResult(t *testing.T) {
+	result := newResult(&pb.QueryResponse{
+		Columns: []string{"a"},
+		Rows:    []*pb.Row{{Values: []*pb.NullableString{{Value: "1"}}}, {Values: []*pb.NullableString{{IsNull: true}}}},
+	})
+	assert.Equal(t, [][]sql.NullString{{{String: "1", Valid: true}}, {{}}}, result.Rows)
+}
+
+func TestLease_WithTx(t *testing.T) {
+	c, _ := setupService(t)
+	ctx := context.Background()
+	failure := errors.New("boom")
+
+	err := c.WithLease(ctx, "acme", func(lease *Lease) error {
+		err := lease.WithTx(ctx, TxOptions{IsoLevel: "serializable"}, func(tx *Tx) error {
+			result, err := tx.Exec(ctx, "SELECT $1, $2", "a", nil)
+			require.NoError(t, err)
+			assert.Equal(t, [][]sql.NullString{{{String: "a", Valid: true}, {}}}, result.Rows)
+			return nil
+		})
+		require.NoError(t, err)
+
+		err = lease.WithTx(ctx, TxOptions{}, func(tx *Tx) error { return failure })
+		assert.ErrorIs(t, err, failure)
+
+		_, err = lease.Begin(ctx, TxOptions{IsoLevel: "snapshot"})
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Errors returned by the client wrap one of these, so callers can test them with errors.Is
+var (
+	// ErrNotFound is returned for unknown or already released leases and tenants without pools
+	ErrNotFound = errors.New("not found")
+	// ErrUnavailable is returned when the service cannot be reached or the tenant is draining
+	ErrUnavailable = errors.New("unavailable")
+	// ErrInvalidArgument is returned for malformed requests, such as an invalid DSN or LSN
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrFailedPrecondition is returned when a statement is not allowed on the lease, such
+	// as a transaction in statement pooling mode
+	ErrFailedPrecondition = errors.New("failed precondition")
+	// ErrAborted is returned by Commit when the transaction had failed and was rolled back
+	ErrAborted = errors.New("aborted")
+	// ErrUnauthenticated is returned when the service rejects the client's credentials
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrPermissionDenied is returned when the caller may not perform the call
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// Error is a failed call to the service
+type Error struct {
+	Code    codes.Code
+	Message string
+	kind    error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the sentinel matching the error's code, if any
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+// GRPCStatus lets status.FromError recover the original status
+func (e *Error) GRPCStatus() *status.Status {
+	return status.New(e.Code, e.Message)
+}
+
+var codeErrors = map[codes.Code]error{
+	codes.NotFound:           ErrNotFound,
+	codes.Unavailable:        ErrUnavailable,
+	codes.InvalidArgument:    ErrInvalidArgument,
+	codes.FailedPrecondition: ErrFailedPrecondition,
+	codes.Aborted:            ErrAborted,
+	codes.Unauthenticated:    ErrUnauthenticated,
+	codes.PermissionDenied:   ErrPermissionDenied,
+	codes.DeadlineExceeded:   context.DeadlineExceeded,
+	codes.Canceled:           context.Canceled,
+}
+
+// convertError turns a gRPC error into an *Error
+func convertError(err error) error {
+	if err == nil {
+		return nil
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	return &Error{Code: s.Code(), Message: s.Message(), kind: codeErrors[s.Code()]}
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Hold leases a connection over a HoldLease stream. The lease lasts as long as the
+// stream: it is pinged in the background and the service releases it as soon as the
+// stream closes, so a worker that crashes does not leak it. Watch Done to learn when
+// the service ends the lease, e.g. when the tenant is evicted. ctx only bounds the
+// acquisition.
+func (c *Client) Hold(ctx context.Context, tenantID string, opts ...AcquireOption) (*Lease, error) {
+	req := c.connectionRequest(tenantID, callSite(0), opts)
+	var lease *Lease
+	err := c.retry(ctx, func(ctx context.Context) (err error) {
+		lease, err = c.openHold(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	go lease.pingLoop()
+	go lease.receiveLoop()
+	return lease, nil
+}
+
+// openHold opens a stream and acquires the lease on it
+func (c *Client) openHold(ctx context.Context, req *pb.ConnectionRequest) (*Lease, error) {
+	// The stream outlives ctx, which only bounds the acquisition
+	streamCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stream, err := c.rpc.HoldLease(streamCtx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	acquired := make(chan error, 1)
+	var resp *pb.HoldLeaseResponse
+	go func() {
+		err := stream.Send(&pb.HoldLeaseRequest{Msg: &pb.HoldLeaseRequest_Acquire{Acquire: req}})
+		if err == nil {
+			resp, err = stream.Recv()
+		}
+		acquired <- err
+	}()
+	select {
+	case err = <-acquired:
+	case <-ctx.Done():
+		err = status.FromContextError(ctx.Err()).Err()
+	}
+	if err == nil && resp.GetLease() == nil {
+		err = status.Error(codes.Internal, "HoldLease did not return a lease")
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	granted := resp.GetLease()
+	return &Lease{
+		ID:          granted.ConnectionId,
+		TenantID:    req.TenantId,
+		FromReplica: granted.FromReplica,
+		client:      c,
+		expiresAt:   granted.ExpiresAt.AsTime(),
+		stop:        make(chan struct{}),
+		hold:        stream,
+		cancelHold:  cancel,
+		done:        make(chan struct{}),
+	}, nil
+}
+
+// Done is closed when a lease taken with Hold ends, after which Err says why. It is nil,
+// and so never closed, for leases taken with Acquire.
+func (l *Lease) Done() <-chan struct{} {
+	return l.done
+}
+
+// Err returns why a lease taken with Hold ended, or nil while it is held or once it
+// was released
+func (l *Lease) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.holdErr
+}
+
+// pingLoop pings the service when a third of the lease's remaining time is left. It is
+// the stream's only sender, so it also closes the stream when the lease is released.
+func (l *Lease) pingLoop() {
+	for {
+		wait := max(time.Until(l.ExpiresAt())/3, minRenewInterval)
+		select {
+		case <-l.stop:
+			l.hold.CloseSend()
+			return
+		case <-l.done:
+			return
+		case <-time.After(wait):
+		}
+		if err := l.hold.Send(&pb.HoldLeaseRequest{Msg: &pb.HoldLeaseRequest_Ping{Ping: &pb.LeasePing{}}}); err != nil {
+			// receiveLoop learns why the stream broke
+			return
+		}
+	}
+}
+
+// receiveLoop records the expiry from every pong and why the stream ended
+func (l *Lease) receiveLoop() {
+	defer close(l.done)
+	for {
+		resp, err := l.hold.Recv()
+		if err != nil {
+			l.mu.Lock()
+			if !l.released || !errors.Is(err, io.EOF) {
+				l.holdErr = convertError(err)
+			}
+			l.mu.Unlock()
+			return
+		}
+		if pong := resp.GetPong(); pong != nil {
+			l.mu.Lock()
+			l.expiresAt = pong.ExpiresAt.AsTime()
+			l.mu.Unlock()
+		}
+	}
+}
+
+// closeHold closes the lease's stream and waits for the service to release the lease
+func (l *Lease) closeHold() error {
+	defer l.cancelHold()
+	timer := time.NewTimer(releaseTimeout)
+	defer timer.Stop()
+	select {
+	case <-l.done:
+		return l.Err()
+	case <-timer.C:
+		return convertError(status.Error(codes.DeadlineExceeded, "timed out releasing lease"))
+	}
+}
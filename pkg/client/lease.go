@@ -0,0 +1,297 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+)
+
+// releaseTimeout bounds the release of a lease whose context is already done
+const releaseTimeout = 5 * time.Second
+
+// minRenewInterval keeps renewal from spinning on leases about to expire
+const minRenewInterval = 100 * time.Millisecond
+
+// AcquireOption changes how a lease is acquired
+type AcquireOption func(*pb.ConnectionRequest)
+
+// WithDSN connects to dsn instead of the tenant's configured database
+func WithDSN(dsn string) AcquireOption {
+	return func(req *pb.ConnectionRequest) { req.Dsn = dsn }
+}
+
+// ReadOnly routes the lease to a replica within the tenant's lag threshold
+func ReadOnly() AcquireOption {
+	return func(req *pb.ConnectionRequest) { req.ReadOnly = true }
+}
+
+// MinLSN routes the lease to a replica that has replayed lsn, e.g. "16/B374D848",
+// falling back to the primary
+func MinLSN(lsn string) AcquireOption {
+	return func(req *pb.ConnectionRequest) { req.MinLsn = lsn }
+}
+
+// WithCallSite reports where in the caller's code the lease is taken, shown by the
+// service when diagnosing leaked leases. Defaults to the function calling Acquire.
+func WithCallSite(site string) AcquireOption {
+	return func(req *pb.ConnectionRequest) { req.CallSite = site }
+}
+
+// callSite describes the caller skip frames above its own caller, e.g.
+// "main.processOrders (worker.go:42)"
+func callSite(skip int) string {
+	pc, file, line, ok := runtime.Caller(skip + 2)
+	if !ok {
+		return ""
+	}
+	site := fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		site = fmt.Sprintf("%s (%s)", fn.Name(), site)
+	}
+	return site
+}
+
+// Lease is a claim on a tenant's pool. It must be released; WithLease does so for you.
+type Lease struct {
+	ID          string
+	TenantID    string
+	FromReplica bool
+
+	client    *Client
+	mu        sync.Mutex
+	released  bool
+	expiresAt time.Time
+	stop      chan struct{}
+
+	// Set for leases held with Hold
+	hold       pb.ConnectionPoolService_HoldLeaseClient
+	cancelHold context.CancelFunc
+	done       chan struct{}
+	holdErr    error
+}
+
+// Acquire leases a connection from the tenant's pool
+func (c *Client) Acquire(ctx context.Context, tenantID string, opts ...AcquireOption) (*Lease, error) {
+	return c.acquire(ctx, c.connectionRequest(tenantID, callSite(0), opts))
+}
+
+// connectionRequest builds the request for a lease taken at site
+func (c *Client) connectionRequest(tenantID, site string, opts []AcquireOption) *pb.ConnectionRequest {
+	req := &pb.ConnectionRequest{TenantId: tenantID, TtlSeconds: int32(c.opts.LeaseTTL / time.Second), CallSite: site}
+	for _, opt := range opts {
+		opt(req)
+	}
+	return req
+}
+
+// acquire leases a connection, renewing it in the background if it expires
+func (c *Client) acquire(ctx context.Context, req *pb.ConnectionRequest) (*Lease, error) {
+	var resp *pb.ConnectionResponse
+	err := c.retry(ctx, func(ctx context.Context) (err error) {
+		resp, err = c.rpc.GetConnection(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	lease := &Lease{
+		ID:          resp.ConnectionId,
+		TenantID:    req.TenantId,
+		FromReplica: resp.FromReplica,
+		client:      c,
+		stop:        make(chan struct{}),
+	}
+	// Leases without an expiry, e.g. from a service predating TTLs, need no renewal
+	if resp.ExpiresAt != nil {
+		lease.expiresAt = resp.ExpiresAt.AsTime()
+		if !c.opts.DisableRenewal {
+			go lease.renewLoop()
+		}
+	}
+	return lease, nil
+}
+
+// WithLease acquires a lease, runs fn with it and releases it, even when fn fails or
+// panics. fn's error is returned in preference to a failed release.
+func (c *Client) WithLease(ctx context.Context, tenantID string, fn func(*Lease) error, opts ...AcquireOption) (err error) {
+	lease, err := c.acquire(ctx, c.connectionRequest(tenantID, callSite(0), opts))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		releaseErr := lease.Release(ctx)
+		if err == nil {
+			err = releaseErr
+		}
+	}()
+	return fn(lease)
+}
+
+// Release returns the lease to the service. Releasing a lease more than once is a no-op.
+// The release goes ahead, bounded by a short timeout, when ctx is already done.
+func (l *Lease) Release(ctx context.Context) error {
+	l.mu.Lock()
+	if l.released {
+		l.mu.Unlock()
+		return nil
+	}
+	l.released = true
+	close(l.stop)
+	l.mu.Unlock()
+
+	if l.hold != nil {
+		return l.closeHold()
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancel()
+	return l.client.retry(ctx, func(ctx context.Context) error {
+		_, err := l.client.rpc.ReleaseConnection(ctx, &pb.ConnectionRelease{ConnectionId: l.ID, TenantId: l.TenantID})
+		return err
+	})
+}
+
+// ExpiresAt returns when the service reclaims the lease unless it is renewed, or the
+// zero time if it never expires
+func (l *Lease) ExpiresAt() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.expiresAt
+}
+
+// Renew extends the lease by the client's LeaseTTL, or the tenant's TTL if unset.
+// Leases are renewed in the background unless DisableRenewal is set.
+func (l *Lease) Renew(ctx context.Context) (time.Time, error) {
+	req := &pb.RenewLeaseRequest{ConnectionId: l.ID, TenantId: l.TenantID, TtlSeconds: int32(l.client.opts.LeaseTTL / time.Second)}
+	var resp *pb.RenewLeaseResponse
+	err := l.client.retry(ctx, func(ctx context.Context) (err error) {
+		resp, err = l.client.rpc.RenewLease(ctx, req)
+		return err
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.expiresAt = resp.ExpiresAt.AsTime()
+	return l.expiresAt, nil
+}
+
+// renewLoop renews the lease when a third of its remaining time is left until it is
+// released. It gives up once the service no longer knows the lease; other failures
+// are retried until the lease expires.
+func (l *Lease) renewLoop() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-l.stop
+		cancel()
+	}()
+
+	for {
+		wait := max(time.Until(l.ExpiresAt())/3, minRenewInterval)
+		select {
+		case <-l.stop:
+			return
+		case <-time.After(wait):
+		}
+		if _, err := l.Renew(ctx); errors.Is(err, ErrNotFound) || time.Now().After(l.ExpiresAt()) {
+			return
+		}
+	}
+}
+
+// Result is the outcome of a statement
+type Result struct {
+	Columns []string
+	// ColumnTypes holds each column's type OID, to decode its values by
+	ColumnTypes []uint32
+	// Rows hold values in PostgreSQL's text format, e.g. bytea as \x followed by hex
+	Rows       [][]sql.NullString
+	CommandTag string
+}
+
+// Exec runs a statement on the lease. Arguments fill $1, $2, ... and are sent in text
+// format; nil is sent as NULL. []byte and json.RawMessage are passed as they are, in
+// binary to a bytea parameter and as text to any other. Without arguments sql may
+// hold several statements and the result of the last is returned. Exec is not retried
+// since the statement may have run.
+func (l *Lease) Exec(ctx context.Context, sql string, args ...interface{}) (*Result, error) {
+	params := make([]*pb.NullableString, len(args))
+	for i, arg := range args {
+		param, err := encodeParam(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument $%d: %w", i+1, err)
+		}
+		params[i] = param
+	}
+
+	resp, err := l.client.rpc.ExecuteQuery(ctx, &pb.QueryRequest{ConnectionId: l.ID, TenantId: l.TenantID, Sql: sql, Params: params})
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return newResult(resp), nil
+}
+
+func newResult(resp *pb.QueryResponse) *Result {
+	rows := make([][]sql.NullString, len(resp.Rows))
+	for i, row := range resp.Rows {
+		rows[i] = make([]sql.NullString, len(row.Values))
+		for j, value := range row.Values {
+			rows[i][j] = sql.NullString{String: value.Value, Valid: !value.IsNull}
+		}
+	}
+	return &Result{Columns: resp.Columns, ColumnTypes: resp.ColumnTypes, Rows: rows, CommandTag: resp.CommandTag}
+}
+
+// encodeParam converts an argument to PostgreSQL's text format, or raw bytes
+func encodeParam(arg interface{}) (*pb.NullableString, error) {
+	var value string
+	switch v := arg.(type) {
+	case nil:
+		return &pb.NullableString{IsNull: true}, nil
+	case string:
+		value = v
+	case []byte:
+		if v == nil {
+			return &pb.NullableString{IsNull: true}, nil
+		}
+		// The server knows the parameter's type, so it decides how the bytes are sent
+		return &pb.NullableString{BytesValue: v, IsBytes: true}, nil
+	case json.RawMessage:
+		return encodeParam([]byte(v))
+	case bool:
+		value = strconv.FormatBool(v)
+	case int:
+		value = strconv.Itoa(v)
+	case int32:
+		value = strconv.FormatInt(int64(v), 10)
+	case int64:
+		value = strconv.FormatInt(v, 10)
+	case uint32:
+		value = strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		value = strconv.FormatUint(v, 10)
+	case float32:
+		value = strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		value = strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		value = v.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		value = v.String()
+	default:
+		return nil, fmt.Errorf("unsupported type %T", arg)
+	}
+	return &pb.NullableString{Value: value}, nil
+}
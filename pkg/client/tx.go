@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+
+	pb "github.com/teresa-solution/connection-pool-manager/proto"
+)
+
+// TxOptions are the characteristics of a transaction
+type TxOptions struct {
+	// IsoLevel is a PostgreSQL isolation level such as "serializable". The server
+	// default is used when empty.
+	IsoLevel   string
+	ReadOnly   bool
+	Deferrable bool
+}
+
+// Tx is a transaction on a lease. Statements of the transaction run through the lease.
+type Tx struct {
+	lease *Lease
+}
+
+// Begin starts a transaction on the lease. Leases in statement pooling mode cannot
+// run transactions.
+func (l *Lease) Begin(ctx context.Context, opts TxOptions) (*Tx, error) {
+	_, err := l.client.rpc.BeginTransaction(ctx, &pb.BeginRequest{
+		ConnectionId:   l.ID,
+		TenantId:       l.TenantID,
+		IsolationLevel: opts.IsoLevel,
+		ReadOnly:       opts.ReadOnly,
+		Deferrable:     opts.Deferrable,
+	})
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return &Tx{lease: l}, nil
+}
+
+// Exec runs a statement in the transaction
+func (tx *Tx) Exec(ctx context.Context, sql string, args ...interface{}) (*Result, error) {
+	return tx.lease.Exec(ctx, sql, args...)
+}
+
+// Commit commits the transaction. It fails with ErrAborted when the transaction had
+// failed and PostgreSQL rolled it back instead.
+func (tx *Tx) Commit(ctx context.Context) error {
+	_, err := tx.lease.client.rpc.CommitTransaction(ctx, &pb.TransactionRequest{ConnectionId: tx.lease.ID, TenantId: tx.lease.TenantID})
+	return convertError(err)
+}
+
+// Rollback rolls back the transaction
+func (tx *Tx) Rollback(ctx context.Context) error {
+	_, err := tx.lease.client.rpc.RollbackTransaction(ctx, &pb.TransactionRequest{ConnectionId: tx.lease.ID, TenantId: tx.lease.TenantID})
+	return convertError(err)
+}
+
+// WithTx runs fn in a transaction on the lease, committing when fn succeeds and
+// rolling back when it fails or panics
+func (l *Lease) WithTx(ctx context.Context, opts TxOptions, fn func(*Tx) error) (err error) {
+	tx, err := l.Begin(ctx, opts)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback(context.WithoutCancel(ctx))
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	committed = true
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,241 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrTenantDraining is returned when a lease is requested for a tenant that is being
+// drained or evicted
+var ErrTenantDraining = errors.New("tenant is draining")
+
+// evictGrace is how long an evicted lease's owner has to release it before the
+// lease's backend is destroyed
+const evictGrace = 5 * time.Second
+
+// PoolInfo describes one tenant pool
+type PoolInfo struct {
+	TenantID string
+	// Server identifies the pool's database as host:port/database
+	Server   string
+	Stats    Stats
+	MaxConns int32
+	Leases   int
+	Draining bool
+}
+
+// DrainResult is what draining or evicting a tenant did
+type DrainResult struct {
+	EvictedLeases int
+	ClosedPools   int
+}
+
+// ListPools describes the pools of a tenant, or of every tenant when tenantID is empty,
+// ordered by tenant and server
+func (cpm *ConnectionPoolManager) ListPools(tenantID string) []PoolInfo {
+	leases := make(map[string]int)
+	cpm.leaseLock.Lock()
+	for _, lease := range cpm.leases {
+		leases[lease.TenantID+":"+lease.DSN]++
+	}
+	cpm.leaseLock.Unlock()
+
+	cpm.poolLocks.Lock()
+	var infos []PoolInfo
+	for key, pool := range cpm.pools {
+		poolTenant, dsn, _ := strings.Cut(key, ":")
+		if tenantID != "" && poolTenant != tenantID {
+			continue
+		}
+		infos = append(infos, PoolInfo{
+			TenantID: poolTenant,
+			Server:   dsnLabel(dsn),
+			Stats:    poolStats(pool),
+			MaxConns: pool.Config().MaxConns,
+			Leases:   leases[key],
+			Draining: cpm.isDraining(poolTenant),
+		})
+	}
+	cpm.poolLocks.Unlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].TenantID != infos[j].TenantID {
+			return infos[i].TenantID < infos[j].TenantID
+		}
+		return infos[i].Server < infos[j].Server
+	})
+	return infos
+}
+
+// TenantStats sums the stats of all of a tenant's pools, primary and replicas
+func (cpm *ConnectionPoolManager) TenantStats(tenantID string) (Stats, error) {
+	cpm.poolLocks.Lock()
+	defer cpm.poolLocks.Unlock()
+
+	var total Stats
+	found := false
+	for key, pool := range cpm.pools {
+		if poolTenant, _, _ := strings.Cut(key, ":"); poolTenant != tenantID {
+			continue
+		}
+		found = true
+		stats := poolStats(pool)
+		total.ActiveConnections += stats.ActiveConnections
+		total.IdleConnections += stats.IdleConnections
+		total.TotalConnections += stats.TotalConnections
+	}
+	if !found {
+		return Stats{}, fmt.Errorf("%w for tenant %s", ErrPoolNotFound, tenantID)
+	}
+	return total, nil
+}
+
+func poolStats(pool *pgxpool.Pool) Stats {
+	stats := pool.Stat()
+	return Stats{
+		ActiveConnections: stats.AcquiredConns(),
+		IdleConnections:   stats.IdleConns(),
+		TotalConnections:  stats.TotalConns(),
+	}
+}
+
+func (cpm *ConnectionPoolManager) isDraining(tenantID string) bool {
+	cpm.stateLock.RLock()
+	defer cpm.stateLock.RUnlock()
+	return cpm.draining[tenantID]
+}
+
+// setDraining marks a tenant as draining. It fails if the tenant already is.
+func (cpm *ConnectionPoolManager) setDraining(tenantID string, draining bool) error {
+	cpm.stateLock.Lock()
+	defer cpm.stateLock.Unlock()
+	if draining && cpm.draining[tenantID] {
+		return fmt.Errorf("tenant %s is already draining", tenantID)
+	}
+	if draining {
+		cpm.draining[tenantID] = true
+	} else {
+		delete(cpm.draining, tenantID)
+	}
+	return nil
+}
+
+// DrainTenant stops new leases for a tenant, waits up to timeout for its leases to be
+// released and then closes its pools. Leases still held after the timeout are evicted.
+// The tenant accepts leases again once its pools are closed.
+func (cpm *ConnectionPoolManager) DrainTenant(ctx context.Context, tenantID string, timeout time.Duration) (DrainResult, error) {
+	if err := cpm.setDraining(tenantID, true); err != nil {
+		return DrainResult{}, err
+	}
+	defer cpm.setDraining(tenantID, false)
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	cpm.waitForLeases(waitCtx, tenantID)
+	cancel()
+
+	// Evict whatever is left even if the caller has gone away, so the tenant is not
+	// left half drained
+	ctx = context.WithoutCancel(ctx)
+	result := DrainResult{EvictedLeases: cpm.evictLeases(ctx, tenantID)}
+	result.ClosedPools = cpm.closePools(tenantID)
+	ctxLog(ctx).Info().Str("tenant_id", tenantID).Int("evicted_leases", result.EvictedLeases).Int("closed_pools", result.ClosedPools).Msg("Drained tenant")
+	return result, nil
+}
+
+// EvictTenant ends all of a tenant's leases straight away and closes its pools. Proxy
+// clients holding a lease are disconnected.
+func (cpm *ConnectionPoolManager) EvictTenant(ctx context.Context, tenantID string) (DrainResult, error) {
+	if err := cpm.setDraining(tenantID, true); err != nil {
+		return DrainResult{}, err
+	}
+	defer cpm.setDraining(tenantID, false)
+
+	ctx = context.WithoutCancel(ctx)
+	result := DrainResult{EvictedLeases: cpm.evictLeases(ctx, tenantID)}
+	result.ClosedPools = cpm.closePools(tenantID)
+	ctxLog(ctx).Info().Str("tenant_id", tenantID).Int("evicted_leases", result.EvictedLeases).Int("closed_pools", result.ClosedPools).Msg("Evicted tenant")
+	return result, nil
+}
+
+func (cpm *ConnectionPoolManager) tenantLeases(tenantID string) []*Lease {
+	cpm.leaseLock.Lock()
+	defer cpm.leaseLock.Unlock()
+	var leases []*Lease
+	for _, lease := range cpm.leases {
+		if lease.TenantID == tenantID {
+			leases = append(leases, lease)
+		}
+	}
+	return leases
+}
+
+// waitForLeases returns once the tenant holds no leases or ctx is done
+func (cpm *ConnectionPoolManager) waitForLeases(ctx context.Context, tenantID string) {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for len(cpm.tenantLeases(tenantID)) > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// evictLeases ends the tenant's leases and returns how many there were, cancelling
+// statements still running on them. Leases with an owner that handles eviction are
+// left for the owner to release; any the owner has not released within evictGrace
+// have their backend destroyed.
+func (cpm *ConnectionPoolManager) evictLeases(ctx context.Context, tenantID string) int {
+	leases := cpm.tenantLeases(tenantID)
+	owned := false
+	for _, lease := range leases {
+		cpm.publish(Event{Type: EventLeaseEvicted, TenantID: tenantID, Lease: lease.Handle()})
+		if lease.evict() {
+			owned = true
+			continue
+		}
+		cpm.leaseLock.Lock()
+		delete(cpm.leases, lease.ID)
+		cpm.leaseLock.Unlock()
+		lease.reclaim(ctx)
+	}
+	if owned {
+		graceCtx, cancel := context.WithTimeout(ctx, evictGrace)
+		cpm.waitForLeases(graceCtx, tenantID)
+		cancel()
+		for _, lease := range cpm.tenantLeases(tenantID) {
+			cpm.leaseLock.Lock()
+			delete(cpm.leases, lease.ID)
+			cpm.leaseLock.Unlock()
+			// The owner may still be using the backend, so it is not reused
+			lease.releaseExclusive(ctx, BackendBroken)
+		}
+	}
+	return len(leases)
+}
+
+// closePools closes and forgets the tenant's pools and returns how many there were
+func (cpm *ConnectionPoolManager) closePools(tenantID string) int {
+	cpm.poolLocks.Lock()
+	pools := make(map[string]*pgxpool.Pool)
+	for key, pool := range cpm.pools {
+		if poolTenant, dsn, _ := strings.Cut(key, ":"); poolTenant == tenantID {
+			pools[dsn] = pool
+			delete(cpm.pools, key)
+		}
+	}
+	cpm.poolLocks.Unlock()
+
+	for dsn, pool := range pools {
+		pool.Close()
+		cpm.publish(Event{Type: EventPoolClosed, TenantID: tenantID, Server: dsnLabel(dsn)})
+	}
+	return len(pools)
+}
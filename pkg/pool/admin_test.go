@@ -0,0 +1,118 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionPoolManager_ListPoolsAndTenantStats(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{})
+	ctx := context.Background()
+
+	_, err := cpm.TenantStats("acme")
+	assert.ErrorContains(t, err, "pool not found")
+
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseLease(ctx, lease.ID)
+
+	pools := cpm.ListPools("")
+	require.Len(t, pools, 1)
+	assert.Equal(t, "acme", pools[0].TenantID)
+	assert.Equal(t, dsnLabel(db.DSN()), pools[0].Server)
+	assert.Equal(t, int32(20), pools[0].MaxConns)
+	assert.Equal(t, 1, pools[0].Leases)
+	assert.Equal(t, int32(1), pools[0].Stats.ActiveConnections)
+	assert.Empty(t, cpm.ListPools("other"))
+
+	stats, err := cpm.TenantStats("acme")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), stats.ActiveConnections)
+}
+
+func TestConnectionPoolManager_DrainTenant(t *testing.T) {
+	tests := []struct {
+		name        string
+		releaseSoon bool
+		wantEvicted int
+	}{
+		{name: "lease released while draining", releaseSoon: true, wantEvicted: 0},
+		{name: "lease held past the timeout", releaseSoon: false, wantEvicted: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpm, db := setupLeaseTenant(t, TenantConfig{})
+			ctx := context.Background()
+
+			lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+			require.NoError(t, err)
+			if tt.releaseSoon {
+				go func() {
+					time.Sleep(100 * time.Millisecond)
+					cpm.ReleaseLease(ctx, lease.ID)
+				}()
+			}
+
+			done := make(chan DrainResult)
+			go func() {
+				result, err := cpm.DrainTenant(ctx, "acme", 500*time.Millisecond)
+				assert.NoError(t, err)
+				done <- result
+			}()
+
+			// New leases are refused while the tenant drains
+			require.Eventually(t, func() bool { return cpm.isDraining("acme") }, time.Second, 10*time.Millisecond)
+			_, err = cpm.AcquireLease(ctx, "acme", db.DSN())
+			assert.ErrorIs(t, err, ErrTenantDraining)
+
+			result := <-done
+			assert.Equal(t, tt.wantEvicted, result.EvictedLeases)
+			assert.Equal(t, 1, result.ClosedPools)
+			assert.Empty(t, cpm.ListPools("acme"))
+			_, ok := cpm.Lease(lease.ID)
+			assert.False(t, ok)
+
+			// The tenant is served again once drained
+			lease, err = cpm.AcquireLease(ctx, "acme", db.DSN())
+			require.NoError(t, err)
+			cpm.ReleaseLease(ctx, lease.ID)
+		})
+	}
+}
+
+func TestConnectionPoolManager_EvictTenant(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{})
+	ctx := context.Background()
+
+	plain, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+
+	// An owned lease is left for its owner to release
+	owned, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	evicted := make(chan struct{})
+	owned.OnEvict(func() {
+		close(evicted)
+		go cpm.ReleaseLease(ctx, owned.ID)
+	})
+
+	result, err := cpm.EvictTenant(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, DrainResult{EvictedLeases: 2, ClosedPools: 1}, result)
+
+	select {
+	case <-evicted:
+	default:
+		t.Fatal("eviction handler was not called")
+	}
+	assert.False(t, plain.Attached())
+	assert.False(t, owned.Attached())
+	_, ok := cpm.Lease(plain.ID)
+	assert.False(t, ok)
+	assert.Empty(t, cpm.ListPools(""))
+}
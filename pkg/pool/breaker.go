@@ -0,0 +1,255 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrCircuitOpen is returned while a tenant's breaker for a database host is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerOpenError is returned for calls rejected by an open breaker
+type BreakerOpenError struct {
+	TenantID string
+	Host     string
+	// RetryAfter is how long until the breaker lets a call through again
+	RetryAfter time.Duration
+}
+
+func (e *BreakerOpenError) Error() string {
+	return fmt.Sprintf("%s for tenant %s on %s, retry in %s", ErrCircuitOpen, e.TenantID, e.Host, e.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *BreakerOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// BreakerState is the state of a circuit breaker
+type BreakerState string
+
+const (
+	// BreakerClosed lets calls through and counts their failures
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen rejects calls until the cooldown is over
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a few probe calls through to decide whether to close again
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// Breaker policy defaults
+const (
+	defaultBreakerFailureRate    = 0.5
+	defaultBreakerMinRequests    = 5
+	defaultBreakerWindow         = time.Minute
+	defaultBreakerCooldown       = 30 * time.Second
+	defaultBreakerHalfOpenProbes = 1
+)
+
+// BreakerStatus is the state of a tenant's breaker for one database host
+type BreakerStatus struct {
+	Host  string
+	State BreakerState
+	// Requests and Failures are counted in the current window
+	Requests int
+	Failures int
+	OpenedAt time.Time
+	// RetryAt is when an open breaker lets a probe through
+	RetryAt time.Time
+}
+
+// breaker tracks calls to one database host for one tenant
+type breaker struct {
+	state       BreakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	// probes counts calls let through while half open that have not reported back
+	probes int
+}
+
+// allow reports whether a call may go ahead and, if not, how long until one may
+func (b *breaker) allow(policy BreakerPolicy, now time.Time) (time.Duration, bool) {
+	switch b.state {
+	case BreakerOpen:
+		if wait := b.openedAt.Add(policy.Cooldown.Duration).Sub(now); wait > 0 {
+			return wait, false
+		}
+		b.state, b.probes = BreakerHalfOpen, 0
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probes >= policy.HalfOpenProbes {
+			// Check back about when the probes should have finished
+			return policy.Cooldown.Duration / 10, false
+		}
+		b.probes++
+	}
+	return 0, true
+}
+
+// record counts the outcome of a call allowed through. Calls that did not get far
+// enough to say anything about the host are neither.
+func (b *breaker) record(policy BreakerPolicy, now time.Time, outcome breakerOutcome) {
+	switch b.state {
+	case BreakerHalfOpen:
+		b.probes--
+		switch outcome {
+		case outcomeFailure:
+			b.state, b.openedAt = BreakerOpen, now
+		case outcomeSuccess:
+			b.state = BreakerClosed
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+	case BreakerClosed:
+		if outcome == outcomeIgnored {
+			return
+		}
+		if now.Sub(b.windowStart) > policy.Window.Duration {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		b.requests++
+		if outcome == outcomeFailure {
+			b.failures++
+		}
+		if b.requests >= policy.MinRequests && float64(b.failures) >= policy.FailureRate*float64(b.requests) {
+			b.state, b.openedAt = BreakerOpen, now
+		}
+	}
+}
+
+type breakerOutcome int
+
+const (
+	outcomeIgnored breakerOutcome = iota
+	outcomeSuccess
+	outcomeFailure
+)
+
+// classifyOutcome decides what a call's error says about the database host. Bad DSNs
+// and callers giving up say nothing.
+func classifyOutcome(err error) breakerOutcome {
+	var parseErr *pgconn.ParseConfigError
+	switch {
+	case err == nil:
+		return outcomeSuccess
+	case errors.As(err, &parseErr), errors.Is(err, context.Canceled), errors.Is(err, ErrCreationQueued):
+		return outcomeIgnored
+	}
+	return outcomeFailure
+}
+
+// classifyConnect decides what getting a backend for a lease says about the database
+// host. Only failing to connect counts against it; a busy pool or a failing session
+// setup does not.
+func classifyConnect(err error) breakerOutcome {
+	var connectErr *pgconn.ConnectError
+	switch {
+	case err == nil:
+		return outcomeSuccess
+	case errors.As(err, &connectErr):
+		return outcomeFailure
+	}
+	return outcomeIgnored
+}
+
+// breakers holds the breakers of every tenant and host
+type breakers struct {
+	mu sync.Mutex
+	// byTenant maps tenants to their breakers by host
+	byTenant map[string]map[string]*breaker
+}
+
+// dsnHost returns the host and port a DSN connects to
+func dsnHost(dsn string) (string, bool) {
+	config, err := pgconn.ParseConfig(dsn)
+	if err != nil {
+		return "", false
+	}
+	return config.Host + ":" + strconv.Itoa(int(config.Port)), true
+}
+
+// allowCall checks the tenant's breaker for the DSN's host. When the call may go ahead,
+// the returned function must be called with its outcome.
+func (cpm *ConnectionPoolManager) allowCall(ctx context.Context, tenantID, dsn string) (func(breakerOutcome), error) {
+	cfg, _ := cpm.TenantConfig(tenantID)
+	policy := cfg.Breaker.withDefaults()
+	host, ok := dsnHost(dsn)
+	if policy.Disabled || !ok {
+		return func(breakerOutcome) {}, nil
+	}
+
+	cpm.breakers.mu.Lock()
+	hosts, exists := cpm.breakers.byTenant[tenantID]
+	if !exists {
+		hosts = make(map[string]*breaker)
+		cpm.breakers.byTenant[tenantID] = hosts
+	}
+	b, exists := hosts[host]
+	if !exists {
+		b = &breaker{state: BreakerClosed, windowStart: time.Now()}
+		hosts[host] = b
+	}
+	wait, allowed := b.allow(policy, time.Now())
+	state := b.state
+	cpm.breakers.mu.Unlock()
+	breakerStates.WithLabelValues(tenantID, host).Set(breakerGauge(state))
+
+	if !allowed {
+		breakerRejections.WithLabelValues(tenantID, host).Inc()
+		return nil, &BreakerOpenError{TenantID: tenantID, Host: host, RetryAfter: wait}
+	}
+	return func(outcome breakerOutcome) {
+		cpm.breakers.mu.Lock()
+		before := b.state
+		b.record(policy, time.Now(), outcome)
+		after := b.state
+		cpm.breakers.mu.Unlock()
+		breakerStates.WithLabelValues(tenantID, host).Set(breakerGauge(after))
+
+		switch {
+		case after == BreakerOpen && before != BreakerOpen:
+			ctxLog(ctx).Warn().Str("tenant_id", tenantID).Str("host", host).
+				Dur("cooldown", policy.Cooldown.Duration).Msg("Circuit breaker opened")
+		case after == BreakerClosed && before == BreakerHalfOpen:
+			ctxLog(ctx).Info().Str("tenant_id", tenantID).Str("host", host).Msg("Circuit breaker closed")
+		}
+	}, nil
+}
+
+// breakerGauge is the pool_circuit_breaker_state value of a state
+func breakerGauge(state BreakerState) float64 {
+	switch state {
+	case BreakerOpen:
+		return 2
+	case BreakerHalfOpen:
+		return 1
+	}
+	return 0
+}
+
+// Breakers returns the state of the tenant's breakers, ordered by host
+func (cpm *ConnectionPoolManager) Breakers(tenantID string) []BreakerStatus {
+	cfg, _ := cpm.TenantConfig(tenantID)
+	policy := cfg.Breaker.withDefaults()
+
+	cpm.breakers.mu.Lock()
+	defer cpm.breakers.mu.Unlock()
+	statuses := make([]BreakerStatus, 0, len(cpm.breakers.byTenant[tenantID]))
+	for host, b := range cpm.breakers.byTenant[tenantID] {
+		status := BreakerStatus{Host: host, State: b.state, Requests: b.requests, Failures: b.failures}
+		if b.state != BreakerClosed {
+			status.OpenedAt = b.openedAt
+			status.RetryAt = b.openedAt.Add(policy.Cooldown.Duration)
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Host < statuses[j].Host })
+	return statuses
+}
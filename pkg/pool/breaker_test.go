@@ -0,0 +1,142 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreaker(t *testing.T) {
+	policy := BreakerPolicy{FailureRate: 0.5, MinRequests: 4, Cooldown: Duration{Duration: time.Minute}}.withDefaults()
+	now := time.Now()
+	b := &breaker{state: BreakerClosed, windowStart: now}
+	call := func(outcome breakerOutcome) {
+		_, ok := b.allow(policy, now)
+		require.True(t, ok)
+		b.record(policy, now, outcome)
+	}
+
+	// Failures below the rate, or too few calls, keep it closed
+	call(outcomeFailure)
+	call(outcomeSuccess)
+	call(outcomeIgnored)
+	call(outcomeSuccess)
+	assert.Equal(t, BreakerClosed, b.state)
+	call(outcomeFailure)
+	assert.Equal(t, BreakerOpen, b.state)
+
+	wait, ok := b.allow(policy, now.Add(10*time.Second))
+	assert.False(t, ok)
+	assert.Equal(t, 50*time.Second, wait)
+
+	// After the cooldown a single probe goes through, and failing reopens it
+	now = now.Add(time.Minute)
+	_, ok = b.allow(policy, now)
+	assert.True(t, ok)
+	assert.Equal(t, BreakerHalfOpen, b.state)
+	_, ok = b.allow(policy, now)
+	assert.False(t, ok)
+	b.record(policy, now, outcomeFailure)
+	assert.Equal(t, BreakerOpen, b.state)
+
+	// A probe that says nothing lets the next one through; one that succeeds closes it
+	now = now.Add(time.Minute)
+	call(outcomeIgnored)
+	assert.Equal(t, BreakerHalfOpen, b.state)
+	call(outcomeSuccess)
+	assert.Equal(t, BreakerClosed, b.state)
+	assert.Zero(t, b.requests)
+
+	// Failures in an earlier window are forgotten
+	for range 3 {
+		call(outcomeFailure)
+	}
+	now = now.Add(2 * time.Minute)
+	call(outcomeFailure)
+	assert.Equal(t, BreakerClosed, b.state)
+	assert.Equal(t, 1, b.requests)
+}
+
+func TestConnectionPoolManager_BreakerFailsFast(t *testing.T) {
+	// Nothing listens on the port once the listener is closed
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	dsn := fmt.Sprintf("postgres://app@%s/acme?sslmode=disable", listener.Addr())
+	listener.Close()
+
+	cpm := NewConnectionPoolManager()
+	cpm.SetTenantConfig(TenantConfig{TenantID: "acme", DSN: dsn, Breaker: BreakerPolicy{MinRequests: 2, Cooldown: Duration{Duration: time.Minute}}})
+	ctx := context.Background()
+	defer cpm.ReleaseConnection(ctx, "acme", dsn)
+	host, _ := dsnHost(dsn)
+	rejections := testutil.ToFloat64(breakerRejections.WithLabelValues("acme", host))
+
+	for range 2 {
+		_, err := cpm.AcquireLease(ctx, "acme", dsn)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrCircuitOpen)
+	}
+
+	_, err = cpm.AcquireLease(ctx, "acme", dsn)
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	var openErr *BreakerOpenError
+	require.True(t, errors.As(err, &openErr))
+	assert.Equal(t, host, openErr.Host)
+	assert.InDelta(t, time.Minute, openErr.RetryAfter, float64(time.Second))
+	assert.Equal(t, rejections+1, testutil.ToFloat64(breakerRejections.WithLabelValues("acme", host)))
+	assert.Equal(t, 2.0, testutil.ToFloat64(breakerStates.WithLabelValues("acme", host)))
+
+	breakers := cpm.Breakers("acme")
+	require.Len(t, breakers, 1)
+	assert.Equal(t, BreakerOpen, breakers[0].State)
+	assert.Equal(t, 2, breakers[0].Failures)
+	assert.WithinDuration(t, breakers[0].OpenedAt.Add(time.Minute), breakers[0].RetryAt, 0)
+
+	// Other tenants on the same host have breakers of their own
+	cpm.SetTenantConfig(TenantConfig{TenantID: "globex", DSN: dsn, Breaker: BreakerPolicy{Disabled: true}})
+	defer cpm.ReleaseConnection(ctx, "globex", dsn)
+	_, err = cpm.AcquireLease(ctx, "globex", dsn)
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+	assert.Empty(t, cpm.Breakers("globex"))
+}
+
+func TestConnectionPoolManager_BreakerCountsLeaseAttach(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	dsn := fmt.Sprintf("postgres://app@%s/acme?sslmode=disable", listener.Addr())
+	listener.Close()
+
+	for _, mode := range []PoolMode{PoolModeTransaction, PoolModeStatement} {
+		t.Run(string(mode), func(t *testing.T) {
+			cpm := NewConnectionPoolManager()
+			cpm.SetTenantConfig(TenantConfig{TenantID: "acme", DSN: dsn, PoolMode: mode, Breaker: BreakerPolicy{MinRequests: 2, Cooldown: Duration{Duration: time.Minute}}})
+			ctx := context.Background()
+			defer cpm.ReleaseConnection(ctx, "acme", dsn)
+
+			// Creating the pool does not connect, so the lease is granted
+			lease, err := cpm.AcquireLease(ctx, "acme", dsn)
+			require.NoError(t, err)
+			defer cpm.ReleaseLease(ctx, lease.ID)
+
+			for range 2 {
+				_, err := lease.Exec(ctx, "SELECT 1", nil)
+				require.Error(t, err)
+				assert.NotErrorIs(t, err, ErrCircuitOpen)
+			}
+			_, err = lease.Exec(ctx, "SELECT 1", nil)
+			require.ErrorIs(t, err, ErrCircuitOpen)
+
+			breakers := cpm.Breakers("acme")
+			require.Len(t, breakers, 1)
+			assert.Equal(t, BreakerOpen, breakers[0].State)
+			assert.Equal(t, 2, breakers[0].Failures)
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package pool
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Duration wraps time.Duration so tenant configs can be written as "30s" in JSON
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch value := v.(type) {
+	case float64:
+		d.Duration = time.Duration(value)
+		return nil
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		d.Duration = parsed
+		return nil
+	default:
+		return fmt.Errorf("invalid duration %s", string(b))
+	}
+}
+
+// TenantConfig holds the per-tenant settings applied to a tenant's pools
+type TenantConfig struct {
+	TenantID string `json:"tenant_id"`
+	DSN      string `json:"dsn"`
+
+	// ReplicaDSNs lists read replicas of the primary DSN
+	ReplicaDSNs []string `json:"replica_dsns,omitempty"`
+	// MaxReplicationLag leaves out replicas whose replay lag is above it. Zero disables the check.
+	MaxReplicationLag Duration `json:"max_replication_lag,omitempty"`
+	// MaxConns is the size of each of the tenant's pools. Defaults to 20. Pools already
+	// open keep their size until they are closed and opened again.
+	MaxConns int32 `json:"max_conns,omitempty"`
+
+	// ProxyPassword authenticates wire-protocol proxy clients. Proxy access is disabled when empty.
+	ProxyPassword string `json:"proxy_password,omitempty"`
+
+	// PoolMode controls how long proxy and lease clients hold a backend. Defaults to session.
+	PoolMode PoolMode `json:"pool_mode,omitempty"`
+	// Reset is run on a backend each time a client returns it
+	Reset ResetPolicy `json:"reset,omitempty"`
+	// Session is applied to every connection the tenant's pools open
+	Session SessionInit `json:"session,omitempty"`
+	// LeaseTTL is how long a lease lives without being renewed. Defaults to the
+	// manager's default lease TTL.
+	LeaseTTL Duration `json:"lease_ttl,omitempty"`
+	// Watchdog limits how long leased backends stay busy
+	Watchdog WatchdogPolicy `json:"watchdog,omitempty"`
+	// Breaker stops calls to a database host that keeps failing
+	Breaker BreakerPolicy `json:"breaker,omitempty"`
+	// Creation controls how the tenant's pools are opened
+	Creation CreationPolicy `json:"creation,omitempty"`
+	// Prewarm opens the tenant's pool at startup
+	Prewarm bool `json:"prewarm,omitempty"`
+	// Critical prewarms the tenant and holds back readiness until its pool is up
+	Critical bool `json:"critical,omitempty"`
+}
+
+// ResetPolicy describes how a returned backend is cleaned up before it is reused.
+// An open transaction is always rolled back first.
+type ResetPolicy struct {
+	// Query clears session state. Defaults to DISCARD ALL.
+	Query string `json:"query,omitempty"`
+	// CheckHealth verifies the backend with pg_backend_pid() after the reset
+	CheckHealth bool `json:"check_health,omitempty"`
+	// Timeout bounds the whole reset. Defaults to 5s.
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+// WatchdogPolicy limits how long a lease keeps its backend busy. A backend over a limit
+// has its statement cancelled, and is terminated if it is still over TerminateAfter
+// later.
+type WatchdogPolicy struct {
+	// MaxStatementDuration limits a single statement. Zero disables the limit.
+	MaxStatementDuration Duration `json:"max_statement_duration,omitempty"`
+	// MaxBusyDuration limits how long a backend runs statements or holds a transaction
+	// open without a break. Zero disables the limit.
+	MaxBusyDuration Duration `json:"max_busy_duration,omitempty"`
+	// TerminateAfter is the grace period after a cancel. Defaults to 5s.
+	TerminateAfter Duration `json:"terminate_after,omitempty"`
+}
+
+// BreakerPolicy configures the circuit breaker kept for each of a tenant's database
+// hosts. Pool creation and backend connects count as calls. Once enough of them fail
+// within the window the breaker opens and calls fail straight away until the cooldown
+// is over; then probe calls decide whether it closes again.
+type BreakerPolicy struct {
+	// Disabled turns the breaker off
+	Disabled bool `json:"disabled,omitempty"`
+	// FailureRate is the share of failed calls that opens the breaker. Defaults to 0.5.
+	FailureRate float64 `json:"failure_rate,omitempty"`
+	// MinRequests is how many calls the window needs before the breaker may open. Defaults to 5.
+	MinRequests int `json:"min_requests,omitempty"`
+	// Window is how long calls are counted for. Defaults to 1m.
+	Window Duration `json:"window,omitempty"`
+	// Cooldown is how long the breaker stays open. Defaults to 30s.
+	Cooldown Duration `json:"cooldown,omitempty"`
+	// HalfOpenProbes is how many calls are let through at once after the cooldown. Defaults to 1.
+	HalfOpenProbes int `json:"half_open_probes,omitempty"`
+}
+
+// Validate checks the policy's failure rate
+func (p BreakerPolicy) Validate() error {
+	if p.FailureRate < 0 || p.FailureRate > 1 {
+		return fmt.Errorf("breaker failure_rate must be between 0 and 1")
+	}
+	return nil
+}
+
+func (p BreakerPolicy) withDefaults() BreakerPolicy {
+	if p.FailureRate <= 0 {
+		p.FailureRate = defaultBreakerFailureRate
+	}
+	if p.MinRequests <= 0 {
+		p.MinRequests = defaultBreakerMinRequests
+	}
+	if p.Window.Duration <= 0 {
+		p.Window.Duration = defaultBreakerWindow
+	}
+	if p.Cooldown.Duration <= 0 {
+		p.Cooldown.Duration = defaultBreakerCooldown
+	}
+	if p.HalfOpenProbes <= 0 {
+		p.HalfOpenProbes = defaultBreakerHalfOpenProbes
+	}
+	return p
+}
+
+// CreationPolicy controls how a pool is opened. Attempts failing for a transient
+// reason (DNS, TCP or a timeout) are retried with jittered exponential backoff, and a
+// failed creation is returned to callers without trying again for FailureCacheTTL.
+type CreationPolicy struct {
+	// Verify pings the database before the pool is handed out. Without it a pool
+	// connects lazily, so an unreachable database only fails the first query.
+	Verify bool `json:"verify,omitempty"`
+	// VerifyTimeout bounds each ping. Defaults to 5s.
+	VerifyTimeout Duration `json:"verify_timeout,omitempty"`
+	// Retries is how often a transient failure is retried. Defaults to 2; negative disables retries.
+	Retries int `json:"retries,omitempty"`
+	// Backoff is the wait before the first retry, doubled for each one after. Defaults to 200ms.
+	Backoff Duration `json:"backoff,omitempty"`
+	// MaxBackoff caps the wait between retries. Defaults to 2s.
+	MaxBackoff Duration `json:"max_backoff,omitempty"`
+	// FailureCacheTTL is how long a failed creation is remembered. Defaults to 5s;
+	// negative disables the cache.
+	FailureCacheTTL Duration `json:"failure_cache_ttl,omitempty"`
+}
+
+func (p CreationPolicy) withDefaults() CreationPolicy {
+	if p.VerifyTimeout.Duration <= 0 {
+		p.VerifyTimeout.Duration = defaultVerifyTimeout
+	}
+	if p.Retries == 0 {
+		p.Retries = defaultCreationRetries
+	}
+	if p.Backoff.Duration <= 0 {
+		p.Backoff.Duration = defaultCreationBackoff
+	}
+	if p.MaxBackoff.Duration <= 0 {
+		p.MaxBackoff.Duration = defaultCreationMaxBackoff
+	}
+	if p.FailureCacheTTL.Duration == 0 {
+		p.FailureCacheTTL.Duration = defaultFailureCacheTTL
+	}
+	return p
+}
+
+// LoadTenantConfigs reads a JSON array of tenant configs from path
+func LoadTenantConfigs(path string) ([]TenantConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []TenantConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse tenant config %s: %w", path, err)
+	}
+	for i, cfg := range configs {
+		if cfg.TenantID == "" {
+			return nil, fmt.Errorf("tenant config %d has no tenant_id", i)
+		}
+		if cfg.MaxConns < 0 {
+			return nil, fmt.Errorf("tenant %s: max_conns must not be negative", cfg.TenantID)
+		}
+		if _, err := ParsePoolMode(string(cfg.PoolMode)); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", cfg.TenantID, err)
+		}
+		if err := cfg.Session.Validate(); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", cfg.TenantID, err)
+		}
+		if err := cfg.Breaker.Validate(); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", cfg.TenantID, err)
+		}
+	}
+	return configs, nil
+}
@@ -0,0 +1,80 @@
+package pool
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuration_JSON(t *testing.T) {
+	var d Duration
+	require.NoError(t, json.Unmarshal([]byte(`"1m30s"`), &d))
+	assert.Equal(t, 90*time.Second, d.Duration)
+
+	data, err := json.Marshal(d)
+	require.NoError(t, err)
+	assert.Equal(t, `"1m30s"`, string(data))
+
+	assert.Error(t, json.Unmarshal([]byte(`"soon"`), &d))
+	assert.Error(t, json.Unmarshal([]byte(`true`), &d))
+}
+
+func TestLoadTenantConfigs(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "tenants.json")
+	require.NoError(t, os.WriteFile(valid, []byte(`[
+		{"tenant_id": "acme", "dsn": "postgres://primary/acme",
+		 "replica_dsns": ["postgres://replica/acme"], "max_replication_lag": "5s", "max_conns": 50,
+		 "creation": {"verify": true, "retries": -1, "failure_cache_ttl": "10s"}}
+	]`), 0644))
+
+	configs, err := LoadTenantConfigs(valid)
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, "acme", configs[0].TenantID)
+	assert.Equal(t, []string{"postgres://replica/acme"}, configs[0].ReplicaDSNs)
+	assert.Equal(t, 5*time.Second, configs[0].MaxReplicationLag.Duration)
+	assert.Equal(t, int32(50), configs[0].MaxConns)
+	creation := configs[0].Creation.withDefaults()
+	assert.True(t, creation.Verify)
+	assert.Equal(t, -1, creation.Retries)
+	assert.Equal(t, 10*time.Second, creation.FailureCacheTTL.Duration)
+	assert.Equal(t, defaultVerifyTimeout, creation.VerifyTimeout.Duration)
+
+	missingID := filepath.Join(dir, "missing.json")
+	require.NoError(t, os.WriteFile(missingID, []byte(`[{"dsn": "postgres://primary/acme"}]`), 0644))
+	_, err = LoadTenantConfigs(missingID)
+	assert.Error(t, err)
+
+	badBreaker := filepath.Join(dir, "breaker.json")
+	require.NoError(t, os.WriteFile(badBreaker, []byte(`[{"tenant_id": "acme", "breaker": {"failure_rate": 50}}]`), 0644))
+	_, err = LoadTenantConfigs(badBreaker)
+	assert.ErrorContains(t, err, "failure_rate")
+
+	badSize := filepath.Join(dir, "size.json")
+	require.NoError(t, os.WriteFile(badSize, []byte(`[{"tenant_id": "acme", "max_conns": -1}]`), 0644))
+	_, err = LoadTenantConfigs(badSize)
+	assert.ErrorContains(t, err, "max_conns")
+
+	_, err = LoadTenantConfigs(filepath.Join(dir, "does-not-exist.json"))
+	assert.Error(t, err)
+}
+
+func TestConnectionPoolManager_TenantConfig(t *testing.T) {
+	cpm := NewConnectionPoolManager()
+
+	_, ok := cpm.TenantConfig("acme")
+	assert.False(t, ok)
+
+	cpm.SetTenantConfig(TenantConfig{TenantID: "acme", DSN: "postgres://primary/acme"})
+	cfg, ok := cpm.TenantConfig("acme")
+	assert.True(t, ok)
+	assert.Equal(t, "postgres://primary/acme", cfg.DSN)
+	assert.Len(t, cpm.TenantConfigs(), 1)
+}
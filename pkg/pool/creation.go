@@ -0,0 +1,207 @@
+package pool
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Creation policy defaults
+const (
+	defaultVerifyTimeout      = 5 * time.Second
+	defaultCreationRetries    = 2
+	defaultCreationBackoff    = 200 * time.Millisecond
+	defaultCreationMaxBackoff = 2 * time.Second
+	defaultFailureCacheTTL    = 5 * time.Second
+)
+
+// CreationReason says why a pool could not be created
+type CreationReason string
+
+const (
+	// ReasonInvalidDSN means the connection string could not be parsed
+	ReasonInvalidDSN CreationReason = "invalid_dsn"
+	// ReasonDNS means the database host name could not be resolved
+	ReasonDNS CreationReason = "dns"
+	// ReasonTCP means the database host could not be reached or refused the connection
+	ReasonTCP CreationReason = "tcp"
+	// ReasonTLS means the TLS handshake failed or the server does not offer TLS
+	ReasonTLS CreationReason = "tls"
+	// ReasonAuth means the server rejected the credentials
+	ReasonAuth CreationReason = "auth"
+	// ReasonDatabaseNotFound means the database does not exist
+	ReasonDatabaseNotFound CreationReason = "database_not_found"
+	// ReasonTimeout means connecting took longer than the verify timeout
+	ReasonTimeout CreationReason = "timeout"
+	// ReasonUnknown is any other failure
+	ReasonUnknown CreationReason = "unknown"
+)
+
+// Transient reports whether an attempt failing for the reason may succeed if tried again
+func (r CreationReason) Transient() bool {
+	return r == ReasonDNS || r == ReasonTCP || r == ReasonTimeout
+}
+
+// CreationError is returned when a pool could not be created
+type CreationError struct {
+	TenantID string
+	// Server is the host, port and database of the pool
+	Server   string
+	Reason   CreationReason
+	Attempts int
+	Err      error
+}
+
+func (e *CreationError) Error() string {
+	return fmt.Sprintf("failed to create pool for tenant %s on %s (%s after %d attempts): %v", e.TenantID, e.Server, e.Reason, e.Attempts, e.Err)
+}
+
+func (e *CreationError) Unwrap() error {
+	return e.Err
+}
+
+// classifyCreation finds the reason an attempt to open or verify a pool failed. pgconn
+// wraps what went wrong while connecting with where it happened, and does not export
+// a type for a server refusing TLS, so some cases are told apart by message.
+func classifyCreation(err error) CreationReason {
+	var (
+		parseErr *pgconn.ParseConfigError
+		pgErr    *pgconn.PgError
+		dnsErr   *net.DNSError
+		certErr  *tls.CertificateVerificationError
+		authErr  x509.UnknownAuthorityError
+		hostErr  x509.HostnameError
+		opErr    *net.OpError
+	)
+	switch {
+	case errors.As(err, &parseErr):
+		return ReasonInvalidDSN
+	case errors.As(err, &pgErr):
+		switch pgErr.Code {
+		case "28P01", "28000":
+			return ReasonAuth
+		case "3D000":
+			return ReasonDatabaseNotFound
+		}
+		return ReasonUnknown
+	case errors.As(err, &dnsErr):
+		return ReasonDNS
+	case errors.Is(err, context.DeadlineExceeded), pgconn.Timeout(err):
+		return ReasonTimeout
+	case errors.As(err, &certErr), errors.As(err, &authErr), errors.As(err, &hostErr),
+		strings.Contains(err.Error(), "tls error"), strings.Contains(err.Error(), "server refused TLS"):
+		return ReasonTLS
+	case errors.As(err, &opErr), strings.Contains(err.Error(), "dial error"):
+		return ReasonTCP
+	}
+	return ReasonUnknown
+}
+
+// cachedFailure is a failed creation returned to callers until it expires
+type cachedFailure struct {
+	err     error
+	expires time.Time
+}
+
+// creation is a pool being created, which callers asking for the same pool wait for
+type creation struct {
+	done chan struct{}
+	pool *pgxpool.Pool
+	err  error
+}
+
+// openPool creates a pool under the tenant's creation policy, retrying transient
+// failures until the retries or ctx run out
+func (cpm *ConnectionPoolManager) openPool(ctx context.Context, tenantID, dsn string) (*pgxpool.Pool, error) {
+	cfg, _ := cpm.TenantConfig(tenantID)
+	policy := cfg.Creation.withDefaults()
+
+	backoff := policy.Backoff.Duration
+	for attempt := 1; ; attempt++ {
+		pool, err := cpm.tryCreate(ctx, tenantID, dsn, policy)
+		if err == nil {
+			return pool, nil
+		}
+		if errors.Is(err, ErrCreationQueued) {
+			return nil, err
+		}
+
+		reason := classifyCreation(err)
+		poolCreationFailures.WithLabelValues(tenantID, string(reason)).Inc()
+		creationErr := &CreationError{TenantID: tenantID, Server: dsnLabel(dsn), Reason: reason, Attempts: attempt, Err: err}
+		if !reason.Transient() || attempt > policy.Retries || ctx.Err() != nil {
+			return nil, creationErr
+		}
+
+		// Jitter spreads out the retries of callers that failed together
+		wait := backoff/2 + rand.N(backoff/2+1)
+		ctxLog(ctx).Debug().Err(err).Str("tenant_id", tenantID).Str("reason", string(reason)).
+			Int("attempt", attempt).Dur("backoff", wait).Msg("Pool creation failed, retrying")
+		select {
+		case <-ctx.Done():
+			return nil, creationErr
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, policy.MaxBackoff.Duration)
+	}
+}
+
+// tryCreate makes one attempt at opening a pool and, when the policy says so, pinging it.
+// The attempt takes a creation slot, held until the pool's first connections are open.
+func (cpm *ConnectionPoolManager) tryCreate(ctx context.Context, tenantID, dsn string, policy CreationPolicy) (*pgxpool.Pool, error) {
+	slot, err := cpm.scheduler.acquire(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer slot.release()
+
+	if !policy.Verify {
+		pool, err := cpm.createPool(ctx, tenantID, dsn, &slot)
+		if err == nil {
+			slot.wait(ctx)
+		}
+		return pool, err
+	}
+
+	// The pool opens its first connections in the background with this context, so a
+	// failed ping cancels them rather than waiting for them to time out on close. The
+	// pool's health check tops it up to its minimum later.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pool, err := cpm.createPool(ctx, tenantID, dsn, &slot)
+	if err != nil {
+		return nil, err
+	}
+	verifyCtx, cancelVerify := context.WithTimeout(ctx, policy.VerifyTimeout.Duration)
+	defer cancelVerify()
+	if err := pool.Ping(verifyCtx); err != nil {
+		cancel()
+		pool.Close()
+		return nil, err
+	}
+	slot.wait(ctx)
+	return pool, nil
+}
+
+// cacheFailure remembers a failed creation under the tenant's policy. Bad DSNs fail
+// without reaching the database and callers giving up say nothing about it, so
+// neither is cached. The caller holds poolLocks.
+func (cpm *ConnectionPoolManager) cacheFailure(ctx context.Context, key, tenantID string, err error) {
+	var creationErr *CreationError
+	if !errors.As(err, &creationErr) || creationErr.Reason == ReasonInvalidDSN || ctx.Err() != nil {
+		return
+	}
+	cfg, _ := cpm.TenantConfig(tenantID)
+	if ttl := cfg.Creation.withDefaults().FailureCacheTTL.Duration; ttl > 0 {
+		cpm.failedCreations[key] = cachedFailure{err: err, expires: time.Now().Add(ttl)}
+	}
+}
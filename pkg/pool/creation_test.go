@@ -0,0 +1,190 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/pgtest"
+)
+
+// closedPortDSN returns a DSN for a local port nothing listens on
+func closedPortDSN(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener.Close()
+	return fmt.Sprintf("postgres://app@%s/acme?sslmode=disable", listener.Addr())
+}
+
+// silentDSN returns a DSN for a local port that accepts connections but never answers
+func silentDSN(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+	return fmt.Sprintf("postgres://app@%s/acme?sslmode=disable", listener.Addr())
+}
+
+func TestConnectionPoolManager_CreationReasons(t *testing.T) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+	defer db.Close()
+
+	tests := []struct {
+		name       string
+		dsn        string
+		startupErr string
+		want       CreationReason
+	}{
+		{name: "invalid dsn", dsn: "postgres://host:notaport/db", want: ReasonInvalidDSN},
+		{name: "dns", dsn: "postgres://app@nonexistent.invalid/acme?sslmode=disable&connect_timeout=2", want: ReasonDNS},
+		{name: "tcp", dsn: closedPortDSN(t), want: ReasonTCP},
+		{name: "tls", dsn: db.DSN() + " sslmode=require", want: ReasonTLS},
+		{name: "auth", dsn: db.DSN(), startupErr: "28P01", want: ReasonAuth},
+		{name: "database not found", dsn: db.DSN(), startupErr: "3D000", want: ReasonDatabaseNotFound},
+		{name: "timeout", dsn: silentDSN(t), want: ReasonTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db.SetStartupError(tt.startupErr, "rejected")
+			defer db.SetStartupError("", "")
+
+			cpm := NewConnectionPoolManager()
+			cpm.SetTenantConfig(TenantConfig{TenantID: "acme", DSN: tt.dsn, Creation: CreationPolicy{
+				Verify:        true,
+				VerifyTimeout: Duration{Duration: 100 * time.Millisecond},
+				Retries:       -1,
+			}})
+			_, err := cpm.GetConnection(context.Background(), "acme", tt.dsn)
+			var creationErr *CreationError
+			require.True(t, errors.As(err, &creationErr), "got %v", err)
+			assert.Equal(t, tt.want, creationErr.Reason, creationErr.Error())
+			assert.Equal(t, 1, creationErr.Attempts)
+			assert.Equal(t, "acme", creationErr.TenantID)
+			assert.Empty(t, cpm.ListPools("acme"))
+		})
+	}
+}
+
+func TestConnectionPoolManager_CreationRetriesAndCache(t *testing.T) {
+	dsn := closedPortDSN(t)
+	cpm := NewConnectionPoolManager()
+	cpm.SetTenantConfig(TenantConfig{TenantID: "acme", DSN: dsn, Creation: CreationPolicy{
+		Verify:          true,
+		Retries:         2,
+		Backoff:         Duration{Duration: time.Millisecond},
+		FailureCacheTTL: Duration{Duration: 50 * time.Millisecond},
+	}})
+	sub := cpm.SubscribeEvents(EventFilter{Types: []EventType{EventCreationFailed}}, false, 0)
+	defer sub.Close()
+	ctx := context.Background()
+
+	_, err := cpm.GetConnection(ctx, "acme", dsn)
+	var creationErr *CreationError
+	require.True(t, errors.As(err, &creationErr))
+	assert.Equal(t, ReasonTCP, creationErr.Reason)
+	assert.Equal(t, 3, creationErr.Attempts)
+	assert.Len(t, sub.Events(), 1)
+
+	// The failure is returned as is until it expires, without another creation
+	_, cachedErr := cpm.GetConnection(ctx, "acme", dsn)
+	assert.Same(t, err, cachedErr)
+	assert.Len(t, sub.Events(), 1)
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = cpm.GetConnection(ctx, "acme", dsn)
+	require.Error(t, err)
+	assert.NotSame(t, cachedErr, err)
+	assert.Len(t, sub.Events(), 2)
+
+	// Failures that are not transient are not retried, and bad DSNs are not cached
+	cpm.SetTenantConfig(TenantConfig{TenantID: "globex", DSN: "invalid-dsn"})
+	for range 2 {
+		_, err = cpm.GetConnection(ctx, "globex", "invalid-dsn")
+		require.True(t, errors.As(err, &creationErr))
+		assert.Equal(t, ReasonInvalidDSN, creationErr.Reason)
+		assert.Equal(t, 1, creationErr.Attempts)
+	}
+	assert.Len(t, sub.Events(), 4)
+}
+
+func TestConnectionPoolManager_CreationVerify(t *testing.T) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+	defer db.Close()
+
+	cpm := NewConnectionPoolManager()
+	cpm.SetTenantConfig(TenantConfig{TenantID: "acme", DSN: db.DSN(), Creation: CreationPolicy{Verify: true}})
+	ctx := context.Background()
+	defer cpm.ReleaseConnection(ctx, "acme", db.DSN())
+
+	pool, err := cpm.GetConnection(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	assert.NotZero(t, pool.Stat().TotalConns())
+	// A verified creation tells the breaker the host works
+	breakers := cpm.Breakers("acme")
+	require.Len(t, breakers, 1)
+	assert.Equal(t, 1, breakers[0].Requests)
+	assert.Zero(t, breakers[0].Failures)
+}
+
+func TestConnectionPoolManager_CreationDoesNotBlockOthers(t *testing.T) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+	defer db.Close()
+
+	dsn := silentDSN(t)
+	cpm := NewConnectionPoolManager()
+	cpm.SetTenantConfig(TenantConfig{TenantID: "acme", DSN: dsn, Creation: CreationPolicy{
+		Verify:        true,
+		VerifyTimeout: Duration{Duration: time.Second},
+		Retries:       -1,
+	}})
+	ctx := context.Background()
+	defer cpm.ReleaseConnection(ctx, "globex", db.DSN())
+
+	created := make(chan error, 1)
+	go func() {
+		_, err := cpm.GetConnection(ctx, "acme", dsn)
+		created <- err
+	}()
+	require.Eventually(t, func() bool {
+		cpm.poolLocks.Lock()
+		defer cpm.poolLocks.Unlock()
+		return len(cpm.creating) == 1
+	}, time.Second, time.Millisecond)
+
+	// A caller waiting for the same pool gives up with its own context
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = cpm.GetConnection(waitCtx, "acme", dsn)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// Other pools are created meanwhile
+	_, err = cpm.GetConnection(ctx, "globex", db.DSN())
+	require.NoError(t, err)
+	select {
+	case err := <-created:
+		t.Fatalf("creation finished early: %v", err)
+	default:
+	}
+
+	var creationErr *CreationError
+	require.True(t, errors.As(<-created, &creationErr))
+	assert.Equal(t, ReasonTimeout, creationErr.Reason)
+}
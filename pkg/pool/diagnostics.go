@@ -0,0 +1,158 @@
+package pool
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+// DefaultHoldWarning is how long a lease is held before a warning is logged about it
+const DefaultHoldWarning = 10 * time.Minute
+
+// ClientInfo describes who acquired a lease
+type ClientInfo struct {
+	// Peer is the client's network address
+	Peer string
+	// Identity is the client's authenticated identity, if any
+	Identity string
+	// CallSite is where in the client's code the lease was taken, as the client reports it
+	CallSite string
+}
+
+type clientKey struct{}
+
+// WithClient returns a context that records who is acquiring leases. Leases acquired
+// with it carry the client.
+func WithClient(ctx context.Context, client ClientInfo) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+func clientFromContext(ctx context.Context) ClientInfo {
+	client, _ := ctx.Value(clientKey{}).(ClientInfo)
+	return client
+}
+
+// Touch records activity on the lease
+func (l *Lease) Touch() {
+	l.lastActive.Store(time.Now().UnixNano())
+}
+
+// LastActive returns when the lease last ran a statement or attached a backend
+func (l *Lease) LastActive() time.Time {
+	return time.Unix(0, l.lastActive.Load())
+}
+
+// LeaseHandle identifies the lease with ID id in diagnostics and logs. Unlike the ID,
+// which is all a client needs to use a lease, it cannot be used to act on the lease.
+func LeaseHandle(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Handle returns the lease's handle
+func (l *Lease) Handle() string {
+	return LeaseHandle(l.ID)
+}
+
+// LeaseInfo is a snapshot of a lease for diagnostics
+type LeaseInfo struct {
+	Handle     string
+	TenantID   string
+	Mode       PoolMode
+	Client     ClientInfo
+	AcquiredAt time.Time
+	LastActive time.Time
+	// ExpiresAt is zero for leases that do not expire
+	ExpiresAt time.Time
+	Attached  bool
+}
+
+// Info returns a snapshot of the lease
+func (l *Lease) Info() LeaseInfo {
+	return LeaseInfo{
+		Handle:     l.Handle(),
+		TenantID:   l.TenantID,
+		Mode:       l.Mode,
+		Client:     l.Client,
+		AcquiredAt: l.AcquiredAt,
+		LastActive: l.LastActive(),
+		ExpiresAt:  l.ExpiresAt(),
+		Attached:   l.Attached(),
+	}
+}
+
+// LongestHeldLeases returns up to limit of each tenant's leases, longest held first,
+// for one tenant or every tenant when tenantID is empty. A limit of zero or less
+// returns all of them.
+func (cpm *ConnectionPoolManager) LongestHeldLeases(tenantID string, limit int) []LeaseInfo {
+	var leases []*Lease
+	cpm.leaseLock.Lock()
+	for _, lease := range cpm.leases {
+		if tenantID == "" || lease.TenantID == tenantID {
+			leases = append(leases, lease)
+		}
+	}
+	cpm.leaseLock.Unlock()
+
+	sort.Slice(leases, func(i, j int) bool {
+		if leases[i].TenantID != leases[j].TenantID {
+			return leases[i].TenantID < leases[j].TenantID
+		}
+		return leases[i].AcquiredAt.Before(leases[j].AcquiredAt)
+	})
+
+	infos := make([]LeaseInfo, 0, len(leases))
+	perTenant := 0
+	for i, lease := range leases {
+		if i > 0 && lease.TenantID != leases[i-1].TenantID {
+			perTenant = 0
+		}
+		perTenant++
+		if limit <= 0 || perTenant <= limit {
+			infos = append(infos, lease.Info())
+		}
+	}
+	return infos
+}
+
+// SetHoldWarning sets how long a lease is held before a warning is logged about it.
+// Zero or less turns the warning off.
+func (cpm *ConnectionPoolManager) SetHoldWarning(d time.Duration) {
+	cpm.leaseLock.Lock()
+	defer cpm.leaseLock.Unlock()
+	cpm.holdWarning = d
+}
+
+// warnLongHeldLeases logs a warning, once per lease, for every lease held past the hold
+// warning and returns how many it warned about
+func (cpm *ConnectionPoolManager) warnLongHeldLeases(ctx context.Context) int {
+	now := time.Now()
+	var held []*Lease
+	cpm.leaseLock.Lock()
+	threshold := cpm.holdWarning
+	if threshold > 0 {
+		for _, lease := range cpm.leases {
+			if !lease.warned && now.Sub(lease.AcquiredAt) > threshold {
+				lease.warned = true
+				held = append(held, lease)
+			}
+		}
+	}
+	cpm.leaseLock.Unlock()
+
+	for _, lease := range held {
+		ctxLog(ctx).Warn().
+			Str("tenant_id", lease.TenantID).
+			Str("lease", lease.Handle()).
+			Str("peer", lease.Client.Peer).
+			Str("identity", lease.Client.Identity).
+			Str("call_site", lease.Client.CallSite).
+			Time("acquired_at", lease.AcquiredAt).
+			Time("last_active", lease.LastActive()).
+			Dur("held", now.Sub(lease.AcquiredAt)).
+			Msg("Lease held past warning threshold")
+	}
+	return len(held)
+}
@@ -0,0 +1,87 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionPoolManager_LongestHeldLeases(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{PoolMode: PoolModeTransaction})
+	cpm.SetTenantConfig(TenantConfig{TenantID: "globex", DSN: db.DSN(), PoolMode: PoolModeTransaction})
+	ctx := WithClient(context.Background(), ClientInfo{Peer: "10.0.0.7:51234", Identity: "app", CallSite: "worker.go:42"})
+
+	var acme []*Lease
+	for range 3 {
+		lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+		require.NoError(t, err)
+		defer cpm.ReleaseLease(ctx, lease.ID)
+		acme = append(acme, lease)
+	}
+	globex, err := cpm.AcquireLease(ctx, "globex", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseLease(ctx, globex.Handle())
+	defer cpm.ReleaseConnection(ctx, "globex", db.DSN())
+
+	tests := []struct {
+		name     string
+		tenantID string
+		limit    int
+		want     []string
+	}{
+		{name: "all", want: []string{acme[0].Handle(), acme[1].Handle(), acme[2].Handle(), globex.Handle()}},
+		{name: "per tenant limit", limit: 2, want: []string{acme[0].Handle(), acme[1].Handle(), globex.Handle()}},
+		{name: "one tenant", tenantID: "acme", limit: 1, want: []string{acme[0].Handle()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			infos := cpm.LongestHeldLeases(tt.tenantID, tt.limit)
+			ids := make([]string, len(infos))
+			for i, info := range infos {
+				ids[i] = info.Handle
+			}
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+
+	info := cpm.LongestHeldLeases("globex", 0)[0]
+	assert.Equal(t, ClientInfo{Peer: "10.0.0.7:51234", Identity: "app", CallSite: "worker.go:42"}, info.Client)
+	assert.Equal(t, PoolModeTransaction, info.Mode)
+	assert.False(t, info.Attached)
+	assert.False(t, info.ExpiresAt.IsZero())
+
+	// Running a statement counts as activity
+	before := globex.LastActive()
+	time.Sleep(time.Millisecond)
+	_, err = globex.Exec(ctx, "SELECT 1", nil)
+	require.NoError(t, err)
+	assert.True(t, globex.LastActive().After(before))
+}
+
+func TestConnectionPoolManager_WarnLongHeldLeases(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{})
+	ctx := context.Background()
+
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseLease(ctx, lease.ID)
+
+	assert.Equal(t, 0, cpm.warnLongHeldLeases(ctx))
+
+	cpm.SetHoldWarning(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, 1, cpm.warnLongHeldLeases(ctx))
+	// Each lease is only reported once
+	assert.Equal(t, 0, cpm.warnLongHeldLeases(ctx))
+
+	cpm.SetHoldWarning(0)
+	fresh, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseLease(ctx, fresh.ID)
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, 0, cpm.warnLongHeldLeases(ctx))
+}
@@ -0,0 +1,420 @@
+package pool
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrDSNNotAllowed is returned for DSNs the DSN policy rejects
+var ErrDSNNotAllowed = errors.New("DSN not allowed")
+
+// DefaultValidateTimeout bounds a DSN validation when the caller sets no timeout
+const DefaultValidateTimeout = 10 * time.Second
+
+// DSNPolicy limits what tenant DSNs may point to. The zero policy allows everything.
+type DSNPolicy struct {
+	// AllowedHosts lists patterns, as in path.Match, that every host of a DSN must
+	// match. Any host is allowed when empty.
+	AllowedHosts []string
+	// RequireTLS rejects DSNs that may connect without TLS, such as sslmode=prefer
+	RequireTLS bool
+}
+
+// Check returns an error wrapping ErrDSNNotAllowed when the parsed DSN breaks the policy
+func (p DSNPolicy) Check(config *pgconn.Config) error {
+	hosts := []string{config.Host}
+	plain := config.TLSConfig == nil
+	for _, fb := range config.Fallbacks {
+		hosts = append(hosts, fb.Host)
+		plain = plain || fb.TLSConfig == nil
+	}
+	if p.RequireTLS && plain {
+		return fmt.Errorf("%w: sslmode=%s may connect without TLS", ErrDSNNotAllowed, sslMode(config))
+	}
+	if len(p.AllowedHosts) == 0 {
+		return nil
+	}
+	for _, host := range hosts {
+		allowed := false
+		for _, pattern := range p.AllowedHosts {
+			if ok, _ := path.Match(pattern, host); ok {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%w: host %s is not allowed", ErrDSNNotAllowed, host)
+		}
+	}
+	return nil
+}
+
+// SetDSNPolicy sets the policy DSNs are checked against before a pool is created
+func (cpm *ConnectionPoolManager) SetDSNPolicy(policy DSNPolicy) {
+	cpm.stateLock.Lock()
+	defer cpm.stateLock.Unlock()
+	cpm.dsnPolicy = policy
+}
+
+// checkDSNPolicy checks a DSN against the policy. DSNs that do not parse are left to
+// fail creation.
+func (cpm *ConnectionPoolManager) checkDSNPolicy(dsn string) error {
+	config, err := pgconn.ParseConfig(dsn)
+	if err != nil {
+		return nil
+	}
+	cpm.stateLock.RLock()
+	policy := cpm.dsnPolicy
+	cpm.stateLock.RUnlock()
+	return policy.Check(config)
+}
+
+// sslMode returns the sslmode a parsed DSN behaves as
+func sslMode(config *pgconn.Config) string {
+	fallbackPlain, fallbackTLS := false, false
+	for _, fb := range config.Fallbacks {
+		if fb.TLSConfig == nil {
+			fallbackPlain = true
+		} else {
+			fallbackTLS = true
+		}
+	}
+	switch {
+	case config.TLSConfig == nil && fallbackTLS:
+		return "allow"
+	case config.TLSConfig == nil:
+		return "disable"
+	case fallbackPlain:
+		return "prefer"
+	case config.TLSConfig.VerifyPeerCertificate != nil:
+		return "verify-ca"
+	case !config.TLSConfig.InsecureSkipVerify:
+		return "verify-full"
+	}
+	return "require"
+}
+
+// normalizeDSN writes a parsed DSN back as a URL, without its password
+func normalizeDSN(config *pgconn.Config) string {
+	hosts := []string{net.JoinHostPort(config.Host, strconv.Itoa(int(config.Port)))}
+	for _, fb := range config.Fallbacks {
+		host := net.JoinHostPort(fb.Host, strconv.Itoa(int(fb.Port)))
+		if hosts[len(hosts)-1] != host {
+			hosts = append(hosts, host)
+		}
+	}
+
+	query := url.Values{}
+	query.Set("sslmode", sslMode(config))
+	if config.ConnectTimeout > 0 {
+		query.Set("connect_timeout", strconv.Itoa(int(config.ConnectTimeout.Seconds())))
+	}
+	for name, value := range config.RuntimeParams {
+		query.Set(name, value)
+	}
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.User(config.User),
+		Host:     strings.Join(hosts, ","),
+		Path:     "/" + config.Database,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// ValidationStage is a step of validating a DSN
+type ValidationStage string
+
+const (
+	StageParse   ValidationStage = "parse"
+	StagePolicy  ValidationStage = "policy"
+	StageResolve ValidationStage = "resolve"
+	StageTCP     ValidationStage = "tcp"
+	StageTLS     ValidationStage = "tls"
+	StageAuth    ValidationStage = "auth"
+	// StageInspect reads the server's settings, extensions and the role's privileges
+	StageInspect ValidationStage = "inspect"
+)
+
+// validationStages lists the stages in the order they run
+var validationStages = []ValidationStage{StageParse, StagePolicy, StageResolve, StageTCP, StageTLS, StageAuth, StageInspect}
+
+// StageStatus is how a validation stage went
+type StageStatus string
+
+const (
+	StageOK      StageStatus = "ok"
+	StageFailed  StageStatus = "failed"
+	StageSkipped StageStatus = "skipped"
+)
+
+// StageResult is the outcome of one validation stage
+type StageResult struct {
+	Stage   ValidationStage
+	Status  StageStatus
+	Latency time.Duration
+	Error   string
+}
+
+// Extension is an extension installed in a database
+type Extension struct {
+	Name    string
+	Version string
+}
+
+// RolePrivileges are what the role a DSN logs in as may do
+type RolePrivileges struct {
+	Role        string
+	Superuser   bool
+	CreateDB    bool
+	CreateRole  bool
+	Replication bool
+	BypassRLS   bool
+	// Connect, Create and Temp are the role's privileges on the database
+	Connect bool
+	Create  bool
+	Temp    bool
+}
+
+// DSNReport is the outcome of validating a DSN
+type DSNReport struct {
+	// Valid is set when the DSN parses, meets the policy and can be connected to
+	Valid bool
+	// NormalizedDSN is the DSN as a URL, without its password
+	NormalizedDSN string
+	Host          string
+	Port          uint16
+	Database      string
+	User          string
+	SSLMode       string
+	// Stages holds every stage in order, including those skipped after a failure
+	Stages []StageResult
+	// Reason classifies a failed connection
+	Reason CreationReason
+	Err    error
+
+	ServerVersion  string
+	MaxConnections int
+	Extensions     []Extension
+	Privileges     *RolePrivileges
+}
+
+// stageTimes collects how long each connection stage took
+type stageTimes struct {
+	done map[ValidationStage]time.Duration
+	// last is when the previous stage finished
+	last time.Time
+}
+
+func (st *stageTimes) finish(stage ValidationStage, at time.Time) {
+	st.done[stage] = at.Sub(st.last)
+	st.last = at
+}
+
+// ValidateDSN parses the DSN, checks it against the DSN policy and makes a single
+// connection to it, timing each stage, and reports what it finds on the server. No
+// pool is created and the connection is closed before returning.
+func (cpm *ConnectionPoolManager) ValidateDSN(ctx context.Context, dsn string, timeout time.Duration) DSNReport {
+	if timeout <= 0 {
+		timeout = DefaultValidateTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	results := make(map[ValidationStage]StageResult)
+	report := func(r DSNReport) DSNReport {
+		for _, stage := range validationStages {
+			result, ok := results[stage]
+			if !ok {
+				result = StageResult{Stage: stage, Status: StageSkipped}
+			}
+			r.Stages = append(r.Stages, result)
+		}
+		return r
+	}
+
+	start := time.Now()
+	config, err := pgx.ParseConfig(dsn)
+	results[StageParse] = stageResult(StageParse, time.Since(start), err)
+	if err != nil {
+		return report(DSNReport{Reason: ReasonInvalidDSN, Err: err})
+	}
+	r := DSNReport{
+		NormalizedDSN: normalizeDSN(&config.Config),
+		Host:          config.Host,
+		Port:          config.Port,
+		Database:      config.Database,
+		User:          config.User,
+		SSLMode:       sslMode(&config.Config),
+	}
+
+	cpm.stateLock.RLock()
+	policy := cpm.dsnPolicy
+	cpm.stateLock.RUnlock()
+	start = time.Now()
+	err = policy.Check(&config.Config)
+	results[StagePolicy] = stageResult(StagePolicy, time.Since(start), err)
+	if err != nil {
+		r.Err = err
+		return report(r)
+	}
+
+	times := &stageTimes{done: make(map[ValidationStage]time.Duration)}
+	instrument(&config.Config, times)
+	times.last = time.Now()
+	connectStart := times.last
+	conn, err := pgx.ConnectConfig(ctx, config)
+	connected := time.Now()
+	if err != nil {
+		r.Reason = classifyCreation(err)
+		r.Err = err
+		failed := failedStage(r.Reason, times, config.TLSConfig != nil)
+		for _, stage := range []ValidationStage{StageResolve, StageTCP, StageTLS, StageAuth} {
+			if stage == failed {
+				results[stage] = StageResult{Stage: stage, Status: StageFailed, Latency: connected.Sub(times.last), Error: err.Error()}
+				break
+			}
+			if latency, ok := times.done[stage]; ok {
+				results[stage] = StageResult{Stage: stage, Status: StageOK, Latency: latency}
+			}
+		}
+		return report(r)
+	}
+	defer conn.Close(context.Background())
+
+	times.finish(StageAuth, connected)
+	for _, stage := range []ValidationStage{StageResolve, StageTCP, StageTLS, StageAuth} {
+		if latency, ok := times.done[stage]; ok {
+			results[stage] = StageResult{Stage: stage, Status: StageOK, Latency: latency}
+		}
+	}
+	r.Valid = true
+	ctxLog(ctx).Debug().Str("server", dsnLabel(dsn)).Dur("latency", connected.Sub(connectStart)).Msg("Validated DSN")
+
+	start = time.Now()
+	r.ServerVersion = conn.PgConn().ParameterStatus("server_version")
+	err = inspectServer(ctx, conn, &r)
+	results[StageInspect] = stageResult(StageInspect, time.Since(start), err)
+	return report(r)
+}
+
+func stageResult(stage ValidationStage, latency time.Duration, err error) StageResult {
+	if err != nil {
+		return StageResult{Stage: stage, Status: StageFailed, Latency: latency, Error: err.Error()}
+	}
+	return StageResult{Stage: stage, Status: StageOK, Latency: latency}
+}
+
+// instrument hooks the config's lookup, dial and TLS handshake to time them. With
+// several hosts or a fallback, the attempt that got furthest last wins.
+func instrument(config *pgconn.Config, times *stageTimes) {
+	lookup := config.LookupFunc
+	config.LookupFunc = func(ctx context.Context, host string) ([]string, error) {
+		addrs, err := lookup(ctx, host)
+		if err == nil {
+			times.finish(StageResolve, time.Now())
+		}
+		return addrs, err
+	}
+	dial := config.DialFunc
+	config.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// Each attempt starts over from a resolved address
+		times.last = time.Now()
+		delete(times.done, StageTLS)
+		conn, err := dial(ctx, network, addr)
+		if err == nil {
+			times.finish(StageTCP, time.Now())
+		}
+		return conn, err
+	}
+
+	hook := func(tlsConfig *tls.Config) *tls.Config {
+		if tlsConfig == nil {
+			return nil
+		}
+		tlsConfig = tlsConfig.Clone()
+		verify := tlsConfig.VerifyConnection
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			if verify != nil {
+				if err := verify(state); err != nil {
+					return err
+				}
+			}
+			times.finish(StageTLS, time.Now())
+			return nil
+		}
+		return tlsConfig
+	}
+	config.TLSConfig = hook(config.TLSConfig)
+	for _, fb := range config.Fallbacks {
+		fb.TLSConfig = hook(fb.TLSConfig)
+	}
+}
+
+// failedStage finds the connection stage a classified failure happened in
+func failedStage(reason CreationReason, times *stageTimes, usesTLS bool) ValidationStage {
+	switch reason {
+	case ReasonDNS:
+		return StageResolve
+	case ReasonTCP:
+		return StageTCP
+	case ReasonTLS:
+		return StageTLS
+	case ReasonTimeout:
+		// The first stage that did not finish timed out
+		for _, stage := range []ValidationStage{StageResolve, StageTCP, StageTLS} {
+			if _, ok := times.done[stage]; !ok && (stage != StageTLS || usesTLS) {
+				return stage
+			}
+		}
+	}
+	return StageAuth
+}
+
+// inspectServer reads the server settings, extensions and role privileges into the report
+func inspectServer(ctx context.Context, conn *pgx.Conn, r *DSNReport) error {
+	var maxConnections string
+	if err := conn.QueryRow(ctx, "SHOW max_connections").Scan(&maxConnections); err != nil {
+		return fmt.Errorf("max_connections: %w", err)
+	}
+	r.MaxConnections, _ = strconv.Atoi(maxConnections)
+
+	rows, err := conn.Query(ctx, "SELECT extname, extversion FROM pg_extension")
+	if err != nil {
+		return fmt.Errorf("extensions: %w", err)
+	}
+	r.Extensions, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (Extension, error) {
+		var ext Extension
+		err := row.Scan(&ext.Name, &ext.Version)
+		return ext, err
+	})
+	if err != nil {
+		return fmt.Errorf("extensions: %w", err)
+	}
+	sort.Slice(r.Extensions, func(i, j int) bool { return r.Extensions[i].Name < r.Extensions[j].Name })
+
+	p := &RolePrivileges{}
+	err = conn.QueryRow(ctx, `SELECT current_user, rolsuper, rolcreatedb, rolcreaterole, rolreplication, rolbypassrls,
+		has_database_privilege(current_database(), 'CONNECT'),
+		has_database_privilege(current_database(), 'CREATE'),
+		has_database_privilege(current_database(), 'TEMP')
+		FROM pg_roles WHERE rolname = current_user`).
+		Scan(&p.Role, &p.Superuser, &p.CreateDB, &p.CreateRole, &p.Replication, &p.BypassRLS, &p.Connect, &p.Create, &p.Temp)
+	if err != nil {
+		return fmt.Errorf("privileges: %w", err)
+	}
+	r.Privileges = p
+	return nil
+}
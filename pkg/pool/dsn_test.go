@@ -0,0 +1,130 @@
+package pool
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/pgtest"
+)
+
+func TestDSNPolicy_Check(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  DSNPolicy
+		dsn     string
+		wantErr string
+	}{
+		{name: "zero policy", dsn: "postgres://app@anywhere/acme?sslmode=disable"},
+		{name: "allowed host", policy: DSNPolicy{AllowedHosts: []string{"*.db.internal"}}, dsn: "postgres://app@acme.db.internal/acme"},
+		{name: "other host", policy: DSNPolicy{AllowedHosts: []string{"*.db.internal"}}, dsn: "postgres://app@db.example.com/acme", wantErr: "host db.example.com is not allowed"},
+		{name: "every host checked", policy: DSNPolicy{AllowedHosts: []string{"a.db.internal"}}, dsn: "postgres://app@a.db.internal,b.db.internal/acme", wantErr: "host b.db.internal"},
+		{name: "tls required", policy: DSNPolicy{RequireTLS: true}, dsn: "postgres://app@db/acme?sslmode=require"},
+		{name: "tls optional", policy: DSNPolicy{RequireTLS: true}, dsn: "postgres://app@db/acme?sslmode=prefer", wantErr: "sslmode=prefer"},
+		{name: "tls off", policy: DSNPolicy{RequireTLS: true}, dsn: "postgres://app@db/acme?sslmode=disable", wantErr: "sslmode=disable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := pgconn.ParseConfig(tt.dsn)
+			require.NoError(t, err)
+			err = tt.policy.Check(config)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrDSNNotAllowed)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestNormalizeDSN(t *testing.T) {
+	config, err := pgconn.ParseConfig("host=db port=5433 user=app password=secret dbname=acme sslmode=verify-full application_name=billing")
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://app@db:5433/acme?application_name=billing&sslmode=verify-full", normalizeDSN(config))
+
+	config, err = pgconn.ParseConfig("postgres://app:secret@a:5432,b:5432/acme?sslmode=prefer")
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://app@a:5432,b:5432/acme?sslmode=prefer", normalizeDSN(config))
+}
+
+func TestConnectionPoolManager_ValidateDSN(t *testing.T) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetHandler(func(pid uint32, query string) (pgtest.Result, bool) {
+		switch {
+		case query == "SHOW max_connections":
+			return pgtest.Result{Columns: []string{"max_connections"}, Rows: [][]string{{"100"}}, Tag: "SHOW"}, true
+		case strings.Contains(query, "pg_extension"):
+			return pgtest.Result{Columns: []string{"extname", "extversion"}, Types: []uint32{pgtype.NameOID, pgtype.TextOID}, Rows: [][]string{{"pgcrypto", "1.3"}, {"plpgsql", "1.0"}}, Tag: "SELECT 2"}, true
+		case strings.Contains(query, "pg_roles"):
+			return pgtest.Result{Columns: []string{"current_user", "rolsuper", "rolcreatedb", "rolcreaterole", "rolreplication", "rolbypassrls", "connect", "create", "temp"},
+				Types: []uint32{pgtype.NameOID, pgtype.BoolOID, pgtype.BoolOID, pgtype.BoolOID, pgtype.BoolOID, pgtype.BoolOID, pgtype.BoolOID, pgtype.BoolOID, pgtype.BoolOID},
+				Rows: [][]string{{"test", "f", "t", "f", "f", "f", "t", "t", "t"}}, Tag: "SELECT 1"}, true
+		}
+		return pgtest.Result{}, false
+	})
+	cpm := NewConnectionPoolManager()
+	ctx := context.Background()
+	statuses := func(report DSNReport) map[ValidationStage]StageStatus {
+		m := make(map[ValidationStage]StageStatus)
+		for _, stage := range report.Stages {
+			m[stage.Stage] = stage.Status
+		}
+		return m
+	}
+
+	report := cpm.ValidateDSN(ctx, db.DSN(), 0)
+	require.NoError(t, report.Err)
+	assert.True(t, report.Valid)
+	assert.Equal(t, "127.0.0.1", report.Host)
+	assert.Equal(t, "disable", report.SSLMode)
+	assert.Equal(t, map[ValidationStage]StageStatus{
+		StageParse: StageOK, StagePolicy: StageOK, StageResolve: StageOK, StageTCP: StageOK,
+		StageTLS: StageSkipped, StageAuth: StageOK, StageInspect: StageOK,
+	}, statuses(report))
+	assert.Len(t, report.Stages, len(validationStages))
+	assert.Equal(t, "16.0", report.ServerVersion)
+	assert.Equal(t, 100, report.MaxConnections)
+	assert.Equal(t, []Extension{{Name: "pgcrypto", Version: "1.3"}, {Name: "plpgsql", Version: "1.0"}}, report.Extensions)
+	require.NotNil(t, report.Privileges)
+	assert.Equal(t, RolePrivileges{Role: "test", CreateDB: true, Connect: true, Create: true, Temp: true}, *report.Privileges)
+	// The connection is not kept
+	assert.Empty(t, cpm.ListPools(""))
+	assert.Eventually(t, func() bool { return db.ConnCount() == 0 }, time.Second, time.Millisecond)
+
+	// A failed login stops at the auth stage
+	db.SetStartupError("28P01", "password authentication failed")
+	report = cpm.ValidateDSN(ctx, db.DSN(), 0)
+	db.SetStartupError("", "")
+	assert.False(t, report.Valid)
+	assert.Equal(t, ReasonAuth, report.Reason)
+	assert.Equal(t, StageFailed, statuses(report)[StageAuth])
+	assert.Equal(t, StageOK, statuses(report)[StageTCP])
+	assert.Equal(t, StageSkipped, statuses(report)[StageInspect])
+
+	report = cpm.ValidateDSN(ctx, closedPortDSN(t), 0)
+	assert.Equal(t, ReasonTCP, report.Reason)
+	assert.Equal(t, StageFailed, statuses(report)[StageTCP])
+	assert.Equal(t, StageSkipped, statuses(report)[StageAuth])
+
+	report = cpm.ValidateDSN(ctx, "postgres://host:notaport/db", 0)
+	assert.Equal(t, ReasonInvalidDSN, report.Reason)
+	assert.Equal(t, StageFailed, statuses(report)[StageParse])
+	assert.Equal(t, StageSkipped, statuses(report)[StagePolicy])
+
+	// The policy is applied before connecting, and to pool creation
+	cpm.SetDSNPolicy(DSNPolicy{RequireTLS: true})
+	report = cpm.ValidateDSN(ctx, db.DSN(), 0)
+	assert.ErrorIs(t, report.Err, ErrDSNNotAllowed)
+	assert.Equal(t, StageFailed, statuses(report)[StagePolicy])
+	assert.Equal(t, StageSkipped, statuses(report)[StageTCP])
+	_, err = cpm.GetConnection(ctx, "acme", db.DSN())
+	assert.ErrorIs(t, err, ErrDSNNotAllowed)
+}
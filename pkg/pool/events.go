@@ -0,0 +1,197 @@
+package pool
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultEventBuffer is how many recent events are kept for replay
+const DefaultEventBuffer = 1000
+
+// subscriberBuffer is how many live events a subscriber may fall behind, on top of its
+// replay, before events are dropped for it
+const subscriberBuffer = 256
+
+// EventType identifies what happened in a pool lifecycle event
+type EventType string
+
+const (
+	// EventPoolCreated is sent when a pool is opened
+	EventPoolCreated EventType = "pool_created"
+	// EventPoolClosed is sent when a pool is closed, by a release, drain or eviction
+	EventPoolClosed EventType = "pool_closed"
+	// EventLeaseEvicted is sent for each lease ended by a drain or eviction
+	EventLeaseEvicted EventType = "lease_evicted"
+	// EventPoolResized is sent when a pool is opened with another size than the last
+	// pool for the same tenant and DSN
+	EventPoolResized EventType = "pool_resized"
+	// EventCreationFailed is sent when a pool could not be created
+	EventCreationFailed EventType = "creation_failed"
+	// EventHealthChanged is sent when a tenant's pool creation or a replica's lag check
+	// starts or stops failing
+	EventHealthChanged EventType = "health_changed"
+	// EventLeaseReclaimed is sent for each lease reclaimed after it expired
+	EventLeaseReclaimed EventType = "lease_reclaimed"
+	// EventFailover is sent when a tenant's reads move to another replica or to the primary
+	EventFailover EventType = "failover"
+)
+
+// EventTypes lists every event type
+var EventTypes = []EventType{
+	EventPoolCreated, EventPoolClosed, EventLeaseEvicted, EventPoolResized,
+	EventCreationFailed, EventHealthChanged, EventLeaseReclaimed, EventFailover,
+}
+
+// Event is something that happened to a tenant's pools or leases
+type Event struct {
+	// Seq numbers events in the order they were published, starting at 1
+	Seq      uint64
+	Time     time.Time
+	Type     EventType
+	TenantID string
+	// Server is the host, port and database of the pool concerned, or the pool reads
+	// moved to on failover
+	Server string
+	// FromServer is the pool reads moved away from on failover
+	FromServer string
+	// Lease is the handle of the lease concerned
+	Lease string
+	// MaxConns is the size of the pool created or resized, and PreviousMaxConns its
+	// size before a resize
+	MaxConns         int32
+	PreviousMaxConns int32
+	// Healthy is the new state on health changes
+	Healthy bool
+	// Error is why a creation or check failed
+	Error string
+}
+
+// EventFilter selects the events a subscriber gets. Empty lists match everything.
+type EventFilter struct {
+	TenantIDs []string
+	Types     []EventType
+}
+
+func (f EventFilter) matches(event Event) bool {
+	return (len(f.TenantIDs) == 0 || slices.Contains(f.TenantIDs, event.TenantID)) &&
+		(len(f.Types) == 0 || slices.Contains(f.Types, event.Type))
+}
+
+// Subscription delivers published events matching its filter
+type Subscription struct {
+	bus     *eventBus
+	filter  EventFilter
+	events  chan Event
+	dropped uint64
+}
+
+// Events returns the channel events are delivered on. It is closed by Close.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns how many events were not delivered because the subscriber fell behind
+func (s *Subscription) Dropped() uint64 {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.dropped
+}
+
+// Close stops delivery and closes the events channel
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if _, ok := s.bus.subs[s]; ok {
+		delete(s.bus.subs, s)
+		close(s.events)
+	}
+}
+
+// eventBus fans events out to subscribers and keeps the most recent for replay
+type eventBus struct {
+	mu     sync.Mutex
+	seq    uint64
+	recent []Event
+	size   int
+	subs   map[*Subscription]struct{}
+}
+
+func newEventBus(size int) *eventBus {
+	if size <= 0 {
+		size = DefaultEventBuffer
+	}
+	return &eventBus{size: size, subs: make(map[*Subscription]struct{})}
+}
+
+// publish numbers the event and delivers it without blocking. Subscribers that fell
+// behind miss it.
+func (b *eventBus) publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	event.Seq = b.seq
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if len(b.recent) >= b.size {
+		b.recent = append(b.recent[:0], b.recent[len(b.recent)-b.size+1:]...)
+	}
+	b.recent = append(b.recent, event)
+	eventsPublished.WithLabelValues(string(event.Type)).Inc()
+
+	for sub := range b.subs {
+		if sub.filter.matches(event) {
+			sub.deliver(event)
+		}
+	}
+}
+
+// deliver hands the event to the subscriber unless it is full. The caller holds the bus lock.
+func (s *Subscription) deliver(event Event) {
+	select {
+	case s.events <- event:
+	default:
+		s.dropped++
+		eventsDropped.Inc()
+	}
+}
+
+// SubscribeEvents delivers events matching filter as they are published. With replay,
+// retained events numbered above after are delivered first, as far as the
+// subscriber's buffer allows. The subscription must be closed.
+func (cpm *ConnectionPoolManager) SubscribeEvents(filter EventFilter, replay bool, after uint64) *Subscription {
+	b := cpm.events
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sub := &Subscription{bus: b, filter: filter, events: make(chan Event, b.size+subscriberBuffer)}
+	if replay {
+		for _, event := range b.recent {
+			if event.Seq > after && filter.matches(event) {
+				sub.deliver(event)
+			}
+		}
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// SetEventBuffer sets how many recent events are kept for replay, using the default for
+// zero or less. It applies to subscriptions made afterwards.
+func (cpm *ConnectionPoolManager) SetEventBuffer(size int) {
+	if size <= 0 {
+		size = DefaultEventBuffer
+	}
+	b := cpm.events
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.size = size
+	if len(b.recent) > size {
+		b.recent = append([]Event(nil), b.recent[len(b.recent)-size:]...)
+	}
+}
+
+// publish sends an event on the manager's bus
+func (cpm *ConnectionPoolManager) publish(event Event) {
+	cpm.events.publish(event)
+}
@@ -0,0 +1,178 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiveEvents collects the events delivered to sub so far
+func receiveEvents(sub *Subscription) []Event {
+	var events []Event
+	for {
+		select {
+		case event := <-sub.Events():
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func eventTypes(events []Event) []EventType {
+	types := make([]EventType, 0, len(events))
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestConnectionPoolManager_SubscribeEvents(t *testing.T) {
+	cpm := NewConnectionPoolManager()
+	cpm.SetEventBuffer(3)
+	for i := range 4 {
+		cpm.publish(Event{Type: EventPoolCreated, TenantID: []string{"acme", "globex"}[i%2]})
+	}
+
+	// Replay starts after the given sequence number, within what is retained
+	sub := cpm.SubscribeEvents(EventFilter{}, true, 0)
+	defer sub.Close()
+	replayed := receiveEvents(sub)
+	require.Len(t, replayed, 3)
+	assert.Equal(t, uint64(2), replayed[0].Seq)
+	assert.False(t, replayed[0].Time.IsZero())
+
+	filtered := cpm.SubscribeEvents(EventFilter{TenantIDs: []string{"acme"}, Types: []EventType{EventPoolClosed}}, true, 2)
+	defer filtered.Close()
+	assert.Empty(t, receiveEvents(filtered))
+	cpm.publish(Event{Type: EventPoolClosed, TenantID: "acme"})
+	cpm.publish(Event{Type: EventPoolClosed, TenantID: "globex"})
+	cpm.publish(Event{Type: EventPoolCreated, TenantID: "acme"})
+
+	events := receiveEvents(filtered)
+	require.Len(t, events, 1)
+	assert.Equal(t, uint64(5), events[0].Seq)
+	assert.Len(t, receiveEvents(sub), 3)
+
+	// A subscriber that falls behind misses events rather than blocking publishers
+	for range 3 + subscriberBuffer + 10 {
+		cpm.publish(Event{Type: EventPoolCreated, TenantID: "acme"})
+	}
+	assert.Equal(t, uint64(10), sub.Dropped())
+
+	// Closing ends the channel once what was delivered is read
+	sub.Close()
+	delivered := 0
+	for range sub.Events() {
+		delivered++
+	}
+	assert.Equal(t, 3+subscriberBuffer, delivered)
+}
+
+func TestConnectionPoolManager_LifecycleEvents(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{})
+	unreachable := closedPortDSN(t)
+	cpm.SetTenantConfig(TenantConfig{TenantID: "broken", DSN: unreachable, Creation: CreationPolicy{Verify: true, Retries: -1, FailureCacheTTL: Duration{Duration: -1}}})
+	ctx := context.Background()
+	sub := cpm.SubscribeEvents(EventFilter{}, false, 0)
+	defer sub.Close()
+
+	_, err := cpm.GetConnection(ctx, "broken", unreachable)
+	require.Error(t, err)
+	_, err = cpm.GetConnection(ctx, "broken", unreachable)
+	require.Error(t, err)
+	events := receiveEvents(sub)
+	// The tenant only turns unhealthy once
+	assert.Equal(t, []EventType{EventHealthChanged, EventCreationFailed, EventCreationFailed}, eventTypes(events))
+	assert.Equal(t, "broken", events[0].TenantID)
+	assert.False(t, events[0].Healthy)
+	assert.NotEmpty(t, events[1].Error)
+
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	_, err = cpm.EvictTenant(ctx, "acme")
+	require.NoError(t, err)
+	events = receiveEvents(sub)
+	assert.Equal(t, []EventType{EventPoolCreated, EventLeaseEvicted, EventPoolClosed}, eventTypes(events))
+	assert.Equal(t, dsnLabel(db.DSN()), events[0].Server)
+	assert.Equal(t, lease.Handle(), events[1].Lease)
+
+	reclaimed, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	reclaimed.SetTTL(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	cpm.reapExpiredLeases(ctx)
+	events = receiveEvents(sub)
+	assert.Equal(t, []EventType{EventPoolCreated, EventLeaseReclaimed}, eventTypes(events))
+	assert.Equal(t, reclaimed.Handle(), events[1].Lease)
+}
+
+func TestConnectionPoolManager_PoolResizedEvents(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{MaxConns: 4})
+	ctx := context.Background()
+	sub := cpm.SubscribeEvents(EventFilter{Types: []EventType{EventPoolCreated, EventPoolResized}}, false, 0)
+	defer sub.Close()
+
+	pool, err := cpm.GetConnection(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), pool.Config().MaxConns)
+
+	// Reopened with the same size, the pool is only created
+	cpm.ReleaseConnection(ctx, "acme", db.DSN())
+	_, err = cpm.GetConnection(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	events := receiveEvents(sub)
+	assert.Equal(t, []EventType{EventPoolCreated, EventPoolCreated}, eventTypes(events))
+	assert.Equal(t, int32(4), events[1].MaxConns)
+
+	// A changed config applies once the pool is opened again
+	cpm.SetTenantConfig(TenantConfig{TenantID: "acme", DSN: db.DSN(), MaxConns: 8})
+	cpm.ReleaseConnection(ctx, "acme", db.DSN())
+	pool, err = cpm.GetConnection(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	assert.Equal(t, int32(8), pool.Config().MaxConns)
+	events = receiveEvents(sub)
+	require.Equal(t, []EventType{EventPoolCreated, EventPoolResized}, eventTypes(events))
+	assert.Equal(t, dsnLabel(db.DSN()), events[1].Server)
+	assert.Equal(t, int32(8), events[1].MaxConns)
+	assert.Equal(t, int32(4), events[1].PreviousMaxConns)
+}
+
+func TestConnectionPoolManager_FailoverEvents(t *testing.T) {
+	cpm := NewConnectionPoolManager()
+	cfg := TenantConfig{
+		TenantID:    "acme",
+		DSN:         "postgres://primary:5432/acme",
+		ReplicaDSNs: []string{"postgres://replica:5432/acme"},
+	}
+	cpm.SetTenantConfig(cfg)
+	sub := cpm.SubscribeEvents(EventFilter{Types: []EventType{EventFailover}}, false, 0)
+	defer sub.Close()
+	setReplica := func(err error) {
+		cpm.stateLock.Lock()
+		cpm.replicas["acme:"+cfg.ReplicaDSNs[0]] = ReplicaStatus{DSN: cfg.ReplicaDSNs[0], Err: err}
+		cpm.stateLock.Unlock()
+		cpm.updateReadTarget(cfg)
+	}
+
+	// The first check only establishes where reads go
+	setReplica(nil)
+	assert.Empty(t, receiveEvents(sub))
+
+	setReplica(errors.New("connection refused"))
+	events := receiveEvents(sub)
+	require.Len(t, events, 1)
+	assert.Equal(t, "primary:5432/acme", events[0].Server)
+	assert.Equal(t, "replica:5432/acme", events[0].FromServer)
+
+	setReplica(errors.New("connection refused"))
+	assert.Empty(t, receiveEvents(sub))
+	setReplica(nil)
+	events = receiveEvents(sub)
+	require.Len(t, events, 1)
+	assert.Equal(t, "replica:5432/acme", events[0].Server)
+}
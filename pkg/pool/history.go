@@ -0,0 +1,218 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHistoryInterval is how often pool stats are sampled into the history
+	DefaultHistoryInterval = 10 * time.Second
+	// DefaultHistoryRetention is how far back the stats history goes
+	DefaultHistoryRetention = time.Hour
+)
+
+// ErrNoStatsHistory is returned for a tenant that has no stats samples
+var ErrNoStatsHistory = errors.New("no stats history")
+
+// StatsSample is a tenant's pools summed at one moment
+type StatsSample struct {
+	Time time.Time
+	Stats
+	MaxConns int32
+	Leases   int32
+}
+
+// StatsPoint summarises the samples of one step of a downsampled series
+type StatsPoint struct {
+	// Time is the start of the step
+	Time      time.Time
+	Samples   int
+	AvgActive float64
+	MaxActive int32
+	AvgIdle   float64
+	AvgTotal  float64
+	MaxTotal  int32
+	MaxConns  int32
+	MaxLeases int32
+}
+
+// statsHistory keeps a ring of samples per tenant
+type statsHistory struct {
+	mu       sync.Mutex
+	interval time.Duration
+	capacity int
+	rings    map[string]*statsRing
+}
+
+func newStatsHistory(interval, retention time.Duration) *statsHistory {
+	if interval <= 0 {
+		interval = DefaultHistoryInterval
+	}
+	if retention <= 0 {
+		retention = DefaultHistoryRetention
+	}
+	return &statsHistory{
+		interval: interval,
+		capacity: max(int(retention/interval), 1),
+		rings:    make(map[string]*statsRing),
+	}
+}
+
+// statsRing is a fixed-size buffer overwriting its oldest sample
+type statsRing struct {
+	samples []StatsSample
+	next    int
+	full    bool
+}
+
+func (r *statsRing) add(sample StatsSample) {
+	r.samples[r.next] = sample
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// ordered returns the samples oldest first
+func (r *statsRing) ordered() []StatsSample {
+	if !r.full {
+		return append([]StatsSample(nil), r.samples[:r.next]...)
+	}
+	return append(append([]StatsSample(nil), r.samples[r.next:]...), r.samples[:r.next]...)
+}
+
+// latest returns the newest sample
+func (r *statsRing) latest() StatsSample {
+	return r.samples[(r.next+len(r.samples)-1)%len(r.samples)]
+}
+
+// SetStatsHistory sets how often stats are sampled and how long samples are kept,
+// using the defaults for values of zero or less. Samples taken so far are dropped.
+func (cpm *ConnectionPoolManager) SetStatsHistory(interval, retention time.Duration) {
+	history := newStatsHistory(interval, retention)
+	cpm.historyLock.Lock()
+	cpm.history = history
+	cpm.historyLock.Unlock()
+
+	select {
+	case cpm.historySet <- struct{}{}:
+	default:
+	}
+}
+
+func (cpm *ConnectionPoolManager) statsHistory() *statsHistory {
+	cpm.historyLock.Lock()
+	defer cpm.historyLock.Unlock()
+	return cpm.history
+}
+
+// StartStatsHistory samples every tenant's pool stats at the history interval until
+// ctx is done. SetStatsHistory may be called before or after; sampling moves to a new
+// interval straight away.
+func (cpm *ConnectionPoolManager) StartStatsHistory(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(cpm.statsHistory().interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-cpm.historySet:
+				ticker.Reset(cpm.statsHistory().interval)
+			case now := <-ticker.C:
+				cpm.sampleStats(now)
+			}
+		}
+	}()
+}
+
+// StatsSnapshot sums the stats of each tenant's pools, keyed by tenant. Tenants without
+// open pools are left out.
+func (cpm *ConnectionPoolManager) StatsSnapshot(now time.Time) map[string]StatsSample {
+	samples := make(map[string]StatsSample)
+	for _, info := range cpm.ListPools("") {
+		sample := samples[info.TenantID]
+		sample.Time = now
+		sample.ActiveConnections += info.Stats.ActiveConnections
+		sample.IdleConnections += info.Stats.IdleConnections
+		sample.TotalConnections += info.Stats.TotalConnections
+		sample.MaxConns += info.MaxConns
+		sample.Leases += int32(info.Leases)
+		samples[info.TenantID] = sample
+	}
+	return samples
+}
+
+// sampleStats records the summed stats of every tenant with open pools. Tenants
+// without samples for a whole retention period are forgotten.
+func (cpm *ConnectionPoolManager) sampleStats(now time.Time) {
+	samples := cpm.StatsSnapshot(now)
+	history := cpm.statsHistory()
+	history.mu.Lock()
+	defer history.mu.Unlock()
+	for tenantID, sample := range samples {
+		ring, ok := history.rings[tenantID]
+		if !ok {
+			ring = &statsRing{samples: make([]StatsSample, history.capacity)}
+			history.rings[tenantID] = ring
+		}
+		ring.add(sample)
+	}
+	retention := time.Duration(history.capacity) * history.interval
+	for tenantID, ring := range history.rings {
+		if now.Sub(ring.latest().Time) > retention {
+			delete(history.rings, tenantID)
+		}
+	}
+}
+
+// StatsHistory returns the tenant's samples between start and end, summarised per step.
+// A step of zero or less uses the sampling interval. Steps without samples are left out.
+func (cpm *ConnectionPoolManager) StatsHistory(tenantID string, start, end time.Time, step time.Duration) ([]StatsPoint, error) {
+	history := cpm.statsHistory()
+	history.mu.Lock()
+	ring, ok := history.rings[tenantID]
+	var samples []StatsSample
+	if ok {
+		samples = ring.ordered()
+	}
+	interval := history.interval
+	history.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w for tenant %s", ErrNoStatsHistory, tenantID)
+	}
+	if step <= 0 {
+		step = interval
+	}
+
+	var points []StatsPoint
+	for _, sample := range samples {
+		if sample.Time.Before(start) || sample.Time.After(end) {
+			continue
+		}
+		bucket := start.Add(sample.Time.Sub(start) / step * step)
+		if len(points) == 0 || !points[len(points)-1].Time.Equal(bucket) {
+			points = append(points, StatsPoint{Time: bucket})
+		}
+		point := &points[len(points)-1]
+		point.Samples++
+		point.AvgActive += float64(sample.ActiveConnections)
+		point.AvgIdle += float64(sample.IdleConnections)
+		point.AvgTotal += float64(sample.TotalConnections)
+		point.MaxActive = max(point.MaxActive, sample.ActiveConnections)
+		point.MaxTotal = max(point.MaxTotal, sample.TotalConnections)
+		point.MaxConns = max(point.MaxConns, sample.MaxConns)
+		point.MaxLeases = max(point.MaxLeases, sample.Leases)
+	}
+	for i := range points {
+		n := float64(points[i].Samples)
+		points[i].AvgActive /= n
+		points[i].AvgIdle /= n
+		points[i].AvgTotal /= n
+	}
+	return points, nil
+}
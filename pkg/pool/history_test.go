@@ -0,0 +1,106 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsRing(t *testing.T) {
+	ring := &statsRing{samples: make([]StatsSample, 3)}
+	base := time.Unix(1700000000, 0)
+	times := func() []time.Time {
+		var out []time.Time
+		for _, sample := range ring.ordered() {
+			out = append(out, sample.Time)
+		}
+		return out
+	}
+
+	assert.Empty(t, ring.ordered())
+	for i := range 2 {
+		ring.add(StatsSample{Time: base.Add(time.Duration(i) * time.Second)})
+	}
+	assert.Equal(t, []time.Time{base, base.Add(time.Second)}, times())
+
+	// The oldest samples are overwritten once the ring is full
+	for i := 2; i < 5; i++ {
+		ring.add(StatsSample{Time: base.Add(time.Duration(i) * time.Second)})
+	}
+	assert.Equal(t, []time.Time{base.Add(2 * time.Second), base.Add(3 * time.Second), base.Add(4 * time.Second)}, times())
+	assert.Equal(t, base.Add(4*time.Second), ring.latest().Time)
+}
+
+func TestConnectionPoolManager_StatsHistory(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{})
+	cpm.SetStatsHistory(time.Second, time.Minute)
+	ctx := context.Background()
+
+	_, err := cpm.StatsHistory("acme", time.Time{}, time.Now(), 0)
+	assert.ErrorIs(t, err, ErrNoStatsHistory)
+
+	// Samples a second apart, with a lease held during the middle two
+	base := time.Now().Truncate(time.Minute)
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	require.NoError(t, cpm.ReleaseLease(ctx, lease.ID))
+	cpm.sampleStats(base)
+	lease, err = cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	cpm.sampleStats(base.Add(time.Second))
+	cpm.sampleStats(base.Add(2 * time.Second))
+	require.NoError(t, cpm.ReleaseLease(ctx, lease.ID))
+	cpm.sampleStats(base.Add(3 * time.Second))
+
+	points, err := cpm.StatsHistory("acme", base, base.Add(time.Minute), 0)
+	require.NoError(t, err)
+	require.Len(t, points, 4)
+	assert.Equal(t, int32(0), points[0].MaxActive)
+	assert.Equal(t, int32(1), points[1].MaxActive)
+	assert.Equal(t, int32(1), points[1].MaxLeases)
+	assert.Equal(t, int32(20), points[1].MaxConns)
+
+	// Downsampling averages each step and keeps its peak
+	points, err = cpm.StatsHistory("acme", base, base.Add(time.Minute), 2*time.Second)
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	assert.Equal(t, base, points[0].Time)
+	assert.Equal(t, 2, points[0].Samples)
+	assert.Equal(t, 0.5, points[0].AvgActive)
+	assert.Equal(t, int32(1), points[0].MaxActive)
+	assert.Equal(t, base.Add(2*time.Second), points[1].Time)
+
+	// The window limits the samples
+	points, err = cpm.StatsHistory("acme", base.Add(3*time.Second), base.Add(time.Minute), 0)
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, int32(0), points[0].MaxLeases)
+
+	// Tenants without pools for a whole retention period are forgotten
+	cpm.ReleaseConnection(ctx, "acme", db.DSN())
+	cpm.sampleStats(base.Add(2 * time.Minute))
+	_, err = cpm.StatsHistory("acme", time.Time{}, time.Now(), 0)
+	assert.ErrorIs(t, err, ErrNoStatsHistory)
+}
+
+func TestConnectionPoolManager_StartStatsHistory(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseLease(context.Background(), lease.ID)
+
+	// The interval set after sampling started is used straight away
+	start := time.Now()
+	cpm.StartStatsHistory(ctx)
+	cpm.SetStatsHistory(10*time.Millisecond, time.Minute)
+	assert.Eventually(t, func() bool {
+		points, err := cpm.StatsHistory("acme", start, time.Now(), 0)
+		return err == nil && len(points) >= 2
+	}, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,474 @@
+package pool
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PoolMode controls how long a client holds a backend connection
+type PoolMode string
+
+const (
+	// PoolModeSession holds a backend for the whole lease
+	PoolModeSession PoolMode = "session"
+	// PoolModeTransaction returns the backend when a transaction ends
+	PoolModeTransaction PoolMode = "transaction"
+	// PoolModeStatement returns the backend after every statement
+	PoolModeStatement PoolMode = "statement"
+)
+
+// DefaultResetQuery clears session state before a backend is reused
+const DefaultResetQuery = "DISCARD ALL"
+
+const defaultResetTimeout = 5 * time.Second
+
+// BackendState describes what a client left a backend in when it is detached
+type BackendState int
+
+const (
+	// BackendIdle is a backend between transactions
+	BackendIdle BackendState = iota
+	// BackendInTransaction is a backend that may still have a transaction open
+	BackendInTransaction
+	// BackendBroken is a backend in an unknown protocol state; it is always destroyed
+	BackendBroken
+)
+
+// ErrTransactionInStatementMode is returned when a statement leaves a transaction open
+// on a lease in statement mode
+var ErrTransactionInStatementMode = errors.New("transactions are not allowed in statement pooling mode")
+
+// ErrLeaseNotFound is returned for a lease ID that is unknown or already released. The
+// errors name the lease by its handle, since they end up in request logs.
+var ErrLeaseNotFound = errors.New("lease not found")
+
+// ParsePoolMode parses a pool mode name. An empty name means session mode.
+func ParsePoolMode(s string) (PoolMode, error) {
+	switch PoolMode(s) {
+	case "", PoolModeSession:
+		return PoolModeSession, nil
+	case PoolModeTransaction, PoolModeStatement:
+		return PoolMode(s), nil
+	default:
+		return "", fmt.Errorf("unknown pool mode %q", s)
+	}
+}
+
+// Param is a statement parameter
+type Param struct {
+	// Value is the parameter in text format, or nil for NULL
+	Value []byte
+	// Raw marks bytes passed through as they are. They are sent as binary when the
+	// statement's parameter is bytea, and as text otherwise, so a bytea parameter
+	// takes any bytes while a text or json one takes them unchanged.
+	Raw bool
+}
+
+// QueryResult is the outcome of a statement run through a lease
+type QueryResult struct {
+	Columns []string
+	// ColumnTypes holds each column's type OID
+	ColumnTypes []uint32
+	// Rows hold values in text format, or nil for NULL
+	Rows       [][][]byte
+	CommandTag string
+}
+
+// Lease is a client's claim on a tenant pool. Depending on the pool mode the lease
+// holds a backend connection for its whole life or only while work is in progress.
+type Lease struct {
+	ID         string
+	TenantID   string
+	DSN        string
+	Mode       PoolMode
+	AcquiredAt time.Time
+	// Client identifies who acquired the lease, for diagnosing leaks
+	Client ClientInfo
+
+	pool    *pgxpool.Pool
+	reset   ResetPolicy
+	session SessionInit
+	// allowCall checks the tenant's breaker before a backend is acquired
+	allowCall func(ctx context.Context) (func(breakerOutcome), error)
+
+	// execMu serialises statements run through Exec
+	execMu sync.Mutex
+
+	mu       sync.Mutex
+	conn     *pgxpool.Conn
+	released bool
+	onEvict  func()
+	// expiresAt is when the reaper reclaims the lease. Zero means never.
+	expiresAt time.Time
+	// lastActive is when the lease last ran a statement, in Unix nanoseconds
+	lastActive atomic.Int64
+	// warned is set once the lease was reported as held too long, under the manager's
+	// leaseLock
+	warned bool
+	// work is what the watchdog tracks of the statements running on the backend
+	work workState
+}
+
+// AcquireLease creates a lease on the tenant's pool for dsn. Session mode leases
+// attach a backend straight away so the caller holds it until release.
+func (cpm *ConnectionPoolManager) AcquireLease(ctx context.Context, tenantID, dsn string) (*Lease, error) {
+	ctx, span := startSpan(ctx, "pool.AcquireLease", tenantID)
+	lease, err := cpm.acquireLease(ctx, tenantID, dsn)
+	if lease != nil {
+		span.SetAttributes(attribute.String("lease.handle", lease.Handle()), attribute.String("pool.mode", string(lease.Mode)))
+	}
+	endSpan(span, err)
+	return lease, err
+}
+
+func (cpm *ConnectionPoolManager) acquireLease(ctx context.Context, tenantID, dsn string) (*Lease, error) {
+	if cpm.isDraining(tenantID) {
+		return nil, fmt.Errorf("%w: %s", ErrTenantDraining, tenantID)
+	}
+	pool, err := cpm.GetConnection(ctx, tenantID, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, _ := cpm.TenantConfig(tenantID)
+	mode, err := ParsePoolMode(string(cfg.PoolMode))
+	if err != nil {
+		return nil, err
+	}
+	reset := cfg.Reset
+	if reset.Query == "" {
+		reset.Query = DefaultResetQuery
+	}
+	if reset.Timeout.Duration <= 0 {
+		reset.Timeout.Duration = defaultResetTimeout
+	}
+
+	lease := &Lease{
+		TenantID:   tenantID,
+		DSN:        dsn,
+		Mode:       mode,
+		AcquiredAt: time.Now(),
+		Client:     clientFromContext(ctx),
+		pool:       pool,
+		reset:      reset,
+		session:    cfg.Session,
+		allowCall: func(ctx context.Context) (func(breakerOutcome), error) {
+			return cpm.allowCall(ctx, tenantID, dsn)
+		},
+	}
+	lease.Touch()
+	lease.SetTTL(cpm.LeaseTTL(tenantID))
+	if mode == PoolModeSession {
+		if _, err := lease.Attach(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// The ID is all a caller needs to use the lease, so it must not be guessable
+	lease.ID = "conn-" + tenantID + "-" + rand.Text()
+	cpm.leaseLock.Lock()
+	cpm.leases[lease.ID] = lease
+	cpm.leaseLock.Unlock()
+
+	leaseClients.WithLabelValues(tenantID, string(mode)).Inc()
+	return lease, nil
+}
+
+// Lease returns an active lease by ID
+func (cpm *ConnectionPoolManager) Lease(id string) (*Lease, bool) {
+	cpm.leaseLock.Lock()
+	defer cpm.leaseLock.Unlock()
+	lease, exists := cpm.leases[id]
+	return lease, exists
+}
+
+// TenantLease returns an active lease by ID if it belongs to the tenant
+func (cpm *ConnectionPoolManager) TenantLease(tenantID, id string) (*Lease, bool) {
+	lease, exists := cpm.Lease(id)
+	if !exists || lease.TenantID != tenantID {
+		return nil, false
+	}
+	return lease, true
+}
+
+// ReleaseLease ends a lease and returns its backend, if any, to the pool
+func (cpm *ConnectionPoolManager) ReleaseLease(ctx context.Context, id string) error {
+	cpm.leaseLock.Lock()
+	lease, exists := cpm.leases[id]
+	delete(cpm.leases, id)
+	cpm.leaseLock.Unlock()
+
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrLeaseNotFound, LeaseHandle(id))
+	}
+	lease.releaseExclusive(ctx, BackendIdle)
+	return nil
+}
+
+// releaseExclusive releases the lease once no statement runs through Exec, so the
+// backend is not reset and returned while Exec still uses it. A running statement is
+// cancelled first.
+func (l *Lease) releaseExclusive(ctx context.Context, state BackendState) {
+	if !l.execMu.TryLock() {
+		l.cancelQuery(ctx)
+		l.execMu.Lock()
+	}
+	defer l.execMu.Unlock()
+	l.release(ctx, state)
+}
+
+// release ends the lease, detaching its backend in the given state
+func (l *Lease) release(ctx context.Context, state BackendState) {
+	l.mu.Lock()
+	if l.released {
+		l.mu.Unlock()
+		return
+	}
+	l.released = true
+	l.mu.Unlock()
+
+	l.Detach(ctx, state)
+	leaseClients.WithLabelValues(l.TenantID, string(l.Mode)).Dec()
+}
+
+// OnEvict registers fn to be called when the lease's tenant is evicted. The owner is
+// then expected to release the lease promptly; leases without a handler are released
+// by the eviction itself.
+func (l *Lease) OnEvict(fn func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onEvict = fn
+}
+
+// evict calls the lease's eviction handler and reports whether it had one
+func (l *Lease) evict() bool {
+	l.mu.Lock()
+	fn := l.onEvict
+	l.mu.Unlock()
+	if fn == nil {
+		return false
+	}
+	fn()
+	return true
+}
+
+// Attach returns the lease's backend connection, acquiring one from the pool if the
+// lease has none. Acquiring goes through the tenant's breaker for the host.
+func (l *Lease) Attach(ctx context.Context) (*pgxpool.Conn, error) {
+	l.Touch()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn != nil {
+		return l.conn, nil
+	}
+
+	report := func(breakerOutcome) {}
+	if l.allowCall != nil {
+		var err error
+		if report, err = l.allowCall(ctx); err != nil {
+			return nil, err
+		}
+	}
+	conn, err := l.pool.Acquire(ctx)
+	report(classifyConnect(err))
+	if err != nil {
+		return nil, err
+	}
+	l.conn = conn
+	attachedBackends.WithLabelValues(l.TenantID).Inc()
+	return conn, nil
+}
+
+// Attached reports whether the lease currently holds a backend
+func (l *Lease) Attached() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.conn != nil
+}
+
+// Detach returns the lease's backend to the pool after running the tenant's reset
+// policy. A broken backend, or one that fails the reset, is destroyed instead.
+func (l *Lease) Detach(ctx context.Context, state BackendState) {
+	l.mu.Lock()
+	conn := l.conn
+	l.conn = nil
+	l.work = workState{}
+	l.mu.Unlock()
+	if conn == nil {
+		return
+	}
+	attachedBackends.WithLabelValues(l.TenantID).Dec()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), l.reset.Timeout.Duration)
+	defer cancel()
+
+	pgConn := conn.Conn().PgConn()
+	err := errBackendBroken
+	if state != BackendBroken && !pgConn.IsClosed() {
+		err = l.resetBackend(ctx, conn, state == BackendInTransaction || pgConn.TxStatus() != 'I')
+	}
+	if err != nil {
+		if err != errBackendBroken {
+			ctxLog(ctx).Warn().Err(err).Str("tenant_id", l.TenantID).Uint32("backend_pid", pgConn.PID()).Msg("Failed to reset backend, destroying it")
+		}
+		backendResets.WithLabelValues(l.TenantID, "destroyed").Inc()
+		// Closed connections are destroyed by the pool on release
+		pgConn.Close(ctx)
+	} else {
+		backendResets.WithLabelValues(l.TenantID, "reset").Inc()
+	}
+	conn.Release()
+}
+
+var errBackendBroken = errors.New("backend is broken")
+
+// resetBackend clears the state a client may have left on the backend: an open
+// transaction and session settings. The tenant's session init is then applied again
+// and, if the policy asks, the backend is checked to be healthy.
+func (l *Lease) resetBackend(ctx context.Context, conn *pgxpool.Conn, inTransaction bool) error {
+	pgConn := conn.Conn().PgConn()
+	if inTransaction {
+		if _, err := pgConn.Exec(ctx, "ROLLBACK").ReadAll(); err != nil {
+			return fmt.Errorf("rollback: %w", err)
+		}
+	}
+	if _, err := pgConn.Exec(ctx, l.reset.Query).ReadAll(); err != nil {
+		return fmt.Errorf("reset query: %w", err)
+	}
+	if err := l.session.apply(ctx, conn.Conn(), false); err != nil {
+		return err
+	}
+	// The reset may have dropped prepared statements pgx has cached for this connection
+	if err := conn.Conn().DeallocateAll(ctx); err != nil {
+		return fmt.Errorf("deallocate: %w", err)
+	}
+
+	if l.reset.CheckHealth {
+		results, err := pgConn.Exec(ctx, "SELECT pg_backend_pid()").ReadAll()
+		if err != nil {
+			return fmt.Errorf("health check: %w", err)
+		}
+		if len(results) != 1 || len(results[0].Rows) != 1 || string(results[0].Rows[0][0]) != fmt.Sprint(pgConn.PID()) {
+			return fmt.Errorf("health check: backend pid does not match %d", pgConn.PID())
+		}
+	}
+	return nil
+}
+
+// Exec runs a statement on the lease's backend and returns it to the pool as the pool
+// mode requires. Without params the simple protocol is used and sql may hold several
+// statements; the result of the last one is returned.
+func (l *Lease) Exec(ctx context.Context, sql string, params []Param) (*QueryResult, error) {
+	l.execMu.Lock()
+	defer l.execMu.Unlock()
+
+	l.mu.Lock()
+	released := l.released
+	l.mu.Unlock()
+	if released {
+		return nil, fmt.Errorf("%w: %s has been released", ErrLeaseNotFound, l.Handle())
+	}
+
+	conn, err := l.Attach(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pgConn := conn.Conn().PgConn()
+
+	// Statements bypass pgx's query methods, so the connection's tracer is called directly
+	tracer := conn.Conn().Config().Tracer
+	if tracer != nil {
+		ctx = tracer.TraceQueryStart(ctx, conn.Conn(), pgx.TraceQueryStartData{SQL: sql})
+	}
+	l.StatementStarted()
+	result, err := execStatement(ctx, pgConn, sql, params)
+	l.StatementFinished(pgConn.TxStatus() == 'I')
+	if tracer != nil {
+		end := pgx.TraceQueryEndData{Err: err}
+		if result != nil {
+			end.CommandTag = pgconn.NewCommandTag(result.CommandTag)
+		}
+		tracer.TraceQueryEnd(ctx, conn.Conn(), end)
+	}
+
+	switch {
+	case pgConn.IsClosed():
+		l.Detach(ctx, BackendBroken)
+	case l.Mode == PoolModeStatement && pgConn.TxStatus() != 'I':
+		l.Detach(ctx, BackendInTransaction)
+		return nil, ErrTransactionInStatementMode
+	case l.Mode != PoolModeSession && pgConn.TxStatus() == 'I':
+		l.Detach(ctx, BackendIdle)
+	}
+	return result, err
+}
+
+func execStatement(ctx context.Context, pgConn *pgconn.PgConn, sql string, params []Param) (*QueryResult, error) {
+	if len(params) == 0 {
+		results, err := pgConn.Exec(ctx, sql).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		if len(results) == 0 {
+			return &QueryResult{}, nil
+		}
+		return newQueryResult(results[len(results)-1]), nil
+	}
+
+	values := make([][]byte, len(params))
+	raw := false
+	for i, param := range params {
+		values[i] = param.Value
+		raw = raw || param.Raw
+	}
+	if !raw {
+		result := pgConn.ExecParams(ctx, sql, values, nil, nil, nil).Read()
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return newQueryResult(result), nil
+	}
+
+	// Raw bytes need the statement's parameter types to know which go in binary
+	statement, err := pgConn.Prepare(ctx, "", sql, nil)
+	if err != nil {
+		return nil, err
+	}
+	formats := make([]int16, len(params))
+	for i, param := range params {
+		if param.Raw && i < len(statement.ParamOIDs) && statement.ParamOIDs[i] == pgtype.ByteaOID {
+			formats[i] = pgtype.BinaryFormatCode
+		}
+	}
+	result := pgConn.ExecPrepared(ctx, "", values, formats, nil).Read()
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return newQueryResult(result), nil
+}
+
+func newQueryResult(result *pgconn.Result) *QueryResult {
+	columns := make([]string, len(result.FieldDescriptions))
+	types := make([]uint32, len(result.FieldDescriptions))
+	for i, field := range result.FieldDescriptions {
+		columns[i] = field.Name
+		types[i] = field.DataTypeOID
+	}
+	return &QueryResult{
+		Columns:     columns,
+		ColumnTypes: types,
+		Rows:        result.Rows,
+		CommandTag:  result.CommandTag.String(),
+	}
+}
@@ -0,0 +1,282 @@
+package pool
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/pgtest"
+)
+
+// setupLeaseTenant starts a fake database and registers tenant "acme" on it
+func setupLeaseTenant(t *testing.T, cfg TenantConfig) (*ConnectionPoolManager, *pgtest.Server) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+
+	cpm := NewConnectionPoolManager()
+	cfg.TenantID = "acme"
+	cfg.DSN = db.DSN()
+	cpm.SetTenantConfig(cfg)
+
+	t.Cleanup(func() {
+		cpm.ReleaseConnection(context.Background(), "acme", db.DSN())
+		db.Close()
+	})
+	return cpm, db
+}
+
+func countQueries(db *pgtest.Server, query string) int {
+	count := 0
+	for _, q := range db.Queries() {
+		if q == query {
+			count++
+		}
+	}
+	return count
+}
+
+func TestParsePoolMode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    PoolMode
+		wantErr bool
+	}{
+		{input: "", want: PoolModeSession},
+		{input: "session", want: PoolModeSession},
+		{input: "transaction", want: PoolModeTransaction},
+		{input: "statement", want: PoolModeStatement},
+		{input: "connection", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			mode, err := ParsePoolMode(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, mode)
+			}
+		})
+	}
+}
+
+func TestLease_SessionModeHoldsBackend(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{})
+	ctx := context.Background()
+
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	assert.Equal(t, PoolModeSession, lease.Mode)
+	assert.True(t, lease.Attached())
+
+	result, err := lease.Exec(ctx, "SELECT pg_backend_pid()", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pg_backend_pid"}, result.Columns)
+	require.Len(t, result.Rows, 1)
+	assert.True(t, lease.Attached())
+
+	found, ok := cpm.Lease(lease.ID)
+	assert.True(t, ok)
+	assert.Equal(t, lease, found)
+
+	require.NoError(t, cpm.ReleaseLease(ctx, lease.ID))
+	assert.False(t, lease.Attached())
+	assert.Equal(t, 1, countQueries(db, DefaultResetQuery))
+
+	_, ok = cpm.Lease(lease.ID)
+	assert.False(t, ok)
+	assert.Error(t, cpm.ReleaseLease(ctx, lease.ID))
+
+	_, err = lease.Exec(ctx, "SELECT 1", nil)
+	assert.Error(t, err)
+}
+
+func TestLease_TransactionModeReturnsBackendAtCommit(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{PoolMode: PoolModeTransaction, Reset: ResetPolicy{Query: "RESET ALL"}})
+	ctx := context.Background()
+
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseLease(ctx, lease.ID)
+	assert.False(t, lease.Attached())
+
+	_, err = lease.Exec(ctx, "BEGIN", nil)
+	require.NoError(t, err)
+	assert.True(t, lease.Attached())
+
+	_, err = lease.Exec(ctx, "SELECT 1", nil)
+	require.NoError(t, err)
+	assert.True(t, lease.Attached())
+
+	_, err = lease.Exec(ctx, "COMMIT", nil)
+	require.NoError(t, err)
+	assert.False(t, lease.Attached())
+	assert.Equal(t, 1, countQueries(db, "RESET ALL"))
+	assert.Equal(t, 0, countQueries(db, DefaultResetQuery))
+}
+
+func TestLease_StatementMode(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{PoolMode: PoolModeStatement})
+	ctx := context.Background()
+
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseLease(ctx, lease.ID)
+
+	_, err = lease.Exec(ctx, "SELECT 1", nil)
+	require.NoError(t, err)
+	assert.False(t, lease.Attached())
+	assert.Equal(t, 1, countQueries(db, DefaultResetQuery))
+
+	_, err = lease.Exec(ctx, "BEGIN", nil)
+	assert.ErrorIs(t, err, ErrTransactionInStatementMode)
+	assert.False(t, lease.Attached())
+	assert.Equal(t, 1, countQueries(db, "ROLLBACK"))
+}
+
+func TestLease_ReleaseRollsBackOpenTransaction(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{})
+	ctx := context.Background()
+	destroyed := testutil.ToFloat64(backendResets.WithLabelValues("acme", "destroyed"))
+
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	_, err = lease.Exec(ctx, "BEGIN", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, cpm.ReleaseLease(ctx, lease.ID))
+	assert.Equal(t, 1, countQueries(db, "ROLLBACK"))
+	assert.Equal(t, 1, countQueries(db, DefaultResetQuery))
+	assert.Equal(t, destroyed, testutil.ToFloat64(backendResets.WithLabelValues("acme", "destroyed")))
+}
+
+func TestLease_ResetFailureDestroysBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		reset   ResetPolicy
+		handler pgtest.Handler
+	}{
+		{
+			name:  "Reset query fails",
+			reset: ResetPolicy{Query: "RESET ALL"},
+			handler: func(pid uint32, query string) (pgtest.Result, bool) {
+				if query == "RESET ALL" {
+					return pgtest.Result{Err: "reset failed"}, true
+				}
+				return pgtest.Result{}, false
+			},
+		},
+		{
+			name:  "Health check fails",
+			reset: ResetPolicy{CheckHealth: true},
+			handler: func(pid uint32, query string) (pgtest.Result, bool) {
+				if query == "SELECT pg_backend_pid()" {
+					return pgtest.Result{Columns: []string{"pg_backend_pid"}, Rows: [][]string{{"0"}}, Tag: "SELECT 1"}, true
+				}
+				return pgtest.Result{}, false
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpm, db := setupLeaseTenant(t, TenantConfig{Reset: tt.reset})
+			ctx := context.Background()
+			destroyed := testutil.ToFloat64(backendResets.WithLabelValues("acme", "destroyed"))
+
+			lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+			require.NoError(t, err)
+			db.SetHandler(tt.handler)
+
+			require.NoError(t, cpm.ReleaseLease(ctx, lease.ID))
+			assert.Equal(t, destroyed+1, testutil.ToFloat64(backendResets.WithLabelValues("acme", "destroyed")))
+		})
+	}
+}
+
+func TestLease_HealthCheckPasses(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{Reset: ResetPolicy{CheckHealth: true}})
+	ctx := context.Background()
+	reset := testutil.ToFloat64(backendResets.WithLabelValues("acme", "reset"))
+
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	require.NoError(t, cpm.ReleaseLease(ctx, lease.ID))
+	assert.Equal(t, 1, countQueries(db, "SELECT pg_backend_pid()"))
+	assert.Equal(t, reset+1, testutil.ToFloat64(backendResets.WithLabelValues("acme", "reset")))
+}
+
+func TestConnectionPoolManager_AcquireLease_InvalidDSN(t *testing.T) {
+	cpm := NewConnectionPoolManager()
+
+	lease, err := cpm.AcquireLease(context.Background(), "tenant1", "invalid-dsn")
+	assert.Error(t, err)
+	assert.Nil(t, lease)
+}
+
+func TestConnectionPoolManager_ReleaseLeaseDuringExec(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{})
+	ctx := context.Background()
+
+	// The statement runs until its backend is sent a cancel request
+	db.SetHandler(func(pid uint32, query string) (pgtest.Result, bool) {
+		if query != "SELECT pg_sleep(3600)" {
+			return pgtest.Result{}, false
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for !slices.Contains(db.CancelRequests(), pid) && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		return pgtest.Result{Err: "canceling statement due to user request"}, true
+	})
+
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() {
+		_, err := lease.Exec(ctx, "SELECT pg_sleep(3600)", nil)
+		done <- err
+	}()
+	require.Eventually(t, func() bool { return countQueries(db, "SELECT pg_sleep(3600)") == 1 }, time.Second, 10*time.Millisecond)
+
+	// Releasing waits for the statement, cancelling it, rather than resetting the
+	// backend under it
+	require.NoError(t, cpm.ReleaseLease(ctx, lease.ID))
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "canceling statement")
+	case <-time.After(time.Second):
+		t.Fatal("query was not cancelled")
+	}
+	assert.False(t, lease.Attached())
+	assert.Equal(t, 1, countQueries(db, DefaultResetQuery))
+}
+
+func TestConnectionPoolManager_TenantLease(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{})
+	ctx := context.Background()
+
+	first, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseLease(ctx, first.ID)
+	second, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseLease(ctx, second.ID)
+
+	// IDs carry 128 random bits rather than the time they were taken
+	assert.Regexp(t, `^conn-acme-[A-Z2-7]{26}$`, first.ID)
+	assert.NotEqual(t, first.ID, second.ID)
+
+	lease, ok := cpm.TenantLease("acme", first.ID)
+	assert.True(t, ok)
+	assert.Same(t, first, lease)
+	_, ok = cpm.TenantLease("", first.ID)
+	assert.False(t, ok)
+	_, ok = cpm.TenantLease("globex", first.ID)
+	assert.False(t, ok)
+}
@@ -0,0 +1,17 @@
+package pool
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// ctxLog returns the request logger carried by ctx, falling back to the global logger
+// so the package still logs when callers have not set one up
+func ctxLog(ctx context.Context) *zerolog.Logger {
+	if logger := zerolog.Ctx(ctx); logger.GetLevel() != zerolog.Disabled {
+		return logger
+	}
+	return &log.Logger
+}
@@ -0,0 +1,79 @@
+package pool
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	replicaLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pool_replica_lag_seconds",
+		Help: "Replay lag of each tenant replica at the last check",
+	}, []string{"tenant_id", "replica"})
+
+	leaseClients = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pool_clients",
+		Help: "Lease and proxy clients per tenant and pool mode",
+	}, []string{"tenant_id", "mode"})
+
+	attachedBackends = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pool_backends_attached",
+		Help: "Backend connections currently held by clients",
+	}, []string{"tenant_id"})
+
+	backendResets = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pool_backend_returns_total",
+		Help: "Backends returned by clients, by whether they were reset or destroyed",
+	}, []string{"tenant_id", "result"})
+
+	leaseLeaks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pool_lease_leaks_total",
+		Help: "Leases reclaimed because they expired without being released",
+	}, []string{"tenant_id"})
+
+	watchdogKills = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pool_watchdog_kills_total",
+		Help: "Backends the watchdog cancelled or terminated for exceeding a limit",
+	}, []string{"tenant_id", "action", "reason"})
+
+	eventsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pool_events_total",
+		Help: "Pool lifecycle events published, by type",
+	}, []string{"type"})
+
+	eventsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pool_events_dropped_total",
+		Help: "Events not delivered to a subscriber that fell behind",
+	})
+
+	breakerStates = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pool_circuit_breaker_state",
+		Help: "Circuit breaker state per tenant and database host: 0 closed, 1 half open, 2 open",
+	}, []string{"tenant_id", "host"})
+
+	breakerRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pool_circuit_breaker_rejections_total",
+		Help: "Calls failed straight away by an open circuit breaker",
+	}, []string{"tenant_id", "host"})
+
+	poolCreationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pool_creation_failures_total",
+		Help: "Failed pool creation attempts, by tenant and reason",
+	}, []string{"tenant_id", "reason"})
+
+	creationQueueLength = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pool_creation_queue_length",
+		Help: "Pool creations waiting for a slot, by database host",
+	}, []string{"host"})
+
+	creationsInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pool_creations_in_flight",
+		Help: "Pool creations holding a slot, by database host",
+	}, []string{"host"})
+
+	creationQueueWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "pool_creation_queue_wait_seconds",
+		Help:    "Time pool creations waited for a slot",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+	})
+)
@@ -2,51 +2,287 @@ package pool
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// ErrPoolNotFound is returned when a tenant has no open pool
+var ErrPoolNotFound = errors.New("pool not found")
+
 type ConnectionPoolManager struct {
 	pools     map[string]*pgxpool.Pool
 	poolLocks sync.Mutex
+	// creating holds the pools being created and failedCreations the recent failures,
+	// both by pool key and guarded by poolLocks
+	creating        map[string]*creation
+	failedCreations map[string]cachedFailure
+	// poolSizes is the size of the last pool opened per pool key, guarded by poolLocks
+	poolSizes map[string]int32
+
+	tenants  map[string]TenantConfig
+	replicas map[string]ReplicaStatus
+	// readTargets is the DSN each tenant with replicas reads from as of the last check
+	readTargets map[string]string
+	failures    map[string]PoolFailure
+	draining    map[string]bool
+	dsnPolicy   DSNPolicy
+	stateLock   sync.RWMutex
+
+	leases      map[string]*Lease
+	leaseTTL    time.Duration
+	holdWarning time.Duration
+	leaseLock   sync.Mutex
+
+	history     *statsHistory
+	historyLock sync.Mutex
+	// historySet wakes the sampler when SetStatsHistory changes the interval
+	historySet chan struct{}
+
+	events    *eventBus
+	breakers  breakers
+	scheduler *creationScheduler
 }
 
 func NewConnectionPoolManager() *ConnectionPoolManager {
 	return &ConnectionPoolManager{
-		pools: make(map[string]*pgxpool.Pool),
+		pools:       make(map[string]*pgxpool.Pool),
+		tenants:     make(map[string]TenantConfig),
+		replicas:    make(map[string]ReplicaStatus),
+		readTargets: make(map[string]string),
+		failures:    make(map[string]PoolFailure),
+		draining:    make(map[string]bool),
+		leases:      make(map[string]*Lease),
+		leaseTTL:    DefaultLeaseTTL,
+
+		holdWarning: DefaultHoldWarning,
+		history:     newStatsHistory(DefaultHistoryInterval, DefaultHistoryRetention),
+		historySet:  make(chan struct{}, 1),
+		events:      newEventBus(DefaultEventBuffer),
+		breakers:    breakers{byTenant: make(map[string]map[string]*breaker)},
+
+		creating:        make(map[string]*creation),
+		failedCreations: make(map[string]cachedFailure),
+		poolSizes:       make(map[string]int32),
+		scheduler:       newCreationScheduler(),
 	}
 }
 
+// SetTenantConfig registers or replaces the config for a tenant
+func (cpm *ConnectionPoolManager) SetTenantConfig(cfg TenantConfig) {
+	cpm.stateLock.Lock()
+	defer cpm.stateLock.Unlock()
+	cpm.tenants[cfg.TenantID] = cfg
+}
+
+// TenantConfig returns the config registered for a tenant
+func (cpm *ConnectionPoolManager) TenantConfig(tenantID string) (TenantConfig, bool) {
+	cpm.stateLock.RLock()
+	defer cpm.stateLock.RUnlock()
+	cfg, ok := cpm.tenants[tenantID]
+	return cfg, ok
+}
+
+// TenantConfigs returns all registered tenant configs
+func (cpm *ConnectionPoolManager) TenantConfigs() []TenantConfig {
+	cpm.stateLock.RLock()
+	defer cpm.stateLock.RUnlock()
+	configs := make([]TenantConfig, 0, len(cpm.tenants))
+	for _, cfg := range cpm.tenants {
+		configs = append(configs, cfg)
+	}
+	return configs
+}
+
 func (cpm *ConnectionPoolManager) GetConnection(ctx context.Context, tenantID, dsn string) (*pgxpool.Pool, error) {
-	cpm.poolLocks.Lock()
-	defer cpm.poolLocks.Unlock()
+	ctx, span := startSpan(ctx, "pool.GetConnection", tenantID, attribute.String("server.address", dsnLabel(dsn)))
+	pool, err := cpm.getConnection(ctx, tenantID, dsn)
+	endSpan(span, err)
+	return pool, err
+}
 
+// getConnection returns the tenant's pool for the DSN, creating it if needed. Callers
+// asking for a pool being created wait for that creation instead of starting another,
+// and a recently failed creation is returned again until it expires.
+func (cpm *ConnectionPoolManager) getConnection(ctx context.Context, tenantID, dsn string) (*pgxpool.Pool, error) {
 	key := tenantID + ":" + dsn
-	if pool, exists := cpm.pools[key]; exists {
-		return pool, nil
+	for {
+		cpm.poolLocks.Lock()
+		if pool, exists := cpm.pools[key]; exists {
+			cpm.poolLocks.Unlock()
+			return pool, nil
+		}
+		if failure, exists := cpm.failedCreations[key]; exists {
+			if time.Now().Before(failure.expires) {
+				cpm.poolLocks.Unlock()
+				return nil, failure.err
+			}
+			delete(cpm.failedCreations, key)
+		}
+		c, exists := cpm.creating[key]
+		if !exists {
+			c = &creation{done: make(chan struct{})}
+			cpm.creating[key] = c
+			cpm.poolLocks.Unlock()
+			return cpm.create(ctx, c, key, tenantID, dsn)
+		}
+		cpm.poolLocks.Unlock()
+
+		select {
+		case <-c.done:
+			if c.err == nil {
+				return c.pool, nil
+			}
+			// Look again: the failure is cached, or else worth trying ourselves
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// create opens the pool for a creation registered under key and hands the outcome to
+// everyone waiting on it
+func (cpm *ConnectionPoolManager) create(ctx context.Context, c *creation, key, tenantID, dsn string) (*pgxpool.Pool, error) {
+	pool, err := cpm.createChecked(ctx, tenantID, dsn)
+
+	var previousSize, size int32
+	cpm.poolLocks.Lock()
+	delete(cpm.creating, key)
+	if err == nil {
+		cpm.pools[key] = pool
+		size = pool.Config().MaxConns
+		previousSize = cpm.poolSizes[key]
+		cpm.poolSizes[key] = size
+	} else {
+		cpm.cacheFailure(ctx, key, tenantID, err)
 	}
+	c.pool, c.err = pool, err
+	cpm.poolLocks.Unlock()
+	close(c.done)
 
+	cpm.recordCreation(ctx, tenantID, dsn, err)
+	if err != nil {
+		cpm.publish(Event{Type: EventCreationFailed, TenantID: tenantID, Server: dsnLabel(dsn), Error: err.Error()})
+		return nil, err
+	}
+	ctxLog(ctx).Info().Str("tenant_id", tenantID).Msg("Created new connection pool")
+	cpm.publish(Event{Type: EventPoolCreated, TenantID: tenantID, Server: dsnLabel(dsn), MaxConns: size})
+	// A pool opened again with another size, e.g. after its tenant's config changed
+	if previousSize != 0 && previousSize != size {
+		cpm.publish(Event{Type: EventPoolResized, TenantID: tenantID, Server: dsnLabel(dsn), MaxConns: size, PreviousMaxConns: previousSize})
+	}
+	return pool, nil
+}
+
+// createChecked opens a pool if the DSN policy and the tenant's breaker for the host allow it
+func (cpm *ConnectionPoolManager) createChecked(ctx context.Context, tenantID, dsn string) (*pgxpool.Pool, error) {
+	if err := cpm.checkDSNPolicy(dsn); err != nil {
+		return nil, err
+	}
+	report, err := cpm.allowCall(ctx, tenantID, dsn)
+	if err != nil {
+		return nil, err
+	}
+	ctx, span := startSpan(ctx, "pool.Create", tenantID)
+	pool, err := cpm.openPool(ctx, tenantID, dsn)
+	endSpan(span, err)
+	// An unverified pool connects lazily, so its creation says nothing about the host.
+	// Its connects are counted as leases attach backends.
+	cfg, _ := cpm.TenantConfig(tenantID)
+	if err == nil && !cfg.Creation.Verify {
+		report(outcomeIgnored)
+	} else {
+		report(classifyOutcome(err))
+	}
+	return pool, err
+}
+
+// defaultMaxConns is the size of a pool when the tenant's config sets none
+const defaultMaxConns = 20
+
+func (cpm *ConnectionPoolManager) createPool(ctx context.Context, tenantID, dsn string, slot *creationSlot) (*pgxpool.Pool, error) {
 	config, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, err
 	}
-	config.MaxConns = 20
+	config.MaxConns = defaultMaxConns
 	config.MinConns = 5
 	config.MaxConnLifetime = 30 * time.Minute
 	config.MaxConnIdleTime = 5 * time.Minute
+	cfg, exists := cpm.TenantConfig(tenantID)
+	if cfg.MaxConns > 0 {
+		config.MaxConns = cfg.MaxConns
+		config.MinConns = min(config.MinConns, cfg.MaxConns)
+	}
+	config.ConnConfig.Tracer = queryTracer{tenantID: tenantID, server: dsnLabel(dsn), warmup: slot.track(config.MinConns)}
+	if exists {
+		session := cfg.Session
+		config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+			return session.apply(ctx, conn, true)
+		}
+	}
+
+	return pgxpool.NewWithConfig(ctx, config)
+}
+
+// PoolFailure is the last failed attempt to create a pool for a tenant
+type PoolFailure struct {
+	DSN string
+	Err error
+	At  time.Time
+}
 
-	pool, err := pgxpool.NewWithConfig(ctx, config)
+// recordCreation remembers a failed pool creation for the tenant, or forgets an
+// earlier failure once a pool is created. A tenant starting or stopping failing
+// publishes a health change. Only failures of the tenant's database count, since the
+// failures feed readiness and a caller must not be able to fail it with a bad DSN.
+func (cpm *ConnectionPoolManager) recordCreation(ctx context.Context, tenantID, dsn string, err error) {
+	if err != nil && !cpm.databaseFailure(ctx, tenantID, err) {
+		return
+	}
+	cpm.stateLock.Lock()
+	_, failing := cpm.failures[tenantID]
 	if err != nil {
-		return nil, err
+		cpm.failures[tenantID] = PoolFailure{DSN: dsnLabel(dsn), Err: err, At: time.Now()}
+	} else {
+		delete(cpm.failures, tenantID)
 	}
+	cpm.stateLock.Unlock()
 
-	cpm.pools[key] = pool
-	log.Info().Str("tenant_id", tenantID).Msg("Created new connection pool")
-	return pool, nil
+	switch {
+	case err != nil && !failing:
+		cpm.publish(Event{Type: EventHealthChanged, TenantID: tenantID, Server: dsnLabel(dsn), Error: err.Error()})
+	case err == nil && failing:
+		cpm.publish(Event{Type: EventHealthChanged, TenantID: tenantID, Server: dsnLabel(dsn), Healthy: true})
+	}
+}
+
+// databaseFailure reports whether a failed creation says the database of a configured
+// tenant cannot be reached or used, rather than that the caller gave a bad or denied
+// DSN, gave up or was turned away before connecting
+func (cpm *ConnectionPoolManager) databaseFailure(ctx context.Context, tenantID string, err error) bool {
+	if _, ok := cpm.TenantConfig(tenantID); !ok || ctx.Err() != nil {
+		return false
+	}
+	var creationErr *CreationError
+	return errors.As(err, &creationErr) && creationErr.Reason != ReasonInvalidDSN
+}
+
+// PoolFailures returns configured tenants whose last pool creation failed because of
+// their database
+func (cpm *ConnectionPoolManager) PoolFailures() map[string]PoolFailure {
+	cpm.stateLock.RLock()
+	defer cpm.stateLock.RUnlock()
+	failures := make(map[string]PoolFailure, len(cpm.failures))
+	for tenantID, failure := range cpm.failures {
+		failures[tenantID] = failure
+	}
+	return failures
 }
 
 func (cpm *ConnectionPoolManager) ReleaseConnection(ctx context.Context, tenantID, dsn string) error {
@@ -57,10 +293,11 @@ func (cpm *ConnectionPoolManager) ReleaseConnection(ctx context.Context, tenantI
 	if pool, exists := cpm.pools[key]; exists {
 		pool.Close()
 		delete(cpm.pools, key)
-		log.Info().Str("tenant_id", tenantID).Msg("Released connection pool")
+		ctxLog(ctx).Info().Str("tenant_id", tenantID).Msg("Released connection pool")
+		cpm.publish(Event{Type: EventPoolClosed, TenantID: tenantID, Server: dsnLabel(dsn)})
 		return nil
 	}
-	return fmt.Errorf("pool not found for tenant %s and dsn %s", tenantID, dsn)
+	return fmt.Errorf("%w for tenant %s and dsn %s", ErrPoolNotFound, tenantID, dsn)
 }
 
 func (cpm *ConnectionPoolManager) GetStats(ctx context.Context, tenantID, dsn string) (Stats, error) {
@@ -69,14 +306,9 @@ func (cpm *ConnectionPoolManager) GetStats(ctx context.Context, tenantID, dsn st
 
 	key := tenantID + ":" + dsn
 	if pool, exists := cpm.pools[key]; exists {
-		stats := pool.Stat()
-		return Stats{
-			ActiveConnections: stats.AcquiredConns(),
-			IdleConnections:   stats.IdleConns(),
-			TotalConnections:  stats.TotalConns(),
-		}, nil
-	}
-	return Stats{}, fmt.Errorf("pool not found for tenant %s and dsn %s", tenantID, dsn)
+		return poolStats(pool), nil
+	}
+	return Stats{}, fmt.Errorf("%w for tenant %s and dsn %s", ErrPoolNotFound, tenantID, dsn)
 }
 
 type Stats struct {
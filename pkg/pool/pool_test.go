@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/pgtest"
 )
 
 func TestNewConnectionPoolManager(t *testing.T) {
@@ -200,3 +202,38 @@ func BenchmarkConnectionPoolManager_GetConnection(b *testing.B) {
 		_, _ = cpm.GetConnection(ctx, tenantID, dsn)
 	}
 }
+
+func TestConnectionPoolManager_PoolFailures(t *testing.T) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+	defer db.Close()
+
+	cpm := NewConnectionPoolManager()
+	unreachable := closedPortDSN(t)
+	cpm.SetTenantConfig(TenantConfig{TenantID: "acme", DSN: unreachable, Creation: CreationPolicy{Verify: true, Retries: -1, FailureCacheTTL: Duration{Duration: -1}}})
+	ctx := context.Background()
+
+	// A caller's bad DSN, a denied DSN and an unconfigured tenant say nothing about a
+	// tenant's database
+	_, err = cpm.GetConnection(ctx, "acme", "invalid-dsn")
+	require.Error(t, err)
+	_, err = cpm.GetConnection(ctx, "anyone", "postgres://u@h:badport/db")
+	require.Error(t, err)
+	cpm.SetDSNPolicy(DSNPolicy{RequireTLS: true})
+	_, err = cpm.GetConnection(ctx, "acme", unreachable)
+	require.ErrorIs(t, err, ErrDSNNotAllowed)
+	cpm.SetDSNPolicy(DSNPolicy{})
+	assert.Empty(t, cpm.PoolFailures())
+
+	_, err = cpm.GetConnection(ctx, "acme", unreachable)
+	require.Error(t, err)
+	failures := cpm.PoolFailures()
+	require.Contains(t, failures, "acme")
+	assert.Error(t, failures["acme"].Err)
+
+	// A pool created later clears the failure
+	_, err = cpm.GetConnection(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseConnection(ctx, "acme", db.DSN())
+	assert.Empty(t, cpm.PoolFailures())
+}
@@ -0,0 +1,239 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Prewarm defaults
+const (
+	DefaultPrewarmConcurrency   = 4
+	defaultPrewarmTimeout       = time.Minute
+	defaultPrewarmRetryInterval = 5 * time.Second
+)
+
+// PrewarmOptions selects the tenants opened at startup and how
+type PrewarmOptions struct {
+	// Tenants and Critical list tenants to prewarm on top of those whose config sets
+	// prewarm or critical. Critical tenants hold back readiness until they are warm.
+	Tenants  []string
+	Critical []string
+	// Concurrency bounds how many pools are opened at once. Defaults to 4.
+	Concurrency int
+	// Timeout bounds each attempt at warming a tenant. Defaults to 1m.
+	Timeout time.Duration
+	// RetryInterval is the wait before a critical tenant that failed is tried again.
+	// Defaults to 5s.
+	RetryInterval time.Duration
+}
+
+// PrewarmState is how far a tenant's prewarming got
+type PrewarmState string
+
+const (
+	PrewarmPending PrewarmState = "pending"
+	PrewarmWarming PrewarmState = "warming"
+	PrewarmReady   PrewarmState = "ready"
+	PrewarmFailed  PrewarmState = "failed"
+)
+
+// PrewarmStatus is the prewarming state of one tenant
+type PrewarmStatus struct {
+	TenantID string
+	Critical bool
+	State    PrewarmState
+	// Err is why the last attempt failed
+	Err error
+}
+
+// Prewarmer opens tenants' pools ahead of their first request, with at least the
+// pool's minimum connections established
+type Prewarmer struct {
+	cpm  *ConnectionPoolManager
+	opts PrewarmOptions
+
+	mu       sync.Mutex
+	statuses map[string]*PrewarmStatus
+}
+
+// NewPrewarmer selects the tenants to prewarm from the options and the registered
+// tenant configs. Listing a tenant that has no config is an error.
+func NewPrewarmer(cpm *ConnectionPoolManager, opts PrewarmOptions) (*Prewarmer, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultPrewarmConcurrency
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultPrewarmTimeout
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = defaultPrewarmRetryInterval
+	}
+
+	p := &Prewarmer{cpm: cpm, opts: opts, statuses: make(map[string]*PrewarmStatus)}
+	add := func(tenantID string, critical bool) error {
+		if _, ok := cpm.TenantConfig(tenantID); !ok {
+			return fmt.Errorf("cannot prewarm tenant %s: no tenant config", tenantID)
+		}
+		status, ok := p.statuses[tenantID]
+		if !ok {
+			status = &PrewarmStatus{TenantID: tenantID, State: PrewarmPending}
+			p.statuses[tenantID] = status
+		}
+		status.Critical = status.Critical || critical
+		return nil
+	}
+	for _, cfg := range cpm.TenantConfigs() {
+		if cfg.Prewarm || cfg.Critical {
+			add(cfg.TenantID, cfg.Critical)
+		}
+	}
+	for _, tenantID := range opts.Tenants {
+		if err := add(tenantID, false); err != nil {
+			return nil, err
+		}
+	}
+	for _, tenantID := range opts.Critical {
+		if err := add(tenantID, true); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Run warms the selected tenants in the background, critical ones first. Critical
+// tenants that fail are retried until they are warm or ctx is done.
+func (p *Prewarmer) Run(ctx context.Context) {
+	queue := make(chan string, len(p.statuses))
+	for _, status := range p.Statuses() {
+		if status.Critical {
+			queue <- status.TenantID
+		}
+	}
+	for _, status := range p.Statuses() {
+		if !status.Critical {
+			queue <- status.TenantID
+		}
+	}
+	close(queue)
+	if len(p.statuses) > 0 {
+		ctxLog(ctx).Info().Int("tenants", len(p.statuses)).Int("concurrency", p.opts.Concurrency).Msg("Prewarming tenant pools")
+	}
+
+	for range p.opts.Concurrency {
+		go func() {
+			for tenantID := range queue {
+				p.warmUntilDone(ctx, tenantID)
+			}
+		}()
+	}
+}
+
+// warmUntilDone warms a tenant, retrying a critical one until it succeeds or ctx is done
+func (p *Prewarmer) warmUntilDone(ctx context.Context, tenantID string) {
+	for {
+		critical, err := p.warm(ctx, tenantID)
+		if err == nil || !critical {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.opts.RetryInterval):
+		}
+	}
+}
+
+// warm makes one attempt at opening the tenant's pool and establishing its minimum
+// connections
+func (p *Prewarmer) warm(ctx context.Context, tenantID string) (bool, error) {
+	critical := p.setState(tenantID, PrewarmWarming, nil)
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, p.opts.Timeout)
+	defer cancel()
+
+	err := p.open(ctx, tenantID)
+	if err != nil {
+		p.setState(tenantID, PrewarmFailed, err)
+		ctxLog(ctx).Warn().Err(err).Str("tenant_id", tenantID).Bool("critical", critical).Msg("Failed to prewarm tenant pool")
+		return critical, err
+	}
+	p.setState(tenantID, PrewarmReady, nil)
+	ctxLog(ctx).Info().Str("tenant_id", tenantID).Dur("duration", time.Since(start)).Msg("Prewarmed tenant pool")
+	return critical, nil
+}
+
+// open gets the tenant's pool and acquires its minimum number of connections at once,
+// so they are all established before they are released back to it
+func (p *Prewarmer) open(ctx context.Context, tenantID string) error {
+	cfg, ok := p.cpm.TenantConfig(tenantID)
+	if !ok {
+		return fmt.Errorf("no tenant config for %s", tenantID)
+	}
+	pool, err := p.cpm.GetConnection(ctx, tenantID, cfg.DSN)
+	if err != nil {
+		return err
+	}
+
+	minConns := int(pool.Config().MinConns)
+	conns := make([]*pgxpool.Conn, 0, minConns)
+	defer func() {
+		for _, conn := range conns {
+			conn.Release()
+		}
+	}()
+	for range minConns {
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to establish %d connections: %w", minConns, err)
+		}
+		conns = append(conns, conn)
+	}
+	return nil
+}
+
+// setState records a tenant's state and returns whether it is critical
+func (p *Prewarmer) setState(tenantID string, state PrewarmState, err error) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := p.statuses[tenantID]
+	status.State, status.Err = state, err
+	return status.Critical
+}
+
+// Statuses returns the state of every tenant being prewarmed, ordered by tenant
+func (p *Prewarmer) Statuses() []PrewarmStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	statuses := make([]PrewarmStatus, 0, len(p.statuses))
+	for _, status := range p.statuses {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].TenantID < statuses[j].TenantID })
+	return statuses
+}
+
+// Ready returns nil once every critical tenant is warm, and otherwise says which are not.
+// It fits a readiness check.
+func (p *Prewarmer) Ready(context.Context) error {
+	var waiting []string
+	for _, status := range p.Statuses() {
+		if !status.Critical || status.State == PrewarmReady {
+			continue
+		}
+		if status.Err != nil {
+			waiting = append(waiting, fmt.Sprintf("%s (%s: %v)", status.TenantID, status.State, status.Err))
+		} else {
+			waiting = append(waiting, fmt.Sprintf("%s (%s)", status.TenantID, status.State))
+		}
+	}
+	if len(waiting) > 0 {
+		return fmt.Errorf("critical tenants not prewarmed: %s", strings.Join(waiting, ", "))
+	}
+	return nil
+}
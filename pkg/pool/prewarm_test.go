@@ -0,0 +1,74 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/pgtest"
+)
+
+func TestNewPrewarmer(t *testing.T) {
+	cpm := NewConnectionPoolManager()
+	cpm.SetTenantConfig(TenantConfig{TenantID: "acme", DSN: "postgres://app@db/acme", Critical: true})
+	cpm.SetTenantConfig(TenantConfig{TenantID: "globex", DSN: "postgres://app@db/globex", Prewarm: true})
+	cpm.SetTenantConfig(TenantConfig{TenantID: "initech", DSN: "postgres://app@db/initech"})
+	cpm.SetTenantConfig(TenantConfig{TenantID: "hooli", DSN: "postgres://app@db/hooli"})
+
+	p, err := NewPrewarmer(cpm, PrewarmOptions{Tenants: []string{"initech"}, Critical: []string{"globex"}})
+	require.NoError(t, err)
+	assert.Equal(t, []PrewarmStatus{
+		{TenantID: "acme", Critical: true, State: PrewarmPending},
+		{TenantID: "globex", Critical: true, State: PrewarmPending},
+		{TenantID: "initech", State: PrewarmPending},
+	}, p.Statuses())
+	assert.ErrorContains(t, p.Ready(context.Background()), "critical tenants not prewarmed: acme (pending), globex (pending)")
+
+	_, err = NewPrewarmer(cpm, PrewarmOptions{Critical: []string{"umbrella"}})
+	assert.ErrorContains(t, err, "cannot prewarm tenant umbrella")
+
+	// Nothing to prewarm is ready straight away
+	p, err = NewPrewarmer(NewConnectionPoolManager(), PrewarmOptions{})
+	require.NoError(t, err)
+	p.Run(context.Background())
+	assert.NoError(t, p.Ready(context.Background()))
+}
+
+func TestPrewarmer_Run(t *testing.T) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+	defer db.Close()
+	other, err := pgtest.NewServer()
+	require.NoError(t, err)
+	defer other.Close()
+
+	cpm := NewConnectionPoolManager()
+	cpm.SetTenantConfig(TenantConfig{TenantID: "acme", DSN: db.DSN(), Critical: true,
+		Creation: CreationPolicy{FailureCacheTTL: Duration{Duration: -1}}})
+	cpm.SetTenantConfig(TenantConfig{TenantID: "globex", DSN: other.DSN(), Prewarm: true})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer cpm.ReleaseConnection(ctx, "acme", db.DSN())
+	defer cpm.ReleaseConnection(ctx, "globex", other.DSN())
+
+	// The critical tenant's database refuses logins at first, so it is retried
+	db.SetStartupError("28P01", "password authentication failed")
+	p, err := NewPrewarmer(cpm, PrewarmOptions{Concurrency: 1, RetryInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	p.Run(ctx)
+
+	require.Eventually(t, func() bool {
+		statuses := p.Statuses()
+		return statuses[0].State == PrewarmFailed && statuses[1].State == PrewarmPending
+	}, 5*time.Second, time.Millisecond, "a failing critical tenant holds its worker")
+	assert.ErrorContains(t, p.Ready(ctx), "acme (failed")
+
+	db.SetStartupError("", "")
+	require.Eventually(t, func() bool { return p.Ready(ctx) == nil }, 5*time.Second, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, db.ConnCount(), 5, "the pool's minimum connections are established")
+	require.Eventually(t, func() bool { return p.Statuses()[1].State == PrewarmReady }, 5*time.Second, time.Millisecond)
+	assert.GreaterOrEqual(t, other.ConnCount(), 5)
+	assert.Len(t, cpm.ListPools(""), 2)
+}
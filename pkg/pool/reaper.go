@@ -0,0 +1,137 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultLeaseTTL is how long a lease lives without renewal unless the manager or the
+// tenant sets otherwise
+const DefaultLeaseTTL = 5 * time.Minute
+
+// SetDefaultLeaseTTL sets the TTL of leases of tenants that do not configure one
+func (cpm *ConnectionPoolManager) SetDefaultLeaseTTL(ttl time.Duration) {
+	cpm.leaseLock.Lock()
+	defer cpm.leaseLock.Unlock()
+	cpm.leaseTTL = ttl
+}
+
+// LeaseTTL returns the TTL of the tenant's leases
+func (cpm *ConnectionPoolManager) LeaseTTL(tenantID string) time.Duration {
+	if cfg, ok := cpm.TenantConfig(tenantID); ok && cfg.LeaseTTL.Duration > 0 {
+		return cfg.LeaseTTL.Duration
+	}
+	cpm.leaseLock.Lock()
+	defer cpm.leaseLock.Unlock()
+	return cpm.leaseTTL
+}
+
+// SetTTL makes the lease expire ttl from now. A ttl of zero or less stops the lease
+// from expiring, for leases tied to a live connection such as a proxy client's.
+func (l *Lease) SetTTL(ttl time.Duration) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if ttl <= 0 {
+		l.expiresAt = time.Time{}
+	} else {
+		l.expiresAt = time.Now().Add(ttl)
+	}
+	return l.expiresAt
+}
+
+// ExpiresAt returns when the lease expires, or the zero time if it does not
+func (l *Lease) ExpiresAt() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.expiresAt
+}
+
+func (l *Lease) expired(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !l.expiresAt.IsZero() && now.After(l.expiresAt)
+}
+
+// RenewLease extends a lease by ttl from now, or by the tenant's lease TTL when ttl is
+// zero, and returns the new expiry
+func (cpm *ConnectionPoolManager) RenewLease(ctx context.Context, id string, ttl time.Duration) (time.Time, error) {
+	lease, ok := cpm.Lease(id)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrLeaseNotFound, LeaseHandle(id))
+	}
+	if ttl <= 0 {
+		ttl = cpm.LeaseTTL(lease.TenantID)
+	}
+	return lease.SetTTL(ttl), nil
+}
+
+// StartLeaseReaper reclaims expired leases, and warns about leases held too long, every
+// interval until ctx is done
+func (cpm *ConnectionPoolManager) StartLeaseReaper(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cpm.reapExpiredLeases(ctx)
+				cpm.warnLongHeldLeases(ctx)
+			}
+		}
+	}()
+}
+
+// reapExpiredLeases reclaims every lease past its expiry and returns how many there were
+func (cpm *ConnectionPoolManager) reapExpiredLeases(ctx context.Context) int {
+	now := time.Now()
+	var expired []*Lease
+	cpm.leaseLock.Lock()
+	for id, lease := range cpm.leases {
+		if lease.expired(now) {
+			expired = append(expired, lease)
+			delete(cpm.leases, id)
+		}
+	}
+	cpm.leaseLock.Unlock()
+
+	for _, lease := range expired {
+		ctxLog(ctx).Warn().
+			Str("tenant_id", lease.TenantID).
+			Str("lease", lease.Handle()).
+			Str("peer", lease.Client.Peer).
+			Str("identity", lease.Client.Identity).
+			Str("call_site", lease.Client.CallSite).
+			Time("acquired_at", lease.AcquiredAt).
+			Time("expired_at", lease.ExpiresAt()).
+			Msg("Reclaiming leaked lease")
+		leaseLeaks.WithLabelValues(lease.TenantID).Inc()
+		cpm.publish(Event{Type: EventLeaseReclaimed, TenantID: lease.TenantID, Lease: lease.Handle()})
+		lease.reclaim(ctx)
+		// Let an owner such as a HoldLease stream know the lease is gone
+		lease.evict()
+	}
+	return len(expired)
+}
+
+// reclaim releases a lease its client abandoned. A statement still running on it is
+// cancelled first so the backend can be reset.
+func (l *Lease) reclaim(ctx context.Context) {
+	l.releaseExclusive(ctx, BackendIdle)
+}
+
+// cancelQuery asks PostgreSQL to cancel the statement running on the lease's backend
+func (l *Lease) cancelQuery(ctx context.Context) {
+	l.mu.Lock()
+	conn := l.conn
+	l.mu.Unlock()
+	if conn == nil {
+		return
+	}
+	pgConn := conn.Conn().PgConn()
+	if err := pgConn.CancelRequest(ctx); err != nil {
+		ctxLog(ctx).Warn().Err(err).Str("tenant_id", l.TenantID).Uint32("backend_pid", pgConn.PID()).Msg("Failed to cancel query")
+	}
+}
@@ -0,0 +1,128 @@
+package pool
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/pgtest"
+)
+
+func TestConnectionPoolManager_LeaseTTL(t *testing.T) {
+	cpm := NewConnectionPoolManager()
+	cpm.SetDefaultLeaseTTL(time.Minute)
+	cpm.SetTenantConfig(TenantConfig{TenantID: "acme", LeaseTTL: Duration{Duration: 10 * time.Second}})
+	cpm.SetTenantConfig(TenantConfig{TenantID: "globex"})
+
+	assert.Equal(t, 10*time.Second, cpm.LeaseTTL("acme"))
+	assert.Equal(t, time.Minute, cpm.LeaseTTL("globex"))
+	assert.Equal(t, time.Minute, cpm.LeaseTTL("unknown"))
+}
+
+func TestConnectionPoolManager_RenewLease(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{LeaseTTL: Duration{Duration: time.Minute}})
+	ctx := context.Background()
+
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseLease(ctx, lease.ID)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), lease.ExpiresAt(), time.Second)
+
+	expiresAt, err := cpm.RenewLease(ctx, lease.ID, time.Hour)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Second)
+	assert.Equal(t, expiresAt, lease.ExpiresAt())
+
+	// Without a TTL the tenant's applies
+	expiresAt, err = cpm.RenewLease(ctx, lease.ID, 0)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), expiresAt, time.Second)
+
+	_, err = cpm.RenewLease(ctx, "unknown", time.Hour)
+	assert.ErrorIs(t, err, ErrLeaseNotFound)
+}
+
+func TestConnectionPoolManager_ReapExpiredLeases(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{})
+	client := ClientInfo{Peer: "10.0.0.7:51234", Identity: "app", CallSite: "worker.go:42"}
+	ctx := WithClient(context.Background(), client)
+	leaks := testutil.ToFloat64(leaseLeaks.WithLabelValues("acme"))
+
+	expired, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	assert.Equal(t, client, expired.Client)
+	expired.SetTTL(time.Millisecond)
+	// The owner of a reclaimed lease is told it is gone
+	notified := false
+	expired.OnEvict(func() { notified = true })
+
+	live, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseLease(ctx, live.ID)
+
+	// Leases without a TTL are never reclaimed
+	pinned, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseLease(ctx, pinned.ID)
+	pinned.SetTTL(0)
+	assert.True(t, pinned.ExpiresAt().IsZero())
+
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, 1, cpm.reapExpiredLeases(ctx))
+	assert.Equal(t, leaks+1, testutil.ToFloat64(leaseLeaks.WithLabelValues("acme")))
+
+	_, ok := cpm.Lease(expired.ID)
+	assert.False(t, ok)
+	assert.False(t, expired.Attached())
+	assert.True(t, notified)
+	_, ok = cpm.Lease(live.ID)
+	assert.True(t, ok)
+	_, ok = cpm.Lease(pinned.ID)
+	assert.True(t, ok)
+}
+
+func TestConnectionPoolManager_ReapCancelsRunningQuery(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{})
+	ctx := context.Background()
+
+	// The statement runs until its backend is sent a cancel request
+	db.SetHandler(func(pid uint32, query string) (pgtest.Result, bool) {
+		if query != "SELECT pg_sleep(3600)" {
+			return pgtest.Result{}, false
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for !slices.Contains(db.CancelRequests(), pid) && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		return pgtest.Result{Err: "canceling statement due to user request"}, true
+	})
+
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	started := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		close(started)
+		_, err := lease.Exec(ctx, "SELECT pg_sleep(3600)", nil)
+		done <- err
+	}()
+	<-started
+	require.Eventually(t, func() bool { return countQueries(db, "SELECT pg_sleep(3600)") == 1 }, time.Second, 10*time.Millisecond)
+
+	lease.SetTTL(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, 1, cpm.reapExpiredLeases(ctx))
+
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "canceling statement")
+	case <-time.After(time.Second):
+		t.Fatal("query was not cancelled")
+	}
+	assert.NotEmpty(t, db.CancelRequests())
+	assert.False(t, lease.Attached())
+}
@@ -0,0 +1,245 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const replicaCheckTimeout = 5 * time.Second
+
+// replicaReceiveTimeout is how long a streaming WAL receiver may go without a message
+// from the primary. The primary sends keepalives at least every wal_sender_timeout/2,
+// 30s by default, even when idle.
+const replicaReceiveTimeout = time.Minute
+
+// replicaLagQuery returns the replica's replay LSN, how far behind the primary it is,
+// whether its WAL receiver is streaming and how long ago it last heard from the
+// primary. A replica that has replayed everything it received reports zero lag even if
+// the primary has been idle for a while, so that lag only holds while WAL still arrives.
+const replicaLagQuery = `SELECT COALESCE(pg_last_wal_replay_lsn()::text, ''),
+	CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)::float8 END,
+	COALESCE(receiver.status = 'streaming', false),
+	COALESCE(EXTRACT(EPOCH FROM now() - receiver.last_msg_receipt_time), 0)::float8
+	FROM (SELECT 1) AS replica LEFT JOIN pg_stat_wal_receiver AS receiver ON true`
+
+// LSN is a PostgreSQL write-ahead log position
+type LSN uint64
+
+// ErrInvalidLSN is returned for an LSN that is not in PostgreSQL's text format
+var ErrInvalidLSN = errors.New("invalid LSN")
+
+// ParseLSN parses an LSN in PostgreSQL's "16/B374D848" text format
+func ParseLSN(s string) (LSN, error) {
+	hi, lo, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrInvalidLSN, s)
+	}
+	h, err := strconv.ParseUint(hi, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w %q", ErrInvalidLSN, s)
+	}
+	l, err := strconv.ParseUint(lo, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w %q", ErrInvalidLSN, s)
+	}
+	return LSN(h<<32 | l), nil
+}
+
+func (l LSN) String() string {
+	return fmt.Sprintf("%X/%X", uint64(l)>>32, uint64(l)&0xFFFFFFFF)
+}
+
+// ReplicaStatus is the last measured state of a replica pool
+type ReplicaStatus struct {
+	DSN       string
+	ReplayLSN LSN
+	Lag       time.Duration
+	CheckedAt time.Time
+	Err       error
+}
+
+// GetReplicaConnection returns a pool for a replica of the tenant that is within the
+// tenant's lag threshold and has replayed at least minLSN. It falls back to the
+// primary when no replica qualifies. The returned DSN identifies the chosen pool.
+func (cpm *ConnectionPoolManager) GetReplicaConnection(ctx context.Context, tenantID string, minLSN LSN) (*pgxpool.Pool, string, error) {
+	cfg, ok := cpm.TenantConfig(tenantID)
+	if !ok {
+		return nil, "", fmt.Errorf("no config for tenant %s", tenantID)
+	}
+
+	cpm.stateLock.RLock()
+	dsn, found := selectReplica(cfg, cpm.replicas, minLSN)
+	cpm.stateLock.RUnlock()
+	if !found {
+		ctxLog(ctx).Debug().Str("tenant_id", tenantID).Msg("No replica within lag threshold, using primary")
+		dsn = cfg.DSN
+	}
+
+	pool, err := cpm.GetConnection(ctx, tenantID, dsn)
+	if err != nil {
+		return nil, "", err
+	}
+	return pool, dsn, nil
+}
+
+// ReplicaStatuses returns the last measured status of each of the tenant's replicas
+func (cpm *ConnectionPoolManager) ReplicaStatuses(tenantID string) []ReplicaStatus {
+	cfg, ok := cpm.TenantConfig(tenantID)
+	if !ok {
+		return nil
+	}
+
+	cpm.stateLock.RLock()
+	defer cpm.stateLock.RUnlock()
+	statuses := make([]ReplicaStatus, 0, len(cfg.ReplicaDSNs))
+	for _, dsn := range cfg.ReplicaDSNs {
+		if status, exists := cpm.replicas[tenantID+":"+dsn]; exists {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// StartReplicaMonitor measures replay lag on every configured replica at the given
+// interval until ctx is cancelled
+func (cpm *ConnectionPoolManager) StartReplicaMonitor(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			cpm.checkReplicas(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (cpm *ConnectionPoolManager) checkReplicas(ctx context.Context) {
+	for _, cfg := range cpm.TenantConfigs() {
+		for _, dsn := range cfg.ReplicaDSNs {
+			status := cpm.checkReplica(ctx, cfg.TenantID, dsn)
+
+			cpm.stateLock.Lock()
+			previous, checked := cpm.replicas[cfg.TenantID+":"+dsn]
+			cpm.replicas[cfg.TenantID+":"+dsn] = status
+			cpm.stateLock.Unlock()
+
+			if healthy := status.Err == nil; healthy != (previous.Err == nil) || (!checked && !healthy) {
+				event := Event{Type: EventHealthChanged, TenantID: cfg.TenantID, Server: dsnLabel(dsn), Healthy: healthy}
+				if !healthy {
+					event.Error = status.Err.Error()
+				}
+				cpm.publish(event)
+			}
+			if status.Err != nil {
+				ctxLog(ctx).Warn().Err(status.Err).Str("tenant_id", cfg.TenantID).Str("replica", dsnLabel(dsn)).Msg("Replica lag check failed")
+				continue
+			}
+			replicaLag.WithLabelValues(cfg.TenantID, dsnLabel(dsn)).Set(status.Lag.Seconds())
+		}
+		if len(cfg.ReplicaDSNs) > 0 {
+			cpm.updateReadTarget(cfg)
+		}
+	}
+}
+
+// updateReadTarget works out where the tenant's reads go after a check and publishes a
+// failover when that moved
+func (cpm *ConnectionPoolManager) updateReadTarget(cfg TenantConfig) {
+	cpm.stateLock.Lock()
+	target, found := selectReplica(cfg, cpm.replicas, 0)
+	if !found {
+		target = cfg.DSN
+	}
+	previous, known := cpm.readTargets[cfg.TenantID]
+	cpm.readTargets[cfg.TenantID] = target
+	cpm.stateLock.Unlock()
+
+	if known && previous != target {
+		cpm.publish(Event{Type: EventFailover, TenantID: cfg.TenantID, Server: dsnLabel(target), FromServer: dsnLabel(previous)})
+	}
+}
+
+func (cpm *ConnectionPoolManager) checkReplica(ctx context.Context, tenantID, dsn string) ReplicaStatus {
+	status := ReplicaStatus{DSN: dsn, CheckedAt: time.Now()}
+
+	ctx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+	defer cancel()
+
+	pool, err := cpm.GetConnection(ctx, tenantID, dsn)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+
+	var lsn string
+	var lagSeconds, receiptAge float64
+	var streaming bool
+	if err := pool.QueryRow(ctx, replicaLagQuery).Scan(&lsn, &lagSeconds, &streaming, &receiptAge); err != nil {
+		status.Err = err
+		return status
+	}
+	if lsn == "" {
+		status.Err = fmt.Errorf("server is not a replica")
+		return status
+	}
+	if status.ReplayLSN, err = ParseLSN(lsn); err != nil {
+		status.Err = err
+		return status
+	}
+	// A replica cut off from the primary has nothing left to replay, so its replay lag
+	// says nothing about how stale it is
+	if !streaming {
+		status.Err = fmt.Errorf("WAL receiver is not streaming")
+		return status
+	}
+	status.Lag = time.Duration(lagSeconds * float64(time.Second))
+	if silence := time.Duration(receiptAge * float64(time.Second)); silence > replicaReceiveTimeout {
+		status.Lag = max(status.Lag, silence)
+	}
+	return status
+}
+
+// selectReplica picks the least lagged healthy replica that satisfies the tenant's
+// lag threshold and minLSN
+func selectReplica(cfg TenantConfig, statuses map[string]ReplicaStatus, minLSN LSN) (string, bool) {
+	best := ""
+	var bestLag time.Duration
+	for _, dsn := range cfg.ReplicaDSNs {
+		status, exists := statuses[cfg.TenantID+":"+dsn]
+		if !exists || status.Err != nil {
+			continue
+		}
+		if cfg.MaxReplicationLag.Duration > 0 && status.Lag > cfg.MaxReplicationLag.Duration {
+			continue
+		}
+		if status.ReplayLSN < minLSN {
+			continue
+		}
+		if best == "" || status.Lag < bestLag {
+			best, bestLag = dsn, status.Lag
+		}
+	}
+	return best, best != ""
+}
+
+// dsnLabel identifies a DSN by host, port and database without exposing credentials
+func dsnLabel(dsn string) string {
+	config, err := pgconn.ParseConfig(dsn)
+	if err != nil {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s:%d/%s", config.Host, config.Port, config.Database)
+}
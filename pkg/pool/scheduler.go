@@ -0,0 +1,207 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCreationWarmup is how long a creation keeps its slot while the pool's first
+// connections open
+const DefaultCreationWarmup = 5 * time.Second
+
+// ErrCreationQueued is returned when a caller gives up waiting for a creation slot
+var ErrCreationQueued = errors.New("gave up waiting for a pool creation slot")
+
+// CreationLimits bounds how many pools are created at once, so a burst of new tenants
+// does not open every pool's minimum connections against the same hosts together
+type CreationLimits struct {
+	// Global limits creations across all hosts. Unlimited when 0.
+	Global int
+	// PerHost limits creations against one database host. Unlimited when 0.
+	PerHost int
+	// Warmup bounds how long a creation holds its slot while its minimum connections
+	// open. Defaults to 5s.
+	Warmup time.Duration
+}
+
+// creationScheduler hands out creation slots under the limits. Callers over a limit
+// queue in arrival order, and a caller whose host is full does not hold up callers
+// for other hosts.
+type creationScheduler struct {
+	mu       sync.Mutex
+	limits   CreationLimits
+	inFlight int
+	byHost   map[string]int
+	queue    []*slotWaiter
+}
+
+// slotWaiter is a caller queued for a creation slot
+type slotWaiter struct {
+	host    string
+	granted chan struct{}
+}
+
+// creationSlot is a granted slot. The zero slot, given out when there are no limits,
+// neither waits for warmup nor releases anything.
+type creationSlot struct {
+	scheduler *creationScheduler
+	host      string
+	warmup    *warmup
+	timeout   time.Duration
+}
+
+func newCreationScheduler() *creationScheduler {
+	return &creationScheduler{byHost: make(map[string]int)}
+}
+
+// SetCreationLimits sets the limits on concurrent pool creations. Callers already
+// queued are let through if the new limits allow it.
+func (cpm *ConnectionPoolManager) SetCreationLimits(limits CreationLimits) {
+	if limits.Warmup <= 0 {
+		limits.Warmup = DefaultCreationWarmup
+	}
+	s := cpm.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limits = limits
+	s.grant()
+}
+
+// acquire waits for a slot to create a pool against the DSN's host, giving up when ctx
+// is done
+func (s *creationScheduler) acquire(ctx context.Context, dsn string) (creationSlot, error) {
+	host, ok := dsnHost(dsn)
+	s.mu.Lock()
+	if !ok || (s.limits.Global <= 0 && s.limits.PerHost <= 0) {
+		s.mu.Unlock()
+		return creationSlot{}, nil
+	}
+	slot := creationSlot{scheduler: s, host: host, timeout: s.limits.Warmup}
+	w := &slotWaiter{host: host, granted: make(chan struct{})}
+	s.queue = append(s.queue, w)
+	creationQueueLength.WithLabelValues(host).Inc()
+	s.grant()
+	s.mu.Unlock()
+
+	start := time.Now()
+	select {
+	case <-w.granted:
+		creationQueueWait.Observe(time.Since(start).Seconds())
+		return slot, nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-w.granted:
+		// Granted as ctx ended; hand the slot on
+		s.release(host)
+	default:
+		s.remove(w)
+	}
+	creationQueueWait.Observe(time.Since(start).Seconds())
+	return creationSlot{}, fmt.Errorf("%w for host %s: %w", ErrCreationQueued, host, ctx.Err())
+}
+
+// available reports whether a creation against host fits the limits. The caller holds mu.
+func (s *creationScheduler) available(host string) bool {
+	return (s.limits.Global <= 0 || s.inFlight < s.limits.Global) &&
+		(s.limits.PerHost <= 0 || s.byHost[host] < s.limits.PerHost)
+}
+
+// take counts a creation against host. The caller holds mu.
+func (s *creationScheduler) take(host string) {
+	s.inFlight++
+	s.byHost[host]++
+	creationsInFlight.WithLabelValues(host).Inc()
+}
+
+// release frees a slot and grants it on. The caller holds mu.
+func (s *creationScheduler) release(host string) {
+	s.inFlight--
+	if s.byHost[host]--; s.byHost[host] == 0 {
+		delete(s.byHost, host)
+	}
+	creationsInFlight.WithLabelValues(host).Dec()
+	s.grant()
+}
+
+// grant lets queued callers through in order while the limits allow. The caller holds mu.
+func (s *creationScheduler) grant() {
+	queue := s.queue[:0]
+	for _, w := range s.queue {
+		if !s.available(w.host) {
+			queue = append(queue, w)
+			continue
+		}
+		s.take(w.host)
+		creationQueueLength.WithLabelValues(w.host).Dec()
+		close(w.granted)
+	}
+	clear(s.queue[len(queue):])
+	s.queue = queue
+}
+
+// remove drops a caller that gave up from the queue. The caller holds mu.
+func (s *creationScheduler) remove(w *slotWaiter) {
+	for i, queued := range s.queue {
+		if queued == w {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			creationQueueLength.WithLabelValues(w.host).Dec()
+			return
+		}
+	}
+}
+
+// track returns the warmup a new pool reports its first connections to, or nil when
+// the slot does not wait for them
+func (slot *creationSlot) track(minConns int32) *warmup {
+	if slot.scheduler != nil && minConns > 0 {
+		slot.warmup = &warmup{done: make(chan struct{})}
+		slot.warmup.remaining.Store(minConns)
+	}
+	return slot.warmup
+}
+
+// wait holds the slot until the pool's first connections have opened or failed, the
+// warmup timeout passes or ctx is done
+func (slot creationSlot) wait(ctx context.Context) {
+	if slot.warmup == nil {
+		return
+	}
+	timer := time.NewTimer(slot.timeout)
+	defer timer.Stop()
+	select {
+	case <-slot.warmup.done:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// release frees the slot for the next creation
+func (slot creationSlot) release() {
+	if slot.scheduler == nil {
+		return
+	}
+	slot.scheduler.mu.Lock()
+	defer slot.scheduler.mu.Unlock()
+	slot.scheduler.release(slot.host)
+}
+
+// warmup counts a new pool's first connection attempts
+type warmup struct {
+	remaining atomic.Int32
+	done      chan struct{}
+}
+
+// connected records a finished connection attempt, whether or not it succeeded
+func (w *warmup) connected() {
+	if w.remaining.Add(-1) == 0 {
+		close(w.done)
+	}
+}
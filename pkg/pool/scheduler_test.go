@@ -0,0 +1,118 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/pgtest"
+)
+
+func TestCreationScheduler(t *testing.T) {
+	s := newCreationScheduler()
+	s.limits = CreationLimits{Global: 2, PerHost: 1, Warmup: time.Second}
+	ctx := context.Background()
+	dsn := func(host string) string { return "postgres://app@" + host + "/acme" }
+
+	// acquireAsync starts waiting for a slot and returns where the slot arrives
+	acquireAsync := func(ctx context.Context, host string) chan creationSlot {
+		ch := make(chan creationSlot, 1)
+		go func() {
+			if slot, err := s.acquire(ctx, dsn(host)); err == nil {
+				ch <- slot
+			}
+		}()
+		return ch
+	}
+	queued := func(n int) func() bool {
+		return func() bool {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return len(s.queue) == n
+		}
+	}
+
+	a, err := s.acquire(ctx, dsn("a"))
+	require.NoError(t, err)
+	// A second creation on host a waits, but one on host b does not wait behind it
+	a2 := acquireAsync(ctx, "a")
+	require.Eventually(t, queued(1), time.Second, time.Millisecond)
+	b, err := s.acquire(ctx, dsn("b"))
+	require.NoError(t, err)
+	// The global limit is reached, so host c waits too
+	c := acquireAsync(ctx, "c")
+	require.Eventually(t, queued(2), time.Second, time.Millisecond)
+
+	// A caller giving up leaves the queue
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = s.acquire(waitCtx, dsn("d"))
+	assert.ErrorIs(t, err, ErrCreationQueued)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, queued(2)())
+
+	// Freed slots go to the queue in order
+	a.release()
+	var slot creationSlot
+	select {
+	case slot = <-a2:
+	case <-time.After(time.Second):
+		t.Fatal("queued creation on host a was not let through")
+	}
+	assert.Equal(t, "a:5432", slot.host)
+	assert.Empty(t, c)
+	b.release()
+	select {
+	case <-c:
+	case <-time.After(time.Second):
+		t.Fatal("queued creation on host c was not let through")
+	}
+	assert.True(t, queued(0)())
+	assert.Equal(t, 2, s.inFlight)
+
+	// Without limits nothing is counted
+	s = newCreationScheduler()
+	slot, err = s.acquire(ctx, dsn("a"))
+	require.NoError(t, err)
+	slot.release()
+	assert.Zero(t, s.inFlight)
+}
+
+func TestConnectionPoolManager_CreationLimits(t *testing.T) {
+	db, err := pgtest.NewServer()
+	require.NoError(t, err)
+	defer db.Close()
+
+	cpm := NewConnectionPoolManager()
+	cpm.SetCreationLimits(CreationLimits{Global: 1})
+	ctx := context.Background()
+	defer cpm.ReleaseConnection(ctx, "acme", db.DSN())
+
+	// The creation holds its slot until the pool's minimum connections are open
+	_, err = cpm.GetConnection(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, db.ConnCount(), 5)
+	assert.Zero(t, cpm.scheduler.inFlight)
+
+	// A caller that gives up in the queue is not a failure of the host
+	held, err := cpm.scheduler.acquire(ctx, db.DSN())
+	require.NoError(t, err)
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = cpm.GetConnection(waitCtx, "globex", db.DSN())
+	assert.ErrorIs(t, err, ErrCreationQueued)
+	var creationErr *CreationError
+	assert.NotErrorAs(t, err, &creationErr)
+	for _, b := range cpm.Breakers("globex") {
+		assert.Zero(t, b.Failures)
+	}
+	held.release()
+
+	// Nor is it cached
+	pool, err := cpm.GetConnection(ctx, "globex", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseConnection(ctx, "globex", db.DSN())
+	assert.NotNil(t, pool)
+}
@@ -0,0 +1,102 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SessionInit is the session state every pooled connection of a tenant starts with.
+// It is applied when a connection is opened and again after each reset.
+type SessionInit struct {
+	// SearchPath sets the schema search path, e.g. ["acme", "public"]
+	SearchPath []string `json:"search_path,omitempty"`
+	// Role is assumed with SET ROLE
+	Role                            string   `json:"role,omitempty"`
+	StatementTimeout                Duration `json:"statement_timeout,omitempty"`
+	IdleInTransactionSessionTimeout Duration `json:"idle_in_transaction_session_timeout,omitempty"`
+	ApplicationName                 string   `json:"application_name,omitempty"`
+	TimeZone                        string   `json:"timezone,omitempty"`
+	// Settings are further run-time parameters applied with SET
+	Settings map[string]string `json:"settings,omitempty"`
+	// Types are custom types (enums, composites, domains) registered with pgx by name
+	Types []string `json:"types,omitempty"`
+}
+
+var settingName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// Validate checks that every setting name can be used in a SET statement
+func (s SessionInit) Validate() error {
+	for name := range s.Settings {
+		if !settingName.MatchString(name) {
+			return fmt.Errorf("invalid setting name %q", name)
+		}
+	}
+	return nil
+}
+
+// Statements returns the SET statements that establish the session state
+func (s SessionInit) Statements() []string {
+	var stmts []string
+	if len(s.SearchPath) > 0 {
+		schemas := make([]string, len(s.SearchPath))
+		for i, schema := range s.SearchPath {
+			schemas[i] = pgx.Identifier{schema}.Sanitize()
+		}
+		stmts = append(stmts, "SET search_path TO "+strings.Join(schemas, ", "))
+	}
+	if s.Role != "" {
+		stmts = append(stmts, "SET ROLE "+pgx.Identifier{s.Role}.Sanitize())
+	}
+	if s.StatementTimeout.Duration > 0 {
+		stmts = append(stmts, fmt.Sprintf("SET statement_timeout = %d", s.StatementTimeout.Milliseconds()))
+	}
+	if s.IdleInTransactionSessionTimeout.Duration > 0 {
+		stmts = append(stmts, fmt.Sprintf("SET idle_in_transaction_session_timeout = %d", s.IdleInTransactionSessionTimeout.Milliseconds()))
+	}
+	if s.ApplicationName != "" {
+		stmts = append(stmts, "SET application_name = "+quoteLiteral(s.ApplicationName))
+	}
+	if s.TimeZone != "" {
+		stmts = append(stmts, "SET TIME ZONE "+quoteLiteral(s.TimeZone))
+	}
+
+	names := make([]string, 0, len(s.Settings))
+	for name := range s.Settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		stmts = append(stmts, fmt.Sprintf("SET %s = %s", name, quoteLiteral(s.Settings[name])))
+	}
+	return stmts
+}
+
+// apply sets up the session state on conn. Custom types are only registered when
+// registerTypes is set, as pgx keeps them for the life of the connection.
+func (s SessionInit) apply(ctx context.Context, conn *pgx.Conn, registerTypes bool) error {
+	if stmts := s.Statements(); len(stmts) > 0 {
+		if _, err := conn.PgConn().Exec(ctx, strings.Join(stmts, "; ")).ReadAll(); err != nil {
+			return fmt.Errorf("session init: %w", err)
+		}
+	}
+	if !registerTypes {
+		return nil
+	}
+	for _, name := range s.Types {
+		dataType, err := conn.LoadType(ctx, name)
+		if err != nil {
+			return fmt.Errorf("register type %s: %w", name, err)
+		}
+		conn.TypeMap().RegisterType(dataType)
+	}
+	return nil
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,90 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/pgtest"
+)
+
+func TestSessionInit_Statements(t *testing.T) {
+	tests := []struct {
+		name    string
+		session SessionInit
+		want    []string
+	}{
+		{
+			name:    "Empty",
+			session: SessionInit{},
+			want:    nil,
+		},
+		{
+			name: "All settings",
+			session: SessionInit{
+				SearchPath:                      []string{"acme", "public"},
+				Role:                            "acme_app",
+				StatementTimeout:                Duration{30 * time.Second},
+				IdleInTransactionSessionTimeout: Duration{time.Minute},
+				ApplicationName:                 "billing",
+				TimeZone:                        "Europe/Berlin",
+				Settings:                        map[string]string{"work_mem": "64MB", "app.tenant": "acme"},
+			},
+			want: []string{
+				`SET search_path TO "acme", "public"`,
+				`SET ROLE "acme_app"`,
+				"SET statement_timeout = 30000",
+				"SET idle_in_transaction_session_timeout = 60000",
+				"SET application_name = 'billing'",
+				"SET TIME ZONE 'Europe/Berlin'",
+				"SET app.tenant = 'acme'",
+				"SET work_mem = '64MB'",
+			},
+		},
+		{
+			name:    "Quoting",
+			session: SessionInit{SearchPath: []string{`we"ird`}, ApplicationName: "o'brien"},
+			want: []string{
+				`SET search_path TO "we""ird"`,
+				"SET application_name = 'o''brien'",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.session.Statements())
+		})
+	}
+}
+
+func TestSessionInit_Validate(t *testing.T) {
+	assert.NoError(t, SessionInit{Settings: map[string]string{"app.tenant_id": "acme"}}.Validate())
+	assert.Error(t, SessionInit{Settings: map[string]string{"work_mem = 1; DROP TABLE x": "1"}}.Validate())
+}
+
+func TestSessionInit_AppliedOnConnectAndAfterReset(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{Session: SessionInit{SearchPath: []string{"acme"}}})
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	byBackend := make(map[uint32][]string)
+	db.SetHandler(func(pid uint32, query string) (pgtest.Result, bool) {
+		mu.Lock()
+		byBackend[pid] = append(byBackend[pid], query)
+		mu.Unlock()
+		return pgtest.Result{}, false
+	})
+
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	pid := lease.conn.Conn().PgConn().PID()
+	require.NoError(t, cpm.ReleaseLease(ctx, lease.ID))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{`SET search_path TO "acme"`, DefaultResetQuery, `SET search_path TO "acme"`, "deallocate all"}, byBackend[pid])
+}
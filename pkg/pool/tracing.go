@@ -0,0 +1,65 @@
+package pool
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TenantAttribute is set on every span that belongs to a tenant
+const TenantAttribute = attribute.Key("tenant.id")
+
+var tracer = otel.Tracer("github.com/teresa-solution/connection-pool-manager/pkg/pool")
+
+// startSpan starts a span carrying the tenant attribute
+func startSpan(ctx context.Context, name, tenantID string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, TenantAttribute.String(tenantID))
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err, if any, and ends the span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// queryTracer is a pgx QueryTracer that records a client span per statement. It also
+// tells a pool's warmup about finished connection attempts.
+type queryTracer struct {
+	tenantID string
+	server   string
+	warmup   *warmup
+}
+
+func (t queryTracer) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	return ctx
+}
+
+func (t queryTracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	if t.warmup != nil {
+		t.warmup.connected()
+	}
+}
+
+func (t queryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = startSpan(ctx, "pool.Query", t.tenantID,
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.query.text", data.SQL),
+		attribute.String("server.address", t.server),
+		attribute.Int64("db.backend_pid", int64(conn.PgConn().PID())),
+	)
+	return ctx
+}
+
+func (t queryTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("db.command_tag", data.CommandTag.String()))
+	endSpan(span, data.Err)
+}
@@ -0,0 +1,66 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var (
+	testTracingOnce sync.Once
+	testTracing     *tracing.Provider
+)
+
+// setupTracing installs an in-memory tracer provider once per test binary, as the
+// package tracer only binds to the first global provider
+func setupTracing(t *testing.T) *tracetest.InMemoryExporter {
+	testTracingOnce.Do(func() {
+		var err error
+		testTracing, err = tracing.Setup(context.Background(), tracing.Config{Exporter: tracing.ExporterMemory})
+		require.NoError(t, err)
+	})
+	testTracing.Memory.Reset()
+	return testTracing.Memory
+}
+
+func TestTracing_LeaseSpans(t *testing.T) {
+	spans := setupTracing(t)
+	cpm, db := setupLeaseTenant(t, TenantConfig{})
+
+	ctx, root := otel.Tracer("test").Start(context.Background(), "request")
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	_, err = lease.Exec(ctx, "SELECT 1", nil)
+	require.NoError(t, err)
+	require.NoError(t, cpm.ReleaseLease(ctx, lease.ID))
+	root.End()
+
+	byName := make(map[string]tracetest.SpanStub)
+	for _, span := range spans.GetSpans() {
+		if span.Name == "request" {
+			continue
+		}
+		byName[span.Name] = span
+		assert.Equal(t, root.SpanContext().TraceID(), span.SpanContext.TraceID(), span.Name)
+
+		tenant := ""
+		for _, attr := range span.Attributes {
+			if attr.Key == TenantAttribute {
+				tenant = attr.Value.AsString()
+			}
+		}
+		assert.Equal(t, "acme", tenant, span.Name)
+	}
+
+	for _, name := range []string{"pool.AcquireLease", "pool.GetConnection", "pool.Create", "pool.Query"} {
+		assert.Contains(t, byName, name)
+	}
+	assert.Equal(t, byName["pool.AcquireLease"].SpanContext.SpanID(), byName["pool.GetConnection"].Parent.SpanID())
+	assert.Equal(t, byName["pool.GetConnection"].SpanContext.SpanID(), byName["pool.Create"].Parent.SpanID())
+}
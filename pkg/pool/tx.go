@@ -0,0 +1,82 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidTxOptions is returned by Begin for options PostgreSQL would not accept
+var ErrInvalidTxOptions = errors.New("invalid transaction options")
+
+// ErrTransactionRolledBack is returned by Commit when the transaction had failed, so
+// PostgreSQL rolled it back instead
+var ErrTransactionRolledBack = errors.New("transaction was rolled back")
+
+// TxOptions are the characteristics of a transaction started with Begin
+type TxOptions struct {
+	// IsoLevel is a PostgreSQL isolation level such as "serializable". The server
+	// default is used when empty.
+	IsoLevel   string
+	ReadOnly   bool
+	Deferrable bool
+}
+
+var isoLevels = map[string]bool{
+	"serializable":     true,
+	"repeatable read":  true,
+	"read committed":   true,
+	"read uncommitted": true,
+}
+
+// beginSQL returns the statement starting a transaction with the options
+func (o TxOptions) beginSQL() (string, error) {
+	sql := "BEGIN"
+	if o.IsoLevel != "" {
+		level := strings.ToLower(strings.Join(strings.Fields(o.IsoLevel), " "))
+		if !isoLevels[level] {
+			return "", fmt.Errorf("%w: unknown isolation level %q", ErrInvalidTxOptions, o.IsoLevel)
+		}
+		sql += " ISOLATION LEVEL " + strings.ToUpper(level)
+	}
+	if o.ReadOnly {
+		sql += " READ ONLY"
+	}
+	if o.Deferrable {
+		sql += " DEFERRABLE"
+	}
+	return sql, nil
+}
+
+// Begin starts a transaction on the lease. In transaction mode the lease keeps its
+// backend until Commit or Rollback.
+func (l *Lease) Begin(ctx context.Context, opts TxOptions) error {
+	sql, err := opts.beginSQL()
+	if err != nil {
+		return err
+	}
+	if l.Mode == PoolModeStatement {
+		return ErrTransactionInStatementMode
+	}
+	_, err = l.Exec(ctx, sql, nil)
+	return err
+}
+
+// Commit commits the lease's transaction
+func (l *Lease) Commit(ctx context.Context) error {
+	result, err := l.Exec(ctx, "COMMIT", nil)
+	if err != nil {
+		return err
+	}
+	if result.CommandTag == "ROLLBACK" {
+		return ErrTransactionRolledBack
+	}
+	return nil
+}
+
+// Rollback rolls back the lease's transaction
+func (l *Lease) Rollback(ctx context.Context) error {
+	_, err := l.Exec(ctx, "ROLLBACK", nil)
+	return err
+}
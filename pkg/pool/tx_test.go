@@ -0,0 +1,85 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/pgtest"
+)
+
+func TestTxOptions_BeginSQL(t *testing.T) {
+	tests := []struct {
+		opts    TxOptions
+		want    string
+		wantErr bool
+	}{
+		{opts: TxOptions{}, want: "BEGIN"},
+		{opts: TxOptions{IsoLevel: "Serializable", ReadOnly: true, Deferrable: true}, want: "BEGIN ISOLATION LEVEL SERIALIZABLE READ ONLY DEFERRABLE"},
+		{opts: TxOptions{IsoLevel: "repeatable  read"}, want: "BEGIN ISOLATION LEVEL REPEATABLE READ"},
+		{opts: TxOptions{IsoLevel: "snapshot"}, wantErr: true},
+		{opts: TxOptions{IsoLevel: "read committed; DROP TABLE x"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.opts.beginSQL()
+		if tt.wantErr {
+			assert.ErrorIs(t, err, ErrInvalidTxOptions)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func TestLease_Transactions(t *testing.T) {
+	tests := []struct {
+		name string
+		mode PoolMode
+	}{
+		{name: "session mode", mode: PoolModeSession},
+		{name: "transaction mode", mode: PoolModeTransaction},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpm, db := setupLeaseTenant(t, TenantConfig{PoolMode: tt.mode})
+			ctx := context.Background()
+			lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+			require.NoError(t, err)
+			defer cpm.ReleaseLease(ctx, lease.ID)
+
+			require.NoError(t, lease.Begin(ctx, TxOptions{ReadOnly: true}))
+			assert.True(t, lease.Attached())
+			require.NoError(t, lease.Commit(ctx))
+			assert.Equal(t, tt.mode == PoolModeSession, lease.Attached())
+
+			require.NoError(t, lease.Begin(ctx, TxOptions{}))
+			require.NoError(t, lease.Rollback(ctx))
+			assert.Equal(t, 1, countQueries(db, "BEGIN READ ONLY"))
+			assert.Equal(t, 1, countQueries(db, "ROLLBACK"))
+
+			// A failed transaction is rolled back on commit
+			db.SetHandler(func(pid uint32, query string) (pgtest.Result, bool) {
+				if query == "COMMIT" {
+					return pgtest.Result{Tag: "ROLLBACK"}, true
+				}
+				return pgtest.Result{}, false
+			})
+			require.NoError(t, lease.Begin(ctx, TxOptions{}))
+			assert.ErrorIs(t, lease.Commit(ctx), ErrTransactionRolledBack)
+		})
+	}
+}
+
+func TestLease_BeginInStatementMode(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{PoolMode: PoolModeStatement})
+	ctx := context.Background()
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseLease(ctx, lease.ID)
+
+	assert.ErrorIs(t, lease.Begin(ctx, TxOptions{}), ErrTransactionInStatementMode)
+	assert.Zero(t, countQueries(db, "BEGIN"))
+}
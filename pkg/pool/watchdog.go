@@ -0,0 +1,180 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// defaultTerminateAfter is how long a cancelled backend may stay over a watchdog limit
+// before it is terminated
+const defaultTerminateAfter = 5 * time.Second
+
+// workState is what the watchdog knows of the work on a lease's backend
+type workState struct {
+	// statementSince is when the running statement started, zero when none is running
+	statementSince time.Time
+	// busySince is when the backend last left the idle state
+	busySince time.Time
+	// cancelledAt is when the watchdog cancelled the current work
+	cancelledAt time.Time
+	terminated  bool
+}
+
+// StatementStarted records that a statement was sent to the lease's backend
+func (l *Lease) StatementStarted() {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.work.statementSince = now
+	if l.work.busySince.IsZero() {
+		l.work.busySince = now
+	}
+}
+
+// StatementFinished records that the backend finished its statements. A backend left in
+// a transaction stays busy.
+func (l *Lease) StatementFinished(idle bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.work.statementSince = time.Time{}
+	if idle {
+		l.work = workState{}
+	}
+}
+
+// watchdogAction is what the watchdog does to a backend over a limit
+type watchdogAction string
+
+const (
+	watchdogCancel    watchdogAction = "cancel"
+	watchdogTerminate watchdogAction = "terminate"
+)
+
+// watchdogKill is a backend the watchdog acted on
+type watchdogKill struct {
+	lease  *Lease
+	pid    uint32
+	action watchdogAction
+	reason string
+	busy   time.Duration
+}
+
+// StartWatchdog enforces the tenants' watchdog policies every interval until ctx is done
+func (cpm *ConnectionPoolManager) StartWatchdog(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cpm.enforceWatchdog(ctx)
+			}
+		}
+	}()
+}
+
+// enforceWatchdog cancels the work of every backend over its tenant's limits and
+// terminates those still over them after the grace period. It returns what it did.
+func (cpm *ConnectionPoolManager) enforceWatchdog(ctx context.Context) []watchdogKill {
+	cpm.leaseLock.Lock()
+	leases := make([]*Lease, 0, len(cpm.leases))
+	for _, lease := range cpm.leases {
+		leases = append(leases, lease)
+	}
+	cpm.leaseLock.Unlock()
+
+	now := time.Now()
+	var kills []watchdogKill
+	for _, lease := range leases {
+		cfg, _ := cpm.TenantConfig(lease.TenantID)
+		if kill, ok := lease.checkWatchdog(cfg.Watchdog, now); ok {
+			kills = append(kills, kill)
+		}
+	}
+
+	for _, kill := range kills {
+		kill.lease.signalBackend(ctx, kill)
+	}
+	return kills
+}
+
+// checkWatchdog works out whether the lease's backend is over a limit of policy and
+// what to do about it, recording the action so it is taken once
+func (l *Lease) checkWatchdog(policy WatchdogPolicy, now time.Time) (watchdogKill, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn == nil || l.work.busySince.IsZero() || l.work.terminated {
+		return watchdogKill{}, false
+	}
+
+	kill := watchdogKill{lease: l, pid: l.conn.Conn().PgConn().PID(), busy: now.Sub(l.work.busySince)}
+	switch {
+	case policy.MaxStatementDuration.Duration > 0 && !l.work.statementSince.IsZero() &&
+		now.Sub(l.work.statementSince) > policy.MaxStatementDuration.Duration:
+		kill.reason = "statement_timeout"
+	case policy.MaxBusyDuration.Duration > 0 && kill.busy > policy.MaxBusyDuration.Duration:
+		kill.reason = "busy_timeout"
+	default:
+		return watchdogKill{}, false
+	}
+
+	terminateAfter := policy.TerminateAfter.Duration
+	if terminateAfter <= 0 {
+		terminateAfter = defaultTerminateAfter
+	}
+	switch {
+	case l.work.cancelledAt.IsZero():
+		kill.action = watchdogCancel
+		l.work.cancelledAt = now
+	case now.Sub(l.work.cancelledAt) > terminateAfter:
+		kill.action = watchdogTerminate
+		l.work.terminated = true
+	default:
+		return watchdogKill{}, false
+	}
+	return kill, true
+}
+
+// signalBackend cancels or terminates the backend with pg_cancel_backend or
+// pg_terminate_backend. They run on a connection of their own since the tenant's pool
+// may well be exhausted by the time the watchdog steps in.
+func (l *Lease) signalBackend(ctx context.Context, kill watchdogKill) {
+	logger := ctxLog(ctx)
+	event := logger.Warn().
+		Bool("audit", true).
+		Str("tenant_id", l.TenantID).
+		Str("lease", l.Handle()).
+		Uint32("backend_pid", kill.pid).
+		Str("action", string(kill.action)).
+		Str("reason", kill.reason).
+		Dur("busy", kill.busy).
+		Str("peer", l.Client.Peer).
+		Str("identity", l.Client.Identity).
+		Str("call_site", l.Client.CallSite)
+	watchdogKills.WithLabelValues(l.TenantID, string(kill.action), kill.reason).Inc()
+
+	err := execControl(ctx, l.pool.Config().ConnConfig.Config.Copy(), fmt.Sprintf("SELECT pg_%s_backend(%d)", kill.action, kill.pid))
+	if err != nil {
+		event.Err(err).Msg("Watchdog failed to signal backend")
+		return
+	}
+	event.Msg("Watchdog signalled backend over its limit")
+}
+
+// execControl runs a statement on a new connection outside the pool
+func execControl(ctx context.Context, cfg *pgconn.Config, sql string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultResetTimeout)
+	defer cancel()
+	conn, err := pgconn.ConnectConfig(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+	_, err = conn.Exec(ctx, sql).ReadAll()
+	return err
+}
@@ -0,0 +1,124 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/pgtest"
+)
+
+func TestConnectionPoolManager_WatchdogCancelsLongStatement(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{Watchdog: WatchdogPolicy{MaxStatementDuration: Duration{Duration: 20 * time.Millisecond}}})
+	ctx := context.Background()
+	kills := testutil.ToFloat64(watchdogKills.WithLabelValues("acme", "cancel", "statement_timeout"))
+
+	// The scan runs until its backend is cancelled
+	db.SetHandler(func(pid uint32, query string) (pgtest.Result, bool) {
+		if query != "SELECT * FROM events" {
+			return pgtest.Result{}, false
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for !slices.Contains(db.Queries(), fmt.Sprintf("SELECT pg_cancel_backend(%d)", pid)) && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		return pgtest.Result{Err: "canceling statement due to user request"}, true
+	})
+
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseLease(ctx, lease.ID)
+	done := make(chan error, 1)
+	go func() {
+		_, err := lease.Exec(ctx, "SELECT * FROM events", nil)
+		done <- err
+	}()
+
+	// A statement within the limit is left alone
+	require.Eventually(t, func() bool { return countQueries(db, "SELECT * FROM events") == 1 }, time.Second, 5*time.Millisecond)
+	assert.Empty(t, cpm.enforceWatchdog(ctx))
+
+	time.Sleep(30 * time.Millisecond)
+	actions := cpm.enforceWatchdog(ctx)
+	require.Len(t, actions, 1)
+	assert.Equal(t, watchdogCancel, actions[0].action)
+	assert.Equal(t, "statement_timeout", actions[0].reason)
+
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "canceling statement")
+	case <-time.After(time.Second):
+		t.Fatal("statement was not cancelled")
+	}
+	assert.Equal(t, kills+1, testutil.ToFloat64(watchdogKills.WithLabelValues("acme", "cancel", "statement_timeout")))
+
+	// The lease goes on with its backend
+	_, err = lease.Exec(ctx, "SELECT 1", nil)
+	require.NoError(t, err)
+	assert.Empty(t, cpm.enforceWatchdog(ctx))
+}
+
+func TestConnectionPoolManager_WatchdogTerminatesBusyBackend(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{Watchdog: WatchdogPolicy{
+		MaxBusyDuration: Duration{Duration: 10 * time.Millisecond},
+		TerminateAfter:  Duration{Duration: 10 * time.Millisecond},
+	}})
+	ctx := context.Background()
+	db.SetHandler(func(_ uint32, query string) (pgtest.Result, bool) {
+		var pid uint32
+		if _, err := fmt.Sscanf(query, "SELECT pg_terminate_backend(%d)", &pid); err != nil {
+			return pgtest.Result{}, false
+		}
+		db.Kill(pid)
+		return pgtest.Result{Columns: []string{"pg_terminate_backend"}, Rows: [][]string{{"t"}}, Tag: "SELECT 1"}, true
+	})
+
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseLease(ctx, lease.ID)
+	conn, err := lease.Attach(ctx)
+	require.NoError(t, err)
+	pid := conn.Conn().PgConn().PID()
+
+	// An idle transaction keeps the backend busy, which a cancel does not fix
+	_, err = lease.Exec(ctx, "BEGIN", nil)
+	require.NoError(t, err)
+	time.Sleep(15 * time.Millisecond)
+	actions := cpm.enforceWatchdog(ctx)
+	require.Len(t, actions, 1)
+	assert.Equal(t, watchdogCancel, actions[0].action)
+	assert.Equal(t, "busy_timeout", actions[0].reason)
+	assert.Empty(t, cpm.enforceWatchdog(ctx))
+
+	time.Sleep(15 * time.Millisecond)
+	actions = cpm.enforceWatchdog(ctx)
+	require.Len(t, actions, 1)
+	assert.Equal(t, watchdogTerminate, actions[0].action)
+	assert.Contains(t, db.Queries(), fmt.Sprintf("SELECT pg_terminate_backend(%d)", pid))
+	assert.Empty(t, cpm.enforceWatchdog(ctx))
+
+	// The lease's next statement fails and a fresh backend serves the one after
+	_, err = lease.Exec(ctx, "SELECT 1", nil)
+	assert.Error(t, err)
+	_, err = lease.Exec(ctx, "SELECT 1", nil)
+	require.NoError(t, err)
+}
+
+func TestConnectionPoolManager_WatchdogWithoutLimits(t *testing.T) {
+	cpm, db := setupLeaseTenant(t, TenantConfig{})
+	ctx := context.Background()
+
+	lease, err := cpm.AcquireLease(ctx, "acme", db.DSN())
+	require.NoError(t, err)
+	defer cpm.ReleaseLease(ctx, lease.ID)
+	_, err = lease.Exec(ctx, "BEGIN", nil)
+	require.NoError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+	assert.Empty(t, cpm.enforceWatchdog(ctx))
+}
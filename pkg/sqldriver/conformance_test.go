@@ -0,0 +1,334 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teresa-solution/connection-pool-manager/internal/auth"
+	"github.com/teresa-solution/connection-pool-manager/internal/pgtest"
+	"github.com/teresa-solution/connection-pool-manager/internal/service"
+	"github.com/teresa-solution/connection-pool-manager/pkg/pool"
+	"google.golang.org/grpc"
+)
+
+// fixture is a database/sql handle on tenant "acme", served through the pool
+// manager by a fake database
+type fixture struct {
+	db          *sql.DB
+	pg          *pgtest.Server
+	poolManager *pool.ConnectionPoolManager
+}
+
+func setupFixture(t *testing.T, mode pool.PoolMode) *fixture {
+	pg, err := pgtest.NewServer()
+	require.NoError(t, err)
+
+	poolManager := pool.NewConnectionPoolManager()
+	poolManager.SetTenantConfig(pool.TenantConfig{TenantID: "acme", DSN: pg.DSN(), PoolMode: mode})
+
+	authenticator := auth.NewAuthenticator([]auth.Token{{Name: "app", Token: "s3cret"}}, false)
+	server := grpc.NewServer(grpc.UnaryInterceptor(authenticator.UnaryServerInterceptor()))
+	service.RegisterServer(server, service.NewConnectionPoolServiceServerWithManager(poolManager))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+
+	db, err := sql.Open(DriverName, fmt.Sprintf("tenant=acme&addr=%s&insecure=true&token=s3cret", listener.Addr()))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		db.Close()
+		server.Stop()
+		poolManager.EvictTenant(context.Background(), "acme")
+		pg.Close()
+	})
+	return &fixture{db: db, pg: pg, poolManager: poolManager}
+}
+
+// handle answers statements matching query with result
+func (f *fixture) handle(query string, result pgtest.Result) {
+	f.pg.SetHandler(func(pid uint32, stmt string) (pgtest.Result, bool) {
+		if stmt == query {
+			return result, true
+		}
+		return pgtest.Result{}, false
+	})
+}
+
+func (f *fixture) count(query string) int {
+	n := 0
+	for _, q := range f.pg.Queries() {
+		if q == query {
+			n++
+		}
+	}
+	return n
+}
+
+// TestConformance checks the driver behaves as database/sql expects, in every pool mode
+// that supports it
+func TestConformance(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name string
+		run  func(t *testing.T, f *fixture)
+	}{
+		{"Ping", func(t *testing.T, f *fixture) {
+			require.NoError(t, f.db.PingContext(ctx))
+		}},
+		{"ParametersRoundTrip", func(t *testing.T, f *fixture) {
+			at := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+			var (
+				s   string
+				n   int64
+				x   float64
+				b   bool
+				ts  string
+				nul sql.NullString
+			)
+			err := f.db.QueryRowContext(ctx, "SELECT $1, $2, $3, $4, $5, $6", "text", 42, 1.5, true, at, nil).Scan(&s, &n, &x, &b, &ts, &nul)
+			require.NoError(t, err)
+			assert.Equal(t, "text", s)
+			assert.Equal(t, int64(42), n)
+			assert.Equal(t, 1.5, x)
+			assert.True(t, b)
+			assert.Equal(t, "2024-05-01T12:30:00Z", ts)
+			assert.False(t, nul.Valid)
+		}},
+		{"ByteaRoundTrip", func(t *testing.T, f *fixture) {
+			// Bytes that are not UTF-8 go into a bytea parameter and come back the same
+			want := []byte{0xff, 0x00, 0x80, '\\', 'x'}
+			var got []byte
+			require.NoError(t, f.db.QueryRowContext(ctx, "SELECT $1::bytea", want).Scan(&got))
+			assert.Equal(t, want, got)
+
+			// Other parameters take []byte as text
+			var text string
+			require.NoError(t, f.db.QueryRowContext(ctx, "SELECT $1", []byte(`{"a":1}`)).Scan(&text))
+			assert.Equal(t, `{"a":1}`, text)
+		}},
+		{"TypedColumns", func(t *testing.T, f *fixture) {
+			f.handle("SELECT id, active, created_at, note FROM accounts", pgtest.Result{
+				Columns: []string{"id", "active", "created_at", "note"},
+				Types:   []uint32{pgtype.Int4OID, pgtype.BoolOID, pgtype.TimestamptzOID, pgtype.TextOID},
+				Rows:    [][]string{{"7", "t", "2024-05-01 12:30:00.5+02", "hi"}},
+				Tag:     "SELECT 1",
+			})
+			var values [4]interface{}
+			err := f.db.QueryRowContext(ctx, "SELECT id, active, created_at, note FROM accounts").Scan(&values[0], &values[1], &values[2], &values[3])
+			require.NoError(t, err)
+			assert.Equal(t, int64(7), values[0])
+			assert.Equal(t, true, values[1])
+			require.IsType(t, time.Time{}, values[2])
+			assert.True(t, time.Date(2024, 5, 1, 10, 30, 0, 5e8, time.UTC).Equal(values[2].(time.Time)))
+			assert.Equal(t, "hi", values[3])
+		}},
+		{"MultipleRows", func(t *testing.T, f *fixture) {
+			f.handle("SELECT id, name FROM accounts", pgtest.Result{
+				Columns: []string{"id", "name"},
+				Rows:    [][]string{{"1", "ada"}, {"2", "grace"}},
+				Tag:     "SELECT 2",
+			})
+			rows, err := f.db.QueryContext(ctx, "SELECT id, name FROM accounts")
+			require.NoError(t, err)
+			defer rows.Close()
+
+			columns, err := rows.Columns()
+			require.NoError(t, err)
+			assert.Equal(t, []string{"id", "name"}, columns)
+			var names []string
+			for rows.Next() {
+				var (
+					id   int
+					name string
+				)
+				require.NoError(t, rows.Scan(&id, &name))
+				names = append(names, name)
+			}
+			require.NoError(t, rows.Err())
+			assert.Equal(t, []string{"ada", "grace"}, names)
+		}},
+		{"NoRows", func(t *testing.T, f *fixture) {
+			var id int
+			err := f.db.QueryRowContext(ctx, "SELECT id FROM accounts WHERE false").Scan(&id)
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+		}},
+		{"RowsAffected", func(t *testing.T, f *fixture) {
+			f.handle("UPDATE accounts SET active = true", pgtest.Result{Tag: "UPDATE 3"})
+			res, err := f.db.ExecContext(ctx, "UPDATE accounts SET active = true")
+			require.NoError(t, err)
+			affected, err := res.RowsAffected()
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), affected)
+			_, err = res.LastInsertId()
+			assert.Error(t, err)
+		}},
+		{"PreparedStatement", func(t *testing.T, f *fixture) {
+			stmt, err := f.db.PrepareContext(ctx, "SELECT $1")
+			require.NoError(t, err)
+			defer stmt.Close()
+			for _, want := range []string{"a", "b"} {
+				var got string
+				require.NoError(t, stmt.QueryRowContext(ctx, want).Scan(&got))
+				assert.Equal(t, want, got)
+			}
+		}},
+		{"StatementError", func(t *testing.T, f *fixture) {
+			f.handle("SELECT broken", pgtest.Result{Err: "syntax error at or near \"broken\""})
+			_, err := f.db.ExecContext(ctx, "SELECT broken")
+			assert.ErrorContains(t, err, "syntax error")
+		}},
+		{"NamedArgumentsRejected", func(t *testing.T, f *fixture) {
+			_, err := f.db.ExecContext(ctx, "SELECT $1", sql.Named("id", 1))
+			assert.ErrorContains(t, err, "named argument")
+		}},
+		{"Commit", func(t *testing.T, f *fixture) {
+			tx, err := f.db.BeginTx(ctx, nil)
+			require.NoError(t, err)
+			_, err = tx.ExecContext(ctx, "INSERT INTO accounts VALUES ($1)", "ada")
+			require.NoError(t, err)
+			require.NoError(t, tx.Commit())
+			assert.Equal(t, 1, f.count("BEGIN"))
+			assert.Equal(t, 1, f.count("COMMIT"))
+		}},
+		{"Rollback", func(t *testing.T, f *fixture) {
+			tx, err := f.db.BeginTx(ctx, nil)
+			require.NoError(t, err)
+			require.NoError(t, tx.Rollback())
+			assert.Equal(t, 1, f.count("ROLLBACK"))
+		}},
+		{"FailedCommit", func(t *testing.T, f *fixture) {
+			f.handle("COMMIT", pgtest.Result{Tag: "ROLLBACK"})
+			tx, err := f.db.BeginTx(ctx, nil)
+			require.NoError(t, err)
+			assert.Error(t, tx.Commit())
+		}},
+		{"TransactionOptions", func(t *testing.T, f *fixture) {
+			tx, err := f.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true})
+			require.NoError(t, err)
+			require.NoError(t, tx.Commit())
+			assert.Equal(t, 1, f.count("BEGIN ISOLATION LEVEL SERIALIZABLE READ ONLY"))
+
+			_, err = f.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSnapshot})
+			assert.ErrorContains(t, err, "unsupported isolation level")
+		}},
+		{"PinnedConnection", func(t *testing.T, f *fixture) {
+			conn, err := f.db.Conn(ctx)
+			require.NoError(t, err)
+			defer conn.Close()
+			tx, err := conn.BeginTx(ctx, nil)
+			require.NoError(t, err)
+			var first, second string
+			require.NoError(t, tx.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&first))
+			require.NoError(t, tx.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&second))
+			require.NoError(t, tx.Commit())
+			// A transaction keeps its backend in every pool mode
+			assert.Equal(t, first, second)
+		}},
+		{"ReconnectsAfterEviction", func(t *testing.T, f *fixture) {
+			f.db.SetMaxIdleConns(1)
+			require.NoError(t, f.db.PingContext(ctx))
+			_, err := f.poolManager.EvictTenant(ctx, "acme")
+			require.NoError(t, err)
+
+			// The idle connection's lease is gone; database/sql retries on a new one
+			var got string
+			require.NoError(t, f.db.QueryRowContext(ctx, "SELECT $1", "again").Scan(&got))
+			assert.Equal(t, "again", got)
+		}},
+		{"ConcurrentConnections", func(t *testing.T, f *fixture) {
+			f.db.SetMaxOpenConns(4)
+			errs := make(chan error, 16)
+			for i := 0; i < 16; i++ {
+				go func(i int) {
+					var got string
+					err := f.db.QueryRowContext(ctx, "SELECT $1", fmt.Sprint(i)).Scan(&got)
+					if err == nil && got != fmt.Sprint(i) {
+						err = fmt.Errorf("got %s, want %d", got, i)
+					}
+					errs <- err
+				}(i)
+			}
+			for i := 0; i < 16; i++ {
+				assert.NoError(t, <-errs)
+			}
+			assert.LessOrEqual(t, f.db.Stats().OpenConnections, 4)
+		}},
+	}
+
+	for _, mode := range []pool.PoolMode{pool.PoolModeSession, pool.PoolModeTransaction} {
+		for _, tc := range cases {
+			t.Run(string(mode)+"/"+tc.name, func(t *testing.T) {
+				tc.run(t, setupFixture(t, mode))
+			})
+		}
+	}
+}
+
+func TestConformance_StatementMode(t *testing.T) {
+	f := setupFixture(t, pool.PoolModeStatement)
+	ctx := context.Background()
+
+	var got string
+	require.NoError(t, f.db.QueryRowContext(ctx, "SELECT $1", "ok").Scan(&got))
+	assert.Equal(t, "ok", got)
+
+	_, err := f.db.BeginTx(ctx, nil)
+	assert.ErrorContains(t, err, "transactions are not allowed")
+}
+
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsn     string
+		check   func(t *testing.T, cfg Config)
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			dsn:  "tenant=acme",
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, "acme", cfg.TenantID)
+				assert.Equal(t, "localhost:50052", cfg.Addr)
+				assert.False(t, cfg.Options.Insecure)
+				assert.NotNil(t, cfg.Options.TLSConfig)
+			},
+		},
+		{
+			name: "all options",
+			dsn:  "tenant=acme&addr=pool:1234&token=t&insecure=true&server_name=pool.internal&read_only=true&min_lsn=16/B374D848&dsn=" + strings.ReplaceAll("host=db dbname=acme", " ", "%20"),
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, "pool:1234", cfg.Addr)
+				assert.Equal(t, "t", cfg.Options.Token)
+				assert.True(t, cfg.Options.Insecure)
+				assert.Equal(t, "pool.internal", cfg.Options.TLSConfig.ServerName)
+				assert.Len(t, cfg.Acquire, 3)
+			},
+		},
+		{name: "no tenant", dsn: "addr=pool:1234", wantErr: "no tenant"},
+		{name: "unknown parameter", dsn: "tenant=acme&colour=blue", wantErr: "unknown data source name parameter"},
+		{name: "invalid bool", dsn: "tenant=acme&insecure=maybe", wantErr: "invalid insecure"},
+		{name: "missing CA", dsn: "tenant=acme&ca=/does/not/exist", wantErr: "failed to read CA file"},
+		{name: "missing certificate", dsn: "tenant=acme&cert=/does/not/exist&key=/does/not/exist", wantErr: "failed to load client certificate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseDSN(tt.dsn)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.check(t, cfg)
+		})
+	}
+}
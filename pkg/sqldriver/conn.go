@@ -0,0 +1,295 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/teresa-solution/connection-pool-manager/pkg/client"
+)
+
+// conn is a database/sql connection holding a lease
+type conn struct {
+	lease *client.Lease
+	// closeClient is closed with the connection when it was opened without a connector
+	closeClient *client.Client
+	// bad is set once the lease is gone, so database/sql discards the connection
+	bad atomic.Bool
+}
+
+var (
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+	_ driver.SessionResetter    = (*conn)(nil)
+	_ driver.Validator          = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+	_ driver.StmtExecContext    = (*stmt)(nil)
+	_ driver.StmtQueryContext   = (*stmt)(nil)
+)
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext returns a statement that is sent with its arguments on every
+// execution; nothing is prepared on the server
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+// Close releases the lease
+func (c *conn) Close() error {
+	err := c.lease.Release(context.Background())
+	if errors.Is(err, client.ErrNotFound) {
+		err = nil
+	}
+	if c.closeClient != nil {
+		c.closeClient.Close()
+	}
+	return err
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+var isoLevels = map[sql.IsolationLevel]string{
+	sql.LevelDefault:         "",
+	sql.LevelReadUncommitted: "read uncommitted",
+	sql.LevelReadCommitted:   "read committed",
+	sql.LevelRepeatableRead:  "repeatable read",
+	sql.LevelSerializable:    "serializable",
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	level, ok := isoLevels[sql.IsolationLevel(opts.Isolation)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported isolation level %s", sql.IsolationLevel(opts.Isolation))
+	}
+	t, err := c.lease.Begin(ctx, client.TxOptions{IsoLevel: level, ReadOnly: opts.ReadOnly})
+	if err != nil {
+		return nil, c.checkErr(err)
+	}
+	return &tx{tx: t}, nil
+}
+
+// CheckNamedValue rejects named arguments, which PostgreSQL does not have, and
+// converts the rest as database/sql does by default
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nv.Name != "" {
+		return fmt.Errorf("named argument %q is not supported; use $%d", nv.Name, nv.Ordinal)
+	}
+	value, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	nv.Value = value
+	return nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.exec(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return result{tag: res.CommandTag}, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res, err := c.exec(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{columns: res.Columns, types: res.ColumnTypes, data: res.Rows}, nil
+}
+
+func (c *conn) exec(ctx context.Context, query string, args []driver.NamedValue) (*client.Result, error) {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	res, err := c.lease.Exec(ctx, query, values...)
+	if err != nil {
+		return nil, c.checkErr(err)
+	}
+	return res, nil
+}
+
+// checkErr marks the connection bad when its lease is gone, e.g. because the tenant
+// was evicted. Nothing ran in that case, so database/sql may retry on a new connection.
+func (c *conn) checkErr(err error) error {
+	if errors.Is(err, client.ErrNotFound) {
+		c.bad.Store(true)
+		return driver.ErrBadConn
+	}
+	return err
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	_, err := c.exec(ctx, "SELECT 1", nil)
+	return err
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if c.bad.Load() {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	return !c.bad.Load()
+}
+
+// stmt is a statement text bound to a connection
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+// NumInput returns -1 since placeholders are counted by the server
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+// tx is a transaction on the connection's lease
+type tx struct {
+	tx *client.Tx
+}
+
+func (t *tx) Commit() error {
+	return t.tx.Commit(context.Background())
+}
+
+func (t *tx) Rollback() error {
+	return t.tx.Rollback(context.Background())
+}
+
+// result reports the rows a statement affected, taken from its command tag
+type result struct {
+	tag string
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return 0, errors.New("LastInsertId is not supported; use RETURNING")
+}
+
+func (r result) RowsAffected() (int64, error) {
+	fields := strings.Fields(r.tag)
+	if len(fields) < 2 {
+		return 0, nil
+	}
+	switch fields[0] {
+	case "INSERT", "UPDATE", "DELETE", "SELECT", "MERGE", "COPY", "FETCH", "MOVE":
+		return strconv.ParseInt(fields[len(fields)-1], 10, 64)
+	}
+	return 0, nil
+}
+
+// rows iterates over a statement's rows. Values of the types database/sql has a Go
+// type for are decoded by the column's type OID, and others are returned as strings in
+// PostgreSQL's text format. NULL is nil.
+type rows struct {
+	columns []string
+	types   []uint32
+	data    [][]sql.NullString
+	next    int
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.data) {
+		return io.EOF
+	}
+	for i, value := range r.data[r.next] {
+		if !value.Valid {
+			dest[i] = nil
+			continue
+		}
+		var oid uint32
+		if i < len(r.types) {
+			oid = r.types[i]
+		}
+		decoded, err := decodeValue(oid, value.String)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", r.columns[i], err)
+		}
+		dest[i] = decoded
+	}
+	r.next++
+	return nil
+}
+
+// typeMap decodes values of the types that have a driver.Value counterpart
+var typeMap = pgtype.NewMap()
+
+// decodeValue turns a value in text format into the driver.Value for its type: bytea
+// into []byte, so binary data is not handed out as hex, and booleans, integers, floats
+// and timestamps into their Go types. Other types stay strings.
+func decodeValue(oid uint32, text string) (driver.Value, error) {
+	switch oid {
+	case pgtype.ByteaOID, pgtype.BoolOID, pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID,
+		pgtype.Float4OID, pgtype.Float8OID, pgtype.DateOID, pgtype.TimestampOID, pgtype.TimestamptzOID:
+	default:
+		return text, nil
+	}
+	t, _ := typeMap.TypeForOID(oid)
+	value, err := t.Codec.DecodeValue(typeMap, oid, pgtype.TextFormatCode, []byte(text))
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case float32:
+		return float64(v), nil
+	}
+	return value, nil
+}
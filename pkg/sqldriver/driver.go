@@ -0,0 +1,193 @@
+// Package sqldriver is a database/sql driver that runs queries through the connection
+// pool manager's gRPC API. Importing it registers the "teresapool" driver:
+//
+//	db, err := sql.Open("teresapool", "tenant=acme&addr=pool-manager:50052&token=s3cret")
+//
+// Each database/sql connection holds a lease on the tenant's pool.
+package sqldriver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/teresa-solution/connection-pool-manager/pkg/client"
+)
+
+// DriverName is the name the driver is registered under
+const DriverName = "teresapool"
+
+func init() {
+	sql.Register(DriverName, &Driver{})
+}
+
+// Config is a parsed data source name
+type Config struct {
+	TenantID string
+	// Addr is the gRPC address of the service. Defaults to localhost:50052.
+	Addr    string
+	Options client.Options
+	Acquire []client.AcquireOption
+}
+
+// ParseDSN parses a data source name of URL query parameters:
+//
+//	tenant                tenant to lease connections for (required)
+//	addr                  gRPC address of the service
+//	token                 bearer token
+//	ca                    CA bundle verifying the server certificate
+//	cert, key             client certificate for mTLS
+//	server_name           name expected on the server certificate
+//	insecure              connect without TLS
+//	insecure_skip_verify  do not verify the server certificate
+//	read_only             route leases to replicas
+//	min_lsn               require replicas that have replayed this LSN
+//	dsn                   database to connect to instead of the tenant's own
+//	application_name      name shown as the call site of the connections' leases
+func ParseDSN(dsn string) (Config, error) {
+	values, err := url.ParseQuery(dsn)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid data source name: %w", err)
+	}
+
+	cfg := Config{Addr: "localhost:50052"}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	var certFile, keyFile string
+	for key := range values {
+		value := values.Get(key)
+		switch key {
+		case "tenant":
+			cfg.TenantID = value
+		case "addr":
+			cfg.Addr = value
+		case "token":
+			cfg.Options.Token = value
+		case "ca":
+			pem, err := os.ReadFile(value)
+			if err != nil {
+				return Config{}, fmt.Errorf("failed to read CA file: %w", err)
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				return Config{}, fmt.Errorf("no certificates found in %s", value)
+			}
+		case "cert":
+			certFile = value
+		case "key":
+			keyFile = value
+		case "server_name":
+			tlsConfig.ServerName = value
+		case "insecure", "insecure_skip_verify", "read_only":
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return Config{}, fmt.Errorf("invalid %s: %q", key, value)
+			}
+			switch {
+			case key == "insecure":
+				cfg.Options.Insecure = enabled
+			case key == "insecure_skip_verify":
+				tlsConfig.InsecureSkipVerify = enabled
+			case enabled:
+				cfg.Acquire = append(cfg.Acquire, client.ReadOnly())
+			}
+		case "min_lsn":
+			cfg.Acquire = append(cfg.Acquire, client.MinLSN(value))
+		case "dsn":
+			cfg.Acquire = append(cfg.Acquire, client.WithDSN(value))
+		case "application_name":
+			cfg.Acquire = append(cfg.Acquire, client.WithCallSite(value))
+		default:
+			return Config{}, fmt.Errorf("unknown data source name parameter %q", key)
+		}
+	}
+	if cfg.TenantID == "" {
+		return Config{}, fmt.Errorf("data source name has no tenant")
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	cfg.Options.TLSConfig = tlsConfig
+	return cfg, nil
+}
+
+// Driver is the database/sql driver
+type Driver struct{}
+
+// Open opens a connection with its own client. sql.Open uses OpenConnector instead,
+// which shares one client between connections.
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	connector, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	c := connector.(*Connector)
+	dc, err := c.Connect(context.Background())
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	dc.(*conn).closeClient = c.client
+	return dc, nil
+}
+
+// OpenConnector parses dsn and creates a client for the service
+func (d *Driver) OpenConnector(dsn string) (driver.Connector, error) {
+	cfg, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	c, err := client.New(cfg.Addr, cfg.Options)
+	if err != nil {
+		return nil, err
+	}
+	return &Connector{client: c, tenantID: cfg.TenantID, acquire: cfg.Acquire, owned: true}, nil
+}
+
+// Connector opens connections leasing from one tenant's pool
+type Connector struct {
+	client   *client.Client
+	tenantID string
+	acquire  []client.AcquireOption
+	// owned is set when the connector created the client and so closes it
+	owned bool
+}
+
+// NewConnector creates a connector using an existing client, for sql.OpenDB
+func NewConnector(c *client.Client, tenantID string, opts ...client.AcquireOption) *Connector {
+	return &Connector{client: c, tenantID: tenantID, acquire: opts}
+}
+
+// Connect leases a connection from the tenant's pool
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	// database/sql opens connections on its own schedule, so the caller is no call site
+	opts := append([]client.AcquireOption{client.WithCallSite(DriverName)}, c.acquire...)
+	lease, err := c.client.Acquire(ctx, c.tenantID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{lease: lease}, nil
+}
+
+// Driver returns the teresapool driver
+func (c *Connector) Driver() driver.Driver {
+	return &Driver{}
+}
+
+// Close closes the client when the connector created it. database/sql calls it from
+// DB.Close.
+func (c *Connector) Close() error {
+	if !c.owned {
+		return nil
+	}
+	return c.client.Close()
+}
@@ -7,8 +7,11 @@
 package connectionpool
 
 import (
+	_ "github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-openapiv2/options"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -24,7 +27,11 @@ const (
 type ConnectionRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	TenantId      string                 `protobuf:"bytes,1,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
-	Dsn           string                 `protobuf:"bytes,2,opt,name=dsn,proto3" json:"dsn,omitempty"` // Data Source Name for PostgreSQL
+	Dsn           string                 `protobuf:"bytes,2,opt,name=dsn,proto3" json:"dsn,omitempty"`                                  // Data Source Name for PostgreSQL
+	ReadOnly      bool                   `protobuf:"varint,3,opt,name=read_only,json=readOnly,proto3" json:"read_only,omitempty"`       // Route to a replica within the tenant's lag threshold
+	MinLsn        string                 `protobuf:"bytes,4,opt,name=min_lsn,json=minLsn,proto3" json:"min_lsn,omitempty"`              // Require a replica that has replayed this LSN, e.g. "16/B374D848"
+	TtlSeconds    int32                  `protobuf:"varint,5,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"` // Lease TTL; the tenant's when zero
+	CallSite      string                 `protobuf:"bytes,6,opt,name=call_site,json=callSite,proto3" json:"call_site,omitempty"`        // Where in the caller's code the lease is taken, for leak diagnostics
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -73,10 +80,40 @@ func (x *ConnectionRequest) GetDsn() string {
 	return ""
 }
 
+func (x *ConnectionRequest) GetReadOnly() bool {
+	if x != nil {
+		return x.ReadOnly
+	}
+	return false
+}
+
+func (x *ConnectionRequest) GetMinLsn() string {
+	if x != nil {
+		return x.MinLsn
+	}
+	return ""
+}
+
+func (x *ConnectionRequest) GetTtlSeconds() int32 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *ConnectionRequest) GetCallSite() string {
+	if x != nil {
+		return x.CallSite
+	}
+	return ""
+}
+
 type ConnectionResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ConnectionId  string                 `protobuf:"bytes,1,opt,name=connection_id,json=connectionId,proto3" json:"connection_id,omitempty"`
 	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	FromReplica   bool                   `protobuf:"varint,3,opt,name=from_replica,json=fromReplica,proto3" json:"from_replica,omitempty"`
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // The lease is reclaimed unless renewed by then
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -125,9 +162,24 @@ func (x *ConnectionResponse) GetError() string {
 	return ""
 }
 
+func (x *ConnectionResponse) GetFromReplica() bool {
+	if x != nil {
+		return x.FromReplica
+	}
+	return false
+}
+
+func (x *ConnectionResponse) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
 type ConnectionRelease struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ConnectionId  string                 `protobuf:"bytes,1,opt,name=connection_id,json=connectionId,proto3" json:"connection_id,omitempty"`
+	TenantId      string                 `protobuf:"bytes,2,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"` // Tenant the lease was acquired for; when set, a lease of another tenant is not found
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -169,6 +221,13 @@ func (x *ConnectionRelease) GetConnectionId() string {
 	return ""
 }
 
+func (x *ConnectionRelease) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
 type ReleaseResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
@@ -221,27 +280,29 @@ func (x *ReleaseResponse) GetError() string {
 	return ""
 }
 
-type StatsRequest struct {
+type RenewLeaseRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	TenantId      string                 `protobuf:"bytes,1,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	ConnectionId  string                 `protobuf:"bytes,1,opt,name=connection_id,json=connectionId,proto3" json:"connection_id,omitempty"`
+	TtlSeconds    int32                  `protobuf:"varint,2,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"` // New TTL from now; the tenant's when zero
+	TenantId      string                 `protobuf:"bytes,3,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`        // Tenant the lease was acquired for; when set, a lease of another tenant is not found
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StatsRequest) Reset() {
-	*x = StatsRequest{}
+func (x *RenewLeaseRequest) Reset() {
+	*x = RenewLeaseRequest{}
 	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StatsRequest) String() string {
+func (x *RenewLeaseRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StatsRequest) ProtoMessage() {}
+func (*RenewLeaseRequest) ProtoMessage() {}
 
-func (x *StatsRequest) ProtoReflect() protoreflect.Message {
+func (x *RenewLeaseRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -253,42 +314,54 @@ func (x *StatsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StatsRequest.ProtoReflect.Descriptor instead.
-func (*StatsRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use RenewLeaseRequest.ProtoReflect.Descriptor instead.
+func (*RenewLeaseRequest) Descriptor() ([]byte, []int) {
 	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *StatsRequest) GetTenantId() string {
+func (x *RenewLeaseRequest) GetConnectionId() string {
+	if x != nil {
+		return x.ConnectionId
+	}
+	return ""
+}
+
+func (x *RenewLeaseRequest) GetTtlSeconds() int32 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *RenewLeaseRequest) GetTenantId() string {
 	if x != nil {
 		return x.TenantId
 	}
 	return ""
 }
 
-type StatsResponse struct {
-	state             protoimpl.MessageState `protogen:"open.v1"`
-	ActiveConnections int32                  `protobuf:"varint,1,opt,name=active_connections,json=activeConnections,proto3" json:"active_connections,omitempty"`
-	IdleConnections   int32                  `protobuf:"varint,2,opt,name=idle_connections,json=idleConnections,proto3" json:"idle_connections,omitempty"`
-	TotalConnections  int32                  `protobuf:"varint,3,opt,name=total_connections,json=totalConnections,proto3" json:"total_connections,omitempty"`
-	Error             string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+type RenewLeaseResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StatsResponse) Reset() {
-	*x = StatsResponse{}
+func (x *RenewLeaseResponse) Reset() {
+	*x = RenewLeaseResponse{}
 	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StatsResponse) String() string {
+func (x *RenewLeaseResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StatsResponse) ProtoMessage() {}
+func (*RenewLeaseResponse) ProtoMessage() {}
 
-func (x *StatsResponse) ProtoReflect() protoreflect.Message {
+func (x *RenewLeaseResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -300,106 +373,3021 @@ func (x *StatsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StatsResponse.ProtoReflect.Descriptor instead.
-func (*StatsResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use RenewLeaseResponse.ProtoReflect.Descriptor instead.
+func (*RenewLeaseResponse) Descriptor() ([]byte, []int) {
 	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *StatsResponse) GetActiveConnections() int32 {
+func (x *RenewLeaseResponse) GetExpiresAt() *timestamppb.Timestamp {
 	if x != nil {
-		return x.ActiveConnections
+		return x.ExpiresAt
 	}
-	return 0
+	return nil
 }
 
-func (x *StatsResponse) GetIdleConnections() int32 {
+func (x *RenewLeaseResponse) GetError() string {
 	if x != nil {
-		return x.IdleConnections
+		return x.Error
 	}
-	return 0
+	return ""
 }
 
-func (x *StatsResponse) GetTotalConnections() int32 {
+type HoldLeaseRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Msg:
+	//
+	//	*HoldLeaseRequest_Acquire
+	//	*HoldLeaseRequest_Ping
+	Msg           isHoldLeaseRequest_Msg `protobuf_oneof:"msg"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HoldLeaseRequest) Reset() {
+	*x = HoldLeaseRequest{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HoldLeaseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HoldLeaseRequest) ProtoMessage() {}
+
+func (x *HoldLeaseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[6]
 	if x != nil {
-		return x.TotalConnections
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *StatsResponse) GetError() string {
+// Deprecated: Use HoldLeaseRequest.ProtoReflect.Descriptor instead.
+func (*HoldLeaseRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *HoldLeaseRequest) GetMsg() isHoldLeaseRequest_Msg {
 	if x != nil {
-		return x.Error
+		return x.Msg
 	}
-	return ""
+	return nil
 }
 
-var File_internal_grpc_connectionpool_connection_pool_proto protoreflect.FileDescriptor
+func (x *HoldLeaseRequest) GetAcquire() *ConnectionRequest {
+	if x != nil {
+		if x, ok := x.Msg.(*HoldLeaseRequest_Acquire); ok {
+			return x.Acquire
+		}
+	}
+	return nil
+}
 
-const file_internal_grpc_connectionpool_connection_pool_proto_rawDesc = "" +
-	"\n" +
-	"2internal/grpc/connectionpool/connection_pool.proto\x12\x0econnectionpool\"B\n" +
-	"\x11ConnectionRequest\x12\x1b\n" +
-	"\ttenant_id\x18\x01 \x01(\tR\btenantId\x12\x10\n" +
-	"\x03dsn\x18\x02 \x01(\tR\x03dsn\"O\n" +
-	"\x12ConnectionResponse\x12#\n" +
-	"\rconnection_id\x18\x01 \x01(\tR\fconnectionId\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error\"8\n" +
-	"\x11ConnectionRelease\x12#\n" +
-	"\rconnection_id\x18\x01 \x01(\tR\fconnectionId\"A\n" +
-	"\x0fReleaseResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error\"+\n" +
-	"\fStatsRequest\x12\x1b\n" +
-	"\ttenant_id\x18\x01 \x01(\tR\btenantId\"\xac\x01\n" +
-	"\rStatsResponse\x12-\n" +
-	"\x12active_connections\x18\x01 \x01(\x05R\x11activeConnections\x12)\n" +
-	"\x10idle_connections\x18\x02 \x01(\x05R\x0fidleConnections\x12+\n" +
-	"\x11total_connections\x18\x03 \x01(\x05R\x10totalConnections\x12\x14\n" +
-	"\x05error\x18\x04 \x01(\tR\x05error2\x9b\x02\n" +
-	"\x15ConnectionPoolService\x12X\n" +
-	"\rGetConnection\x12!.connectionpool.ConnectionRequest\x1a\".connectionpool.ConnectionResponse\"\x00\x12Y\n" +
-	"\x11ReleaseConnection\x12!.connectionpool.ConnectionRelease\x1a\x1f.connectionpool.ReleaseResponse\"\x00\x12M\n" +
-	"\fGetPoolStats\x12\x1c.connectionpool.StatsRequest\x1a\x1d.connectionpool.StatsResponse\"\x00BQZOgithub.com/teresa-solution/connection-pool-manager/internal/grpc/connectionpoolb\x06proto3"
+func (x *HoldLeaseRequest) GetPing() *LeasePing {
+	if x != nil {
+		if x, ok := x.Msg.(*HoldLeaseRequest_Ping); ok {
+			return x.Ping
+		}
+	}
+	return nil
+}
 
-var (
-	file_internal_grpc_connectionpool_connection_pool_proto_rawDescOnce sync.Once
-	file_internal_grpc_connectionpool_connection_pool_proto_rawDescData []byte
-)
+type isHoldLeaseRequest_Msg interface {
+	isHoldLeaseRequest_Msg()
+}
 
-func file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP() []byte {
-	file_internal_grpc_connectionpool_connection_pool_proto_rawDescOnce.Do(func() {
-		file_internal_grpc_connectionpool_connection_pool_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_internal_grpc_connectionpool_connection_pool_proto_rawDesc), len(file_internal_grpc_connectionpool_connection_pool_proto_rawDesc)))
-	})
-	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescData
+type HoldLeaseRequest_Acquire struct {
+	Acquire *ConnectionRequest `protobuf:"bytes,1,opt,name=acquire,proto3,oneof"`
 }
 
-var file_internal_grpc_connectionpool_connection_pool_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
-var file_internal_grpc_connectionpool_connection_pool_proto_goTypes = []any{
-	(*ConnectionRequest)(nil),  // 0: connectionpool.ConnectionRequest
-	(*ConnectionResponse)(nil), // 1: connectionpool.ConnectionResponse
-	(*ConnectionRelease)(nil),  // 2: connectionpool.ConnectionRelease
-	(*ReleaseResponse)(nil),    // 3: connectionpool.ReleaseResponse
-	(*StatsRequest)(nil),       // 4: connectionpool.StatsRequest
-	(*StatsResponse)(nil),      // 5: connectionpool.StatsResponse
+type HoldLeaseRequest_Ping struct {
+	Ping *LeasePing `protobuf:"bytes,2,opt,name=ping,proto3,oneof"`
 }
-var file_internal_grpc_connectionpool_connection_pool_proto_depIdxs = []int32{
-	0, // 0: connectionpool.ConnectionPoolService.GetConnection:input_type -> connectionpool.ConnectionRequest
-	2, // 1: connectionpool.ConnectionPoolService.ReleaseConnection:input_type -> connectionpool.ConnectionRelease
-	4, // 2: connectionpool.ConnectionPoolService.GetPoolStats:input_type -> connectionpool.StatsRequest
-	1, // 3: connectionpool.ConnectionPoolService.GetConnection:output_type -> connectionpool.ConnectionResponse
-	3, // 4: connectionpool.ConnectionPoolService.ReleaseConnection:output_type -> connectionpool.ReleaseResponse
-	5, // 5: connectionpool.ConnectionPoolService.GetPoolStats:output_type -> connectionpool.StatsResponse
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+
+func (*HoldLeaseRequest_Acquire) isHoldLeaseRequest_Msg() {}
+
+func (*HoldLeaseRequest_Ping) isHoldLeaseRequest_Msg() {}
+
+type LeasePing struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func init() { file_internal_grpc_connectionpool_connection_pool_proto_init() }
-func file_internal_grpc_connectionpool_connection_pool_proto_init() {
-	if File_internal_grpc_connectionpool_connection_pool_proto != nil {
-		return
+func (x *LeasePing) Reset() {
+	*x = LeasePing{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeasePing) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeasePing) ProtoMessage() {}
+
+func (x *LeasePing) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeasePing.ProtoReflect.Descriptor instead.
+func (*LeasePing) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{7}
+}
+
+type HoldLeaseResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Msg:
+	//
+	//	*HoldLeaseResponse_Lease
+	//	*HoldLeaseResponse_Pong
+	Msg           isHoldLeaseResponse_Msg `protobuf_oneof:"msg"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HoldLeaseResponse) Reset() {
+	*x = HoldLeaseResponse{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HoldLeaseResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HoldLeaseResponse) ProtoMessage() {}
+
+func (x *HoldLeaseResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HoldLeaseResponse.ProtoReflect.Descriptor instead.
+func (*HoldLeaseResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *HoldLeaseResponse) GetMsg() isHoldLeaseResponse_Msg {
+	if x != nil {
+		return x.Msg
+	}
+	return nil
+}
+
+func (x *HoldLeaseResponse) GetLease() *ConnectionResponse {
+	if x != nil {
+		if x, ok := x.Msg.(*HoldLeaseResponse_Lease); ok {
+			return x.Lease
+		}
+	}
+	return nil
+}
+
+func (x *HoldLeaseResponse) GetPong() *LeasePong {
+	if x != nil {
+		if x, ok := x.Msg.(*HoldLeaseResponse_Pong); ok {
+			return x.Pong
+		}
+	}
+	return nil
+}
+
+type isHoldLeaseResponse_Msg interface {
+	isHoldLeaseResponse_Msg()
+}
+
+type HoldLeaseResponse_Lease struct {
+	Lease *ConnectionResponse `protobuf:"bytes,1,opt,name=lease,proto3,oneof"`
+}
+
+type HoldLeaseResponse_Pong struct {
+	Pong *LeasePong `protobuf:"bytes,2,opt,name=pong,proto3,oneof"`
+}
+
+func (*HoldLeaseResponse_Lease) isHoldLeaseResponse_Msg() {}
+
+func (*HoldLeaseResponse_Pong) isHoldLeaseResponse_Msg() {}
+
+type LeasePong struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // The lease is reclaimed unless pinged by then
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeasePong) Reset() {
+	*x = LeasePong{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeasePong) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeasePong) ProtoMessage() {}
+
+func (x *LeasePong) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeasePong.ProtoReflect.Descriptor instead.
+func (*LeasePong) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *LeasePong) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+type StatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TenantId      string                 `protobuf:"bytes,1,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsRequest) Reset() {
+	*x = StatsRequest{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsRequest) ProtoMessage() {}
+
+func (x *StatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsRequest.ProtoReflect.Descriptor instead.
+func (*StatsRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *StatsRequest) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+type StatsResponse struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	ActiveConnections int32                  `protobuf:"varint,1,opt,name=active_connections,json=activeConnections,proto3" json:"active_connections,omitempty"`
+	IdleConnections   int32                  `protobuf:"varint,2,opt,name=idle_connections,json=idleConnections,proto3" json:"idle_connections,omitempty"`
+	TotalConnections  int32                  `protobuf:"varint,3,opt,name=total_connections,json=totalConnections,proto3" json:"total_connections,omitempty"`
+	Error             string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	Breakers          []*CircuitBreaker      `protobuf:"bytes,5,rep,name=breakers,proto3" json:"breakers,omitempty"` // One per database host the tenant has called
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *StatsResponse) Reset() {
+	*x = StatsResponse{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsResponse) ProtoMessage() {}
+
+func (x *StatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsResponse.ProtoReflect.Descriptor instead.
+func (*StatsResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *StatsResponse) GetActiveConnections() int32 {
+	if x != nil {
+		return x.ActiveConnections
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetIdleConnections() int32 {
+	if x != nil {
+		return x.IdleConnections
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetTotalConnections() int32 {
+	if x != nil {
+		return x.TotalConnections
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *StatsResponse) GetBreakers() []*CircuitBreaker {
+	if x != nil {
+		return x.Breakers
+	}
+	return nil
+}
+
+type CircuitBreaker struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Host          string                 `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`
+	State         string                 `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`        // "closed", "open" or "half_open"
+	Requests      int32                  `protobuf:"varint,3,opt,name=requests,proto3" json:"requests,omitempty"` // Calls counted in the current window
+	Failures      int32                  `protobuf:"varint,4,opt,name=failures,proto3" json:"failures,omitempty"`
+	OpenedAt      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=opened_at,json=openedAt,proto3" json:"opened_at,omitempty"` // Unset while closed
+	RetryAt       *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=retry_at,json=retryAt,proto3" json:"retry_at,omitempty"`    // When probe calls are let through; unset while closed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CircuitBreaker) Reset() {
+	*x = CircuitBreaker{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CircuitBreaker) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CircuitBreaker) ProtoMessage() {}
+
+func (x *CircuitBreaker) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CircuitBreaker.ProtoReflect.Descriptor instead.
+func (*CircuitBreaker) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CircuitBreaker) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *CircuitBreaker) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *CircuitBreaker) GetRequests() int32 {
+	if x != nil {
+		return x.Requests
+	}
+	return 0
+}
+
+func (x *CircuitBreaker) GetFailures() int32 {
+	if x != nil {
+		return x.Failures
+	}
+	return 0
+}
+
+func (x *CircuitBreaker) GetOpenedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OpenedAt
+	}
+	return nil
+}
+
+func (x *CircuitBreaker) GetRetryAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.RetryAt
+	}
+	return nil
+}
+
+type StatsHistoryRequest struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	TenantId          string                 `protobuf:"bytes,1,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	Start             *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=start,proto3" json:"start,omitempty"`                                                   // Defaults to an hour before end
+	End               *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=end,proto3" json:"end,omitempty"`                                                       // Defaults to now
+	ResolutionSeconds int32                  `protobuf:"varint,4,opt,name=resolution_seconds,json=resolutionSeconds,proto3" json:"resolution_seconds,omitempty"` // Width of each point; the sampling interval when zero
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *StatsHistoryRequest) Reset() {
+	*x = StatsHistoryRequest{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsHistoryRequest) ProtoMessage() {}
+
+func (x *StatsHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsHistoryRequest.ProtoReflect.Descriptor instead.
+func (*StatsHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *StatsHistoryRequest) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+func (x *StatsHistoryRequest) GetStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *StatsHistoryRequest) GetEnd() *timestamppb.Timestamp {
+	if x != nil {
+		return x.End
+	}
+	return nil
+}
+
+func (x *StatsHistoryRequest) GetResolutionSeconds() int32 {
+	if x != nil {
+		return x.ResolutionSeconds
+	}
+	return 0
+}
+
+type StatsPoint struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	Time                 *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"` // Start of the point's step
+	Samples              int32                  `protobuf:"varint,2,opt,name=samples,proto3" json:"samples,omitempty"`
+	AvgActiveConnections float64                `protobuf:"fixed64,3,opt,name=avg_active_connections,json=avgActiveConnections,proto3" json:"avg_active_connections,omitempty"`
+	MaxActiveConnections int32                  `protobuf:"varint,4,opt,name=max_active_connections,json=maxActiveConnections,proto3" json:"max_active_connections,omitempty"`
+	AvgIdleConnections   float64                `protobuf:"fixed64,5,opt,name=avg_idle_connections,json=avgIdleConnections,proto3" json:"avg_idle_connections,omitempty"`
+	AvgTotalConnections  float64                `protobuf:"fixed64,6,opt,name=avg_total_connections,json=avgTotalConnections,proto3" json:"avg_total_connections,omitempty"`
+	MaxTotalConnections  int32                  `protobuf:"varint,7,opt,name=max_total_connections,json=maxTotalConnections,proto3" json:"max_total_connections,omitempty"`
+	MaxConnections       int32                  `protobuf:"varint,8,opt,name=max_connections,json=maxConnections,proto3" json:"max_connections,omitempty"`
+	MaxLeases            int32                  `protobuf:"varint,9,opt,name=max_leases,json=maxLeases,proto3" json:"max_leases,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *StatsPoint) Reset() {
+	*x = StatsPoint{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsPoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsPoint) ProtoMessage() {}
+
+func (x *StatsPoint) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsPoint.ProtoReflect.Descriptor instead.
+func (*StatsPoint) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *StatsPoint) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *StatsPoint) GetSamples() int32 {
+	if x != nil {
+		return x.Samples
+	}
+	return 0
+}
+
+func (x *StatsPoint) GetAvgActiveConnections() float64 {
+	if x != nil {
+		return x.AvgActiveConnections
+	}
+	return 0
+}
+
+func (x *StatsPoint) GetMaxActiveConnections() int32 {
+	if x != nil {
+		return x.MaxActiveConnections
+	}
+	return 0
+}
+
+func (x *StatsPoint) GetAvgIdleConnections() float64 {
+	if x != nil {
+		return x.AvgIdleConnections
+	}
+	return 0
+}
+
+func (x *StatsPoint) GetAvgTotalConnections() float64 {
+	if x != nil {
+		return x.AvgTotalConnections
+	}
+	return 0
+}
+
+func (x *StatsPoint) GetMaxTotalConnections() int32 {
+	if x != nil {
+		return x.MaxTotalConnections
+	}
+	return 0
+}
+
+func (x *StatsPoint) GetMaxConnections() int32 {
+	if x != nil {
+		return x.MaxConnections
+	}
+	return 0
+}
+
+func (x *StatsPoint) GetMaxLeases() int32 {
+	if x != nil {
+		return x.MaxLeases
+	}
+	return 0
+}
+
+type StatsHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Points        []*StatsPoint          `protobuf:"bytes,1,rep,name=points,proto3" json:"points,omitempty"` // Oldest first; steps without samples are left out
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsHistoryResponse) Reset() {
+	*x = StatsHistoryResponse{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsHistoryResponse) ProtoMessage() {}
+
+func (x *StatsHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsHistoryResponse.ProtoReflect.Descriptor instead.
+func (*StatsHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *StatsHistoryResponse) GetPoints() []*StatsPoint {
+	if x != nil {
+		return x.Points
+	}
+	return nil
+}
+
+func (x *StatsHistoryResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type WatchPoolStatsRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	TenantIds  []string               `protobuf:"bytes,1,rep,name=tenant_ids,json=tenantIds,proto3" json:"tenant_ids,omitempty"`     // Tenants to watch; every tenant with pools when empty
+	IntervalMs int32                  `protobuf:"varint,2,opt,name=interval_ms,json=intervalMs,proto3" json:"interval_ms,omitempty"` // Time between updates; 1000 when zero, at least 100
+	DeltasOnly bool                   `protobuf:"varint,3,opt,name=deltas_only,json=deltasOnly,proto3" json:"deltas_only,omitempty"` // Only send tenants whose stats changed since they were last sent
+	// Send a tenant straight away, between updates, when its active connections reach this
+	// fraction of its pool size, and again when they drop below it. Disabled when zero.
+	UtilizationThreshold float64 `protobuf:"fixed64,4,opt,name=utilization_threshold,json=utilizationThreshold,proto3" json:"utilization_threshold,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *WatchPoolStatsRequest) Reset() {
+	*x = WatchPoolStatsRequest{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchPoolStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchPoolStatsRequest) ProtoMessage() {}
+
+func (x *WatchPoolStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchPoolStatsRequest.ProtoReflect.Descriptor instead.
+func (*WatchPoolStatsRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *WatchPoolStatsRequest) GetTenantIds() []string {
+	if x != nil {
+		return x.TenantIds
+	}
+	return nil
+}
+
+func (x *WatchPoolStatsRequest) GetIntervalMs() int32 {
+	if x != nil {
+		return x.IntervalMs
+	}
+	return 0
+}
+
+func (x *WatchPoolStatsRequest) GetDeltasOnly() bool {
+	if x != nil {
+		return x.DeltasOnly
+	}
+	return false
+}
+
+func (x *WatchPoolStatsRequest) GetUtilizationThreshold() float64 {
+	if x != nil {
+		return x.UtilizationThreshold
+	}
+	return 0
+}
+
+type TenantStats struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	TenantId          string                 `protobuf:"bytes,1,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	ActiveConnections int32                  `protobuf:"varint,2,opt,name=active_connections,json=activeConnections,proto3" json:"active_connections,omitempty"`
+	IdleConnections   int32                  `protobuf:"varint,3,opt,name=idle_connections,json=idleConnections,proto3" json:"idle_connections,omitempty"`
+	TotalConnections  int32                  `protobuf:"varint,4,opt,name=total_connections,json=totalConnections,proto3" json:"total_connections,omitempty"`
+	MaxConnections    int32                  `protobuf:"varint,5,opt,name=max_connections,json=maxConnections,proto3" json:"max_connections,omitempty"`
+	Leases            int32                  `protobuf:"varint,6,opt,name=leases,proto3" json:"leases,omitempty"`
+	OverThreshold     bool                   `protobuf:"varint,7,opt,name=over_threshold,json=overThreshold,proto3" json:"over_threshold,omitempty"` // Active connections are at or above the utilization threshold
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *TenantStats) Reset() {
+	*x = TenantStats{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TenantStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TenantStats) ProtoMessage() {}
+
+func (x *TenantStats) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TenantStats.ProtoReflect.Descriptor instead.
+func (*TenantStats) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *TenantStats) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+func (x *TenantStats) GetActiveConnections() int32 {
+	if x != nil {
+		return x.ActiveConnections
+	}
+	return 0
+}
+
+func (x *TenantStats) GetIdleConnections() int32 {
+	if x != nil {
+		return x.IdleConnections
+	}
+	return 0
+}
+
+func (x *TenantStats) GetTotalConnections() int32 {
+	if x != nil {
+		return x.TotalConnections
+	}
+	return 0
+}
+
+func (x *TenantStats) GetMaxConnections() int32 {
+	if x != nil {
+		return x.MaxConnections
+	}
+	return 0
+}
+
+func (x *TenantStats) GetLeases() int32 {
+	if x != nil {
+		return x.Leases
+	}
+	return 0
+}
+
+func (x *TenantStats) GetOverThreshold() bool {
+	if x != nil {
+		return x.OverThreshold
+	}
+	return false
+}
+
+type WatchPoolStatsResponse struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Time               *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	Tenants            []*TenantStats         `protobuf:"bytes,2,rep,name=tenants,proto3" json:"tenants,omitempty"`
+	ThresholdTriggered bool                   `protobuf:"varint,3,opt,name=threshold_triggered,json=thresholdTriggered,proto3" json:"threshold_triggered,omitempty"` // Sent between updates because a tenant crossed the threshold
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *WatchPoolStatsResponse) Reset() {
+	*x = WatchPoolStatsResponse{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchPoolStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchPoolStatsResponse) ProtoMessage() {}
+
+func (x *WatchPoolStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchPoolStatsResponse.ProtoReflect.Descriptor instead.
+func (*WatchPoolStatsResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *WatchPoolStatsResponse) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *WatchPoolStatsResponse) GetTenants() []*TenantStats {
+	if x != nil {
+		return x.Tenants
+	}
+	return nil
+}
+
+func (x *WatchPoolStatsResponse) GetThresholdTriggered() bool {
+	if x != nil {
+		return x.ThresholdTriggered
+	}
+	return false
+}
+
+type WatchEventsRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	TenantIds []string               `protobuf:"bytes,1,rep,name=tenant_ids,json=tenantIds,proto3" json:"tenant_ids,omitempty"` // Tenants to watch; every tenant when empty
+	// Event types to watch, e.g. "pool_created" or "failover"; every type when empty
+	Types         []string `protobuf:"bytes,2,rep,name=types,proto3" json:"types,omitempty"`
+	Replay        bool     `protobuf:"varint,3,opt,name=replay,proto3" json:"replay,omitempty"` // Send the retained events numbered above after_seq first
+	AfterSeq      uint64   `protobuf:"varint,4,opt,name=after_seq,json=afterSeq,proto3" json:"after_seq,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchEventsRequest) Reset() {
+	*x = WatchEventsRequest{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEventsRequest) ProtoMessage() {}
+
+func (x *WatchEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchEventsRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *WatchEventsRequest) GetTenantIds() []string {
+	if x != nil {
+		return x.TenantIds
+	}
+	return nil
+}
+
+func (x *WatchEventsRequest) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+func (x *WatchEventsRequest) GetReplay() bool {
+	if x != nil {
+		return x.Replay
+	}
+	return false
+}
+
+func (x *WatchEventsRequest) GetAfterSeq() uint64 {
+	if x != nil {
+		return x.AfterSeq
+	}
+	return 0
+}
+
+type PoolEvent struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	Seq                    uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"` // Increases by one per event published, so gaps show missed events
+	Time                   *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	Type                   string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	TenantId               string                 `protobuf:"bytes,4,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	Server                 string                 `protobuf:"bytes,5,opt,name=server,proto3" json:"server,omitempty"`                           // Host, port and database of the pool; where reads moved to on failover
+	FromServer             string                 `protobuf:"bytes,6,opt,name=from_server,json=fromServer,proto3" json:"from_server,omitempty"` // Where reads moved from on failover
+	Healthy                bool                   `protobuf:"varint,8,opt,name=healthy,proto3" json:"healthy,omitempty"`                        // New state on health_changed
+	Error                  string                 `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"`
+	Lease                  string                 `protobuf:"bytes,10,opt,name=lease,proto3" json:"lease,omitempty"`                                                                    // Handle of the lease, as ListLeases reports it
+	MaxConnections         int32                  `protobuf:"varint,11,opt,name=max_connections,json=maxConnections,proto3" json:"max_connections,omitempty"`                           // Size of the pool on pool_created and pool_resized
+	PreviousMaxConnections int32                  `protobuf:"varint,12,opt,name=previous_max_connections,json=previousMaxConnections,proto3" json:"previous_max_connections,omitempty"` // Size of the pool before a pool_resized
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *PoolEvent) Reset() {
+	*x = PoolEvent{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PoolEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PoolEvent) ProtoMessage() {}
+
+func (x *PoolEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PoolEvent.ProtoReflect.Descriptor instead.
+func (*PoolEvent) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *PoolEvent) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *PoolEvent) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *PoolEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *PoolEvent) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+func (x *PoolEvent) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+func (x *PoolEvent) GetFromServer() string {
+	if x != nil {
+		return x.FromServer
+	}
+	return ""
+}
+
+func (x *PoolEvent) GetHealthy() bool {
+	if x != nil {
+		return x.Healthy
+	}
+	return false
+}
+
+func (x *PoolEvent) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *PoolEvent) GetLease() string {
+	if x != nil {
+		return x.Lease
+	}
+	return ""
+}
+
+func (x *PoolEvent) GetMaxConnections() int32 {
+	if x != nil {
+		return x.MaxConnections
+	}
+	return 0
+}
+
+func (x *PoolEvent) GetPreviousMaxConnections() int32 {
+	if x != nil {
+		return x.PreviousMaxConnections
+	}
+	return 0
+}
+
+type NullableString struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Value  string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	IsNull bool                   `protobuf:"varint,2,opt,name=is_null,json=isNull,proto3" json:"is_null,omitempty"`
+	// Raw bytes of a parameter, used instead of value when is_bytes is set. They are
+	// sent as bytea when the parameter is bytea, and as text otherwise.
+	BytesValue    []byte `protobuf:"bytes,3,opt,name=bytes_value,json=bytesValue,proto3" json:"bytes_value,omitempty"`
+	IsBytes       bool   `protobuf:"varint,4,opt,name=is_bytes,json=isBytes,proto3" json:"is_bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NullableString) Reset() {
+	*x = NullableString{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NullableString) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NullableString) ProtoMessage() {}
+
+func (x *NullableString) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NullableString.ProtoReflect.Descriptor instead.
+func (*NullableString) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *NullableString) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *NullableString) GetIsNull() bool {
+	if x != nil {
+		return x.IsNull
+	}
+	return false
+}
+
+func (x *NullableString) GetBytesValue() []byte {
+	if x != nil {
+		return x.BytesValue
+	}
+	return nil
+}
+
+func (x *NullableString) GetIsBytes() bool {
+	if x != nil {
+		return x.IsBytes
+	}
+	return false
+}
+
+type QueryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ConnectionId  string                 `protobuf:"bytes,1,opt,name=connection_id,json=connectionId,proto3" json:"connection_id,omitempty"`
+	Sql           string                 `protobuf:"bytes,2,opt,name=sql,proto3" json:"sql,omitempty"`
+	Params        []*NullableString      `protobuf:"bytes,3,rep,name=params,proto3" json:"params,omitempty"`                     // Text-format or raw byte parameters for $1, $2, ...
+	TenantId      string                 `protobuf:"bytes,4,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"` // Tenant the lease was acquired for; when set, a lease of another tenant is not found
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryRequest) Reset() {
+	*x = QueryRequest{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryRequest) ProtoMessage() {}
+
+func (x *QueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryRequest.ProtoReflect.Descriptor instead.
+func (*QueryRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *QueryRequest) GetConnectionId() string {
+	if x != nil {
+		return x.ConnectionId
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetSql() string {
+	if x != nil {
+		return x.Sql
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetParams() []*NullableString {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+type Row struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []*NullableString      `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Row) Reset() {
+	*x = Row{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Row) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Row) ProtoMessage() {}
+
+func (x *Row) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Row.ProtoReflect.Descriptor instead.
+func (*Row) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *Row) GetValues() []*NullableString {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type QueryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Columns       []string               `protobuf:"bytes,1,rep,name=columns,proto3" json:"columns,omitempty"`
+	Rows          []*Row                 `protobuf:"bytes,2,rep,name=rows,proto3" json:"rows,omitempty"` // Values in PostgreSQL's text format
+	CommandTag    string                 `protobuf:"bytes,3,opt,name=command_tag,json=commandTag,proto3" json:"command_tag,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	ColumnTypes   []uint32               `protobuf:"varint,5,rep,packed,name=column_types,json=columnTypes,proto3" json:"column_types,omitempty"` // Type OID of each column, to decode its values by
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryResponse) Reset() {
+	*x = QueryResponse{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryResponse) ProtoMessage() {}
+
+func (x *QueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryResponse.ProtoReflect.Descriptor instead.
+func (*QueryResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *QueryResponse) GetColumns() []string {
+	if x != nil {
+		return x.Columns
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetRows() []*Row {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetCommandTag() string {
+	if x != nil {
+		return x.CommandTag
+	}
+	return ""
+}
+
+func (x *QueryResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *QueryResponse) GetColumnTypes() []uint32 {
+	if x != nil {
+		return x.ColumnTypes
+	}
+	return nil
+}
+
+type BeginRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConnectionId   string                 `protobuf:"bytes,1,opt,name=connection_id,json=connectionId,proto3" json:"connection_id,omitempty"`
+	IsolationLevel string                 `protobuf:"bytes,2,opt,name=isolation_level,json=isolationLevel,proto3" json:"isolation_level,omitempty"` // e.g. "serializable"; the server default when empty
+	ReadOnly       bool                   `protobuf:"varint,3,opt,name=read_only,json=readOnly,proto3" json:"read_only,omitempty"`
+	Deferrable     bool                   `protobuf:"varint,4,opt,name=deferrable,proto3" json:"deferrable,omitempty"`
+	TenantId       string                 `protobuf:"bytes,5,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"` // Tenant the lease was acquired for; when set, a lease of another tenant is not found
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *BeginRequest) Reset() {
+	*x = BeginRequest{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BeginRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BeginRequest) ProtoMessage() {}
+
+func (x *BeginRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BeginRequest.ProtoReflect.Descriptor instead.
+func (*BeginRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *BeginRequest) GetConnectionId() string {
+	if x != nil {
+		return x.ConnectionId
+	}
+	return ""
+}
+
+func (x *BeginRequest) GetIsolationLevel() string {
+	if x != nil {
+		return x.IsolationLevel
+	}
+	return ""
+}
+
+func (x *BeginRequest) GetReadOnly() bool {
+	if x != nil {
+		return x.ReadOnly
+	}
+	return false
+}
+
+func (x *BeginRequest) GetDeferrable() bool {
+	if x != nil {
+		return x.Deferrable
+	}
+	return false
+}
+
+func (x *BeginRequest) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+type TransactionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ConnectionId  string                 `protobuf:"bytes,1,opt,name=connection_id,json=connectionId,proto3" json:"connection_id,omitempty"`
+	TenantId      string                 `protobuf:"bytes,2,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"` // Tenant the lease was acquired for; when set, a lease of another tenant is not found
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransactionRequest) Reset() {
+	*x = TransactionRequest{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionRequest) ProtoMessage() {}
+
+func (x *TransactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionRequest.ProtoReflect.Descriptor instead.
+func (*TransactionRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *TransactionRequest) GetConnectionId() string {
+	if x != nil {
+		return x.ConnectionId
+	}
+	return ""
+}
+
+func (x *TransactionRequest) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+type TransactionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Error         string                 `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransactionResponse) Reset() {
+	*x = TransactionResponse{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionResponse) ProtoMessage() {}
+
+func (x *TransactionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionResponse.ProtoReflect.Descriptor instead.
+func (*TransactionResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *TransactionResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ListPoolsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TenantId      string                 `protobuf:"bytes,1,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"` // Only list this tenant's pools
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPoolsRequest) Reset() {
+	*x = ListPoolsRequest{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPoolsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPoolsRequest) ProtoMessage() {}
+
+func (x *ListPoolsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPoolsRequest.ProtoReflect.Descriptor instead.
+func (*ListPoolsRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *ListPoolsRequest) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+type PoolInfo struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	TenantId          string                 `protobuf:"bytes,1,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	Server            string                 `protobuf:"bytes,2,opt,name=server,proto3" json:"server,omitempty"` // host:port/database
+	ActiveConnections int32                  `protobuf:"varint,3,opt,name=active_connections,json=activeConnections,proto3" json:"active_connections,omitempty"`
+	IdleConnections   int32                  `protobuf:"varint,4,opt,name=idle_connections,json=idleConnections,proto3" json:"idle_connections,omitempty"`
+	TotalConnections  int32                  `protobuf:"varint,5,opt,name=total_connections,json=totalConnections,proto3" json:"total_connections,omitempty"`
+	MaxConnections    int32                  `protobuf:"varint,6,opt,name=max_connections,json=maxConnections,proto3" json:"max_connections,omitempty"`
+	Leases            int32                  `protobuf:"varint,7,opt,name=leases,proto3" json:"leases,omitempty"`
+	Draining          bool                   `protobuf:"varint,8,opt,name=draining,proto3" json:"draining,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *PoolInfo) Reset() {
+	*x = PoolInfo{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PoolInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PoolInfo) ProtoMessage() {}
+
+func (x *PoolInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PoolInfo.ProtoReflect.Descriptor instead.
+func (*PoolInfo) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *PoolInfo) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+func (x *PoolInfo) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+func (x *PoolInfo) GetActiveConnections() int32 {
+	if x != nil {
+		return x.ActiveConnections
+	}
+	return 0
+}
+
+func (x *PoolInfo) GetIdleConnections() int32 {
+	if x != nil {
+		return x.IdleConnections
+	}
+	return 0
+}
+
+func (x *PoolInfo) GetTotalConnections() int32 {
+	if x != nil {
+		return x.TotalConnections
+	}
+	return 0
+}
+
+func (x *PoolInfo) GetMaxConnections() int32 {
+	if x != nil {
+		return x.MaxConnections
+	}
+	return 0
+}
+
+func (x *PoolInfo) GetLeases() int32 {
+	if x != nil {
+		return x.Leases
+	}
+	return 0
+}
+
+func (x *PoolInfo) GetDraining() bool {
+	if x != nil {
+		return x.Draining
+	}
+	return false
+}
+
+type ListPoolsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pools         []*PoolInfo            `protobuf:"bytes,1,rep,name=pools,proto3" json:"pools,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPoolsResponse) Reset() {
+	*x = ListPoolsResponse{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPoolsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPoolsResponse) ProtoMessage() {}
+
+func (x *ListPoolsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPoolsResponse.ProtoReflect.Descriptor instead.
+func (*ListPoolsResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ListPoolsResponse) GetPools() []*PoolInfo {
+	if x != nil {
+		return x.Pools
+	}
+	return nil
+}
+
+func (x *ListPoolsResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ListLeasesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TenantId      string                 `protobuf:"bytes,1,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"` // Only list this tenant's leases
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`                      // Leases per tenant; all when zero
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLeasesRequest) Reset() {
+	*x = ListLeasesRequest{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLeasesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLeasesRequest) ProtoMessage() {}
+
+func (x *ListLeasesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLeasesRequest.ProtoReflect.Descriptor instead.
+func (*ListLeasesRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ListLeasesRequest) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+func (x *ListLeasesRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type LeaseInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Handle        string                 `protobuf:"bytes,11,opt,name=handle,proto3" json:"handle,omitempty"` // Identifies the lease in logs and events; unlike its connection ID it cannot be used to act on it
+	TenantId      string                 `protobuf:"bytes,2,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	PoolMode      string                 `protobuf:"bytes,3,opt,name=pool_mode,json=poolMode,proto3" json:"pool_mode,omitempty"`
+	Peer          string                 `protobuf:"bytes,4,opt,name=peer,proto3" json:"peer,omitempty"`         // Client address
+	Identity      string                 `protobuf:"bytes,5,opt,name=identity,proto3" json:"identity,omitempty"` // Authenticated client identity
+	CallSite      string                 `protobuf:"bytes,6,opt,name=call_site,json=callSite,proto3" json:"call_site,omitempty"`
+	AcquiredAt    *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=acquired_at,json=acquiredAt,proto3" json:"acquired_at,omitempty"`
+	LastActiveAt  *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=last_active_at,json=lastActiveAt,proto3" json:"last_active_at,omitempty"`
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // Unset for leases that do not expire
+	Attached      bool                   `protobuf:"varint,10,opt,name=attached,proto3" json:"attached,omitempty"`                  // Whether the lease holds a backend connection
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaseInfo) Reset() {
+	*x = LeaseInfo{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaseInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaseInfo) ProtoMessage() {}
+
+func (x *LeaseInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaseInfo.ProtoReflect.Descriptor instead.
+func (*LeaseInfo) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *LeaseInfo) GetHandle() string {
+	if x != nil {
+		return x.Handle
+	}
+	return ""
+}
+
+func (x *LeaseInfo) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+func (x *LeaseInfo) GetPoolMode() string {
+	if x != nil {
+		return x.PoolMode
+	}
+	return ""
+}
+
+func (x *LeaseInfo) GetPeer() string {
+	if x != nil {
+		return x.Peer
+	}
+	return ""
+}
+
+func (x *LeaseInfo) GetIdentity() string {
+	if x != nil {
+		return x.Identity
+	}
+	return ""
+}
+
+func (x *LeaseInfo) GetCallSite() string {
+	if x != nil {
+		return x.CallSite
+	}
+	return ""
+}
+
+func (x *LeaseInfo) GetAcquiredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AcquiredAt
+	}
+	return nil
+}
+
+func (x *LeaseInfo) GetLastActiveAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastActiveAt
+	}
+	return nil
+}
+
+func (x *LeaseInfo) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *LeaseInfo) GetAttached() bool {
+	if x != nil {
+		return x.Attached
+	}
+	return false
+}
+
+type ListLeasesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Leases        []*LeaseInfo           `protobuf:"bytes,1,rep,name=leases,proto3" json:"leases,omitempty"` // Grouped by tenant, longest held first
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLeasesResponse) Reset() {
+	*x = ListLeasesResponse{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLeasesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLeasesResponse) ProtoMessage() {}
+
+func (x *ListLeasesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLeasesResponse.ProtoReflect.Descriptor instead.
+func (*ListLeasesResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ListLeasesResponse) GetLeases() []*LeaseInfo {
+	if x != nil {
+		return x.Leases
+	}
+	return nil
+}
+
+func (x *ListLeasesResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type DrainRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	TenantId       string                 `protobuf:"bytes,1,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	TimeoutSeconds int32                  `protobuf:"varint,2,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"` // How long to wait for leases before evicting them. Defaults to 30.
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *DrainRequest) Reset() {
+	*x = DrainRequest{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainRequest) ProtoMessage() {}
+
+func (x *DrainRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainRequest.ProtoReflect.Descriptor instead.
+func (*DrainRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *DrainRequest) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+func (x *DrainRequest) GetTimeoutSeconds() int32 {
+	if x != nil {
+		return x.TimeoutSeconds
+	}
+	return 0
+}
+
+type DrainResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EvictedLeases int32                  `protobuf:"varint,1,opt,name=evicted_leases,json=evictedLeases,proto3" json:"evicted_leases,omitempty"`
+	ClosedPools   int32                  `protobuf:"varint,2,opt,name=closed_pools,json=closedPools,proto3" json:"closed_pools,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrainResponse) Reset() {
+	*x = DrainResponse{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainResponse) ProtoMessage() {}
+
+func (x *DrainResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainResponse.ProtoReflect.Descriptor instead.
+func (*DrainResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *DrainResponse) GetEvictedLeases() int32 {
+	if x != nil {
+		return x.EvictedLeases
+	}
+	return 0
+}
+
+func (x *DrainResponse) GetClosedPools() int32 {
+	if x != nil {
+		return x.ClosedPools
+	}
+	return 0
+}
+
+func (x *DrainResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type EvictRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TenantId      string                 `protobuf:"bytes,1,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvictRequest) Reset() {
+	*x = EvictRequest{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvictRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvictRequest) ProtoMessage() {}
+
+func (x *EvictRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvictRequest.ProtoReflect.Descriptor instead.
+func (*EvictRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *EvictRequest) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+type EvictResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EvictedLeases int32                  `protobuf:"varint,1,opt,name=evicted_leases,json=evictedLeases,proto3" json:"evicted_leases,omitempty"`
+	ClosedPools   int32                  `protobuf:"varint,2,opt,name=closed_pools,json=closedPools,proto3" json:"closed_pools,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvictResponse) Reset() {
+	*x = EvictResponse{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvictResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvictResponse) ProtoMessage() {}
+
+func (x *EvictResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvictResponse.ProtoReflect.Descriptor instead.
+func (*EvictResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *EvictResponse) GetEvictedLeases() int32 {
+	if x != nil {
+		return x.EvictedLeases
+	}
+	return 0
+}
+
+func (x *EvictResponse) GetClosedPools() int32 {
+	if x != nil {
+		return x.ClosedPools
+	}
+	return 0
+}
+
+func (x *EvictResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ValidateDSNRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dsn           string                 `protobuf:"bytes,1,opt,name=dsn,proto3" json:"dsn,omitempty"`
+	TimeoutMs     int32                  `protobuf:"varint,2,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"` // Bounds the whole validation. Defaults to 10s.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateDSNRequest) Reset() {
+	*x = ValidateDSNRequest{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateDSNRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateDSNRequest) ProtoMessage() {}
+
+func (x *ValidateDSNRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateDSNRequest.ProtoReflect.Descriptor instead.
+func (*ValidateDSNRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *ValidateDSNRequest) GetDsn() string {
+	if x != nil {
+		return x.Dsn
+	}
+	return ""
+}
+
+func (x *ValidateDSNRequest) GetTimeoutMs() int32 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+type ValidationStage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Stage         string                 `protobuf:"bytes,1,opt,name=stage,proto3" json:"stage,omitempty"`   // parse, policy, resolve, tcp, tls, auth or inspect
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"` // ok, failed or skipped
+	LatencyMs     float64                `protobuf:"fixed64,3,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidationStage) Reset() {
+	*x = ValidationStage{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidationStage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidationStage) ProtoMessage() {}
+
+func (x *ValidationStage) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidationStage.ProtoReflect.Descriptor instead.
+func (*ValidationStage) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *ValidationStage) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *ValidationStage) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ValidationStage) GetLatencyMs() float64 {
+	if x != nil {
+		return x.LatencyMs
+	}
+	return 0
+}
+
+func (x *ValidationStage) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type Extension struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Extension) Reset() {
+	*x = Extension{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Extension) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Extension) ProtoMessage() {}
+
+func (x *Extension) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Extension.ProtoReflect.Descriptor instead.
+func (*Extension) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *Extension) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Extension) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type RolePrivileges struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Role          string                 `protobuf:"bytes,1,opt,name=role,proto3" json:"role,omitempty"`
+	Superuser     bool                   `protobuf:"varint,2,opt,name=superuser,proto3" json:"superuser,omitempty"`
+	CreateDb      bool                   `protobuf:"varint,3,opt,name=create_db,json=createDb,proto3" json:"create_db,omitempty"`
+	CreateRole    bool                   `protobuf:"varint,4,opt,name=create_role,json=createRole,proto3" json:"create_role,omitempty"`
+	Replication   bool                   `protobuf:"varint,5,opt,name=replication,proto3" json:"replication,omitempty"`
+	BypassRls     bool                   `protobuf:"varint,6,opt,name=bypass_rls,json=bypassRls,proto3" json:"bypass_rls,omitempty"`
+	Connect       bool                   `protobuf:"varint,7,opt,name=connect,proto3" json:"connect,omitempty"` // Privileges on the database
+	Create        bool                   `protobuf:"varint,8,opt,name=create,proto3" json:"create,omitempty"`
+	Temp          bool                   `protobuf:"varint,9,opt,name=temp,proto3" json:"temp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RolePrivileges) Reset() {
+	*x = RolePrivileges{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RolePrivileges) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RolePrivileges) ProtoMessage() {}
+
+func (x *RolePrivileges) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RolePrivileges.ProtoReflect.Descriptor instead.
+func (*RolePrivileges) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *RolePrivileges) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *RolePrivileges) GetSuperuser() bool {
+	if x != nil {
+		return x.Superuser
+	}
+	return false
+}
+
+func (x *RolePrivileges) GetCreateDb() bool {
+	if x != nil {
+		return x.CreateDb
+	}
+	return false
+}
+
+func (x *RolePrivileges) GetCreateRole() bool {
+	if x != nil {
+		return x.CreateRole
+	}
+	return false
+}
+
+func (x *RolePrivileges) GetReplication() bool {
+	if x != nil {
+		return x.Replication
+	}
+	return false
+}
+
+func (x *RolePrivileges) GetBypassRls() bool {
+	if x != nil {
+		return x.BypassRls
+	}
+	return false
+}
+
+func (x *RolePrivileges) GetConnect() bool {
+	if x != nil {
+		return x.Connect
+	}
+	return false
+}
+
+func (x *RolePrivileges) GetCreate() bool {
+	if x != nil {
+		return x.Create
+	}
+	return false
+}
+
+func (x *RolePrivileges) GetTemp() bool {
+	if x != nil {
+		return x.Temp
+	}
+	return false
+}
+
+type ValidateDSNResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Valid          bool                   `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`                                     // Parses, meets the policy and can be connected to
+	NormalizedDsn  string                 `protobuf:"bytes,2,opt,name=normalized_dsn,json=normalizedDsn,proto3" json:"normalized_dsn,omitempty"` // As a URL, without the password
+	Host           string                 `protobuf:"bytes,3,opt,name=host,proto3" json:"host,omitempty"`
+	Port           int32                  `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
+	Database       string                 `protobuf:"bytes,5,opt,name=database,proto3" json:"database,omitempty"`
+	User           string                 `protobuf:"bytes,6,opt,name=user,proto3" json:"user,omitempty"`
+	SslMode        string                 `protobuf:"bytes,7,opt,name=ssl_mode,json=sslMode,proto3" json:"ssl_mode,omitempty"`
+	Stages         []*ValidationStage     `protobuf:"bytes,8,rep,name=stages,proto3" json:"stages,omitempty"` // Every stage in order, skipped ones included
+	Reason         string                 `protobuf:"bytes,9,opt,name=reason,proto3" json:"reason,omitempty"` // Why the connection failed, e.g. dns, tcp, tls, auth or database_not_found
+	ServerVersion  string                 `protobuf:"bytes,10,opt,name=server_version,json=serverVersion,proto3" json:"server_version,omitempty"`
+	MaxConnections int32                  `protobuf:"varint,11,opt,name=max_connections,json=maxConnections,proto3" json:"max_connections,omitempty"`
+	Extensions     []*Extension           `protobuf:"bytes,12,rep,name=extensions,proto3" json:"extensions,omitempty"`
+	Privileges     *RolePrivileges        `protobuf:"bytes,13,opt,name=privileges,proto3" json:"privileges,omitempty"`
+	Error          string                 `protobuf:"bytes,14,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ValidateDSNResponse) Reset() {
+	*x = ValidateDSNResponse{}
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateDSNResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateDSNResponse) ProtoMessage() {}
+
+func (x *ValidateDSNResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateDSNResponse.ProtoReflect.Descriptor instead.
+func (*ValidateDSNResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *ValidateDSNResponse) GetValid() bool {
+	if x != nil {
+		return x.Valid
+	}
+	return false
+}
+
+func (x *ValidateDSNResponse) GetNormalizedDsn() string {
+	if x != nil {
+		return x.NormalizedDsn
+	}
+	return ""
+}
+
+func (x *ValidateDSNResponse) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *ValidateDSNResponse) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *ValidateDSNResponse) GetDatabase() string {
+	if x != nil {
+		return x.Database
+	}
+	return ""
+}
+
+func (x *ValidateDSNResponse) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *ValidateDSNResponse) GetSslMode() string {
+	if x != nil {
+		return x.SslMode
+	}
+	return ""
+}
+
+func (x *ValidateDSNResponse) GetStages() []*ValidationStage {
+	if x != nil {
+		return x.Stages
+	}
+	return nil
+}
+
+func (x *ValidateDSNResponse) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *ValidateDSNResponse) GetServerVersion() string {
+	if x != nil {
+		return x.ServerVersion
+	}
+	return ""
+}
+
+func (x *ValidateDSNResponse) GetMaxConnections() int32 {
+	if x != nil {
+		return x.MaxConnections
+	}
+	return 0
+}
+
+func (x *ValidateDSNResponse) GetExtensions() []*Extension {
+	if x != nil {
+		return x.Extensions
+	}
+	return nil
+}
+
+func (x *ValidateDSNResponse) GetPrivileges() *RolePrivileges {
+	if x != nil {
+		return x.Privileges
+	}
+	return nil
+}
+
+func (x *ValidateDSNResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_internal_grpc_connectionpool_connection_pool_proto protoreflect.FileDescriptor
+
+const file_internal_grpc_connectionpool_connection_pool_proto_rawDesc = "" +
+	"\n" +
+	"2internal/grpc/connectionpool/connection_pool.proto\x12\x0econnectionpool\x1a\x1cgoogle/api/annotations.proto\x1a\x1fgoogle/protobuf/timestamp.proto\x1a.protoc-gen-openapiv2/options/annotations.proto\"\xb6\x01\n" +
+	"\x11ConnectionRequest\x12\x1b\n" +
+	"\ttenant_id\x18\x01 \x01(\tR\btenantId\x12\x10\n" +
+	"\x03dsn\x18\x02 \x01(\tR\x03dsn\x12\x1b\n" +
+	"\tread_only\x18\x03 \x01(\bR\breadOnly\x12\x17\n" +
+	"\amin_lsn\x18\x04 \x01(\tR\x06minLsn\x12\x1f\n" +
+	"\vttl_seconds\x18\x05 \x01(\x05R\n" +
+	"ttlSeconds\x12\x1b\n" +
+	"\tcall_site\x18\x06 \x01(\tR\bcallSite\"\xad\x01\n" +
+	"\x12ConnectionResponse\x12#\n" +
+	"\rconnection_id\x18\x01 \x01(\tR\fconnectionId\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12!\n" +
+	"\ffrom_replica\x18\x03 \x01(\bR\vfromReplica\x129\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\"U\n" +
+	"\x11ConnectionRelease\x12#\n" +
+	"\rconnection_id\x18\x01 \x01(\tR\fconnectionId\x12\x1b\n" +
+	"\ttenant_id\x18\x02 \x01(\tR\btenantId\"A\n" +
+	"\x0fReleaseResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"v\n" +
+	"\x11RenewLeaseRequest\x12#\n" +
+	"\rconnection_id\x18\x01 \x01(\tR\fconnectionId\x12\x1f\n" +
+	"\vttl_seconds\x18\x02 \x01(\x05R\n" +
+	"ttlSeconds\x12\x1b\n" +
+	"\ttenant_id\x18\x03 \x01(\tR\btenantId\"e\n" +
+	"\x12RenewLeaseResponse\x129\n" +
+	"\n" +
+	"expires_at\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\x89\x01\n" +
+	"\x10HoldLeaseRequest\x12=\n" +
+	"\aacquire\x18\x01 \x01(\v2!.connectionpool.ConnectionRequestH\x00R\aacquire\x12/\n" +
+	"\x04ping\x18\x02 \x01(\v2\x19.connectionpool.LeasePingH\x00R\x04pingB\x05\n" +
+	"\x03msg\"\v\n" +
+	"\tLeasePing\"\x87\x01\n" +
+	"\x11HoldLeaseResponse\x12:\n" +
+	"\x05lease\x18\x01 \x01(\v2\".connectionpool.ConnectionResponseH\x00R\x05lease\x12/\n" +
+	"\x04pong\x18\x02 \x01(\v2\x19.connectionpool.LeasePongH\x00R\x04pongB\x05\n" +
+	"\x03msg\"F\n" +
+	"\tLeasePong\x129\n" +
+	"\n" +
+	"expires_at\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\"+\n" +
+	"\fStatsRequest\x12\x1b\n" +
+	"\ttenant_id\x18\x01 \x01(\tR\btenantId\"\xe8\x01\n" +
+	"\rStatsResponse\x12-\n" +
+	"\x12active_connections\x18\x01 \x01(\x05R\x11activeConnections\x12)\n" +
+	"\x10idle_connections\x18\x02 \x01(\x05R\x0fidleConnections\x12+\n" +
+	"\x11total_connections\x18\x03 \x01(\x05R\x10totalConnections\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12:\n" +
+	"\bbreakers\x18\x05 \x03(\v2\x1e.connectionpool.CircuitBreakerR\bbreakers\"\xe2\x01\n" +
+	"\x0eCircuitBreaker\x12\x12\n" +
+	"\x04host\x18\x01 \x01(\tR\x04host\x12\x14\n" +
+	"\x05state\x18\x02 \x01(\tR\x05state\x12\x1a\n" +
+	"\brequests\x18\x03 \x01(\x05R\brequests\x12\x1a\n" +
+	"\bfailures\x18\x04 \x01(\x05R\bfailures\x127\n" +
+	"\topened_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\bopenedAt\x125\n" +
+	"\bretry_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\aretryAt\"\xc1\x01\n" +
+	"\x13StatsHistoryRequest\x12\x1b\n" +
+	"\ttenant_id\x18\x01 \x01(\tR\btenantId\x120\n" +
+	"\x05start\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x05start\x12,\n" +
+	"\x03end\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x03end\x12-\n" +
+	"\x12resolution_seconds\x18\x04 \x01(\x05R\x11resolutionSeconds\"\xa4\x03\n" +
+	"\n" +
+	"StatsPoint\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x18\n" +
+	"\asamples\x18\x02 \x01(\x05R\asamples\x124\n" +
+	"\x16avg_active_connections\x18\x03 \x01(\x01R\x14avgActiveConnections\x124\n" +
+	"\x16max_active_connections\x18\x04 \x01(\x05R\x14maxActiveConnections\x120\n" +
+	"\x14avg_idle_connections\x18\x05 \x01(\x01R\x12avgIdleConnections\x122\n" +
+	"\x15avg_total_connections\x18\x06 \x01(\x01R\x13avgTotalConnections\x122\n" +
+	"\x15max_total_connections\x18\a \x01(\x05R\x13maxTotalConnections\x12'\n" +
+	"\x0fmax_connections\x18\b \x01(\x05R\x0emaxConnections\x12\x1d\n" +
+	"\n" +
+	"max_leases\x18\t \x01(\x05R\tmaxLeases\"`\n" +
+	"\x14StatsHistoryResponse\x122\n" +
+	"\x06points\x18\x01 \x03(\v2\x1a.connectionpool.StatsPointR\x06points\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\xad\x01\n" +
+	"\x15WatchPoolStatsRequest\x12\x1d\n" +
+	"\n" +
+	"tenant_ids\x18\x01 \x03(\tR\ttenantIds\x12\x1f\n" +
+	"\vinterval_ms\x18\x02 \x01(\x05R\n" +
+	"intervalMs\x12\x1f\n" +
+	"\vdeltas_only\x18\x03 \x01(\bR\n" +
+	"deltasOnly\x123\n" +
+	"\x15utilization_threshold\x18\x04 \x01(\x01R\x14utilizationThreshold\"\x99\x02\n" +
+	"\vTenantStats\x12\x1b\n" +
+	"\ttenant_id\x18\x01 \x01(\tR\btenantId\x12-\n" +
+	"\x12active_connections\x18\x02 \x01(\x05R\x11activeConnections\x12)\n" +
+	"\x10idle_connections\x18\x03 \x01(\x05R\x0fidleConnections\x12+\n" +
+	"\x11total_connections\x18\x04 \x01(\x05R\x10totalConnections\x12'\n" +
+	"\x0fmax_connections\x18\x05 \x01(\x05R\x0emaxConnections\x12\x16\n" +
+	"\x06leases\x18\x06 \x01(\x05R\x06leases\x12%\n" +
+	"\x0eover_threshold\x18\a \x01(\bR\roverThreshold\"\xb0\x01\n" +
+	"\x16WatchPoolStatsResponse\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x125\n" +
+	"\atenants\x18\x02 \x03(\v2\x1b.connectionpool.TenantStatsR\atenants\x12/\n" +
+	"\x13threshold_triggered\x18\x03 \x01(\bR\x12thresholdTriggered\"~\n" +
+	"\x12WatchEventsRequest\x12\x1d\n" +
+	"\n" +
+	"tenant_ids\x18\x01 \x03(\tR\ttenantIds\x12\x14\n" +
+	"\x05types\x18\x02 \x03(\tR\x05types\x12\x16\n" +
+	"\x06replay\x18\x03 \x01(\bR\x06replay\x12\x1b\n" +
+	"\tafter_seq\x18\x04 \x01(\x04R\bafterSeq\"\xf0\x02\n" +
+	"\tPoolEvent\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x12.\n" +
+	"\x04time\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x1b\n" +
+	"\ttenant_id\x18\x04 \x01(\tR\btenantId\x12\x16\n" +
+	"\x06server\x18\x05 \x01(\tR\x06server\x12\x1f\n" +
+	"\vfrom_server\x18\x06 \x01(\tR\n" +
+	"fromServer\x12\x18\n" +
+	"\ahealthy\x18\b \x01(\bR\ahealthy\x12\x14\n" +
+	"\x05error\x18\t \x01(\tR\x05error\x12\x14\n" +
+	"\x05lease\x18\n" +
+	" \x01(\tR\x05lease\x12'\n" +
+	"\x0fmax_connections\x18\v \x01(\x05R\x0emaxConnections\x128\n" +
+	"\x18previous_max_connections\x18\f \x01(\x05R\x16previousMaxConnectionsJ\x04\b\a\x10\bR\blease_id\"{\n" +
+	"\x0eNullableString\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\tR\x05value\x12\x17\n" +
+	"\ais_null\x18\x02 \x01(\bR\x06isNull\x12\x1f\n" +
+	"\vbytes_value\x18\x03 \x01(\fR\n" +
+	"bytesValue\x12\x19\n" +
+	"\bis_bytes\x18\x04 \x01(\bR\aisBytes\"\x9a\x01\n" +
+	"\fQueryRequest\x12#\n" +
+	"\rconnection_id\x18\x01 \x01(\tR\fconnectionId\x12\x10\n" +
+	"\x03sql\x18\x02 \x01(\tR\x03sql\x126\n" +
+	"\x06params\x18\x03 \x03(\v2\x1e.connectionpool.NullableStringR\x06params\x12\x1b\n" +
+	"\ttenant_id\x18\x04 \x01(\tR\btenantId\"=\n" +
+	"\x03Row\x126\n" +
+	"\x06values\x18\x01 \x03(\v2\x1e.connectionpool.NullableStringR\x06values\"\xac\x01\n" +
+	"\rQueryResponse\x12\x18\n" +
+	"\acolumns\x18\x01 \x03(\tR\acolumns\x12'\n" +
+	"\x04rows\x18\x02 \x03(\v2\x13.connectionpool.RowR\x04rows\x12\x1f\n" +
+	"\vcommand_tag\x18\x03 \x01(\tR\n" +
+	"commandTag\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12!\n" +
+	"\fcolumn_types\x18\x05 \x03(\rR\vcolumnTypes\"\xb6\x01\n" +
+	"\fBeginRequest\x12#\n" +
+	"\rconnection_id\x18\x01 \x01(\tR\fconnectionId\x12'\n" +
+	"\x0fisolation_level\x18\x02 \x01(\tR\x0eisolationLevel\x12\x1b\n" +
+	"\tread_only\x18\x03 \x01(\bR\breadOnly\x12\x1e\n" +
+	"\n" +
+	"deferrable\x18\x04 \x01(\bR\n" +
+	"deferrable\x12\x1b\n" +
+	"\ttenant_id\x18\x05 \x01(\tR\btenantId\"V\n" +
+	"\x12TransactionRequest\x12#\n" +
+	"\rconnection_id\x18\x01 \x01(\tR\fconnectionId\x12\x1b\n" +
+	"\ttenant_id\x18\x02 \x01(\tR\btenantId\"+\n" +
+	"\x13TransactionResponse\x12\x14\n" +
+	"\x05error\x18\x01 \x01(\tR\x05error\"/\n" +
+	"\x10ListPoolsRequest\x12\x1b\n" +
+	"\ttenant_id\x18\x01 \x01(\tR\btenantId\"\xa3\x02\n" +
+	"\bPoolInfo\x12\x1b\n" +
+	"\ttenant_id\x18\x01 \x01(\tR\btenantId\x12\x16\n" +
+	"\x06server\x18\x02 \x01(\tR\x06server\x12-\n" +
+	"\x12active_connections\x18\x03 \x01(\x05R\x11activeConnections\x12)\n" +
+	"\x10idle_connections\x18\x04 \x01(\x05R\x0fidleConnections\x12+\n" +
+	"\x11total_connections\x18\x05 \x01(\x05R\x10totalConnections\x12'\n" +
+	"\x0fmax_connections\x18\x06 \x01(\x05R\x0emaxConnections\x12\x16\n" +
+	"\x06leases\x18\a \x01(\x05R\x06leases\x12\x1a\n" +
+	"\bdraining\x18\b \x01(\bR\bdraining\"Y\n" +
+	"\x11ListPoolsResponse\x12.\n" +
+	"\x05pools\x18\x01 \x03(\v2\x18.connectionpool.PoolInfoR\x05pools\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"F\n" +
+	"\x11ListLeasesRequest\x12\x1b\n" +
+	"\ttenant_id\x18\x01 \x01(\tR\btenantId\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"\x95\x03\n" +
+	"\tLeaseInfo\x12\x16\n" +
+	"\x06handle\x18\v \x01(\tR\x06handle\x12\x1b\n" +
+	"\ttenant_id\x18\x02 \x01(\tR\btenantId\x12\x1b\n" +
+	"\tpool_mode\x18\x03 \x01(\tR\bpoolMode\x12\x12\n" +
+	"\x04peer\x18\x04 \x01(\tR\x04peer\x12\x1a\n" +
+	"\bidentity\x18\x05 \x01(\tR\bidentity\x12\x1b\n" +
+	"\tcall_site\x18\x06 \x01(\tR\bcallSite\x12;\n" +
+	"\vacquired_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"acquiredAt\x12@\n" +
+	"\x0elast_active_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\flastActiveAt\x129\n" +
+	"\n" +
+	"expires_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12\x1a\n" +
+	"\battached\x18\n" +
+	" \x01(\bR\battachedJ\x04\b\x01\x10\x02R\rconnection_id\"]\n" +
+	"\x12ListLeasesResponse\x121\n" +
+	"\x06leases\x18\x01 \x03(\v2\x19.connectionpool.LeaseInfoR\x06leases\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"T\n" +
+	"\fDrainRequest\x12\x1b\n" +
+	"\ttenant_id\x18\x01 \x01(\tR\btenantId\x12'\n" +
+	"\x0ftimeout_seconds\x18\x02 \x01(\x05R\x0etimeoutSeconds\"o\n" +
+	"\rDrainResponse\x12%\n" +
+	"\x0eevicted_leases\x18\x01 \x01(\x05R\revictedLeases\x12!\n" +
+	"\fclosed_pools\x18\x02 \x01(\x05R\vclosedPools\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"+\n" +
+	"\fEvictRequest\x12\x1b\n" +
+	"\ttenant_id\x18\x01 \x01(\tR\btenantId\"o\n" +
+	"\rEvictResponse\x12%\n" +
+	"\x0eevicted_leases\x18\x01 \x01(\x05R\revictedLeases\x12!\n" +
+	"\fclosed_pools\x18\x02 \x01(\x05R\vclosedPools\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"E\n" +
+	"\x12ValidateDSNRequest\x12\x10\n" +
+	"\x03dsn\x18\x01 \x01(\tR\x03dsn\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\x02 \x01(\x05R\ttimeoutMs\"t\n" +
+	"\x0fValidationStage\x12\x14\n" +
+	"\x05stage\x18\x01 \x01(\tR\x05stage\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x1d\n" +
+	"\n" +
+	"latency_ms\x18\x03 \x01(\x01R\tlatencyMs\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"9\n" +
+	"\tExtension\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\"\x87\x02\n" +
+	"\x0eRolePrivileges\x12\x12\n" +
+	"\x04role\x18\x01 \x01(\tR\x04role\x12\x1c\n" +
+	"\tsuperuser\x18\x02 \x01(\bR\tsuperuser\x12\x1b\n" +
+	"\tcreate_db\x18\x03 \x01(\bR\bcreateDb\x12\x1f\n" +
+	"\vcreate_role\x18\x04 \x01(\bR\n" +
+	"createRole\x12 \n" +
+	"\vreplication\x18\x05 \x01(\bR\vreplication\x12\x1d\n" +
+	"\n" +
+	"bypass_rls\x18\x06 \x01(\bR\tbypassRls\x12\x18\n" +
+	"\aconnect\x18\a \x01(\bR\aconnect\x12\x16\n" +
+	"\x06create\x18\b \x01(\bR\x06create\x12\x12\n" +
+	"\x04temp\x18\t \x01(\bR\x04temp\"\xf7\x03\n" +
+	"\x13ValidateDSNResponse\x12\x14\n" +
+	"\x05valid\x18\x01 \x01(\bR\x05valid\x12%\n" +
+	"\x0enormalized_dsn\x18\x02 \x01(\tR\rnormalizedDsn\x12\x12\n" +
+	"\x04host\x18\x03 \x01(\tR\x04host\x12\x12\n" +
+	"\x04port\x18\x04 \x01(\x05R\x04port\x12\x1a\n" +
+	"\bdatabase\x18\x05 \x01(\tR\bdatabase\x12\x12\n" +
+	"\x04user\x18\x06 \x01(\tR\x04user\x12\x19\n" +
+	"\bssl_mode\x18\a \x01(\tR\asslMode\x127\n" +
+	"\x06stages\x18\b \x03(\v2\x1f.connectionpool.ValidationStageR\x06stages\x12\x16\n" +
+	"\x06reason\x18\t \x01(\tR\x06reason\x12%\n" +
+	"\x0eserver_version\x18\n" +
+	" \x01(\tR\rserverVersion\x12'\n" +
+	"\x0fmax_connections\x18\v \x01(\x05R\x0emaxConnections\x129\n" +
+	"\n" +
+	"extensions\x18\f \x03(\v2\x19.connectionpool.ExtensionR\n" +
+	"extensions\x12>\n" +
+	"\n" +
+	"privileges\x18\r \x01(\v2\x1e.connectionpool.RolePrivilegesR\n" +
+	"privileges\x12\x14\n" +
+	"\x05error\x18\x0e \x01(\tR\x05error2\x93\x11\n" +
+	"\x15ConnectionPoolService\x12\x86\x01\n" +
+	"\rGetConnection\x12!.connectionpool.ConnectionRequest\x1a\".connectionpool.ConnectionResponse\".\x82\xd3\xe4\x93\x02(:\x01*\"#/v1/tenants/{tenant_id}/connections\x12\x94\x01\n" +
+	"\x11ReleaseConnection\x12!.connectionpool.ConnectionRelease\x1a\x1f.connectionpool.ReleaseResponse\";\x82\xd3\xe4\x93\x025*3/v1/tenants/{tenant_id}/connections/{connection_id}\x12\x99\x01\n" +
+	"\n" +
+	"RenewLease\x12!.connectionpool.RenewLeaseRequest\x1a\".connectionpool.RenewLeaseResponse\"D\x82\xd3\xe4\x93\x02>:\x01*\"9/v1/tenants/{tenant_id}/connections/{connection_id}/renew\x12T\n" +
+	"\tHoldLease\x12 .connectionpool.HoldLeaseRequest\x1a!.connectionpool.HoldLeaseResponse(\x010\x01\x12\x8f\x01\n" +
+	"\x13GetPoolStatsHistory\x12#.connectionpool.StatsHistoryRequest\x1a$.connectionpool.StatsHistoryResponse\"-\x82\xd3\xe4\x93\x02'\x12%/v1/tenants/{tenant_id}/stats/history\x12a\n" +
+	"\x0eWatchPoolStats\x12%.connectionpool.WatchPoolStatsRequest\x1a&.connectionpool.WatchPoolStatsResponse0\x01\x12N\n" +
+	"\vWatchEvents\x12\".connectionpool.WatchEventsRequest\x1a\x19.connectionpool.PoolEvent0\x01\x12r\n" +
+	"\fGetPoolStats\x12\x1c.connectionpool.StatsRequest\x1a\x1d.connectionpool.StatsResponse\"%\x82\xd3\xe4\x93\x02\x1f\x12\x1d/v1/tenants/{tenant_id}/stats\x12\x91\x01\n" +
+	"\fExecuteQuery\x12\x1c.connectionpool.QueryRequest\x1a\x1d.connectionpool.QueryResponse\"D\x82\xd3\xe4\x93\x02>:\x01*\"9/v1/tenants/{tenant_id}/connections/{connection_id}/query\x12\x9b\x01\n" +
+	"\x10BeginTransaction\x12\x1c.connectionpool.BeginRequest\x1a#.connectionpool.TransactionResponse\"D\x82\xd3\xe4\x93\x02>:\x01*\"9/v1/tenants/{tenant_id}/connections/{connection_id}/begin\x12\xa3\x01\n" +
+	"\x11CommitTransaction\x12\".connectionpool.TransactionRequest\x1a#.connectionpool.TransactionResponse\"E\x82\xd3\xe4\x93\x02?:\x01*\":/v1/tenants/{tenant_id}/connections/{connection_id}/commit\x12\xa7\x01\n" +
+	"\x13RollbackTransaction\x12\".connectionpool.TransactionRequest\x1a#.connectionpool.TransactionResponse\"G\x82\xd3\xe4\x93\x02A:\x01*\"</v1/tenants/{tenant_id}/connections/{connection_id}/rollback\x12c\n" +
+	"\tListPools\x12 .connectionpool.ListPoolsRequest\x1a!.connectionpool.ListPoolsResponse\"\x11\x82\xd3\xe4\x93\x02\v\x12\t/v1/pools\x12g\n" +
+	"\n" +
+	"ListLeases\x12!.connectionpool.ListLeasesRequest\x1a\".connectionpool.ListLeasesResponse\"\x12\x82\xd3\xe4\x93\x02\f\x12\n" +
+	"/v1/leases\x12t\n" +
+	"\vDrainTenant\x12\x1c.connectionpool.DrainRequest\x1a\x1d.connectionpool.DrainResponse\"(\x82\xd3\xe4\x93\x02\":\x01*\"\x1d/v1/tenants/{tenant_id}/drain\x12t\n" +
+	"\vEvictTenant\x12\x1c.connectionpool.EvictRequest\x1a\x1d.connectionpool.EvictResponse\"(\x82\xd3\xe4\x93\x02\":\x01*\"\x1d/v1/tenants/{tenant_id}/evict\x12s\n" +
+	"\vValidateDSN\x12\".connectionpool.ValidateDSNRequest\x1a#.connectionpool.ValidateDSNResponse\"\x1b\x82\xd3\xe4\x93\x02\x15:\x01*\"\x10/v1/dsn/validateB\x87\x02\x92A\xb2\x01\x12\x1e\n" +
+	"\x17Connection Pool Manager2\x031.0*\x01\x02Z\x7f\n" +
+	"}\n" +
+	"\x06bearer\x12s\b\x02\x12^Bearer token, e.g. \"Bearer s3cret\". Not needed when a trusted client certificate is presented.\x1a\rAuthorization \x02b\f\n" +
+	"\n" +
+	"\n" +
+	"\x06bearer\x12\x00ZOgithub.com/teresa-solution/connection-pool-manager/internal/grpc/connectionpoolb\x06proto3"
+
+var (
+	file_internal_grpc_connectionpool_connection_pool_proto_rawDescOnce sync.Once
+	file_internal_grpc_connectionpool_connection_pool_proto_rawDescData []byte
+)
+
+func file_internal_grpc_connectionpool_connection_pool_proto_rawDescGZIP() []byte {
+	file_internal_grpc_connectionpool_connection_pool_proto_rawDescOnce.Do(func() {
+		file_internal_grpc_connectionpool_connection_pool_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_internal_grpc_connectionpool_connection_pool_proto_rawDesc), len(file_internal_grpc_connectionpool_connection_pool_proto_rawDesc)))
+	})
+	return file_internal_grpc_connectionpool_connection_pool_proto_rawDescData
+}
+
+var file_internal_grpc_connectionpool_connection_pool_proto_msgTypes = make([]protoimpl.MessageInfo, 43)
+var file_internal_grpc_connectionpool_connection_pool_proto_goTypes = []any{
+	(*ConnectionRequest)(nil),      // 0: connectionpool.ConnectionRequest
+	(*ConnectionResponse)(nil),     // 1: connectionpool.ConnectionResponse
+	(*ConnectionRelease)(nil),      // 2: connectionpool.ConnectionRelease
+	(*ReleaseResponse)(nil),        // 3: connectionpool.ReleaseResponse
+	(*RenewLeaseRequest)(nil),      // 4: connectionpool.RenewLeaseRequest
+	(*RenewLeaseResponse)(nil),     // 5: connectionpool.RenewLeaseResponse
+	(*HoldLeaseRequest)(nil),       // 6: connectionpool.HoldLeaseRequest
+	(*LeasePing)(nil),              // 7: connectionpool.LeasePing
+	(*HoldLeaseResponse)(nil),      // 8: connectionpool.HoldLeaseResponse
+	(*LeasePong)(nil),              // 9: connectionpool.LeasePong
+	(*StatsRequest)(nil),           // 10: connectionpool.StatsRequest
+	(*StatsResponse)(nil),          // 11: connectionpool.StatsResponse
+	(*CircuitBreaker)(nil),         // 12: connectionpool.CircuitBreaker
+	(*StatsHistoryRequest)(nil),    // 13: connectionpool.StatsHistoryRequest
+	(*StatsPoint)(nil),             // 14: connectionpool.StatsPoint
+	(*StatsHistoryResponse)(nil),   // 15: connectionpool.StatsHistoryResponse
+	(*WatchPoolStatsRequest)(nil),  // 16: connectionpool.WatchPoolStatsRequest
+	(*TenantStats)(nil),            // 17: connectionpool.TenantStats
+	(*WatchPoolStatsResponse)(nil), // 18: connectionpool.WatchPoolStatsResponse
+	(*WatchEventsRequest)(nil),     // 19: connectionpool.WatchEventsRequest
+	(*PoolEvent)(nil),              // 20: connectionpool.PoolEvent
+	(*NullableString)(nil),         // 21: connectionpool.NullableString
+	(*QueryRequest)(nil),           // 22: connectionpool.QueryRequest
+	(*Row)(nil),                    // 23: connectionpool.Row
+	(*QueryResponse)(nil),          // 24: connectionpool.QueryResponse
+	(*BeginRequest)(nil),           // 25: connectionpool.BeginRequest
+	(*TransactionRequest)(nil),     // 26: connectionpool.TransactionRequest
+	(*TransactionResponse)(nil),    // 27: connectionpool.TransactionResponse
+	(*ListPoolsRequest)(nil),       // 28: connectionpool.ListPoolsRequest
+	(*PoolInfo)(nil),               // 29: connectionpool.PoolInfo
+	(*ListPoolsResponse)(nil),      // 30: connectionpool.ListPoolsResponse
+	(*ListLeasesRequest)(nil),      // 31: connectionpool.ListLeasesRequest
+	(*LeaseInfo)(nil),              // 32: connectionpool.LeaseInfo
+	(*ListLeasesResponse)(nil),     // 33: connectionpool.ListLeasesResponse
+	(*DrainRequest)(nil),           // 34: connectionpool.DrainRequest
+	(*DrainResponse)(nil),          // 35: connectionpool.DrainResponse
+	(*EvictRequest)(nil),           // 36: connectionpool.EvictRequest
+	(*EvictResponse)(nil),          // 37: connectionpool.EvictResponse
+	(*ValidateDSNRequest)(nil),     // 38: connectionpool.ValidateDSNRequest
+	(*ValidationStage)(nil),        // 39: connectionpool.ValidationStage
+	(*Extension)(nil),              // 40: connectionpool.Extension
+	(*RolePrivileges)(nil),         // 41: connectionpool.RolePrivileges
+	(*ValidateDSNResponse)(nil),    // 42: connectionpool.ValidateDSNResponse
+	(*timestamppb.Timestamp)(nil),  // 43: google.protobuf.Timestamp
+}
+var file_internal_grpc_connectionpool_connection_pool_proto_depIdxs = []int32{
+	43, // 0: connectionpool.ConnectionResponse.expires_at:type_name -> google.protobuf.Timestamp
+	43, // 1: connectionpool.RenewLeaseResponse.expires_at:type_name -> google.protobuf.Timestamp
+	0,  // 2: connectionpool.HoldLeaseRequest.acquire:type_name -> connectionpool.ConnectionRequest
+	7,  // 3: connectionpool.HoldLeaseRequest.ping:type_name -> connectionpool.LeasePing
+	1,  // 4: connectionpool.HoldLeaseResponse.lease:type_name -> connectionpool.ConnectionResponse
+	9,  // 5: connectionpool.HoldLeaseResponse.pong:type_name -> connectionpool.LeasePong
+	43, // 6: connectionpool.LeasePong.expires_at:type_name -> google.protobuf.Timestamp
+	12, // 7: connectionpool.StatsResponse.breakers:type_name -> connectionpool.CircuitBreaker
+	43, // 8: connectionpool.CircuitBreaker.opened_at:type_name -> google.protobuf.Timestamp
+	43, // 9: connectionpool.CircuitBreaker.retry_at:type_name -> google.protobuf.Timestamp
+	43, // 10: connectionpool.StatsHistoryRequest.start:type_name -> google.protobuf.Timestamp
+	43, // 11: connectionpool.StatsHistoryRequest.end:type_name -> google.protobuf.Timestamp
+	43, // 12: connectionpool.StatsPoint.time:type_name -> google.protobuf.Timestamp
+	14, // 13: connectionpool.StatsHistoryResponse.points:type_name -> connectionpool.StatsPoint
+	43, // 14: connectionpool.WatchPoolStatsResponse.time:type_name -> google.protobuf.Timestamp
+	17, // 15: connectionpool.WatchPoolStatsResponse.tenants:type_name -> connectionpool.TenantStats
+	43, // 16: connectionpool.PoolEvent.time:type_name -> google.protobuf.Timestamp
+	21, // 17: connectionpool.QueryRequest.params:type_name -> connectionpool.NullableString
+	21, // 18: connectionpool.Row.values:type_name -> connectionpool.NullableString
+	23, // 19: connectionpool.QueryResponse.rows:type_name -> connectionpool.Row
+	29, // 20: connectionpool.ListPoolsResponse.pools:type_name -> connectionpool.PoolInfo
+	43, // 21: connectionpool.LeaseInfo.acquired_at:type_name -> google.protobuf.Timestamp
+	43, // 22: connectionpool.LeaseInfo.last_active_at:type_name -> google.protobuf.Timestamp
+	43, // 23: connectionpool.LeaseInfo.expires_at:type_name -> google.protobuf.Timestamp
+	32, // 24: connectionpool.ListLeasesResponse.leases:type_name -> connectionpool.LeaseInfo
+	39, // 25: connectionpool.ValidateDSNResponse.stages:type_name -> connectionpool.ValidationStage
+	40, // 26: connectionpool.ValidateDSNResponse.extensions:type_name -> connectionpool.Extension
+	41, // 27: connectionpool.ValidateDSNResponse.privileges:type_name -> connectionpool.RolePrivileges
+	0,  // 28: connectionpool.ConnectionPoolService.GetConnection:input_type -> connectionpool.ConnectionRequest
+	2,  // 29: connectionpool.ConnectionPoolService.ReleaseConnection:input_type -> connectionpool.ConnectionRelease
+	4,  // 30: connectionpool.ConnectionPoolService.RenewLease:input_type -> connectionpool.RenewLeaseRequest
+	6,  // 31: connectionpool.ConnectionPoolService.HoldLease:input_type -> connectionpool.HoldLeaseRequest
+	13, // 32: connectionpool.ConnectionPoolService.GetPoolStatsHistory:input_type -> connectionpool.StatsHistoryRequest
+	16, // 33: connectionpool.ConnectionPoolService.WatchPoolStats:input_type -> connectionpool.WatchPoolStatsRequest
+	19, // 34: connectionpool.ConnectionPoolService.WatchEvents:input_type -> connectionpool.WatchEventsRequest
+	10, // 35: connectionpool.ConnectionPoolService.GetPoolStats:input_type -> connectionpool.StatsRequest
+	22, // 36: connectionpool.ConnectionPoolService.ExecuteQuery:input_type -> connectionpool.QueryRequest
+	25, // 37: connectionpool.ConnectionPoolService.BeginTransaction:input_type -> connectionpool.BeginRequest
+	26, // 38: connectionpool.ConnectionPoolService.CommitTransaction:input_type -> connectionpool.TransactionRequest
+	26, // 39: connectionpool.ConnectionPoolService.RollbackTransaction:input_type -> connectionpool.TransactionRequest
+	28, // 40: connectionpool.ConnectionPoolService.ListPools:input_type -> connectionpool.ListPoolsRequest
+	31, // 41: connectionpool.ConnectionPoolService.ListLeases:input_type -> connectionpool.ListLeasesRequest
+	34, // 42: connectionpool.ConnectionPoolService.DrainTenant:input_type -> connectionpool.DrainRequest
+	36, // 43: connectionpool.ConnectionPoolService.EvictTenant:input_type -> connectionpool.EvictRequest
+	38, // 44: connectionpool.ConnectionPoolService.ValidateDSN:input_type -> connectionpool.ValidateDSNRequest
+	1,  // 45: connectionpool.ConnectionPoolService.GetConnection:output_type -> connectionpool.ConnectionResponse
+	3,  // 46: connectionpool.ConnectionPoolService.ReleaseConnection:output_type -> connectionpool.ReleaseResponse
+	5,  // 47: connectionpool.ConnectionPoolService.RenewLease:output_type -> connectionpool.RenewLeaseResponse
+	8,  // 48: connectionpool.ConnectionPoolService.HoldLease:output_type -> connectionpool.HoldLeaseResponse
+	15, // 49: connectionpool.ConnectionPoolService.GetPoolStatsHistory:output_type -> connectionpool.StatsHistoryResponse
+	18, // 50: connectionpool.ConnectionPoolService.WatchPoolStats:output_type -> connectionpool.WatchPoolStatsResponse
+	20, // 51: connectionpool.ConnectionPoolService.WatchEvents:output_type -> connectionpool.PoolEvent
+	11, // 52: connectionpool.ConnectionPoolService.GetPoolStats:output_type -> connectionpool.StatsResponse
+	24, // 53: connectionpool.ConnectionPoolService.ExecuteQuery:output_type -> connectionpool.QueryResponse
+	27, // 54: connectionpool.ConnectionPoolService.BeginTransaction:output_type -> connectionpool.TransactionResponse
+	27, // 55: connectionpool.ConnectionPoolService.CommitTransaction:output_type -> connectionpool.TransactionResponse
+	27, // 56: connectionpool.ConnectionPoolService.RollbackTransaction:output_type -> connectionpool.TransactionResponse
+	30, // 57: connectionpool.ConnectionPoolService.ListPools:output_type -> connectionpool.ListPoolsResponse
+	33, // 58: connectionpool.ConnectionPoolService.ListLeases:output_type -> connectionpool.ListLeasesResponse
+	35, // 59: connectionpool.ConnectionPoolService.DrainTenant:output_type -> connectionpool.DrainResponse
+	37, // 60: connectionpool.ConnectionPoolService.EvictTenant:output_type -> connectionpool.EvictResponse
+	42, // 61: connectionpool.ConnectionPoolService.ValidateDSN:output_type -> connectionpool.ValidateDSNResponse
+	45, // [45:62] is the sub-list for method output_type
+	28, // [28:45] is the sub-list for method input_type
+	28, // [28:28] is the sub-list for extension type_name
+	28, // [28:28] is the sub-list for extension extendee
+	0,  // [0:28] is the sub-list for field type_name
+}
+
+func init() { file_internal_grpc_connectionpool_connection_pool_proto_init() }
+func file_internal_grpc_connectionpool_connection_pool_proto_init() {
+	if File_internal_grpc_connectionpool_connection_pool_proto != nil {
+		return
+	}
+	file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[6].OneofWrappers = []any{
+		(*HoldLeaseRequest_Acquire)(nil),
+		(*HoldLeaseRequest_Ping)(nil),
+	}
+	file_internal_grpc_connectionpool_connection_pool_proto_msgTypes[8].OneofWrappers = []any{
+		(*HoldLeaseResponse_Lease)(nil),
+		(*HoldLeaseResponse_Pong)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -407,7 +3395,7 @@ func file_internal_grpc_connectionpool_connection_pool_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_internal_grpc_connectionpool_connection_pool_proto_rawDesc), len(file_internal_grpc_connectionpool_connection_pool_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   43,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
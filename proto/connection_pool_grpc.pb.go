@@ -19,9 +19,23 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ConnectionPoolService_GetConnection_FullMethodName     = "/connectionpool.ConnectionPoolService/GetConnection"
-	ConnectionPoolService_ReleaseConnection_FullMethodName = "/connectionpool.ConnectionPoolService/ReleaseConnection"
-	ConnectionPoolService_GetPoolStats_FullMethodName      = "/connectionpool.ConnectionPoolService/GetPoolStats"
+	ConnectionPoolService_GetConnection_FullMethodName       = "/connectionpool.ConnectionPoolService/GetConnection"
+	ConnectionPoolService_ReleaseConnection_FullMethodName   = "/connectionpool.ConnectionPoolService/ReleaseConnection"
+	ConnectionPoolService_RenewLease_FullMethodName          = "/connectionpool.ConnectionPoolService/RenewLease"
+	ConnectionPoolService_HoldLease_FullMethodName           = "/connectionpool.ConnectionPoolService/HoldLease"
+	ConnectionPoolService_GetPoolStatsHistory_FullMethodName = "/connectionpool.ConnectionPoolService/GetPoolStatsHistory"
+	ConnectionPoolService_WatchPoolStats_FullMethodName      = "/connectionpool.ConnectionPoolService/WatchPoolStats"
+	ConnectionPoolService_WatchEvents_FullMethodName         = "/connectionpool.ConnectionPoolService/WatchEvents"
+	ConnectionPoolService_GetPoolStats_FullMethodName        = "/connectionpool.ConnectionPoolService/GetPoolStats"
+	ConnectionPoolService_ExecuteQuery_FullMethodName        = "/connectionpool.ConnectionPoolService/ExecuteQuery"
+	ConnectionPoolService_BeginTransaction_FullMethodName    = "/connectionpool.ConnectionPoolService/BeginTransaction"
+	ConnectionPoolService_CommitTransaction_FullMethodName   = "/connectionpool.ConnectionPoolService/CommitTransaction"
+	ConnectionPoolService_RollbackTransaction_FullMethodName = "/connectionpool.ConnectionPoolService/RollbackTransaction"
+	ConnectionPoolService_ListPools_FullMethodName           = "/connectionpool.ConnectionPoolService/ListPools"
+	ConnectionPoolService_ListLeases_FullMethodName          = "/connectionpool.ConnectionPoolService/ListLeases"
+	ConnectionPoolService_DrainTenant_FullMethodName         = "/connectionpool.ConnectionPoolService/DrainTenant"
+	ConnectionPoolService_EvictTenant_FullMethodName         = "/connectionpool.ConnectionPoolService/EvictTenant"
+	ConnectionPoolService_ValidateDSN_FullMethodName         = "/connectionpool.ConnectionPoolService/ValidateDSN"
 )
 
 // ConnectionPoolServiceClient is the client API for ConnectionPoolService service.
@@ -32,8 +46,39 @@ type ConnectionPoolServiceClient interface {
 	GetConnection(ctx context.Context, in *ConnectionRequest, opts ...grpc.CallOption) (*ConnectionResponse, error)
 	// Release a connection back to the pool
 	ReleaseConnection(ctx context.Context, in *ConnectionRelease, opts ...grpc.CallOption) (*ReleaseResponse, error)
+	// Extend a lease before it expires
+	RenewLease(ctx context.Context, in *RenewLeaseRequest, opts ...grpc.CallOption) (*RenewLeaseResponse, error)
+	// Hold a lease for as long as the stream is open. The first message acquires the
+	// lease; pings keep it alive and closing the stream releases it.
+	HoldLease(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[HoldLeaseRequest, HoldLeaseResponse], error)
+	// Get a tenant's pool statistics over a time window, downsampled
+	GetPoolStatsHistory(ctx context.Context, in *StatsHistoryRequest, opts ...grpc.CallOption) (*StatsHistoryResponse, error)
+	// Stream pool statistics of some or all tenants as they change
+	WatchPoolStats(ctx context.Context, in *WatchPoolStatsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchPoolStatsResponse], error)
+	// Stream pool lifecycle events, optionally replaying recent ones first
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PoolEvent], error)
 	// Get pool statistics
 	GetPoolStats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
+	// Run a statement on a leased connection
+	ExecuteQuery(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*QueryResponse, error)
+	// Start a transaction on a leased connection
+	BeginTransaction(ctx context.Context, in *BeginRequest, opts ...grpc.CallOption) (*TransactionResponse, error)
+	// Commit the transaction of a leased connection
+	CommitTransaction(ctx context.Context, in *TransactionRequest, opts ...grpc.CallOption) (*TransactionResponse, error)
+	// Roll back the transaction of a leased connection
+	RollbackTransaction(ctx context.Context, in *TransactionRequest, opts ...grpc.CallOption) (*TransactionResponse, error)
+	// List the open pools
+	ListPools(ctx context.Context, in *ListPoolsRequest, opts ...grpc.CallOption) (*ListPoolsResponse, error)
+	// List the longest-held leases of each tenant and who holds them. Admin only.
+	ListLeases(ctx context.Context, in *ListLeasesRequest, opts ...grpc.CallOption) (*ListLeasesResponse, error)
+	// Stop new leases for a tenant, wait for its leases to be released and close its pools.
+	// Admin only.
+	DrainTenant(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainResponse, error)
+	// End a tenant's leases straight away and close its pools. Admin only.
+	EvictTenant(ctx context.Context, in *EvictRequest, opts ...grpc.CallOption) (*EvictResponse, error)
+	// Check that a DSN parses, meets the DSN policy and can be connected to, without
+	// creating a pool
+	ValidateDSN(ctx context.Context, in *ValidateDSNRequest, opts ...grpc.CallOption) (*ValidateDSNResponse, error)
 }
 
 type connectionPoolServiceClient struct {
@@ -64,6 +109,77 @@ func (c *connectionPoolServiceClient) ReleaseConnection(ctx context.Context, in
 	return out, nil
 }
 
+func (c *connectionPoolServiceClient) RenewLease(ctx context.Context, in *RenewLeaseRequest, opts ...grpc.CallOption) (*RenewLeaseResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RenewLeaseResponse)
+	err := c.cc.Invoke(ctx, ConnectionPoolService_RenewLease_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectionPoolServiceClient) HoldLease(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[HoldLeaseRequest, HoldLeaseResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ConnectionPoolService_ServiceDesc.Streams[0], ConnectionPoolService_HoldLease_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[HoldLeaseRequest, HoldLeaseResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConnectionPoolService_HoldLeaseClient = grpc.BidiStreamingClient[HoldLeaseRequest, HoldLeaseResponse]
+
+func (c *connectionPoolServiceClient) GetPoolStatsHistory(ctx context.Context, in *StatsHistoryRequest, opts ...grpc.CallOption) (*StatsHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatsHistoryResponse)
+	err := c.cc.Invoke(ctx, ConnectionPoolService_GetPoolStatsHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectionPoolServiceClient) WatchPoolStats(ctx context.Context, in *WatchPoolStatsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchPoolStatsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ConnectionPoolService_ServiceDesc.Streams[1], ConnectionPoolService_WatchPoolStats_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchPoolStatsRequest, WatchPoolStatsResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConnectionPoolService_WatchPoolStatsClient = grpc.ServerStreamingClient[WatchPoolStatsResponse]
+
+func (c *connectionPoolServiceClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PoolEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ConnectionPoolService_ServiceDesc.Streams[2], ConnectionPoolService_WatchEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchEventsRequest, PoolEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConnectionPoolService_WatchEventsClient = grpc.ServerStreamingClient[PoolEvent]
+
 func (c *connectionPoolServiceClient) GetPoolStats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(StatsResponse)
@@ -74,6 +190,96 @@ func (c *connectionPoolServiceClient) GetPoolStats(ctx context.Context, in *Stat
 	return out, nil
 }
 
+func (c *connectionPoolServiceClient) ExecuteQuery(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*QueryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueryResponse)
+	err := c.cc.Invoke(ctx, ConnectionPoolService_ExecuteQuery_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectionPoolServiceClient) BeginTransaction(ctx context.Context, in *BeginRequest, opts ...grpc.CallOption) (*TransactionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TransactionResponse)
+	err := c.cc.Invoke(ctx, ConnectionPoolService_BeginTransaction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectionPoolServiceClient) CommitTransaction(ctx context.Context, in *TransactionRequest, opts ...grpc.CallOption) (*TransactionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TransactionResponse)
+	err := c.cc.Invoke(ctx, ConnectionPoolService_CommitTransaction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectionPoolServiceClient) RollbackTransaction(ctx context.Context, in *TransactionRequest, opts ...grpc.CallOption) (*TransactionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TransactionResponse)
+	err := c.cc.Invoke(ctx, ConnectionPoolService_RollbackTransaction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectionPoolServiceClient) ListPools(ctx context.Context, in *ListPoolsRequest, opts ...grpc.CallOption) (*ListPoolsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPoolsResponse)
+	err := c.cc.Invoke(ctx, ConnectionPoolService_ListPools_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectionPoolServiceClient) ListLeases(ctx context.Context, in *ListLeasesRequest, opts ...grpc.CallOption) (*ListLeasesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListLeasesResponse)
+	err := c.cc.Invoke(ctx, ConnectionPoolService_ListLeases_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectionPoolServiceClient) DrainTenant(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DrainResponse)
+	err := c.cc.Invoke(ctx, ConnectionPoolService_DrainTenant_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectionPoolServiceClient) EvictTenant(ctx context.Context, in *EvictRequest, opts ...grpc.CallOption) (*EvictResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EvictResponse)
+	err := c.cc.Invoke(ctx, ConnectionPoolService_EvictTenant_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectionPoolServiceClient) ValidateDSN(ctx context.Context, in *ValidateDSNRequest, opts ...grpc.CallOption) (*ValidateDSNResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateDSNResponse)
+	err := c.cc.Invoke(ctx, ConnectionPoolService_ValidateDSN_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ConnectionPoolServiceServer is the server API for ConnectionPoolService service.
 // All implementations must embed UnimplementedConnectionPoolServiceServer
 // for forward compatibility.
@@ -82,8 +288,39 @@ type ConnectionPoolServiceServer interface {
 	GetConnection(context.Context, *ConnectionRequest) (*ConnectionResponse, error)
 	// Release a connection back to the pool
 	ReleaseConnection(context.Context, *ConnectionRelease) (*ReleaseResponse, error)
+	// Extend a lease before it expires
+	RenewLease(context.Context, *RenewLeaseRequest) (*RenewLeaseResponse, error)
+	// Hold a lease for as long as the stream is open. The first message acquires the
+	// lease; pings keep it alive and closing the stream releases it.
+	HoldLease(grpc.BidiStreamingServer[HoldLeaseRequest, HoldLeaseResponse]) error
+	// Get a tenant's pool statistics over a time window, downsampled
+	GetPoolStatsHistory(context.Context, *StatsHistoryRequest) (*StatsHistoryResponse, error)
+	// Stream pool statistics of some or all tenants as they change
+	WatchPoolStats(*WatchPoolStatsRequest, grpc.ServerStreamingServer[WatchPoolStatsResponse]) error
+	// Stream pool lifecycle events, optionally replaying recent ones first
+	WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[PoolEvent]) error
 	// Get pool statistics
 	GetPoolStats(context.Context, *StatsRequest) (*StatsResponse, error)
+	// Run a statement on a leased connection
+	ExecuteQuery(context.Context, *QueryRequest) (*QueryResponse, error)
+	// Start a transaction on a leased connection
+	BeginTransaction(context.Context, *BeginRequest) (*TransactionResponse, error)
+	// Commit the transaction of a leased connection
+	CommitTransaction(context.Context, *TransactionRequest) (*TransactionResponse, error)
+	// Roll back the transaction of a leased connection
+	RollbackTransaction(context.Context, *TransactionRequest) (*TransactionResponse, error)
+	// List the open pools
+	ListPools(context.Context, *ListPoolsRequest) (*ListPoolsResponse, error)
+	// List the longest-held leases of each tenant and who holds them. Admin only.
+	ListLeases(context.Context, *ListLeasesRequest) (*ListLeasesResponse, error)
+	// Stop new leases for a tenant, wait for its leases to be released and close its pools.
+	// Admin only.
+	DrainTenant(context.Context, *DrainRequest) (*DrainResponse, error)
+	// End a tenant's leases straight away and close its pools. Admin only.
+	EvictTenant(context.Context, *EvictRequest) (*EvictResponse, error)
+	// Check that a DSN parses, meets the DSN policy and can be connected to, without
+	// creating a pool
+	ValidateDSN(context.Context, *ValidateDSNRequest) (*ValidateDSNResponse, error)
 	mustEmbedUnimplementedConnectionPoolServiceServer()
 }
 
@@ -100,9 +337,51 @@ func (UnimplementedConnectionPoolServiceServer) GetConnection(context.Context, *
 func (UnimplementedConnectionPoolServiceServer) ReleaseConnection(context.Context, *ConnectionRelease) (*ReleaseResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ReleaseConnection not implemented")
 }
+func (UnimplementedConnectionPoolServiceServer) RenewLease(context.Context, *RenewLeaseRequest) (*RenewLeaseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenewLease not implemented")
+}
+func (UnimplementedConnectionPoolServiceServer) HoldLease(grpc.BidiStreamingServer[HoldLeaseRequest, HoldLeaseResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method HoldLease not implemented")
+}
+func (UnimplementedConnectionPoolServiceServer) GetPoolStatsHistory(context.Context, *StatsHistoryRequest) (*StatsHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPoolStatsHistory not implemented")
+}
+func (UnimplementedConnectionPoolServiceServer) WatchPoolStats(*WatchPoolStatsRequest, grpc.ServerStreamingServer[WatchPoolStatsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchPoolStats not implemented")
+}
+func (UnimplementedConnectionPoolServiceServer) WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[PoolEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
 func (UnimplementedConnectionPoolServiceServer) GetPoolStats(context.Context, *StatsRequest) (*StatsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetPoolStats not implemented")
 }
+func (UnimplementedConnectionPoolServiceServer) ExecuteQuery(context.Context, *QueryRequest) (*QueryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExecuteQuery not implemented")
+}
+func (UnimplementedConnectionPoolServiceServer) BeginTransaction(context.Context, *BeginRequest) (*TransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BeginTransaction not implemented")
+}
+func (UnimplementedConnectionPoolServiceServer) CommitTransaction(context.Context, *TransactionRequest) (*TransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CommitTransaction not implemented")
+}
+func (UnimplementedConnectionPoolServiceServer) RollbackTransaction(context.Context, *TransactionRequest) (*TransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RollbackTransaction not implemented")
+}
+func (UnimplementedConnectionPoolServiceServer) ListPools(context.Context, *ListPoolsRequest) (*ListPoolsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPools not implemented")
+}
+func (UnimplementedConnectionPoolServiceServer) ListLeases(context.Context, *ListLeasesRequest) (*ListLeasesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLeases not implemented")
+}
+func (UnimplementedConnectionPoolServiceServer) DrainTenant(context.Context, *DrainRequest) (*DrainResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DrainTenant not implemented")
+}
+func (UnimplementedConnectionPoolServiceServer) EvictTenant(context.Context, *EvictRequest) (*EvictResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EvictTenant not implemented")
+}
+func (UnimplementedConnectionPoolServiceServer) ValidateDSN(context.Context, *ValidateDSNRequest) (*ValidateDSNResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateDSN not implemented")
+}
 func (UnimplementedConnectionPoolServiceServer) mustEmbedUnimplementedConnectionPoolServiceServer() {}
 func (UnimplementedConnectionPoolServiceServer) testEmbeddedByValue()                               {}
 
@@ -160,6 +439,71 @@ func _ConnectionPoolService_ReleaseConnection_Handler(srv interface{}, ctx conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ConnectionPoolService_RenewLease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenewLeaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectionPoolServiceServer).RenewLease(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConnectionPoolService_RenewLease_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectionPoolServiceServer).RenewLease(ctx, req.(*RenewLeaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConnectionPoolService_HoldLease_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ConnectionPoolServiceServer).HoldLease(&grpc.GenericServerStream[HoldLeaseRequest, HoldLeaseResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConnectionPoolService_HoldLeaseServer = grpc.BidiStreamingServer[HoldLeaseRequest, HoldLeaseResponse]
+
+func _ConnectionPoolService_GetPoolStatsHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectionPoolServiceServer).GetPoolStatsHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConnectionPoolService_GetPoolStatsHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectionPoolServiceServer).GetPoolStatsHistory(ctx, req.(*StatsHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConnectionPoolService_WatchPoolStats_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchPoolStatsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ConnectionPoolServiceServer).WatchPoolStats(m, &grpc.GenericServerStream[WatchPoolStatsRequest, WatchPoolStatsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConnectionPoolService_WatchPoolStatsServer = grpc.ServerStreamingServer[WatchPoolStatsResponse]
+
+func _ConnectionPoolService_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ConnectionPoolServiceServer).WatchEvents(m, &grpc.GenericServerStream[WatchEventsRequest, PoolEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConnectionPoolService_WatchEventsServer = grpc.ServerStreamingServer[PoolEvent]
+
 func _ConnectionPoolService_GetPoolStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(StatsRequest)
 	if err := dec(in); err != nil {
@@ -178,6 +522,168 @@ func _ConnectionPoolService_GetPoolStats_Handler(srv interface{}, ctx context.Co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ConnectionPoolService_ExecuteQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectionPoolServiceServer).ExecuteQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConnectionPoolService_ExecuteQuery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectionPoolServiceServer).ExecuteQuery(ctx, req.(*QueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConnectionPoolService_BeginTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BeginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectionPoolServiceServer).BeginTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConnectionPoolService_BeginTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectionPoolServiceServer).BeginTransaction(ctx, req.(*BeginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConnectionPoolService_CommitTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectionPoolServiceServer).CommitTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConnectionPoolService_CommitTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectionPoolServiceServer).CommitTransaction(ctx, req.(*TransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConnectionPoolService_RollbackTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectionPoolServiceServer).RollbackTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConnectionPoolService_RollbackTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectionPoolServiceServer).RollbackTransaction(ctx, req.(*TransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConnectionPoolService_ListPools_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPoolsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectionPoolServiceServer).ListPools(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConnectionPoolService_ListPools_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectionPoolServiceServer).ListPools(ctx, req.(*ListPoolsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConnectionPoolService_ListLeases_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLeasesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectionPoolServiceServer).ListLeases(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConnectionPoolService_ListLeases_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectionPoolServiceServer).ListLeases(ctx, req.(*ListLeasesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConnectionPoolService_DrainTenant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectionPoolServiceServer).DrainTenant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConnectionPoolService_DrainTenant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectionPoolServiceServer).DrainTenant(ctx, req.(*DrainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConnectionPoolService_EvictTenant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvictRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectionPoolServiceServer).EvictTenant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConnectionPoolService_EvictTenant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectionPoolServiceServer).EvictTenant(ctx, req.(*EvictRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConnectionPoolService_ValidateDSN_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateDSNRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectionPoolServiceServer).ValidateDSN(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConnectionPoolService_ValidateDSN_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectionPoolServiceServer).ValidateDSN(ctx, req.(*ValidateDSNRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ConnectionPoolService_ServiceDesc is the grpc.ServiceDesc for ConnectionPoolService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -193,11 +699,72 @@ var ConnectionPoolService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ReleaseConnection",
 			Handler:    _ConnectionPoolService_ReleaseConnection_Handler,
 		},
+		{
+			MethodName: "RenewLease",
+			Handler:    _ConnectionPoolService_RenewLease_Handler,
+		},
+		{
+			MethodName: "GetPoolStatsHistory",
+			Handler:    _ConnectionPoolService_GetPoolStatsHistory_Handler,
+		},
 		{
 			MethodName: "GetPoolStats",
 			Handler:    _ConnectionPoolService_GetPoolStats_Handler,
 		},
+		{
+			MethodName: "ExecuteQuery",
+			Handler:    _ConnectionPoolService_ExecuteQuery_Handler,
+		},
+		{
+			MethodName: "BeginTransaction",
+			Handler:    _ConnectionPoolService_BeginTransaction_Handler,
+		},
+		{
+			MethodName: "CommitTransaction",
+			Handler:    _ConnectionPoolService_CommitTransaction_Handler,
+		},
+		{
+			MethodName: "RollbackTransaction",
+			Handler:    _ConnectionPoolService_RollbackTransaction_Handler,
+		},
+		{
+			MethodName: "ListPools",
+			Handler:    _ConnectionPoolService_ListPools_Handler,
+		},
+		{
+			MethodName: "ListLeases",
+			Handler:    _ConnectionPoolService_ListLeases_Handler,
+		},
+		{
+			MethodName: "DrainTenant",
+			Handler:    _ConnectionPoolService_DrainTenant_Handler,
+		},
+		{
+			MethodName: "EvictTenant",
+			Handler:    _ConnectionPoolService_EvictTenant_Handler,
+		},
+		{
+			MethodName: "ValidateDSN",
+			Handler:    _ConnectionPoolService_ValidateDSN_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "HoldLease",
+			Handler:       _ConnectionPoolService_HoldLease_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchPoolStats",
+			Handler:       _ConnectionPoolService_WatchPoolStats_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _ConnectionPoolService_WatchEvents_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/grpc/connectionpool/connection_pool.proto",
 }
@@ -0,0 +1,9 @@
+package connectionpool
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI 2.0 document of the REST/JSON gateway, generated from
+// connection_pool.proto by protoc-gen-openapiv2
+//
+//go:embed connection_pool.swagger.json
+var OpenAPI []byte